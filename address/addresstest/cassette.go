@@ -0,0 +1,222 @@
+package addresstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type CassetteMode int
+
+const (
+	// CASSETTE_REPLAY answers from the recording and never touches the
+	// network.
+	CASSETTE_REPLAY CassetteMode = iota
+	// CASSETTE_RECORD sends requests to the real APIs and records them.
+	CASSETTE_RECORD
+)
+
+const REDACTED = "REDACTED"
+
+// SCRUBBED_HEADERS are replaced with REDACTED in recordings, so tokens never
+// end up in fixtures checked into the repository.
+var SCRUBBED_HEADERS = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+
+// RECORDED_RESPONSE_HEADERS are the response headers kept in recordings; the
+// rest (dates, rate limit counters, tracing ids) only adds noise to diffs.
+var RECORDED_RESPONSE_HEADERS = []string{"Content-Type"}
+
+var ErrUnmatchedRequest = errors.New("no recorded interaction")
+
+// cepInPath matches a CEP written with or without its dash.
+var cepInPath = regexp.MustCompile(`\b(\d{5})-?(\d{3})\b`)
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette is an http.RoundTripper that records provider interactions to a
+// JSON file and replays them deterministically. Requests match on method and
+// URL, with any CEP in the URL normalized to its 8 digits. A URL recorded
+// more than once replays its answers in order, repeating the last one.
+type Cassette struct {
+	path string
+	mode CassetteMode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       map[string]int
+}
+
+// NewCassette opens the recording at path. In CASSETTE_REPLAY mode the file
+// must exist; in CASSETTE_RECORD mode requests go through next (nil means
+// http.DefaultTransport) and Save writes them to path.
+func NewCassette(path string, mode CassetteMode, next http.RoundTripper) (*Cassette, error) {
+	cassette := &Cassette{path: path, mode: mode, next: next, played: make(map[string]int)}
+	if mode == CASSETTE_RECORD {
+		if cassette.next == nil {
+			cassette.next = http.DefaultTransport
+		}
+		return cassette, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading cassette: %w", err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("loading cassette %s: %w", path, err)
+	}
+
+	cassette.interactions = file.Interactions
+	return cassette, nil
+}
+
+func (c *Cassette) RoundTrip(request *http.Request) (*http.Response, error) {
+	if c.mode == CASSETTE_RECORD {
+		return c.record(request)
+	}
+
+	return c.replay(request)
+}
+
+func (c *Cassette) record(request *http.Request) (*http.Response, error) {
+	response, err := c.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	for _, name := range RECORDED_RESPONSE_HEADERS {
+		if values := response.Header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: RecordedRequest{
+			Method: request.Method,
+			URL:    normalizeURL(request.URL.String()),
+			Header: scrub(request.Header),
+		},
+		Response: RecordedResponse{
+			Status: response.StatusCode,
+			Header: scrub(header),
+			Body:   string(body),
+		},
+	})
+	c.mu.Unlock()
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+func (c *Cassette) replay(request *http.Request) (*http.Response, error) {
+	method, url := request.Method, normalizeURL(request.URL.String())
+	key := method + " " + url
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var matches []Interaction
+	for _, interaction := range c.interactions {
+		if interaction.Request.Method == method && interaction.Request.URL == url {
+			matches = append(matches, interaction)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w for %s in %s", ErrUnmatchedRequest, key, c.path)
+	}
+
+	played := c.played[key]
+	c.played[key]++
+	recorded := matches[min(played, len(matches)-1)].Response
+
+	return &http.Response{
+		Status:        http.StatusText(recorded.Status),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}, nil
+}
+
+// Save writes the interactions recorded so far to the cassette's path. It
+// does nothing in CASSETTE_REPLAY mode.
+func (c *Cassette) Save() error {
+	if c.mode != CASSETTE_RECORD {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+func normalizeURL(url string) string {
+	return cepInPath.ReplaceAllString(url, "$1$2")
+}
+
+func scrub(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+
+	scrubbed := header.Clone()
+	for _, name := range SCRUBBED_HEADERS {
+		if _, ok := scrubbed[http.CanonicalHeaderKey(name)]; ok {
+			scrubbed[http.CanonicalHeaderKey(name)] = []string{REDACTED}
+		}
+	}
+
+	return scrubbed
+}
@@ -0,0 +1,131 @@
+package addresstest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func roundTrip(t *testing.T, transport http.RoundTripper, url string, header http.Header) (int, string, error) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != nil {
+		request.Header = header
+	}
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(body), nil
+}
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	defer viaCEP.Close()
+	path := filepath.Join(t.TempDir(), "cassettes", "viacep.json")
+
+	recorder, err := addresstest.NewCassette(path, addresstest.CASSETTE_RECORD, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := http.Header{"Authorization": {"Token token=s3cr3t"}, "Accept": {"application/json"}}
+	_, found, err := roundTrip(t, recorder, viaCEP.BaseURL()+"/01001000/json", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, missing, err := roundTrip(t, recorder, viaCEP.BaseURL()+"/99999999/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || !strings.Contains(string(data), addresstest.REDACTED) {
+		t.Errorf("the token was not scrubbed:\n%s", data)
+	}
+
+	viaCEP.Close()
+	player, err := addresstest.NewCassette(path, addresstest.CASSETTE_REPLAY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The CEP matches with or without its dash.
+	for url, want := range map[string]string{
+		viaCEP.BaseURL() + "/01001000/json":  found,
+		viaCEP.BaseURL() + "/01001-000/json": found,
+		viaCEP.BaseURL() + "/99999-999/json": missing,
+	} {
+		status, body, err := roundTrip(t, player, url, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		if status != http.StatusOK || body != want {
+			t.Errorf("%s replayed %d %q, want %q", url, status, body, want)
+		}
+	}
+}
+
+func TestCassetteFailsOnUnmatchedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(path, []byte(`{"interactions": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := addresstest.NewCassette(path, addresstest.CASSETTE_REPLAY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = roundTrip(t, player, "https://viacep.com.br/ws/01001000/json", nil)
+	if !errors.Is(err, addresstest.ErrUnmatchedRequest) || !strings.Contains(err.Error(), "viacep.com.br/ws/01001000/json") {
+		t.Errorf("err = %v, want ErrUnmatchedRequest naming the request", err)
+	}
+}
+
+func TestCassetteReplaysRepeatedRequestsInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flaky.json")
+	recording := `{"interactions": [
+		{"request": {"method": "GET", "url": "https://viacep.com.br/ws/01001000/json"}, "response": {"status": 503, "body": "down"}},
+		{"request": {"method": "GET", "url": "https://viacep.com.br/ws/01001000/json"}, "response": {"status": 200, "body": "up"}}
+	]}`
+	if err := os.WriteFile(path, []byte(recording), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := addresstest.NewCassette(path, addresstest.CASSETTE_REPLAY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"down", "up", "up"} {
+		_, body, err := roundTrip(t, player, "https://viacep.com.br/ws/01001000/json", nil)
+		if err != nil || body != want {
+			t.Errorf("request %d replayed %q (%v), want %q", i+1, body, err, want)
+		}
+	}
+}
+
+func TestCassetteReplayNeedsRecording(t *testing.T) {
+	if _, err := addresstest.NewCassette(filepath.Join(t.TempDir(), "missing.json"), addresstest.CASSETTE_REPLAY, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want the missing file", err)
+	}
+}
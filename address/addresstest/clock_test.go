@@ -0,0 +1,94 @@
+package addresstest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fired(timer interface{ C() <-chan time.Time }) (time.Time, bool) {
+	select {
+	case at := <-timer.C():
+		return at, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeClockFiresDueTimers(t *testing.T) {
+	clock := addresstest.NewFakeClock(epoch)
+	short := clock.NewTimer(10 * time.Millisecond)
+	long := clock.NewTimer(time.Second)
+
+	clock.Advance(9 * time.Millisecond)
+	if _, ok := fired(short); ok {
+		t.Fatal("timer fired early")
+	}
+
+	clock.Advance(time.Millisecond)
+	if at, ok := fired(short); !ok || !at.Equal(epoch.Add(10*time.Millisecond)) {
+		t.Fatalf("timer fired %v at %v, want at 10ms", ok, at)
+	}
+	if _, ok := fired(long); ok {
+		t.Fatal("the long timer fired with the short one")
+	}
+	if clock.Timers() != 1 {
+		t.Errorf("%d timers waiting, want 1", clock.Timers())
+	}
+
+	clock.Advance(time.Hour)
+	if at, ok := fired(long); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("timer fired %v at %v, want at 1s", ok, at)
+	}
+	if now := clock.Now(); !now.Equal(epoch.Add(time.Hour + 10*time.Millisecond)) {
+		t.Errorf("Now = %v", now)
+	}
+}
+
+func TestFakeClockStop(t *testing.T) {
+	clock := addresstest.NewFakeClock(epoch)
+	timer := clock.NewTimer(time.Second)
+
+	if !timer.Stop() {
+		t.Fatal("Stop of a waiting timer returned false")
+	}
+	clock.Advance(time.Hour)
+	if _, ok := fired(timer); ok {
+		t.Error("a stopped timer fired")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a stopped timer returned true")
+	}
+}
+
+func TestFakeClockExpiredTimerFiresAtOnce(t *testing.T) {
+	clock := addresstest.NewFakeClock(epoch)
+
+	if _, ok := fired(clock.NewTimer(0)); !ok {
+		t.Error("a zero timer did not fire")
+	}
+	if clock.Timers() != 0 {
+		t.Errorf("%d timers waiting, want none", clock.Timers())
+	}
+}
+
+func TestFakeClockBlockUntilTimers(t *testing.T) {
+	clock := addresstest.NewFakeClock(epoch)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-clock.NewTimer(time.Minute).C()
+	}()
+
+	clock.BlockUntilTimers(1)
+	clock.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the timer never fired")
+	}
+}
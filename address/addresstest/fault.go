@@ -0,0 +1,209 @@
+// Package addresstest provides helpers for testing code built on the address
+// package without depending on the real providers behaving.
+package addresstest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrConnectionRefused is the network error NetworkError fails with.
+var ErrConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+// Fault describes how one request misbehaves. Latency is applied first and
+// honours the request context. Then Err fails the request, a non-zero
+// Status answers it without reaching the network, and otherwise the request
+// goes on to the next transport.
+type Fault struct {
+	Latency time.Duration
+	Err     error
+	Status  int
+	Header  http.Header
+	Body    string
+	// Cut ends the body with io.ErrUnexpectedEOF after CutAfter bytes, as a
+	// connection dropped mid-response would.
+	Cut      bool
+	CutAfter int
+}
+
+// Latency delays the request by latency and then lets it through.
+func Latency(latency time.Duration) Fault {
+	return Fault{Latency: latency}
+}
+
+// Status answers with status and body.
+func Status(status int, body string) Fault {
+	return Fault{Status: status, Body: body}
+}
+
+// MalformedJSON answers 200 with a body no provider can decode.
+func MalformedJSON() Fault {
+	return Fault{Status: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: `{"cep": "01001-000", "logradouro": `}
+}
+
+// CutAfter answers 200 with body and drops the connection after n bytes.
+func CutAfter(body string, n int) Fault {
+	return Fault{Status: http.StatusOK, Body: body, Cut: true, CutAfter: n}
+}
+
+// NetworkError fails the request as if the host refused the connection.
+func NetworkError() Fault {
+	return Fault{Err: ErrConnectionRefused}
+}
+
+// Pass lets the request through untouched, to script faults on later
+// attempts only.
+func Pass() Fault {
+	return Fault{}
+}
+
+type rule struct {
+	pattern  string
+	faults   []Fault
+	requests int
+}
+
+// FaultTransport is an http.RoundTripper that injects scripted faults. Each
+// rule matches requests whose URL contains its pattern ("viacep.com.br",
+// "/ws/01001000/") and scripts them by attempt number: the first matching
+// request gets the first fault, the second the second fault, and requests
+// past the end of the script go through to Next. Point a service at it with
+// AddressService.SetTransport.
+type FaultTransport struct {
+	// Next serves the requests that are not failed; nil means
+	// http.DefaultTransport.
+	Next http.RoundTripper
+
+	mu    sync.Mutex
+	rules []*rule
+}
+
+func NewFaultTransport(next http.RoundTripper) *FaultTransport {
+	return &FaultTransport{Next: next}
+}
+
+// On scripts faults for the requests matching pattern; an empty pattern
+// matches every request. Rules are tried in the order they were added.
+func (t *FaultTransport) On(pattern string, faults ...Fault) *FaultTransport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = append(t.rules, &rule{pattern: pattern, faults: faults})
+	return t
+}
+
+// Requests reports how many requests matched the rules for pattern so far.
+func (t *FaultTransport) Requests(pattern string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests := 0
+	for _, rule := range t.rules {
+		if rule.pattern == pattern {
+			requests += rule.requests
+		}
+	}
+
+	return requests
+}
+
+func (t *FaultTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	fault, ok := t.next(request.URL.String())
+	if !ok {
+		return t.pass(request)
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+
+	if fault.Status == 0 {
+		return t.pass(request)
+	}
+
+	header := fault.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	// A cut body still announces its full length, like a response whose
+	// connection dropped halfway.
+	var body io.Reader = strings.NewReader(fault.Body)
+	if fault.Cut {
+		body = &cutReader{reader: io.LimitReader(strings.NewReader(fault.Body), int64(fault.CutAfter))}
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(fault.Status),
+		StatusCode:    fault.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(body),
+		ContentLength: int64(len(fault.Body)),
+		Request:       request,
+	}, nil
+}
+
+// next returns the fault scripted for this attempt of the first rule
+// matching url, counting the attempt.
+func (t *FaultTransport) next(url string) (Fault, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rule := range t.rules {
+		if !strings.Contains(url, rule.pattern) {
+			continue
+		}
+
+		rule.requests++
+		if rule.requests > len(rule.faults) {
+			return Fault{}, false
+		}
+
+		return rule.faults[rule.requests-1], true
+	}
+
+	return Fault{}, false
+}
+
+func (t *FaultTransport) pass(request *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return next.RoundTrip(request)
+}
+
+// cutReader reads reader to its end and then fails like a dropped
+// connection.
+type cutReader struct {
+	reader io.Reader
+}
+
+func (r *cutReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
@@ -0,0 +1,151 @@
+package addresstest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// answer is the transport behind the fault transport, answering 200 "ok" and
+// counting what reaches it.
+type answer struct {
+	requests int
+}
+
+func (a *answer) RoundTrip(request *http.Request) (*http.Response, error) {
+	a.requests++
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: request}, nil
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) (*http.Response, error) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport.RoundTrip(request)
+}
+
+func TestFaultTransportScriptsAttempts(t *testing.T) {
+	next := &answer{}
+	transport := addresstest.NewFaultTransport(next).
+		On("/ws/", addresstest.Status(http.StatusServiceUnavailable, "down"), addresstest.Pass(), addresstest.Status(http.StatusBadGateway, ""))
+
+	want := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadGateway, http.StatusOK}
+	for attempt, status := range want {
+		response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt+1, err)
+		}
+		if response.StatusCode != status {
+			t.Errorf("attempt %d: status %d, want %d", attempt+1, response.StatusCode, status)
+		}
+	}
+
+	// Requests no rule matches go through uncounted.
+	if _, err := get(t, transport, "https://brasilapi.com.br/api/cep/v1/01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := transport.Requests("/ws/"); n != 4 {
+		t.Errorf("Requests = %d, want 4", n)
+	}
+	if next.requests != 3 {
+		t.Errorf("%d requests passed through, want 3", next.requests)
+	}
+}
+
+func TestFaultTransportFirstMatchingRuleWins(t *testing.T) {
+	transport := addresstest.NewFaultTransport(&answer{}).
+		On("viacep.com.br/ws/01001000", addresstest.Status(http.StatusTooManyRequests, "")).
+		On("viacep.com.br", addresstest.Status(http.StatusInternalServerError, ""))
+
+	response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+	if err != nil || response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %v (%v), want 429", response, err)
+	}
+	response, err = get(t, transport, "https://viacep.com.br/ws/20040010/json")
+	if err != nil || response.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status %v (%v), want 500", response, err)
+	}
+}
+
+func TestFaultTransportStatusBody(t *testing.T) {
+	transport := addresstest.NewFaultTransport(&answer{}).On("", addresstest.Status(http.StatusNotFound, `{"erro": true}`))
+
+	response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil || string(body) != `{"erro": true}` || response.ContentLength != int64(len(body)) {
+		t.Errorf("body %q (%v), length %d", body, err, response.ContentLength)
+	}
+}
+
+func TestFaultTransportMalformedJSON(t *testing.T) {
+	transport := addresstest.NewFaultTransport(&answer{}).On("", addresstest.MalformedJSON())
+
+	response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err == nil {
+		t.Errorf("malformed JSON decoded to %v", decoded)
+	}
+}
+
+func TestFaultTransportCutAfter(t *testing.T) {
+	transport := addresstest.NewFaultTransport(&answer{}).On("", addresstest.CutAfter(`{"cep": "01001-000"}`, 5))
+
+	response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(response.Body)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || string(body) != `{"cep` {
+		t.Errorf("read %q, %v; want 5 bytes and io.ErrUnexpectedEOF", body, err)
+	}
+}
+
+func TestFaultTransportNetworkError(t *testing.T) {
+	next := &answer{}
+	transport := addresstest.NewFaultTransport(next).On("", addresstest.NetworkError())
+
+	if _, err := get(t, transport, "https://viacep.com.br/ws/01001000/json"); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("err = %v, want a refused connection", err)
+	}
+	if next.requests != 0 {
+		t.Errorf("a failed request reached the network")
+	}
+}
+
+func TestFaultTransportLatencyHonoursContext(t *testing.T) {
+	transport := addresstest.NewFaultTransport(&answer{}).
+		On("", addresstest.Latency(time.Minute), addresstest.Latency(time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://viacep.com.br/ws/01001000/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.RoundTrip(request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the request's deadline", err)
+	}
+
+	// A short latency is waited out and the request goes through.
+	response, err := get(t, transport, "https://viacep.com.br/ws/01001000/json")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("status %v (%v), want 200 after the latency", response, err)
+	}
+}
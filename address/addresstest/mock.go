@@ -0,0 +1,203 @@
+package addresstest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// Outcome is what one call to a MockProvider does: it waits Latency, or
+// until its context is done, and then answers Address or fails with Err.
+type Outcome struct {
+	Address address.AddressResult
+	Err     error
+	Latency time.Duration
+}
+
+// Answer succeeds with result.
+func Answer(result address.AddressResult) Outcome {
+	return Outcome{Address: result}
+}
+
+// Fail fails with err.
+func Fail(err error) Outcome {
+	return Outcome{Err: err}
+}
+
+// MockProvider is an address.Provider for tests that need full control of a
+// provider without an HTTP server:
+//
+//	slow := addresstest.NewMockProvider("Slow").
+//		Returns(result).
+//		SetLatency(time.Second)
+//	service.RegisterProvider(slow)
+//	...
+//	slow.AssertCalledWith(t, "01001000")
+//
+// Like a real provider it gives up as soon as its context is done, with the
+// context's error, so races and timeouts behave as they would in production.
+// Calls follow the script set with Script, one outcome per call, and fall
+// back to Returns or Fails once the script runs out. A provider configured
+// with neither fails with address.ErrNotFound.
+type MockProvider struct {
+	name string
+
+	mu      sync.Mutex
+	clock   address.Clock
+	result  address.AddressResult
+	err     error
+	latency time.Duration
+	script  []Outcome
+	calls   []string
+}
+
+func NewMockProvider(name string) *MockProvider {
+	return &MockProvider{name: name, clock: address.RealClock(), err: address.ErrNotFound}
+}
+
+// Returns makes the calls past the script succeed with result. Its Source
+// defaults to the provider name.
+func (p *MockProvider) Returns(result address.AddressResult) *MockProvider {
+	if result.Source == "" {
+		result.Source = p.name
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.result = result
+	p.err = nil
+	return p
+}
+
+// Fails makes the calls past the script fail with err.
+func (p *MockProvider) Fails(err error) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.result = address.AddressResult{}
+	p.err = err
+	return p
+}
+
+// Script sets the outcomes of the next calls, in order. Outcomes without a
+// Latency use the one from SetLatency.
+func (p *MockProvider) Script(outcomes ...Outcome) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.script = append(p.script, outcomes...)
+	return p
+}
+
+// SetLatency delays every call by latency.
+func (p *MockProvider) SetLatency(latency time.Duration) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.latency = latency
+	return p
+}
+
+// SetClock makes the provider wait on clock, typically a FakeClock shared
+// with the service.
+func (p *MockProvider) SetClock(clock address.Clock) *MockProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clock = clock
+	return p
+}
+
+func (p *MockProvider) Name() string {
+	return p.name
+}
+
+func (p *MockProvider) GetAddress(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+	outcome, clock := p.next(cep)
+
+	if outcome.Latency > 0 {
+		timer := clock.NewTimer(outcome.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return address.AddressResult{}, fmt.Errorf("%s: %w", p.name, ctx.Err())
+		case <-timer.C():
+		}
+	}
+
+	if ctx.Err() != nil {
+		return address.AddressResult{}, fmt.Errorf("%s: %w", p.name, ctx.Err())
+	}
+
+	if outcome.Err != nil {
+		return address.AddressResult{}, fmt.Errorf("%s: %w", p.name, outcome.Err)
+	}
+
+	if outcome.Address.Source == "" {
+		outcome.Address.Source = p.name
+	}
+
+	return outcome.Address, nil
+}
+
+// next records a call for cep and returns its outcome.
+func (p *MockProvider) next(cep string) (Outcome, address.Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls = append(p.calls, cep)
+
+	outcome := Outcome{Address: p.result, Err: p.err}
+	if len(p.script) > 0 {
+		outcome = p.script[0]
+		p.script = p.script[1:]
+	}
+
+	if outcome.Latency == 0 {
+		outcome.Latency = p.latency
+	}
+
+	return outcome, p.clock
+}
+
+// Calls reports how many times the provider was called.
+func (p *MockProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.calls)
+}
+
+// CEPs returns the CEPs the provider was called with, in call order.
+func (p *MockProvider) CEPs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.calls)
+}
+
+// AssertCalledWith fails the test unless the provider was called with cep.
+func (p *MockProvider) AssertCalledWith(t testing.TB, cep string) {
+	t.Helper()
+
+	if calls := p.CEPs(); !slices.Contains(calls, cep) {
+		t.Errorf("%s was not called with %q, calls: %q", p.name, cep, calls)
+	}
+}
+
+// AssertCalls fails the test unless the provider was called exactly n times.
+func (p *MockProvider) AssertCalls(t testing.TB, n int) {
+	t.Helper()
+
+	if calls := p.Calls(); calls != n {
+		t.Errorf("%s was called %d times, want %d", p.name, calls, n)
+	}
+}
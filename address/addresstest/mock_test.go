@@ -0,0 +1,162 @@
+package addresstest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+var sé = address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", City: "São Paulo", State: "SP"}
+
+func call(provider address.Provider, cep string) (address.AddressResult, error) {
+	return provider.GetAddress(context.Background(), http.DefaultClient, cep)
+}
+
+func TestMockProviderDefaultsToNotFound(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock")
+
+	if _, err := call(provider, "01001000"); !errors.Is(err, address.ErrNotFound) || err.Error() != "Mock: not found" {
+		t.Errorf("err = %v, want Mock: not found", err)
+	}
+}
+
+func TestMockProviderReturns(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé)
+
+	result, err := call(provider, "01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Street != sé.Street || result.Source != "Mock" {
+		t.Errorf("result = %+v, want sé from Mock", result)
+	}
+
+	other := sé
+	other.Source = "Elsewhere"
+	if result, _ := call(provider.Returns(other), "01001000"); result.Source != "Elsewhere" {
+		t.Errorf("Source = %q, want the one given", result.Source)
+	}
+}
+
+func TestMockProviderFails(t *testing.T) {
+	down := errors.New("503 from upstream")
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).Fails(down)
+
+	result, err := call(provider, "01001000")
+	if !errors.Is(err, down) || err.Error() != "Mock: 503 from upstream" {
+		t.Errorf("err = %v, want Mock: 503 from upstream", err)
+	}
+	if result.Street != "" {
+		t.Errorf("result = %+v, want none", result)
+	}
+}
+
+func TestMockProviderScript(t *testing.T) {
+	down := errors.New("down")
+	provider := addresstest.NewMockProvider("Mock").
+		Returns(sé).
+		Script(addresstest.Fail(down), addresstest.Answer(address.AddressResult{City: "Rio de Janeiro"}))
+
+	if _, err := call(provider, "1"); !errors.Is(err, down) {
+		t.Errorf("call 1: err = %v, want the scripted failure", err)
+	}
+	if result, err := call(provider, "2"); err != nil || result.City != "Rio de Janeiro" || result.Source != "Mock" {
+		t.Errorf("call 2: %+v (%v), want the scripted answer", result, err)
+	}
+	for _, cep := range []string{"3", "4"} {
+		if result, err := call(provider, cep); err != nil || result.City != sé.City {
+			t.Errorf("call %s: %+v (%v), want the default answer", cep, result, err)
+		}
+	}
+
+	provider.AssertCalls(t, 4)
+	if ceps := fmt.Sprint(provider.CEPs()); ceps != "[1 2 3 4]" {
+		t.Errorf("CEPs = %s", ceps)
+	}
+}
+
+func TestMockProviderLatency(t *testing.T) {
+	clock := addresstest.NewFakeClock(epoch)
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).SetLatency(time.Second).SetClock(clock).
+		Script(addresstest.Outcome{Address: sé, Latency: time.Minute})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := call(provider, "01001000")
+		done <- err
+	}()
+
+	// The scripted latency overrides SetLatency.
+	clock.BlockUntilTimers(1)
+	clock.Advance(time.Second)
+	select {
+	case <-done:
+		t.Fatal("answered before its scripted latency")
+	default:
+	}
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMockProviderHonoursCancellation(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).SetLatency(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := provider.GetAddress(ctx, http.DefaultClient, "01001000")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %v", elapsed)
+	}
+
+	// A context already done is not answered even without latency.
+	cancel()
+	if _, err := addresstest.NewMockProvider("Instant").Returns(sé).GetAddress(ctx, http.DefaultClient, "01001000"); !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's", err)
+	}
+}
+
+// recordingTB records the failures reported to it.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMockProviderAssertions(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé)
+	call(provider, "01001000")
+
+	recorder := &recordingTB{TB: t}
+	provider.AssertCalledWith(recorder, "01001000")
+	provider.AssertCalls(recorder, 1)
+	if len(recorder.failures) != 0 {
+		t.Fatalf("passing assertions failed: %q", recorder.failures)
+	}
+
+	provider.AssertCalledWith(recorder, "20040010")
+	provider.AssertCalls(recorder, 2)
+	want := []string{
+		`Mock was not called with "20040010", calls: ["01001000"]`,
+		`Mock was called 1 times, want 2`,
+	}
+	if fmt.Sprint(recorder.failures) != fmt.Sprint(want) {
+		t.Errorf("failures = %q, want %q", recorder.failures, want)
+	}
+}
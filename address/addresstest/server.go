@@ -0,0 +1,263 @@
+package addresstest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// Server is an in-process fake of one provider API, answering from fixtures
+// keyed by CEP in the exact wire format of the real API.
+type Server struct {
+	*httptest.Server
+
+	basePath string
+	fixtures map[string]address.AddressResult
+
+	mu       sync.Mutex
+	latency  time.Duration
+	requests int
+}
+
+// NewViaCEPServer fakes ViaCEP: GET /ws/{cep}/json answers the fixture, or
+// 200 with {"erro": "true"} for unknown CEPs and 400 for malformed ones.
+func NewViaCEPServer(fixtures map[string]address.AddressResult) *Server {
+	s := newServer("/ws", fixtures)
+	s.Server = httptest.NewServer(s.count(http.HandlerFunc(s.serveViaCEP)))
+	return s
+}
+
+// NewBrasilAPIServer fakes BrasilAPI: GET /api/cep/v1/{cep} answers the
+// fixture, or the CepPromiseError JSON with 404 for unknown CEPs and 400 for
+// malformed ones.
+func NewBrasilAPIServer(fixtures map[string]address.AddressResult) *Server {
+	s := newServer("/api/cep/v1", fixtures)
+	s.Server = httptest.NewServer(s.count(http.HandlerFunc(s.serveBrasilAPI)))
+	return s
+}
+
+func newServer(basePath string, fixtures map[string]address.AddressResult) *Server {
+	normalized := make(map[string]address.AddressResult, len(fixtures))
+	for cep, fixture := range fixtures {
+		if digits, err := address.NormalizeCEP(cep); err == nil {
+			cep = digits
+		}
+		normalized[cep] = fixture
+	}
+
+	return &Server{basePath: basePath, fixtures: normalized}
+}
+
+// BaseURL is the base URL to build the matching provider with.
+func (s *Server) BaseURL() string {
+	return s.URL + s.basePath
+}
+
+// SetLatency delays every answer by latency, or until the client gives up.
+func (s *Server) SetLatency(latency time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+	return s
+}
+
+// Requests reports how many requests the server received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		latency := s.latency
+		s.mu.Unlock()
+
+		if latency > 0 {
+			timer := time.NewTimer(latency)
+			defer timer.Stop()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-timer.C:
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lookup splits the CEP off path and reports whether it is well formed, the
+// way the real APIs validate it: exactly 8 digits.
+func (s *Server) lookup(path string) (cep string, fixture address.AddressResult, valid bool, found bool) {
+	cep = strings.TrimPrefix(path, s.basePath+"/")
+	if len(cep) != 8 || strings.Trim(cep, "0123456789") != "" {
+		return cep, address.AddressResult{}, false, false
+	}
+
+	fixture, found = s.fixtures[cep]
+	return cep, fixture, true, found
+}
+
+func (s *Server) serveViaCEP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/json")
+	if r.Method != http.MethodGet || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	cep, fixture, valid, found := s.lookup(path)
+	if !valid {
+		// ViaCEP answers malformed CEPs with an HTML error page.
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("<h3>Http 400</h3><p>Verifique a URL</p>"))
+		return
+	}
+
+	if !found {
+		writeFakeJSON(w, http.StatusOK, map[string]string{"erro": "true"})
+		return
+	}
+
+	stateName, _ := address.StateName(fixture.State)
+	writeFakeJSON(w, http.StatusOK, viaCEPBody{
+		CEP:          cep[:5] + "-" + cep[5:],
+		Street:       fixture.Street,
+		Neighborhood: fixture.Neighborhood,
+		City:         fixture.City,
+		State:        fixture.State,
+		StateName:    stateName,
+		Region:       regions[fixture.State],
+	})
+}
+
+// regions are the regions ViaCEP names for each UF.
+var regions = map[string]string{
+	"AC": "Norte", "AP": "Norte", "AM": "Norte", "PA": "Norte", "RO": "Norte", "RR": "Norte", "TO": "Norte",
+	"AL": "Nordeste", "BA": "Nordeste", "CE": "Nordeste", "MA": "Nordeste", "PB": "Nordeste",
+	"PE": "Nordeste", "PI": "Nordeste", "RN": "Nordeste", "SE": "Nordeste",
+	"DF": "Centro-Oeste", "GO": "Centro-Oeste", "MT": "Centro-Oeste", "MS": "Centro-Oeste",
+	"ES": "Sudeste", "MG": "Sudeste", "RJ": "Sudeste", "SP": "Sudeste",
+	"PR": "Sul", "RS": "Sul", "SC": "Sul",
+}
+
+// viaCEPBody keeps the field order of ViaCEP's answers.
+type viaCEPBody struct {
+	CEP          string `json:"cep"`
+	Street       string `json:"logradouro"`
+	Complement   string `json:"complemento"`
+	Unit         string `json:"unidade"`
+	Neighborhood string `json:"bairro"`
+	City         string `json:"localidade"`
+	State        string `json:"uf"`
+	StateName    string `json:"estado"`
+	Region       string `json:"regiao"`
+	IBGE         string `json:"ibge"`
+	GIA          string `json:"gia"`
+	DDD          string `json:"ddd"`
+	SIAFI        string `json:"siafi"`
+}
+
+type brasilAPIError struct {
+	Name    string                 `json:"name"`
+	Message string                 `json:"message"`
+	Type    string                 `json:"type"`
+	Errors  []brasilAPIErrorDetail `json:"errors"`
+}
+
+type brasilAPIErrorDetail struct {
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+	Service string `json:"service"`
+}
+
+func (s *Server) serveBrasilAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, s.basePath+"/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	cep, fixture, valid, found := s.lookup(r.URL.Path)
+	if !valid {
+		writeFakeJSON(w, http.StatusBadRequest, brasilAPIError{
+			Name:    "CepPromiseError",
+			Message: "CEP deve conter exatamente 8 caracteres.",
+			Type:    "validation_error",
+			Errors:  []brasilAPIErrorDetail{{Message: "CEP informado possui mais ou menos do que 8 caracteres.", Service: "cep_validation"}},
+		})
+		return
+	}
+
+	if !found {
+		writeFakeJSON(w, http.StatusNotFound, brasilAPIError{
+			Name:    "CepPromiseError",
+			Message: "Todos os serviços de CEP retornaram erro.",
+			Type:    "service_error",
+			Errors: []brasilAPIErrorDetail{
+				{Name: "ServiceError", Message: "CEP INVÁLIDO", Service: "correios"},
+				{Name: "ServiceError", Message: "CEP não encontrado na base do ViaCEP.", Service: "viacep"},
+			},
+		})
+		return
+	}
+
+	writeFakeJSON(w, http.StatusOK, brasilAPIBody{
+		CEP:          cep,
+		State:        fixture.State,
+		City:         fixture.City,
+		Neighborhood: fixture.Neighborhood,
+		Street:       fixture.Street,
+		Service:      "open-cep",
+	})
+}
+
+type brasilAPIBody struct {
+	CEP          string `json:"cep"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	Street       string `json:"street"`
+	Service      string `json:"service"`
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// NewService returns an AddressService racing fake ViaCEP and BrasilAPI
+// servers that both answer from fixtures. The servers and the service are
+// closed when the test ends.
+func NewService(t testing.TB, fixtures map[string]address.AddressResult) *address.AddressService {
+	t.Helper()
+
+	viaCEP := NewViaCEPServer(fixtures)
+	brasilAPI := NewBrasilAPIServer(fixtures)
+
+	service := address.NewAddressService(context.Background()).
+		SetLogger(address.NopLogger()).
+		RegisterProvider(address.NewViaCEPProvider(viaCEP.BaseURL())).
+		RegisterProvider(address.NewBrasilAPIProvider(brasilAPI.BaseURL()))
+
+	t.Cleanup(func() {
+		service.Close()
+		viaCEP.Close()
+		brasilAPI.Close()
+	})
+
+	return service
+}
@@ -0,0 +1,225 @@
+package addresstest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+var fixtures = map[string]address.AddressResult{
+	"01001-000": {ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+}
+
+// shape describes the JSON in data by its keys, in order, and the types of
+// their values, so two answers compare alike when only the values differ.
+func shape(t *testing.T, data []byte) string {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var b strings.Builder
+	var walk func() error
+	walk = func() error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case json.Delim:
+			b.WriteRune(rune(token))
+			for decoder.More() {
+				if token == '{' {
+					key, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(&b, "%s:", key)
+				}
+				if err := walk(); err != nil {
+					return err
+				}
+				b.WriteByte(',')
+			}
+			closing, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			b.WriteRune(rune(closing.(json.Delim)))
+		default:
+			fmt.Fprintf(&b, "%T", token)
+		}
+		return nil
+	}
+	if err := walk(); err != nil {
+		t.Fatalf("%v in %s", err, data)
+	}
+	return b.String()
+}
+
+func fetch(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := response.Header.Get("Content-Type"); response.StatusCode != http.StatusBadRequest && !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("%s: Content-Type %q", url, contentType)
+	}
+	return response.StatusCode, body
+}
+
+// The recorded answers under testdata were captured from the real APIs.
+func TestServersMatchRecordedAnswers(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	defer viaCEP.Close()
+	brasilAPI := addresstest.NewBrasilAPIServer(fixtures)
+	defer brasilAPI.Close()
+
+	tests := []struct {
+		recorded string
+		url      string
+		status   int
+		// same are the keys whose values the fake reproduces.
+		same []string
+	}{
+		{"viacep_01001000.json", viaCEP.BaseURL() + "/01001000/json", http.StatusOK, []string{"cep", "logradouro", "bairro", "localidade", "uf", "estado", "regiao"}},
+		{"viacep_99999999.json", viaCEP.BaseURL() + "/99999999/json", http.StatusOK, []string{"erro"}},
+		{"brasilapi_01001000.json", brasilAPI.BaseURL() + "/01001000", http.StatusOK, []string{"cep", "state", "city", "neighborhood", "street", "service"}},
+		{"brasilapi_99999999.json", brasilAPI.BaseURL() + "/99999999", http.StatusNotFound, []string{"name", "message", "type", "errors"}},
+		{"brasilapi_1234.json", brasilAPI.BaseURL() + "/1234", http.StatusBadRequest, []string{"name", "message", "type", "errors"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.recorded, func(t *testing.T) {
+			recorded, err := os.ReadFile(filepath.Join("testdata", test.recorded))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, body := fetch(t, test.url)
+			if status != test.status {
+				t.Fatalf("status %d, want %d", status, test.status)
+			}
+			if got, want := shape(t, body), shape(t, recorded); got != want {
+				t.Errorf("answer shaped\n%s\nwant\n%s", got, want)
+			}
+
+			var got, want map[string]json.RawMessage
+			json.Unmarshal(body, &got)
+			json.Unmarshal(recorded, &want)
+			for _, key := range test.same {
+				var a, b any
+				json.Unmarshal(got[key], &a)
+				json.Unmarshal(want[key], &b)
+				if fmt.Sprint(a) != fmt.Sprint(b) {
+					t.Errorf("%s = %s, want %s", key, got[key], want[key])
+				}
+			}
+		})
+	}
+}
+
+func TestViaCEPServerRejectsMalformedCEP(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	defer viaCEP.Close()
+
+	response, err := http.Get(viaCEP.BaseURL() + "/0100100/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest || !strings.HasPrefix(response.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("status %d, %s; want ViaCEP's HTML 400", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+}
+
+// The providers map the fakes' answers as they map the recorded ones.
+func TestProvidersDecodeServersLikeRecordedAnswers(t *testing.T) {
+	replay := func(name string) *http.Client {
+		recorded, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Client{Transport: addresstest.NewFaultTransport(nil).On("", addresstest.Status(http.StatusOK, string(recorded)))}
+	}
+
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	defer viaCEP.Close()
+	brasilAPI := addresstest.NewBrasilAPIServer(fixtures)
+	defer brasilAPI.Close()
+
+	tests := []struct {
+		provider address.Provider
+		recorded string
+	}{
+		{address.NewViaCEPProvider(viaCEP.BaseURL()), "viacep_01001000.json"},
+		{address.NewBrasilAPIProvider(brasilAPI.BaseURL()), "brasilapi_01001000.json"},
+	}
+	for _, test := range tests {
+		fake, err := test.provider.GetAddress(context.Background(), http.DefaultClient, "01001000")
+		if err != nil {
+			t.Fatalf("%s: %v", test.provider.Name(), err)
+		}
+		real, err := test.provider.GetAddress(context.Background(), replay(test.recorded), "01001000")
+		if err != nil {
+			t.Fatalf("%s on the recorded answer: %v", test.provider.Name(), err)
+		}
+		if diffs := address.Diff(fake, real); len(diffs) != 0 {
+			t.Errorf("%s maps the fake differently: %v", test.provider.Name(), diffs)
+		}
+	}
+}
+
+func TestServerLatencyAndRequests(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(fixtures).SetLatency(20 * time.Millisecond)
+	defer viaCEP.Close()
+
+	start := time.Now()
+	fetch(t, viaCEP.BaseURL()+"/01001000/json")
+	fetch(t, viaCEP.BaseURL()+"/01001000/json")
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("two answers took %v, want at least 40ms", elapsed)
+	}
+	if n := viaCEP.Requests(); n != 2 {
+		t.Errorf("Requests = %d, want 2", n)
+	}
+}
+
+func TestNewService(t *testing.T) {
+	service := addresstest.NewService(t, fixtures)
+
+	results, err := service.ExecuteAll("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("%d results, want ViaCEP and BrasilAPI", len(results))
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Address.Street != "Praça da Sé" || result.Address.Source != result.Provider {
+			t.Errorf("%s: %+v (%v)", result.Provider, result.Address, result.Err)
+		}
+	}
+
+	if _, err := service.Execute("99999999"); !errors.Is(err, address.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,120 @@
+package address
+
+import (
+	"context"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/race"
+)
+
+type ProviderResult struct {
+	Provider string
+	Address  AddressResult
+	Err      error
+	Latency  time.Duration
+}
+
+// ExecuteAll queries every enabled provider and waits for all of them, up to
+// the service timeout, instead of returning the first answer. Providers that
+// have not answered by then are reported with ErrTimeout; like Execute it
+// returns on time even when a provider ignores its context. Options, such as
+// WithoutProviders, apply to this call only.
+func (s *AddressService) ExecuteAll(cep string, options ...CallOption) ([]ProviderResult, error) {
+	config, err := s.callSettings(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.executeAll(context.Background(), config, cep)
+}
+
+func (s *AddressService) executeAll(parent context.Context, config settings, cep string) ([]ProviderResult, error) {
+	cep, err := NormalizeCEP(cep)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	stop := context.AfterFunc(s.ctx, func() { cancel(s.ctx.Err()) })
+	defer stop()
+
+	timeout := config.clock.NewTimer(config.timeout)
+	defer timeout.Stop()
+
+	start := config.clock.Now()
+	recorder := newRecorder(config.clock, false, config.raw, config.schemaOff)
+	results := make([]ProviderResult, len(config.providers))
+	answered := make([]bool, len(config.providers))
+	lookups := make([]func(context.Context) (ProviderResult, error), len(config.providers))
+
+	for i, provider := range config.providers {
+		results[i] = ProviderResult{Provider: provider.Name(), Err: ErrTimeout}
+
+		lookups[i] = func(ctx context.Context) (ProviderResult, error) {
+			response := s.getAddress(ctx, config, recorder, provider, cep)
+			return ProviderResult{
+				Provider: provider.Name(),
+				Address:  response.address,
+				Err:      response.err,
+				Latency:  config.clock.Now().Sub(start),
+			}, response.err
+		}
+	}
+	// abandon gives every provider still running err, once the lookup
+	// stopped waiting for them.
+	abandon := func(err error) []ProviderResult {
+		for i := range results {
+			if !answered[i] {
+				results[i].Err = err
+				results[i].Latency = config.clock.Now().Sub(start)
+			}
+		}
+		return scoreResults(results)
+	}
+
+	contest := race.Race[ProviderResult]{
+		Deadline: timeout.C(),
+		Observe: func(answer race.Result[ProviderResult]) {
+			results[answer.Index] = answer.Value
+			answered[answer.Index] = true
+		},
+	}
+
+	// ctx is done once parent or the service is, with the cause to report.
+	err = contest.All(ctx, lookups...)
+	switch {
+	case err == nil:
+		return scoreResults(results), nil
+	case contest.Expired():
+		cancel(ErrTimeout)
+		return abandon(ErrTimeout), nil
+	default:
+		return abandon(err), nil
+	}
+}
+
+// scoreResults sets the Confidence of every successful result against the
+// others.
+func scoreResults(results []ProviderResult) []ProviderResult {
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+
+		var others []AddressResult
+		for j, other := range results {
+			if j != i && other.Err == nil {
+				others = append(others, other.Address)
+			}
+		}
+		results[i].Address = scoreConfidence(results[i].Address, others)
+	}
+
+	return results
+}
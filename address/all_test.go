@@ -0,0 +1,52 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+var sé = address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", City: "São Paulo", State: "SP"}
+
+// newService returns a quiet service racing providers, and only them.
+func newService(t *testing.T, providers ...address.Provider) *address.AddressService {
+	t.Helper()
+
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		service.RegisterProvider(provider)
+		names[i] = provider.Name()
+	}
+	if err := service.SetProviders(names...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+
+	return service
+}
+
+func TestExecuteAllReportsCloseAsCancellation(t *testing.T) {
+	for run := 0; run < 20; run++ {
+		var providers []address.Provider
+		for _, name := range []string{"A", "B", "C", "D"} {
+			providers = append(providers, addresstest.NewMockProvider(name).Returns(sé).SetLatency(5*time.Second))
+		}
+		service := newService(t, providers...).SetTimeout(5 * time.Second)
+		time.AfterFunc(20*time.Millisecond, service.Close)
+
+		results, err := service.ExecuteAll("01001000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if result.Provider == "" || !errors.Is(result.Err, context.Canceled) {
+				t.Fatalf("run %d: result %+v, want a named provider cancelled", run, result)
+			}
+		}
+	}
+}
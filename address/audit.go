@@ -0,0 +1,430 @@
+package address
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DEFAULT_AUDIT_QUEUE_SIZE is how many audit entries wait for the
+// AuditWriter before new ones are dropped.
+const DEFAULT_AUDIT_QUEUE_SIZE = 1024
+
+const (
+	AUDIT_OK               = "ok"
+	AUDIT_INVALID_CEP      = "invalid_cep"
+	AUDIT_NOT_FOUND        = "not_found"
+	AUDIT_TIMEOUT          = "timeout"
+	AUDIT_CANCELLED        = "cancelled"
+	AUDIT_PROVIDERS_FAILED = "providers_failed"
+	AUDIT_ERROR            = "error"
+)
+
+// AuditEntry records one lookup.
+type AuditEntry struct {
+	// Time is when the lookup started.
+	Time      time.Time
+	RequestID string
+	// CEP is masked, as MaskCEPs does, when SetLogRedaction is on.
+	CEP string
+	// Outcome is one of the AUDIT_ constants.
+	Outcome string
+	// Provider is the provider that answered, empty when none did or the
+	// answer came from the cache.
+	Provider string
+	Cached   bool
+	Latency  time.Duration
+	Error    string
+}
+
+// AuditWriter stores audit entries. Write is only ever called from one
+// goroutine at a time. A writer that has a Flush() error method is flushed
+// by FlushAudit and Close, and one that is an io.Closer is closed once the
+// service is done with it.
+type AuditWriter interface {
+	Write(ctx context.Context, entry AuditEntry) error
+}
+
+// SetAuditWriter makes every lookup, cached or not, failed or not, write an
+// AuditEntry to writer, or stops auditing when writer is nil. Entries go
+// through a queue of DEFAULT_AUDIT_QUEUE_SIZE and are written on their own
+// goroutine, so a slow writer never delays a lookup; entries that find the
+// queue full are dropped and counted by AuditDropped. Write errors are
+// logged. The service takes writer over: Close, or replacing it, writes what
+// is still queued, then flushes and closes it.
+func (s *AddressService) SetAuditWriter(writer AuditWriter) *AddressService {
+	var queue *auditQueue
+	if writer != nil {
+		queue = newAuditQueue(writer, DEFAULT_AUDIT_QUEUE_SIZE, &s.auditDrops)
+	}
+
+	s.mu.Lock()
+	previous := s.audit
+	s.audit = queue
+	logger := s.logOutput()
+	s.mu.Unlock()
+
+	previous.close(logger)
+	return s
+}
+
+// AuditDropped returns how many audit entries were dropped because the queue
+// was full.
+func (s *AddressService) AuditDropped() uint64 {
+	return s.auditDrops.Load()
+}
+
+// FlushAudit waits until every audit entry queued so far is written, then
+// flushes the AuditWriter.
+func (s *AddressService) FlushAudit() {
+	s.mu.RLock()
+	queue := s.audit
+	logger := s.logOutput()
+	s.mu.RUnlock()
+
+	queue.flush(logger)
+}
+
+// auditLookup queues the entry of a lookup that started at start.
+func (config settings) auditLookup(ctx context.Context, start time.Time, cep string, report *Report, err error) {
+	if config.audit == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:      start,
+		RequestID: RequestID(ctx),
+		CEP:       cep,
+		Outcome:   auditOutcome(err),
+	}
+	if report != nil {
+		entry.Provider = report.Winner
+		entry.Cached = report.Cached
+		entry.Latency = report.Duration
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if config.redact {
+		entry.CEP = MaskCEPs(entry.CEP)
+		entry.Error = MaskCEPs(entry.Error)
+	}
+
+	// The entry is written after the lookup returned, when ctx may be
+	// cancelled; the writer still gets its values.
+	config.audit.push(context.WithoutCancel(ctx), entry, config.logger)
+}
+
+func auditOutcome(err error) string {
+	switch {
+	case err == nil:
+		return AUDIT_OK
+	case errors.Is(err, ErrInvalidCEP):
+		return AUDIT_INVALID_CEP
+	case errors.Is(err, ErrNotFound):
+		return AUDIT_NOT_FOUND
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return AUDIT_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		return AUDIT_CANCELLED
+	case errors.Is(err, ErrAllProvidersFailed):
+		return AUDIT_PROVIDERS_FAILED
+	}
+
+	return AUDIT_ERROR
+}
+
+type auditItem struct {
+	ctx    context.Context
+	entry  AuditEntry
+	logger *slog.Logger
+	// flushed, when set, makes the item a flush request rather than an
+	// entry; it is closed once everything queued before it is written.
+	flushed chan struct{}
+}
+
+// auditQueue feeds an AuditWriter from a single goroutine. A nil queue does
+// nothing.
+type auditQueue struct {
+	mu      sync.RWMutex
+	closed  bool
+	items   chan auditItem
+	done    chan struct{}
+	writer  AuditWriter
+	dropped *atomic.Uint64
+}
+
+func newAuditQueue(writer AuditWriter, size int, dropped *atomic.Uint64) *auditQueue {
+	q := &auditQueue{
+		items:   make(chan auditItem, size),
+		done:    make(chan struct{}),
+		writer:  writer,
+		dropped: dropped,
+	}
+	go q.run()
+	return q
+}
+
+func (q *auditQueue) run() {
+	defer close(q.done)
+
+	for item := range q.items {
+		if item.flushed != nil {
+			q.flushWriter(item.logger)
+			close(item.flushed)
+			continue
+		}
+
+		if err := q.writer.Write(item.ctx, item.entry); err != nil {
+			item.logger.Warn("audit write failed", "cep", item.entry.CEP, "error", err)
+		}
+	}
+}
+
+func (q *auditQueue) flushWriter(logger *slog.Logger) {
+	if flusher, ok := q.writer.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			logger.Warn("audit flush failed", "error", err)
+		}
+	}
+}
+
+func (q *auditQueue) push(ctx context.Context, entry AuditEntry, logger *slog.Logger) {
+	if q == nil {
+		return
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+
+	select {
+	case q.items <- auditItem{ctx: ctx, entry: entry, logger: logger}:
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+func (q *auditQueue) flush(logger *slog.Logger) {
+	if q == nil {
+		return
+	}
+
+	flushed := make(chan struct{})
+
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return
+	}
+	// Unlike an entry, a flush request waits for room in the queue.
+	q.items <- auditItem{logger: logger, flushed: flushed}
+	q.mu.RUnlock()
+
+	<-flushed
+}
+
+// close writes what is still queued, then flushes and closes the writer.
+func (q *auditQueue) close(logger *slog.Logger) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.items)
+	q.mu.Unlock()
+
+	<-q.done
+	q.flushWriter(logger)
+	if closer, ok := q.writer.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Warn("audit close failed", "error", err)
+		}
+	}
+}
+
+// auditLine is how the JSONL audit writers encode an entry.
+type auditLine struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	CEP       string    `json:"cep"`
+	Outcome   string    `json:"outcome"`
+	Provider  string    `json:"provider,omitempty"`
+	Cached    bool      `json:"cached,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func marshalAuditLine(entry AuditEntry) ([]byte, error) {
+	line, err := json.Marshal(auditLine{
+		Time:      entry.Time,
+		RequestID: entry.RequestID,
+		CEP:       entry.CEP,
+		Outcome:   entry.Outcome,
+		Provider:  entry.Provider,
+		Cached:    entry.Cached,
+		LatencyMS: entry.Latency.Milliseconds(),
+		Error:     entry.Error,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+type ioAuditWriter struct {
+	w io.Writer
+}
+
+// NewIOAuditWriter returns an AuditWriter writing every entry to w as one
+// line of JSON.
+func NewIOAuditWriter(w io.Writer) AuditWriter {
+	return ioAuditWriter{w: w}
+}
+
+func (a ioAuditWriter) Write(ctx context.Context, entry AuditEntry) error {
+	line, err := marshalAuditLine(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.w.Write(line)
+	return err
+}
+
+// FileAuditWriter writes audit entries to a file as JSON lines, rotating it
+// by size.
+type FileAuditWriter struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+// NewFileAuditWriter appends audit entries to the file at path, creating it
+// readable by its owner only. Once an entry would take the file past
+// maxSize bytes it is renamed to path.1, path.1 to path.2 and so on up to
+// path.<backups>, the oldest being removed, and a new file is started. A
+// maxSize of 0 or less never rotates.
+func NewFileAuditWriter(path string, maxSize int64, backups int) (*FileAuditWriter, error) {
+	w := &FileAuditWriter{path: path, maxSize: maxSize, backups: max(backups, 0)}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *FileAuditWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file, w.size = file, info.Size()
+	return nil
+}
+
+func (w *FileAuditWriter) Write(ctx context.Context, entry AuditEntry) error {
+	line, err := marshalAuditLine(entry)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotating %s: %w", w.path, err)
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+// rotate moves the current file out of the way and opens a new one. When
+// that fails, writing goes on at the end of whatever file is at path.
+func (w *FileAuditWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err == nil {
+		err = w.shift()
+	}
+
+	if openErr := w.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+func (w *FileAuditWriter) shift() error {
+	if w.backups == 0 {
+		return os.Remove(w.path)
+	}
+
+	for i := w.backups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(w.path, w.path+".1")
+}
+
+// Flush commits the file to disk.
+func (w *FileAuditWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Sync()
+}
+
+func (w *FileAuditWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,183 @@
+package address_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// auditRecorder keeps the entries it is given and counts flushes and
+// closes.
+type auditRecorder struct {
+	mu      sync.Mutex
+	entries []address.AuditEntry
+	flushes int
+	closed  bool
+}
+
+func (r *auditRecorder) Write(ctx context.Context, entry address.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *auditRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushes++
+	return nil
+}
+
+func (r *auditRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return nil
+}
+
+func TestAuditRecordsEveryLookup(t *testing.T) {
+	recorder := &auditRecorder{}
+	service := newService(t,
+		addresstest.NewMockProvider("A").Script(
+			addresstest.Answer(sé),
+			addresstest.Fail(address.ErrNotFound),
+			addresstest.Fail(errors.New("503 from upstream")),
+		),
+	).SetCache(address.NewMemoryCache(time.Hour)).SetAuditWriter(recorder)
+
+	service.Execute("01001-000")
+	service.Execute("01001000")
+	service.Execute("123")
+	service.Execute("20040010")
+	service.Execute("88010400")
+	service.FlushAudit()
+
+	want := []address.AuditEntry{
+		{CEP: "01001000", Outcome: address.AUDIT_OK, Provider: "A"},
+		{CEP: "01001000", Outcome: address.AUDIT_OK, Cached: true},
+		{CEP: "123", Outcome: address.AUDIT_INVALID_CEP},
+		{CEP: "20040010", Outcome: address.AUDIT_NOT_FOUND},
+		{CEP: "88010400", Outcome: address.AUDIT_PROVIDERS_FAILED},
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.entries) != len(want) {
+		t.Fatalf("audited %d lookups, want %d: %+v", len(recorder.entries), len(want), recorder.entries)
+	}
+	for i, entry := range recorder.entries {
+		if entry.CEP != want[i].CEP || entry.Outcome != want[i].Outcome || entry.Provider != want[i].Provider || entry.Cached != want[i].Cached {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+		if entry.Time.IsZero() || (entry.Outcome == address.AUDIT_OK) != (entry.Error == "") {
+			t.Errorf("entry %d = %+v, want a time and an error only on failure", i, entry)
+		}
+	}
+	if recorder.flushes != 1 {
+		t.Errorf("flushed %d times, want once", recorder.flushes)
+	}
+}
+
+func TestAuditWriterIsClosedWithTheService(t *testing.T) {
+	recorder := &auditRecorder{}
+	service := newService(t, addresstest.NewMockProvider("A").Returns(sé)).SetAuditWriter(recorder)
+
+	service.Execute("01001000")
+	service.Close()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.entries) != 1 || !recorder.closed {
+		t.Errorf("after Close: %d entries, closed %t, want the queued entry written and the writer closed", len(recorder.entries), recorder.closed)
+	}
+}
+
+func TestAuditRedaction(t *testing.T) {
+	recorder := &auditRecorder{}
+	service := newService(t, addresstest.NewMockProvider("A").Returns(sé)).
+		SetLogRedaction(true).
+		SetAuditWriter(recorder)
+
+	service.Execute("01001000")
+	service.FlushAudit()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.entries) != 1 || recorder.entries[0].CEP != "01001***" {
+		t.Errorf("entries = %+v, want the CEP masked", recorder.entries)
+	}
+}
+
+func TestFileAuditWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	entry := address.AuditEntry{CEP: "01001000", Outcome: address.AUDIT_OK, Provider: "ViaCEP"}
+
+	writer, err := address.NewFileAuditWriter(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each line is about 100 bytes, so every other one rotates.
+	for range 7 {
+		if err := writer.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"audit.jsonl", "audit.jsonl.1", "audit.jsonl.2"} {
+		file, err := os.Open(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		lines := 0
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var line struct {
+				CEP     string `json:"cep"`
+				Outcome string `json:"outcome"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.CEP != entry.CEP || line.Outcome != entry.Outcome {
+				t.Errorf("%s: line %q, want the entry as JSON", name, scanner.Text())
+			}
+			lines++
+		}
+		if lines == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s.3 exists, want at most 2 backups", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode %v, want 0600", perm)
+	}
+
+	if err := writer.Write(context.Background(), entry); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write after Close: %v, want os.ErrClosed", err)
+	}
+}
@@ -0,0 +1,165 @@
+package address
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type BatchResult struct {
+	Index   int
+	CEP     string
+	Address AddressResult
+	Err     error
+	Latency time.Duration
+	Report  *Report
+
+	// wait is how long the lookup waited for the rate limit.
+	wait time.Duration
+}
+
+func (s *AddressService) ExecuteBatch(ceps []string) []BatchResult {
+	return s.ExecuteBatchContext(context.Background(), ceps)
+}
+
+// ExecuteBatchContext is ExecuteBatch bounded by ctx: once ctx is done the
+// lookups in flight are abandoned and every CEP without a result gets ctx's
+// error.
+func (s *AddressService) ExecuteBatchContext(ctx context.Context, ceps []string) []BatchResult {
+	results := make([]BatchResult, len(ceps))
+	resolved := make([]bool, len(ceps))
+	for result := range s.StreamBatchContext(ctx, ceps) {
+		results[result.Index] = result
+		resolved[result.Index] = true
+	}
+
+	for i, cep := range ceps {
+		if !resolved[i] {
+			results[i] = BatchResult{Index: i, CEP: cep, Err: ctx.Err()}
+		}
+	}
+
+	return results
+}
+
+// StreamBatchContext resolves ceps like ExecuteBatchContext but emits each
+// result as soon as it completes. Once ctx is done the lookups in flight
+// fail with ctx's error and the CEPs not started yet are never emitted. The
+// channel must be drained.
+func (s *AddressService) StreamBatchContext(ctx context.Context, ceps []string) <-chan BatchResult {
+	input := make(chan string)
+	go func() {
+		defer close(input)
+		for _, cep := range ceps {
+			select {
+			case <-ctx.Done():
+				return
+			case input <- cep:
+			}
+		}
+	}()
+
+	return s.stream(ctx, ctx, input)
+}
+
+// ExecuteStream resolves CEPs as they arrive on ceps and emits each result as
+// soon as it completes. Results carry the input position in Index, so callers
+// that need input order can reassemble it.
+func (s *AddressService) ExecuteStream(ceps <-chan string) <-chan BatchResult {
+	return s.ExecuteStreamContext(s.ctx, ceps)
+}
+
+// ExecuteStreamContext is ExecuteStream that stops taking CEPs from ceps once
+// ctx is done. Lookups already in flight are not cancelled: their results are
+// still emitted before the channel closes, so callers can flush a partial
+// batch. Close the service to abandon them as well.
+func (s *AddressService) ExecuteStreamContext(ctx context.Context, ceps <-chan string) <-chan BatchResult {
+	return s.stream(ctx, context.Background(), ceps)
+}
+
+// stream stops dispatching when ctx is done and runs every lookup under
+// lookupCtx.
+func (s *AddressService) stream(ctx context.Context, lookupCtx context.Context, ceps <-chan string) <-chan BatchResult {
+	config := s.settings()
+
+	concurrency := config.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan BatchResult)
+	results := make(chan BatchResult)
+	var workers errgroup.Group
+
+	go func() {
+		defer close(jobs)
+
+		var interval time.Duration
+		if config.rateLimit > 0 {
+			interval = time.Duration(float64(time.Second) / config.rateLimit)
+		}
+
+		var last time.Time
+		index := 0
+		for {
+			var cep string
+			select {
+			case <-ctx.Done():
+				return
+			case next, ok := <-ceps:
+				if !ok {
+					return
+				}
+				cep = next
+			}
+
+			var waited time.Duration
+			if wait := last.Add(interval).Sub(config.clock.Now()); interval > 0 && index > 0 && wait > 0 {
+				waited = wait
+				config.stats.rateLimitWait(wait)
+				timer := config.clock.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C():
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- BatchResult{Index: index, CEP: cep, wait: waited}:
+			}
+			last = config.clock.Now()
+			index++
+		}
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		workers.Go(func() error {
+			for job := range jobs {
+				start := config.clock.Now()
+				job.Address, job.Report, job.Err = s.ExecuteWithReportContext(lookupCtx, job.CEP)
+				job.Latency = config.clock.Now().Sub(start)
+				if job.Report != nil {
+					job.Report.RateLimitWait = job.wait
+				}
+				results <- job
+			}
+			return nil
+		})
+	}
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	return results
+}
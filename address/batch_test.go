@@ -0,0 +1,78 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// lastFirst answers each CEP after a latency that shrinks along ceps, so the
+// last CEP finishes first.
+func lastFirst(ceps []string) address.Provider {
+	latencies := make(map[string]time.Duration, len(ceps))
+	for i, cep := range ceps {
+		latencies[cep] = time.Duration(len(ceps)-i) * 5 * time.Millisecond
+	}
+
+	return address.NewProvider("LastFirst", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		latency, ok := latencies[cep]
+		if !ok {
+			return address.AddressResult{}, address.ErrNotFound
+		}
+
+		select {
+		case <-ctx.Done():
+			return address.AddressResult{}, ctx.Err()
+		case <-time.After(latency):
+		}
+		return address.AddressResult{ZipCode: cep, Street: "Rua " + cep, Source: "LastFirst"}, nil
+	})
+}
+
+func TestExecuteBatchKeepsInputOrder(t *testing.T) {
+	ceps := []string{"01001000", "01310100", "04538133", "20040010", "30130010", "40020000"}
+	input := append([]string{"01001-000", "99999999"}, ceps[1:]...)
+	service := newService(t, lastFirst(ceps)).SetConcurrency(len(input))
+
+	results := service.ExecuteBatch(input)
+	if len(results) != len(input) {
+		t.Fatalf("%d results for %d CEPs", len(results), len(input))
+	}
+
+	for i, result := range results {
+		if result.Index != i || result.CEP != input[i] {
+			t.Errorf("results[%d] is %q at index %d, want %q, as given", i, result.CEP, result.Index, input[i])
+		}
+	}
+
+	if results[0].Err != nil || results[0].Address.ZipCode != "01001000" || results[0].Address.Source != "LastFirst" {
+		t.Errorf("results[0] = %+v, want 01001-000 resolved by LastFirst", results[0])
+	}
+	if !errors.Is(results[1].Err, address.ErrNotFound) {
+		t.Errorf("results[1] = %+v, want ErrNotFound", results[1])
+	}
+	for i, result := range results[2:] {
+		if result.Err != nil || result.Address.ZipCode != input[i+2] {
+			t.Errorf("results[%d] = %+v, want the address of %s", i+2, result, input[i+2])
+		}
+	}
+}
+
+func TestExecuteBatchWithOneWorker(t *testing.T) {
+	ceps := []string{"01001000", "20040010", "40020000"}
+	service := newService(t, lastFirst(ceps)).SetConcurrency(1)
+
+	for i, result := range service.ExecuteBatch(ceps) {
+		if result.Err != nil || result.Address.ZipCode != ceps[i] {
+			t.Errorf("results[%d] = %+v, want the address of %s", i, result, ceps[i])
+		}
+	}
+
+	if results := service.ExecuteBatch(nil); len(results) != 0 {
+		t.Errorf("ExecuteBatch(nil) = %+v, want no results", results)
+	}
+}
@@ -0,0 +1,128 @@
+package address_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// BENCH_LATENCIES are the simulated provider latencies the lookups are
+// measured at: none, to measure the service, and a realistic one.
+var BENCH_LATENCIES = []time.Duration{0, time.Millisecond}
+
+// benchService returns a service querying fake servers of the named
+// providers, each answering after latency.
+func benchService(b *testing.B, latency time.Duration, providers ...string) *address.AddressService {
+	b.Helper()
+
+	fixtures := map[string]address.AddressResult{sé.ZipCode: sé}
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	for _, name := range providers {
+		var server *addresstest.Server
+		var provider address.Provider
+		switch name {
+		case "ViaCEP":
+			server = addresstest.NewViaCEPServer(fixtures)
+			provider = address.NewViaCEPProvider(server.BaseURL())
+		case "BrasilAPI":
+			server = addresstest.NewBrasilAPIServer(fixtures)
+			provider = address.NewBrasilAPIProvider(server.BaseURL())
+		}
+		server.SetLatency(latency)
+		b.Cleanup(server.Close)
+		service.RegisterProvider(provider)
+	}
+	if err := service.SetProviders(providers...); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(service.Close)
+
+	return service
+}
+
+func benchmarkExecute(b *testing.B, providers ...string) {
+	for _, latency := range BENCH_LATENCIES {
+		b.Run(fmt.Sprintf("latency=%v", latency), func(b *testing.B) {
+			service := benchService(b, latency, providers...)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := service.Execute("01001000"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExecuteSingleProvider(b *testing.B) {
+	benchmarkExecute(b, "ViaCEP")
+}
+
+func BenchmarkExecuteRace(b *testing.B) {
+	benchmarkExecute(b, "ViaCEP", "BrasilAPI")
+}
+
+func BenchmarkExecuteBatch(b *testing.B) {
+	ceps := make([]string, 64)
+	for i := range ceps {
+		ceps[i] = "01001000"
+	}
+
+	for _, concurrency := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			service := benchService(b, time.Millisecond, "ViaCEP", "BrasilAPI").SetConcurrency(concurrency)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for _, result := range service.ExecuteBatch(ceps) {
+					if result.Err != nil {
+						b.Fatal(result.Err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCacheHit(b *testing.B) {
+	service := benchService(b, 0, "ViaCEP", "BrasilAPI").SetCache(address.NewMemoryCache(time.Hour))
+	if _, err := service.Execute("01001000"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := service.Execute("01001000"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// missCache never has the CEP asked for.
+type missCache struct{}
+
+func (missCache) Get(cep string) (address.AddressResult, bool) {
+	return address.AddressResult{}, false
+}
+
+func (missCache) Set(cep string, address address.AddressResult) {}
+
+func BenchmarkCacheMiss(b *testing.B) {
+	service := benchService(b, 0, "ViaCEP", "BrasilAPI").SetCache(missCache{})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := service.Execute("01001000"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
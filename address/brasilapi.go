@@ -0,0 +1,108 @@
+package address
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const BRASILAPI_BASE_URL = "https://brasilapi.com.br/api/cep/v1"
+
+// BRASILAPI_V2_BASE_URL is BrasilAPI's CEP API that also answers with
+// coordinates, when it has them.
+const BRASILAPI_V2_BASE_URL = "https://brasilapi.com.br/api/cep/v2"
+
+type BrasilAPIResponse struct {
+	CEP          string `json:"cep"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	State        string `json:"state"`
+	Street       string `json:"street"`
+	// Location is only sent by the v2 API, with the coordinates as
+	// strings, or empty when they are unknown.
+	Location struct {
+		Coordinates struct {
+			Latitude  string `json:"latitude"`
+			Longitude string `json:"longitude"`
+		} `json:"coordinates"`
+	} `json:"location"`
+}
+
+// incomplete reports an answer without the fields BrasilAPI always fills.
+func (r BrasilAPIResponse) incomplete() bool {
+	return r.CEP == "" || r.City == "" || r.State == ""
+}
+
+func (r BrasilAPIResponse) ToAddressResult() AddressResult {
+	return cleanResult(AddressResult{
+		Source:       "BrasilAPI",
+		State:        r.State,
+		City:         r.City,
+		Street:       r.Street,
+		ZipCode:      r.CEP,
+		Neighborhood: r.Neighborhood,
+		Location:     parseCoordinates(r.Location.Coordinates.Latitude, r.Location.Coordinates.Longitude),
+	})
+}
+
+type brasilAPIProvider struct {
+	name        string
+	baseURL     string
+	coordinates bool
+}
+
+func NewBrasilAPIProvider(baseURL string) Provider {
+	return brasilAPIProvider{name: "BrasilAPI", baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// NewBrasilAPIV2Provider is the BrasilAPIv2 provider, which queries the v2
+// API at baseURL and answers with coordinates. Distance falls back to it for
+// CEPs resolved without coordinates.
+func NewBrasilAPIV2Provider(baseURL string) CoordinateProvider {
+	return brasilAPIProvider{name: "BrasilAPIv2", baseURL: strings.TrimSuffix(baseURL, "/"), coordinates: true}
+}
+
+func (p brasilAPIProvider) Name() string {
+	return p.name
+}
+
+func (p brasilAPIProvider) ProvidesCoordinates() bool {
+	return p.coordinates
+}
+
+func (p brasilAPIProvider) BaseURL() string {
+	return p.baseURL
+}
+
+func (p brasilAPIProvider) GetAddress(ctx context.Context, client *http.Client, cep string) (AddressResult, error) {
+	source := p.Name()
+	url := p.baseURL + "/" + cep
+
+	response, err := doRequest(ctx, client, source, url)
+	if err != nil {
+		return AddressResult{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return AddressResult{}, fmt.Errorf("%s: %w", source, ErrNotFound)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return AddressResult{}, fmt.Errorf("%s: unexpected status %d", source, response.StatusCode)
+	}
+
+	var brasilAPIResponse BrasilAPIResponse
+	if err := decodeResponse(response, &brasilAPIResponse, responseSchema{provider: source, fields: brasilAPIFields}); err != nil {
+		return AddressResult{}, fmt.Errorf("%s: %w", source, err)
+	}
+
+	result := brasilAPIResponse.ToAddressResult()
+	result.Source = source
+	return result, nil
+}
+
+func BrasilAPI(ctx context.Context, client *http.Client, cep string) (AddressResult, error) {
+	return NewBrasilAPIProvider(BRASILAPI_BASE_URL).GetAddress(ctx, client, cep)
+}
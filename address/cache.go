@@ -0,0 +1,110 @@
+package address
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores successful lookups by normalized CEP. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	Get(cep string) (AddressResult, bool)
+	Set(cep string, address AddressResult)
+}
+
+type cacheEntry struct {
+	address AddressResult
+	expires time.Time
+}
+
+// MemoryCache keeps entries in memory for a fixed TTL. Expired entries are
+// dropped when read, and swept from the whole map at most once per TTL while
+// writing.
+type MemoryCache struct {
+	mu        sync.Mutex
+	clock     Clock
+	ttl       time.Duration
+	entries   map[string]cacheEntry
+	lastSweep time.Time
+}
+
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	clock := RealClock()
+
+	return &MemoryCache{
+		clock:     clock,
+		ttl:       ttl,
+		entries:   make(map[string]cacheEntry),
+		lastSweep: clock.Now(),
+	}
+}
+
+// SetClock replaces the clock entries expire by. A nil clock restores
+// RealClock.
+func (c *MemoryCache) SetClock(clock Clock) *MemoryCache {
+	if clock == nil {
+		clock = RealClock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock = clock
+	c.lastSweep = clock.Now()
+	return c
+}
+
+func (c *MemoryCache) Get(cep string) (AddressResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cep]
+	if !ok {
+		return AddressResult{}, false
+	}
+
+	if c.clock.Now().After(entry.expires) {
+		delete(c.entries, cep)
+		return AddressResult{}, false
+	}
+
+	return entry.address, true
+}
+
+func (c *MemoryCache) Set(cep string, address AddressResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	c.entries[cep] = cacheEntry{address: address, expires: now.Add(c.ttl)}
+
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of entries, expired ones included until they are
+// swept.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// SetCache makes Execute answer from cache when it can and store every
+// successful lookup in it. A nil cache disables caching.
+func (s *AddressService) SetCache(cache Cache) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = cache
+	return s
+}
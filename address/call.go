@@ -0,0 +1,111 @@
+package address
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CallOption changes how a single lookup runs, without touching the
+// service's settings, so concurrent lookups can each pass their own.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	only    []string
+	hasOnly bool
+	without []string
+}
+
+// WithOnlyProviders makes the lookup query only the registered providers
+// with the given names, matched case-insensitively, whether or not
+// SetProviders enabled them. A cached answer from another provider is not
+// used.
+func WithOnlyProviders(names ...string) CallOption {
+	return func(options *callOptions) {
+		options.only = append(options.only, names...)
+		options.hasOnly = true
+	}
+}
+
+// WithoutProviders keeps the registered providers with the given names,
+// matched case-insensitively, out of the lookup, for instance to ask again
+// without the provider that gave a suspicious answer. A cached answer from
+// one of them is not used.
+func WithoutProviders(names ...string) CallOption {
+	return func(options *callOptions) {
+		options.without = append(options.without, names...)
+	}
+}
+
+// callSettings is the configuration of a lookup run with options. A name
+// that no registered provider has fails with ErrUnknownProvider, and a
+// filter that leaves no provider with ErrNoProviders.
+func (s *AddressService) callSettings(options []CallOption) (settings, error) {
+	config := s.settings()
+	if len(options) == 0 {
+		return config, nil
+	}
+
+	var call callOptions
+	for _, option := range options {
+		option(&call)
+	}
+	if !call.hasOnly && len(call.without) == 0 {
+		return config, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if call.hasOnly {
+		config.providers = config.providers[:0]
+		for _, name := range call.only {
+			provider, err := s.registeredProvider(name)
+			if err != nil {
+				return config, err
+			}
+			if !slices.ContainsFunc(config.providers, func(selected Provider) bool { return selected.Name() == provider.Name() }) {
+				config.providers = append(config.providers, provider)
+			}
+		}
+	}
+
+	for _, name := range call.without {
+		excluded, err := s.registeredProvider(name)
+		if err != nil {
+			return config, err
+		}
+		config.providers = slices.DeleteFunc(config.providers, func(provider Provider) bool {
+			return provider.Name() == excluded.Name()
+		})
+	}
+
+	if len(config.providers) == 0 {
+		return config, fmt.Errorf("%w: the provider filter leaves none", ErrNoProviders)
+	}
+
+	config.filtered = true
+	return config, nil
+}
+
+// registeredProvider looks name up in the registry. s.mu must be held.
+func (s *AddressService) registeredProvider(name string) (Provider, error) {
+	provider, ok := s.findProvider(strings.TrimSpace(name))
+	if !ok {
+		return nil, fmt.Errorf("%w %q (valid: %s)", ErrUnknownProvider, name, strings.Join(providerNames(s.registry), ", "))
+	}
+
+	return provider, nil
+}
+
+// serves reports whether a cached answer may answer a lookup, which it may
+// not when it came from a provider the lookup's options left out.
+func (config settings) serves(cached AddressResult) bool {
+	if !config.filtered {
+		return true
+	}
+
+	return slices.ContainsFunc(config.providers, func(provider Provider) bool {
+		return strings.EqualFold(provider.Name(), cached.Source)
+	})
+}
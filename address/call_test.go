@@ -0,0 +1,143 @@
+package address_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestWithOnlyProviders(t *testing.T) {
+	fast := addresstest.NewMockProvider("Fast").Returns(sé)
+	slow := addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(20 * time.Millisecond)
+	spare := addresstest.NewMockProvider("Spare").Returns(sé)
+	service := newService(t, fast, slow)
+	service.RegisterProvider(spare)
+	if err := service.SetProviders("Fast", "Slow"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.Execute("01001000", address.WithOnlyProviders("slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "Slow" {
+		t.Errorf("won by %q, want only Slow asked", result.Source)
+	}
+	fast.AssertCalls(t, 0)
+
+	// Registered but not enabled is fine.
+	if result, err := service.Execute("01001000", address.WithOnlyProviders("Spare")); err != nil || result.Source != "Spare" {
+		t.Errorf("Spare: %+v, %v, want it asked", result, err)
+	}
+
+	// The service's own settings are untouched.
+	if got := service.EnabledProviderNames(); len(got) != 2 || got[0] != "Fast" || got[1] != "Slow" {
+		t.Errorf("enabled providers %v after filtered lookups, want [Fast Slow]", got)
+	}
+}
+
+func TestWithoutProviders(t *testing.T) {
+	fast := addresstest.NewMockProvider("Fast").Returns(sé)
+	slow := addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(20 * time.Millisecond)
+	service := newService(t, fast, slow)
+
+	results, err := service.ExecuteAll("01001000", address.WithoutProviders("FAST"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Provider != "Slow" {
+		t.Errorf("results %+v, want Slow's alone", results)
+	}
+	fast.AssertCalls(t, 0)
+}
+
+func TestCallOptionErrors(t *testing.T) {
+	service := newService(t, addresstest.NewMockProvider("A").Returns(sé))
+
+	if _, err := service.Execute("01001000", address.WithOnlyProviders("Nope")); !errors.Is(err, address.ErrUnknownProvider) {
+		t.Errorf("unknown provider: %v, want ErrUnknownProvider", err)
+	}
+	if _, err := service.Execute("01001000", address.WithoutProviders("A")); !errors.Is(err, address.ErrNoProviders) {
+		t.Errorf("every provider left out: %v, want ErrNoProviders", err)
+	}
+	if _, err := service.Execute("01001000", address.WithOnlyProviders()); !errors.Is(err, address.ErrNoProviders) {
+		t.Errorf("empty WithOnlyProviders: %v, want ErrNoProviders", err)
+	}
+}
+
+func TestCallOptionsSkipOtherProvidersCache(t *testing.T) {
+	a := addresstest.NewMockProvider("A").Returns(sé)
+	b := addresstest.NewMockProvider("B").Returns(sé)
+	service := newService(t, a, b).SetCache(address.NewMemoryCache(time.Hour))
+
+	if _, err := service.Execute("01001000", address.WithOnlyProviders("A")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A's cached answer serves A, but not a lookup without it.
+	if result, err := service.Execute("01001000", address.WithOnlyProviders("A")); err != nil || result.Source != "A" {
+		t.Fatalf("second lookup from A: %+v, %v", result, err)
+	}
+	a.AssertCalls(t, 1)
+
+	if result, err := service.Execute("01001000", address.WithoutProviders("A")); err != nil || result.Source != "B" {
+		t.Errorf("lookup without A: %+v, %v, want B asked", result, err)
+	}
+	b.AssertCalls(t, 1)
+}
+
+func TestConcurrentCallsWithDifferentFilters(t *testing.T) {
+	providers := map[string]*addresstest.MockProvider{
+		"A": addresstest.NewMockProvider("A").Returns(sé).SetLatency(time.Millisecond),
+		"B": addresstest.NewMockProvider("B").Returns(sé).SetLatency(2 * time.Millisecond),
+		"C": addresstest.NewMockProvider("C").Returns(sé).SetLatency(3 * time.Millisecond),
+		"D": addresstest.NewMockProvider("D").Returns(sé),
+	}
+	service := newService(t, providers["A"], providers["B"], providers["C"])
+	service.RegisterProvider(providers["D"])
+	if err := service.SetProviders("A", "B", "C"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options []address.CallOption
+		allowed string
+	}{
+		{"default", nil, "ABC"},
+		{"only B", []address.CallOption{address.WithOnlyProviders("B")}, "B"},
+		{"only A and C", []address.CallOption{address.WithOnlyProviders("A", "C")}, "AC"},
+		{"without A and B", []address.CallOption{address.WithoutProviders("A", "B")}, "C"},
+		{"only D", []address.CallOption{address.WithOnlyProviders("D")}, "D"},
+	}
+
+	var wg sync.WaitGroup
+	for i := range 500 {
+		test := tests[i%len(tests)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := service.Execute("01001000", test.options...)
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				return
+			}
+			if result.Source == "" || !strings.Contains(test.allowed, result.Source) {
+				t.Errorf("%s: won by %q, want one of %q", test.name, result.Source, test.allowed)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := service.EnabledProviderNames(); !slices.Equal(got, []string{"A", "B", "C"}) {
+		t.Errorf("enabled providers %v after concurrent filtered lookups, want [A B C]", got)
+	}
+	providers["D"].AssertCalls(t, 100)
+}
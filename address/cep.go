@@ -0,0 +1,46 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeCEP strips the usual separators from a CEP ("01001-000",
+// " 01.001-000 ") and returns its 8 digits, or ErrInvalidCEP.
+func NormalizeCEP(cep string) (string, error) {
+	var digits strings.Builder
+
+	for _, r := range strings.TrimSpace(cep) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == '.' || r == ' ':
+		default:
+			return "", fmt.Errorf("%w %q", ErrInvalidCEP, cep)
+		}
+	}
+
+	if digits.Len() != 8 {
+		return "", fmt.Errorf("%w %q", ErrInvalidCEP, cep)
+	}
+
+	return digits.String(), nil
+}
+
+// cleanResult trims every field of a decoded address, writes its CEP as 8
+// digits and its state as a UF with StateName filled in, whichever way the
+// provider formatted them. Values that do not normalize are kept as the
+// provider sent them.
+func cleanResult(result AddressResult) AddressResult {
+	result.State = strings.TrimSpace(result.State)
+	result.City = strings.TrimSpace(result.City)
+	result.Street = strings.TrimSpace(result.Street)
+	result.Neighborhood = strings.TrimSpace(result.Neighborhood)
+	result.ZipCode = strings.TrimSpace(result.ZipCode)
+
+	if cep, err := NormalizeCEP(result.ZipCode); err == nil {
+		result.ZipCode = cep
+	}
+
+	return normalizeState(result)
+}
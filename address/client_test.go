@@ -0,0 +1,100 @@
+package address_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+type countingTransport struct {
+	requests atomic.Int64
+	next     http.RoundTripper
+}
+
+func (c *countingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return c.next.RoundTrip(request)
+}
+
+// fakeProviders returns the two built-in providers pointed at the
+// addresstest fakes, and the fakes.
+func fakeProviders(t *testing.T) ([]address.Provider, []*addresstest.Server) {
+	fixtures := map[string]address.AddressResult{"01001000": sé}
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	brasilAPI := addresstest.NewBrasilAPIServer(fixtures)
+	t.Cleanup(viaCEP.Close)
+	t.Cleanup(brasilAPI.Close)
+
+	providers := []address.Provider{
+		address.NewViaCEPProvider(viaCEP.BaseURL()),
+		address.NewBrasilAPIProvider(brasilAPI.BaseURL()),
+	}
+	return providers, []*addresstest.Server{viaCEP, brasilAPI}
+}
+
+func TestHTTPClientCarriesEveryRequest(t *testing.T) {
+	providers, servers := fakeProviders(t)
+	counting := &countingTransport{next: http.DefaultTransport}
+	client := &http.Client{Transport: counting, Timeout: time.Minute}
+	service := newService(t, providers...).SetHTTPClient(client).SetTimeout(5 * time.Second)
+
+	for range 10 {
+		results, err := service.ExecuteAll("01001000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if result.Err != nil {
+				t.Fatalf("%s: %v", result.Provider, result.Err)
+			}
+		}
+	}
+
+	served := 0
+	for _, server := range servers {
+		served += server.Requests()
+	}
+	if n := counting.requests.Load(); n != 20 || served != 20 {
+		t.Errorf("%d requests through the client and %d served, want 20 of each", n, served)
+	}
+	// The service bounds lookups with its own timeout, not by editing the
+	// client it was given.
+	if client.Timeout != time.Minute || client.Transport != counting {
+		t.Errorf("the service changed the injected client: %+v", client)
+	}
+}
+
+func TestHTTPClientSharedByServices(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	counting := &countingTransport{next: http.DefaultTransport}
+	client := &http.Client{Transport: counting}
+
+	first := newService(t, providers[0]).SetHTTPClient(client)
+	second := newService(t, providers[1]).SetHTTPClient(client)
+	for _, service := range []*address.AddressService{first, second} {
+		if _, err := service.Execute("01001000"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := counting.requests.Load(); n != 2 {
+		t.Errorf("%d requests through the shared client, want 2", n)
+	}
+}
+
+func TestNilHTTPClientRestoresTheDefault(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	counting := &countingTransport{next: http.DefaultTransport}
+	service := newService(t, providers...).SetHTTPClient(&http.Client{Transport: counting}).SetHTTPClient(nil)
+
+	if _, err := service.ExecuteAll("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := counting.requests.Load(); n != 0 {
+		t.Errorf("%d requests through the dropped client, want 0", n)
+	}
+}
@@ -0,0 +1,63 @@
+package address
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// The client profiles reported in Attempt.Client.
+const (
+	// CLIENT_DEFAULT is the client the service builds itself.
+	CLIENT_DEFAULT = "default"
+	// CLIENT_SHARED is the client from SetHTTPClient.
+	CLIENT_SHARED = "shared"
+	// CLIENT_PROVIDER is a client from SetProviderClient.
+	CLIENT_PROVIDER = "provider"
+)
+
+// SetProviderClient makes the provider named name send its requests with
+// client instead of the service-wide one, for example to present an mTLS
+// certificate to a single upstream. The User-Agent, compression and provider
+// headers still apply, and lookups are still bounded by the service timeout.
+// A nil client goes back to the service-wide one.
+func (s *AddressService) SetProviderClient(name string, client *http.Client) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Lookups in flight hold the old maps, so they are replaced rather than
+	// changed.
+	clients := maps.Clone(s.clients)
+	if clients == nil {
+		clients = make(map[string]*http.Client)
+	}
+
+	if client == nil {
+		delete(clients, strings.ToLower(name))
+	} else {
+		clients[strings.ToLower(name)] = client
+	}
+
+	s.clients = clients
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// clientProfile is the profile of the service-wide client. s.mu must be
+// held.
+func (s *AddressService) clientProfile() string {
+	if s.ownClient {
+		return CLIENT_DEFAULT
+	}
+
+	return CLIENT_SHARED
+}
+
+// clientFor returns the client the provider named name uses and its profile.
+func (config settings) clientFor(name string) (*http.Client, string) {
+	if client, ok := config.clients[strings.ToLower(name)]; ok {
+		return client, CLIENT_PROVIDER
+	}
+
+	return config.client, config.profile
+}
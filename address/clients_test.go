@@ -0,0 +1,157 @@
+package address_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// clientCertificate returns a self-signed certificate for mTLS clients.
+func clientCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lookups"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// tlsUpstream serves handler over TLS with clientAuth, counting the requests
+// that came with a client certificate.
+func tlsUpstream(t *testing.T, handler http.Handler, clientAuth tls.ClientAuthType, clientCAs *x509.CertPool) (*httptest.Server, *atomic.Int32) {
+	presented := new(atomic.Int32)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			presented.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	server.TLS = &tls.Config{ClientAuth: clientAuth, ClientCAs: clientCAs}
+	// The handshakes refused without a certificate are expected.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, presented
+}
+
+func TestProviderClientPresentsItsCertificate(t *testing.T) {
+	certificate := clientCertificate(t)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(leaf)
+
+	fixtures := map[string]address.AddressResult{"01001000": sé}
+	viaCEP := addresstest.NewViaCEPServer(fixtures)
+	brasilAPI := addresstest.NewBrasilAPIServer(fixtures)
+	t.Cleanup(viaCEP.Close)
+	t.Cleanup(brasilAPI.Close)
+	mirror, mirrorPresented := tlsUpstream(t, viaCEP.Config.Handler, tls.RequireAndVerifyClientCert, clientCAs)
+	public, publicPresented := tlsUpstream(t, brasilAPI.Config.Handler, tls.RequestClientCert, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(mirror.Certificate())
+	service := newService(t, address.NewViaCEPProvider(mirror.URL+"/ws"), address.NewBrasilAPIProvider(public.URL+"/api/cep/v1"))
+	if err := service.SetTLSConfig(&tls.Config{RootCAs: roots}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without its client, the mirror refuses the handshake.
+	if _, err := service.Execute("01001000", address.WithOnlyProviders("ViaCEP")); err == nil {
+		t.Fatal("the mirror answered without a client certificate")
+	}
+
+	recording := &recordingTransport{next: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{certificate}}}}
+	service.SetProviderClient("viacep", &http.Client{Transport: recording}).SetUserAgent("mine/1")
+
+	for _, test := range []struct {
+		provider string
+		profile  string
+	}{
+		{provider: "ViaCEP", profile: address.CLIENT_PROVIDER},
+		{provider: "BrasilAPI", profile: address.CLIENT_DEFAULT},
+	} {
+		_, report, err := service.ExecuteWithReport("01001000", address.WithOnlyProviders(test.provider))
+		if err != nil {
+			t.Fatalf("%s: %v", test.provider, err)
+		}
+		if attempt := attemptOf(t, report, test.provider); attempt.Client != test.profile {
+			t.Errorf("%s attempt client = %q, want %q", test.provider, attempt.Client, test.profile)
+		}
+	}
+
+	if n := mirrorPresented.Load(); n != 1 {
+		t.Errorf("the mirror saw %d certificates, want 1", n)
+	}
+	if n := publicPresented.Load(); n != 0 {
+		t.Errorf("the public API saw %d certificates, want none", n)
+	}
+	// The service's own request settings still apply to the provider's
+	// client.
+	if agent := recording.sent("/ws/")[0].Get("User-Agent"); agent != "mine/1" {
+		t.Errorf("User-Agent through the provider client = %q, want mine/1", agent)
+	}
+}
+
+func TestProviderClientRemoved(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	counting := &countingTransport{next: http.DefaultTransport}
+	service := newService(t, providers[0]).
+		SetProviderClient("ViaCEP", &http.Client{Transport: counting}).
+		SetProviderClient("ViaCEP", nil)
+
+	_, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := counting.requests.Load(); n != 0 {
+		t.Errorf("%d requests through the removed client", n)
+	}
+	if attempt := attemptOf(t, report, "ViaCEP"); attempt.Client != address.CLIENT_DEFAULT {
+		t.Errorf("attempt client = %q, want %q", attempt.Client, address.CLIENT_DEFAULT)
+	}
+}
+
+func TestSharedClientProfile(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers[0]).SetHTTPClient(&http.Client{})
+
+	_, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt := attemptOf(t, report, "ViaCEP"); attempt.Client != address.CLIENT_SHARED {
+		t.Errorf("attempt client = %q, want %q", attempt.Client, address.CLIENT_SHARED)
+	}
+}
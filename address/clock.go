@@ -0,0 +1,58 @@
+package address
+
+import "time"
+
+// Clock is where the service reads time: lookup deadlines, retry backoff,
+// batch rate limiting, report durations and cache expiry. Tests replace it
+// with addresstest.FakeClock to exercise timeouts without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the part of time.Timer the service uses.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+// RealClock is the Clock backed by the time package, used by default.
+func RealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// SetClock replaces the clock of the service. A nil clock restores
+// RealClock.
+func (s *AddressService) SetClock(clock Clock) *AddressService {
+	if clock == nil {
+		clock = RealClock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = clock
+	return s
+}
@@ -0,0 +1,91 @@
+package address_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestMemoryCacheExpiresOnClock(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	cache := address.NewMemoryCache(time.Minute).SetClock(clock)
+
+	cache.Set("01001000", sé)
+	clock.Advance(time.Minute)
+	if _, ok := cache.Get("01001000"); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if _, ok := cache.Get("01001000"); ok {
+		t.Fatal("entry outlived its TTL")
+	}
+
+	// Setting after a TTL sweeps the expired entries.
+	cache.Set("20040010", sé)
+	clock.Advance(2 * time.Minute)
+	cache.Set("01001000", sé)
+	if n := cache.Len(); n != 1 {
+		t.Errorf("%d entries after the sweep, want 1", n)
+	}
+}
+
+func TestReportTimesOnClock(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	fast := addresstest.NewMockProvider("Fast").Returns(sé).SetLatency(30 * time.Millisecond).SetClock(clock)
+	service := newService(t, fast).SetClock(clock)
+
+	done := startLookup(service, "01001000")
+	clock.BlockUntilTimers(2)
+	clock.Advance(30 * time.Millisecond)
+	got := <-done
+
+	if got.err != nil {
+		t.Fatal(got.err)
+	}
+	if got.report.Duration != 30*time.Millisecond {
+		t.Errorf("lookup took %v, want exactly 30ms", got.report.Duration)
+	}
+	if attempt := attemptOf(t, got.report, "Fast"); attempt.Start != 0 || attempt.Duration != 30*time.Millisecond {
+		t.Errorf("attempt ran from %v for %v, want from 0 for 30ms", attempt.Start, attempt.Duration)
+	}
+}
+
+func TestRateLimitWaitsOnClock(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	service := newService(t, addresstest.NewMockProvider("Fast").Returns(sé)).
+		SetClock(clock).
+		SetConcurrency(1).
+		SetRateLimit(10)
+
+	ceps := make(chan string, 3)
+	ceps <- "01001000"
+	ceps <- "01001001"
+	ceps <- "01001002"
+	close(ceps)
+	results := service.ExecuteStream(ceps)
+
+	if first := <-results; first.Err != nil || first.Report.RateLimitWait != 0 {
+		t.Fatalf("first lookup: %v, waited %v", first.Err, first.Report.RateLimitWait)
+	}
+	for i := 1; i < 3; i++ {
+		// The dispatcher waits 100ms on the clock before each next CEP.
+		clock.BlockUntilTimers(1)
+		select {
+		case result := <-results:
+			t.Fatalf("lookup %d of %s ran before the rate limit allowed", i, result.CEP)
+		default:
+		}
+		clock.Advance(100 * time.Millisecond)
+
+		result := <-results
+		if result.Err != nil || result.Report.RateLimitWait != 100*time.Millisecond {
+			t.Errorf("lookup %d: %v, waited %v; want 100ms", i, result.Err, result.Report.RateLimitWait)
+		}
+	}
+	if _, ok := <-results; ok {
+		t.Error("more results than CEPs")
+	}
+}
@@ -0,0 +1,230 @@
+package address
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"sync"
+)
+
+const (
+	CACHE_FORMAT_JSON byte = 'j'
+	CACHE_FORMAT_GOB  byte = 'g'
+)
+
+// CACHE_SCHEMA_VERSION is the version of the AddressResult layout written in
+// every cache entry. It changes when a field changes meaning, not when one is
+// added; entries of another version are cache misses.
+const CACHE_SCHEMA_VERSION byte = 1
+
+// Codec serializes the results a StoreCache keeps. Format is the byte that
+// tags the entries it writes, so entries written by another codec are told
+// apart; it must not be CACHE_FORMAT_JSON or CACHE_FORMAT_GOB unless the
+// codec reads their entries.
+type Codec interface {
+	Format() byte
+	Marshal(result AddressResult) ([]byte, error)
+	Unmarshal(data []byte) (AddressResult, error)
+}
+
+// JSONCodec stores results as JSON, the default. Like json.Marshal, it
+// leaves Raw and Header out.
+type JSONCodec struct{}
+
+func (JSONCodec) Format() byte {
+	return CACHE_FORMAT_JSON
+}
+
+func (JSONCodec) Marshal(result AddressResult) ([]byte, error) {
+	return json.Marshal(result)
+}
+
+func (JSONCodec) Unmarshal(data []byte) (AddressResult, error) {
+	var result AddressResult
+	err := json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GobCodec stores results with encoding/gob, smaller and faster to decode
+// than JSON. It leaves Raw and Header out, as JSONCodec does.
+//
+// A gob stream describes its types once, before the first value, so entries
+// keep only the value and a checksum of the type descriptions it was written
+// with, and decoders primed with the descriptions are reused. The checksum
+// changes with the fields of AddressResult and may change between binaries,
+// and entries written under another one are cache misses.
+type GobCodec struct{}
+
+type gobDecoder struct {
+	source  *bytes.Reader
+	decoder *gob.Decoder
+}
+
+type gobEncoder struct {
+	buffer  *bytes.Buffer
+	encoder *gob.Encoder
+}
+
+// gobTypes is the checksum of the type descriptions gob sends before the
+// first AddressResult of a stream. It is computed when the package is
+// initialized, before gob has seen other types, so the type ids in it are
+// the same in every run of a binary.
+var gobTypes = func() uint32 {
+	var stream bytes.Buffer
+	encoder := gob.NewEncoder(&stream)
+	encoder.Encode(AddressResult{})
+	first := stream.Len()
+	encoder.Encode(AddressResult{})
+
+	return crc32.ChecksumIEEE(stream.Bytes()[:first-(stream.Len()-first)])
+}()
+
+var gobEncoders = sync.Pool{
+	New: func() any {
+		buffer := new(bytes.Buffer)
+		encoder := gob.NewEncoder(buffer)
+		encoder.Encode(AddressResult{})
+		return &gobEncoder{buffer: buffer, encoder: encoder}
+	},
+}
+
+var gobDecoders = sync.Pool{
+	New: func() any {
+		var primer bytes.Buffer
+		gob.NewEncoder(&primer).Encode(AddressResult{})
+
+		source := bytes.NewReader(primer.Bytes())
+		decoder := gob.NewDecoder(source)
+		var zero AddressResult
+		if err := decoder.Decode(&zero); err != nil {
+			return nil
+		}
+		return &gobDecoder{source: source, decoder: decoder}
+	},
+}
+
+func (GobCodec) Format() byte {
+	return CACHE_FORMAT_GOB
+}
+
+func (GobCodec) Marshal(result AddressResult) ([]byte, error) {
+	result.Raw, result.Header = nil, nil
+
+	encoder := gobEncoders.Get().(*gobEncoder)
+	encoder.buffer.Reset()
+	if err := encoder.encoder.Encode(result); err != nil {
+		// The stream may be left half written; the encoder is dropped.
+		return nil, err
+	}
+
+	data := binary.BigEndian.AppendUint32(make([]byte, 0, 4+encoder.buffer.Len()), gobTypes)
+	data = append(data, encoder.buffer.Bytes()...)
+	gobEncoders.Put(encoder)
+	return data, nil
+}
+
+func (GobCodec) Unmarshal(data []byte) (AddressResult, error) {
+	var result AddressResult
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != gobTypes {
+		return result, errGobTypes
+	}
+
+	decoder, ok := gobDecoders.Get().(*gobDecoder)
+	if !ok {
+		return result, errGobTypes
+	}
+	decoder.source.Reset(data[4:])
+	if err := decoder.decoder.Decode(&result); err != nil {
+		// The decoder may be left mid-message; it is dropped.
+		return AddressResult{}, err
+	}
+
+	gobDecoders.Put(decoder)
+	return result, nil
+}
+
+var errGobTypes = errors.New("gob cache entry written with other types")
+
+// CacheStore is the storage behind a StoreCache, for instance a Redis client
+// wrapped to it. Expiring entries is up to the store. Implementations must be
+// safe for concurrent use.
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// StoreCache is a Cache that keeps results serialized in a CacheStore. Each
+// entry starts with its codec's format byte and CACHE_SCHEMA_VERSION, so
+// entries written with the built-in codecs stay readable after SetCodec
+// switches codecs, and entries it cannot read are cache misses.
+type StoreCache struct {
+	mu    sync.RWMutex
+	store CacheStore
+	codec Codec
+}
+
+func NewStoreCache(store CacheStore) *StoreCache {
+	return &StoreCache{store: store, codec: JSONCodec{}}
+}
+
+// SetCodec replaces the codec new entries are written with. A nil codec
+// restores JSONCodec.
+func (c *StoreCache) SetCodec(codec Codec) *StoreCache {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.codec = codec
+	return c
+}
+
+func (c *StoreCache) Get(cep string) (AddressResult, bool) {
+	data, ok := c.store.Get(cep)
+	if !ok || len(data) < 2 || data[1] != CACHE_SCHEMA_VERSION {
+		return AddressResult{}, false
+	}
+
+	c.mu.RLock()
+	codec := c.codec
+	c.mu.RUnlock()
+
+	switch {
+	case data[0] == codec.Format():
+	case data[0] == CACHE_FORMAT_JSON:
+		codec = JSONCodec{}
+	case data[0] == CACHE_FORMAT_GOB:
+		codec = GobCodec{}
+	default:
+		return AddressResult{}, false
+	}
+
+	result, err := codec.Unmarshal(data[2:])
+	if err != nil {
+		return AddressResult{}, false
+	}
+
+	return result, true
+}
+
+// Set writes address with the current codec. A result the codec fails to
+// marshal is not cached.
+func (c *StoreCache) Set(cep string, address AddressResult) {
+	c.mu.RLock()
+	codec := c.codec
+	c.mu.RUnlock()
+
+	payload, err := codec.Marshal(address)
+	if err != nil {
+		return
+	}
+
+	data := make([]byte, 0, len(payload)+2)
+	data = append(data, codec.Format(), CACHE_SCHEMA_VERSION)
+	c.store.Set(cep, append(data, payload...))
+}
@@ -0,0 +1,148 @@
+package address_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// mapStore is a CacheStore in a map.
+type mapStore struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{entries: make(map[string][]byte)}
+}
+
+func (s *mapStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.entries[key]
+	return value, ok
+}
+
+func (s *mapStore) Set(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = value
+}
+
+var cached = address.AddressResult{
+	ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP",
+	Location:   &address.Coordinates{Latitude: -23.5503, Longitude: -46.6339},
+	Confidence: address.CONFIDENCE_AGREED,
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	withPayload := cached
+	withPayload.Raw = []byte(`{"cep":"01001-000"}`)
+	withPayload.Header = http.Header{"Date": {"Wed, 01 May 2024 12:00:00 GMT"}}
+
+	for _, codec := range []address.Codec{address.JSONCodec{}, address.GobCodec{}} {
+		data, err := codec.Marshal(withPayload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Twice, so the gob decoder is reused from the pool.
+		for range 2 {
+			got, err := codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("%T: %v", codec, err)
+			}
+			if !reflect.DeepEqual(got, cached) {
+				t.Errorf("%T round trip gave %+v, want %+v without Raw and Header", codec, got, cached)
+			}
+		}
+	}
+}
+
+func TestGobCodecRejectsOtherTypes(t *testing.T) {
+	data, err := address.GobCodec{}.Marshal(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[0] ^= 0xff
+	if _, err := (address.GobCodec{}).Unmarshal(data); err == nil {
+		t.Error("decoded an entry with another type checksum")
+	}
+	if _, err := (address.GobCodec{}).Unmarshal(data[:2]); err == nil {
+		t.Error("decoded a truncated entry")
+	}
+}
+
+func TestStoreCacheSwitchingCodecs(t *testing.T) {
+	store := newMapStore()
+	cache := address.NewStoreCache(store)
+
+	cache.Set("01001000", cached)
+	if entry, _ := store.Get("01001000"); entry[0] != address.CACHE_FORMAT_JSON || entry[1] != address.CACHE_SCHEMA_VERSION {
+		t.Errorf("entry starts %q, want the JSON format and the schema version", entry[:2])
+	}
+
+	cache.SetCodec(address.GobCodec{})
+	cache.Set("20040010", cached)
+	if entry, _ := store.Get("20040010"); entry[0] != address.CACHE_FORMAT_GOB {
+		t.Errorf("entry starts %q after SetCodec, want the gob format", entry[:1])
+	}
+
+	// Entries of either built-in codec stay readable, whichever is set.
+	for _, codec := range []address.Codec{address.GobCodec{}, nil} {
+		cache.SetCodec(codec)
+		for _, cep := range []string{"01001000", "20040010"} {
+			if got, ok := cache.Get(cep); !ok || !reflect.DeepEqual(got, cached) {
+				t.Errorf("Get(%s) with %T = %+v, %t, want the entry", cep, codec, got, ok)
+			}
+		}
+	}
+}
+
+func TestStoreCacheMisses(t *testing.T) {
+	store := newMapStore()
+	cache := address.NewStoreCache(store)
+
+	for key, entry := range map[string][]byte{
+		"short":          {address.CACHE_FORMAT_JSON},
+		"old schema":     append([]byte{address.CACHE_FORMAT_JSON, address.CACHE_SCHEMA_VERSION + 1}, `{"cep":"01001000"}`...),
+		"unknown format": append([]byte{'x', address.CACHE_SCHEMA_VERSION}, `{"cep":"01001000"}`...),
+		"garbled":        append([]byte{address.CACHE_FORMAT_JSON, address.CACHE_SCHEMA_VERSION}, `{"cep":`...),
+	} {
+		store.Set(key, entry)
+		if got, ok := cache.Get(key); ok {
+			t.Errorf("%s entry read as %+v, want a miss", key, got)
+		}
+	}
+
+	if _, ok := cache.Get("absent"); ok {
+		t.Error("absent entry read, want a miss")
+	}
+}
+
+func BenchmarkCodecDecode(b *testing.B) {
+	for _, codec := range []address.Codec{address.JSONCodec{}, address.GobCodec{}} {
+		data, err := codec.Marshal(cached)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("%T", codec), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.Unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
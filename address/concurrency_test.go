@@ -0,0 +1,98 @@
+package address_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// echoProvider answers every CEP with an address of its own, after a random
+// latency of up to maxLatency.
+func echoProvider(name string, maxLatency time.Duration) address.Provider {
+	return address.NewProvider(name, func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		timer := time.NewTimer(rand.N(maxLatency))
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return address.AddressResult{}, ctx.Err()
+		case <-timer.C:
+		}
+		return address.AddressResult{ZipCode: cep, Street: "Rua " + cep, City: "São Paulo", State: "SP", Source: name}, nil
+	})
+}
+
+// One service shared by 100 goroutines doing 100 lookups each, while its
+// settings change under them.
+func TestConcurrentLookups(t *testing.T) {
+	const GOROUTINES, LOOKUPS = 100, 100
+
+	service := newService(t, echoProvider("Fast", time.Millisecond), echoProvider("Slow", 3*time.Millisecond), echoProvider("Slower", 5*time.Millisecond)).
+		SetCache(address.NewMemoryCache(time.Minute)).
+		SetTimeout(time.Minute)
+
+	stop := make(chan struct{})
+	var setters sync.WaitGroup
+	setters.Add(1)
+	go func() {
+		defer setters.Done()
+		providers := [][]string{{"Fast", "Slow"}, {"Slow", "Slower"}, {"Fast", "Slow", "Slower"}}
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			service.SetTimeout(time.Duration(50+i%10) * time.Second)
+			service.SetRetries(i%3, time.Millisecond)
+			if err := service.SetProviders(providers[i%len(providers)]...); err != nil {
+				t.Error(err)
+				return
+			}
+			service.Stats()
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	var lookups sync.WaitGroup
+	errs := make(chan error, GOROUTINES)
+	for g := 0; g < GOROUTINES; g++ {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			for i := 0; i < LOOKUPS; i++ {
+				// 500 CEPs, so that some lookups hit the cache.
+				cep := fmt.Sprintf("%05d%03d", 1000+rand.N(500), rand.N(1000))
+				result, err := service.Execute(cep)
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", cep, err)
+					return
+				}
+				if result.ZipCode != cep || result.Street != "Rua "+cep {
+					errs <- fmt.Errorf("lookup of %s answered %+v", cep, result)
+					return
+				}
+			}
+		}()
+	}
+	lookups.Wait()
+	close(stop)
+	setters.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	// Lookups are counted once their providers settle, which Close waits
+	// for.
+	service.Close()
+	if stats := service.Stats(); stats.Lookups != GOROUTINES*LOOKUPS || stats.Failed != 0 {
+		t.Errorf("stats counted %d lookups, %d failed; want %d, none failed", stats.Lookups, stats.Failed, GOROUTINES*LOOKUPS)
+	}
+}
@@ -0,0 +1,47 @@
+package address
+
+// The Confidence of a result.
+const (
+	// CONFIDENCE_AGREED is for results at least one other provider agrees
+	// with.
+	CONFIDENCE_AGREED = 1.0
+	// CONFIDENCE_SINGLE is for results no other provider answered in time to
+	// confirm.
+	CONFIDENCE_SINGLE = 0.5
+	// CONFIDENCE_CONFLICT is for results every other answer contradicts.
+	CONFIDENCE_CONFLICT = 0.1
+)
+
+// agree reports whether a and b name the same street in the same city and
+// state, once normalized for comparison.
+func agree(a, b AddressResult) bool {
+	return NormalizeForComparison(a.State) == NormalizeForComparison(b.State) &&
+		NormalizeForComparison(a.City) == NormalizeForComparison(b.City) &&
+		NormalizeForComparison(a.Street) == NormalizeForComparison(b.Street)
+}
+
+// scoreConfidence sets the Confidence of result from the other answers to
+// the same lookup. A result contradicted by all of them gets Conflicts with
+// the fields on which the first one disagrees.
+func scoreConfidence(result AddressResult, others []AddressResult) AddressResult {
+	result.Confidence = CONFIDENCE_SINGLE
+	result.Conflicts = nil
+	if len(others) == 0 {
+		return result
+	}
+
+	for _, other := range others {
+		if agree(result, other) {
+			result.Confidence = CONFIDENCE_AGREED
+			return result
+		}
+	}
+
+	result.Confidence = CONFIDENCE_CONFLICT
+	for _, diff := range Diff(result, others[0]) {
+		if diff.Substantive && (diff.Field == "state" || diff.Field == "city" || diff.Field == "street") {
+			result.Conflicts = append(result.Conflicts, diff)
+		}
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package address_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestExecuteAllScoresConfidence(t *testing.T) {
+	// The same address as sé, as another provider formats it.
+	sameSé := address.AddressResult{ZipCode: "01001-000", Street: "PRACA DA SE", City: "Sao Paulo", State: "sp"}
+	elsewhere := address.AddressResult{ZipCode: "01001000", Street: "Rua Direita", City: "São Paulo", State: "SP"}
+
+	tests := []struct {
+		name      string
+		providers []address.Provider
+		want      map[string]float64
+		conflicts address.FieldDiffs
+	}{
+		{
+			name:      "single",
+			providers: []address.Provider{addresstest.NewMockProvider("A").Returns(sé)},
+			want:      map[string]float64{"A": address.CONFIDENCE_SINGLE},
+		},
+		{
+			name: "agreed after normalization",
+			providers: []address.Provider{
+				addresstest.NewMockProvider("A").Returns(sé),
+				addresstest.NewMockProvider("B").Returns(sameSé),
+			},
+			want: map[string]float64{"A": address.CONFIDENCE_AGREED, "B": address.CONFIDENCE_AGREED},
+		},
+		{
+			name: "conflict",
+			providers: []address.Provider{
+				addresstest.NewMockProvider("A").Returns(sé),
+				addresstest.NewMockProvider("B").Returns(elsewhere),
+			},
+			want:      map[string]float64{"A": address.CONFIDENCE_CONFLICT, "B": address.CONFIDENCE_CONFLICT},
+			conflicts: address.FieldDiffs{{Field: "street", A: "Praça da Sé", B: "Rua Direita", Substantive: true}},
+		},
+		{
+			name: "outvoted",
+			providers: []address.Provider{
+				addresstest.NewMockProvider("A").Returns(sé),
+				addresstest.NewMockProvider("B").Returns(sameSé),
+				addresstest.NewMockProvider("C").Returns(elsewhere),
+			},
+			want: map[string]float64{"A": address.CONFIDENCE_AGREED, "B": address.CONFIDENCE_AGREED, "C": address.CONFIDENCE_CONFLICT},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := newService(t, test.providers...).ExecuteAll("01001000")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, result := range results {
+				if result.Err != nil {
+					t.Fatalf("%s failed: %v", result.Provider, result.Err)
+				}
+				if got, want := result.Address.Confidence, test.want[result.Provider]; got != want {
+					t.Errorf("%s confidence = %v, want %v", result.Provider, got, want)
+				}
+				if result.Address.Confidence != address.CONFIDENCE_CONFLICT && result.Address.Conflicts != nil {
+					t.Errorf("%s conflicts = %+v, want none", result.Provider, result.Address.Conflicts)
+				}
+			}
+			if test.conflicts != nil {
+				if got := results[0].Address.Conflicts; !reflect.DeepEqual(got, test.conflicts) {
+					t.Errorf("conflicts = %+v, want %+v", got, test.conflicts)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteScoresALoneWinnerSingle(t *testing.T) {
+	service := newService(t,
+		addresstest.NewMockProvider("Fast").Returns(sé),
+		addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Hour),
+	)
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Confidence != address.CONFIDENCE_SINGLE || result.Conflicts != nil {
+		t.Errorf("confidence = %v with conflicts %+v, want CONFIDENCE_SINGLE without waiting for Slow", result.Confidence, result.Conflicts)
+	}
+}
@@ -0,0 +1,134 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// DEADLINE_EPSILON is how late past its timeout a lookup may return here.
+// Execute promises a few milliseconds; the rest is headroom for -race and
+// loaded machines.
+const DEADLINE_EPSILON = 25 * time.Millisecond
+
+// stubborn returns a provider that ignores its context and only returns
+// once the test is over.
+func stubborn(t *testing.T, name string) address.Provider {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	return address.NewProvider(name, func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		<-release
+		return sé, nil
+	})
+}
+
+// trickling returns a ViaCEP provider whose server sends the start of an
+// answer and then a byte every 20ms, for as long as the client listens.
+func trickling(t *testing.T) address.Provider {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cep": "01001-000", "logradouro": "`))
+		w.(http.Flusher).Flush()
+
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				w.Write([]byte("a"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return address.NewViaCEPProvider(server.URL + "/ws")
+}
+
+func TestExecuteReturnsOnTimeWithFakeClock(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	service := newService(t, stubborn(t, "A"), stubborn(t, "B")).SetClock(clock).SetTimeout(50 * time.Millisecond)
+
+	done := startLookup(service, "01001000")
+	clock.BlockUntilTimers(1)
+	clock.Advance(50 * time.Millisecond)
+	select {
+	case got := <-done:
+		if !errors.Is(got.err, address.ErrTimeout) || got.report.Duration != 50*time.Millisecond {
+			t.Errorf("err = %v after %v, want ErrTimeout after 50ms", got.err, got.report.Duration)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Execute waited for providers ignoring their context")
+	}
+}
+
+func TestExecuteAllReturnsOnTimeWithFakeClock(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	service := newService(t, stubborn(t, "A"), stubborn(t, "B")).SetClock(clock).SetTimeout(50 * time.Millisecond)
+
+	done := make(chan []address.ProviderResult, 1)
+	go func() {
+		results, _ := service.ExecuteAll("01001000")
+		done <- results
+	}()
+	clock.BlockUntilTimers(1)
+	clock.Advance(50 * time.Millisecond)
+
+	select {
+	case results := <-done:
+		for _, result := range results {
+			if !errors.Is(result.Err, address.ErrTimeout) || result.Latency != 50*time.Millisecond {
+				t.Errorf("%s: %v after %v, want ErrTimeout after 50ms", result.Provider, result.Err, result.Latency)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ExecuteAll waited for providers ignoring their context")
+	}
+}
+
+// On the real clock, with every kind of slow provider at once.
+func TestExecuteNeverOverrunsItsTimeout(t *testing.T) {
+	const TIMEOUT = 30 * time.Millisecond
+
+	huge := addresstest.NewViaCEPServer(nil)
+	t.Cleanup(huge.Close)
+	faults := addresstest.NewFaultTransport(nil).
+		On("/huge/", addresstest.Fault{Status: http.StatusOK, Body: `{"cep": "` + strings.Repeat("0", 4<<20) + `"}`, Latency: 25 * time.Millisecond})
+
+	service := newService(t,
+		stubborn(t, "Stubborn"),
+		trickling(t),
+		address.NewBrasilAPIProvider(huge.URL+"/huge"),
+	).SetTransport(faults).SetTimeout(TIMEOUT)
+
+	worst := time.Duration(0)
+	for run := 0; run < 50; run++ {
+		start := time.Now()
+		_, err := service.Execute("01001000")
+		elapsed := time.Since(start)
+		if !errors.Is(err, address.ErrTimeout) {
+			t.Fatalf("run %d: err = %v, want ErrTimeout", run, err)
+		}
+		worst = max(worst, elapsed-TIMEOUT)
+
+		start = time.Now()
+		if _, err := service.ExecuteAll("01001000"); err != nil {
+			t.Fatalf("run %d: ExecuteAll: %v", run, err)
+		}
+		worst = max(worst, time.Since(start)-TIMEOUT)
+	}
+
+	if worst > DEADLINE_EPSILON {
+		t.Errorf("a lookup returned %v past its timeout, want at most %v", worst, DEADLINE_EPSILON)
+	}
+}
@@ -0,0 +1,80 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldDiff is a field on which two results differ.
+type FieldDiff struct {
+	// Field is "street", "neighborhood", "city", "state" or "zip".
+	Field string `json:"field" xml:"field,attr"`
+	A     string `json:"a" xml:"a"`
+	B     string `json:"b" xml:"b"`
+	// Substantive is false when the values only differ in formatting,
+	// that is they are equal once normalized for comparison.
+	Substantive bool `json:"substantive" xml:"substantive"`
+}
+
+// FieldDiffs is the outcome of Diff.
+type FieldDiffs []FieldDiff
+
+var diffFields = []struct {
+	name      string
+	value     func(AddressResult) string
+	normalize func(string) string
+}{
+	{"street", func(r AddressResult) string { return r.Street }, NormalizeForComparison},
+	{"neighborhood", func(r AddressResult) string { return r.Neighborhood }, NormalizeForComparison},
+	{"city", func(r AddressResult) string { return r.City }, NormalizeForComparison},
+	{"state", func(r AddressResult) string { return r.State }, NormalizeForComparison},
+	{"zip", func(r AddressResult) string { return r.ZipCode }, digits},
+}
+
+// Diff lists the fields on which a and b differ, telling formatting
+// differences, like accents, case or the dash of a CEP, from real
+// disagreements. Identical results give no diffs; Source is not compared.
+func Diff(a, b AddressResult) FieldDiffs {
+	var diffs FieldDiffs
+	for _, field := range diffFields {
+		valueA, valueB := field.value(a), field.value(b)
+		if valueA == valueB {
+			continue
+		}
+
+		diffs = append(diffs, FieldDiff{
+			Field:       field.name,
+			A:           valueA,
+			B:           valueB,
+			Substantive: field.normalize(valueA) != field.normalize(valueB),
+		})
+	}
+
+	return diffs
+}
+
+// Substantive reports whether any of the diffs is a real disagreement.
+func (d FieldDiffs) Substantive() bool {
+	for _, diff := range d {
+		if diff.Substantive {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String renders one diff per line, as `city: "São Paulo" vs "SAO PAULO"
+// (formatting)`.
+func (d FieldDiffs) String() string {
+	var b strings.Builder
+	for _, diff := range d {
+		fmt.Fprintf(&b, "%s: %q vs %q", diff.Field, diff.A, diff.B)
+		if !diff.Substantive {
+			b.WriteString(" (formatting)")
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package address_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestDiff(t *testing.T) {
+	sé := address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", Source: "ViaCEP"}
+
+	tests := []struct {
+		name        string
+		b           address.AddressResult
+		want        address.FieldDiffs
+		substantive bool
+	}{
+		{
+			name: "identical",
+			b:    address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", Source: "BrasilAPI"},
+		},
+		{
+			name: "formatting only",
+			b:    address.AddressResult{ZipCode: "01001-000", Street: "PRAÇA DA SÉ", Neighborhood: "Sé", City: "Sao Paulo", State: "sp"},
+			want: address.FieldDiffs{
+				{Field: "street", A: "Praça da Sé", B: "PRAÇA DA SÉ"},
+				{Field: "city", A: "São Paulo", B: "Sao Paulo"},
+				{Field: "state", A: "SP", B: "sp"},
+				{Field: "zip", A: "01001000", B: "01001-000"},
+			},
+		},
+		{
+			name: "substantive",
+			b:    address.AddressResult{ZipCode: "01001001", Street: "Praça da Sé", Neighborhood: "Centro", City: "São Paulo", State: "SP"},
+			want: address.FieldDiffs{
+				{Field: "neighborhood", A: "Sé", B: "Centro", Substantive: true},
+				{Field: "zip", A: "01001000", B: "01001001", Substantive: true},
+			},
+			substantive: true,
+		},
+		{
+			name: "mixed",
+			b:    address.AddressResult{ZipCode: "01001000", Street: "Praca da Se", Neighborhood: "Sé", City: "São Paulo", State: "RJ"},
+			want: address.FieldDiffs{
+				{Field: "street", A: "Praça da Sé", B: "Praca da Se"},
+				{Field: "state", A: "SP", B: "RJ", Substantive: true},
+			},
+			substantive: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs := address.Diff(sé, test.b)
+			if !reflect.DeepEqual(diffs, test.want) {
+				t.Errorf("Diff = %+v, want %+v", diffs, test.want)
+			}
+			if diffs.Substantive() != test.substantive {
+				t.Errorf("Substantive() = %t, want %t", diffs.Substantive(), test.substantive)
+			}
+		})
+	}
+}
+
+func TestFieldDiffsString(t *testing.T) {
+	diffs := address.FieldDiffs{
+		{Field: "city", A: "São Paulo", B: "SAO PAULO"},
+		{Field: "zip", A: "01001000", B: "01001001", Substantive: true},
+	}
+
+	want := "city: \"São Paulo\" vs \"SAO PAULO\" (formatting)\nzip: \"01001000\" vs \"01001001\"\n"
+	if got := diffs.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := address.FieldDiffs(nil).String(); got != "" {
+		t.Errorf("no diffs render as %q, want nothing", got)
+	}
+}
@@ -0,0 +1,130 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EARTH_RADIUS_KM is the mean radius of the Earth used by Distance.
+const EARTH_RADIUS_KM = 6371.0088
+
+var ErrNoCoordinates = errors.New("no coordinates")
+
+// Coordinates locate an address, in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude" xml:"latitude"`
+	Longitude float64 `json:"longitude" xml:"longitude"`
+}
+
+// CoordinateProvider is a Provider that can answer with coordinates.
+type CoordinateProvider interface {
+	Provider
+	ProvidesCoordinates() bool
+}
+
+// parseCoordinates parses coordinates sent as strings, or returns nil when
+// either is missing or out of range.
+func parseCoordinates(latitude, longitude string) *Coordinates {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latitude), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(longitude), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil
+	}
+
+	return &Coordinates{Latitude: lat, Longitude: lon}
+}
+
+// Haversine returns the great-circle distance between a and b in
+// kilometers.
+func Haversine(a, b Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTH_RADIUS_KM * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Distance resolves cepA and cepB concurrently, like ExecuteContext, and
+// returns the great-circle distance between them in kilometers. A CEP whose
+// answer has no coordinates is queried again with a registered
+// CoordinateProvider, or with BrasilAPIv2 when none is registered; if that
+// has none either, Distance fails with ErrNoCoordinates naming the CEP.
+func (s *AddressService) Distance(ctx context.Context, cepA, cepB string) (float64, error) {
+	type located struct {
+		coordinates Coordinates
+		err         error
+	}
+
+	second := make(chan located, 1)
+	go func() {
+		coordinates, err := s.locate(ctx, cepB)
+		second <- located{coordinates, err}
+	}()
+
+	a, err := s.locate(ctx, cepA)
+	b := <-second
+	if err != nil {
+		return 0, err
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return Haversine(a, b.coordinates), nil
+}
+
+// locate returns the coordinates of cep, from its lookup or from a
+// CoordinateProvider.
+func (s *AddressService) locate(ctx context.Context, cep string) (Coordinates, error) {
+	result, err := s.ExecuteContext(ctx, cep)
+	if err != nil {
+		return Coordinates{}, err
+	}
+
+	if result.Location != nil {
+		return *result.Location, nil
+	}
+
+	provider := s.coordinateProvider()
+	if provider.Name() != result.Source {
+		config := s.settings()
+		client, _ := config.clientFor(provider.Name())
+
+		ctx, cancel := context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+
+		located, err := callProvider(ctx, provider, client, result.ZipCode)
+		if err == nil && located.Location != nil {
+			return *located.Location, nil
+		}
+		if err != nil {
+			config.logger.Info("coordinate fallback failed", "provider", provider.Name(), "cep", result.ZipCode, "error", err)
+		}
+	}
+
+	return Coordinates{}, fmt.Errorf("%w for CEP %s", ErrNoCoordinates, result.ZipCode)
+}
+
+// coordinateProvider is the first registered provider that provides
+// coordinates, or BrasilAPIv2.
+func (s *AddressService) coordinateProvider() Provider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, provider := range s.registry {
+		if capable, ok := provider.(CoordinateProvider); ok && capable.ProvidesCoordinates() {
+			return provider
+		}
+	}
+
+	return NewBrasilAPIV2Provider(BRASILAPI_V2_BASE_URL)
+}
@@ -0,0 +1,144 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+var (
+	séLocation         = &address.Coordinates{Latitude: -23.5503, Longitude: -46.6339}
+	candeláriaLocation = &address.Coordinates{Latitude: -22.9009, Longitude: -43.1770}
+)
+
+// SÉ_TO_CANDELÁRIA_KM is the great-circle distance between the fixture
+// coordinates.
+const SÉ_TO_CANDELÁRIA_KM = 360.53
+
+// gazetteer answers the Sé and Candelária CEPs, with their coordinates when
+// located is set, and counts its calls.
+type gazetteer struct {
+	name    string
+	located bool
+	calls   atomic.Int32
+}
+
+func (g *gazetteer) Name() string {
+	return g.name
+}
+
+func (g *gazetteer) ProvidesCoordinates() bool {
+	return g.located
+}
+
+func (g *gazetteer) GetAddress(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+	g.calls.Add(1)
+
+	var result address.AddressResult
+	switch cep {
+	case "01001000":
+		result = address.AddressResult{ZipCode: cep, Street: "Praça da Sé", City: "São Paulo", State: "SP", Location: séLocation}
+	case "20040010":
+		result = address.AddressResult{ZipCode: cep, Street: "Rua da Candelária", City: "Rio de Janeiro", State: "RJ", Location: candeláriaLocation}
+	default:
+		return address.AddressResult{}, address.ErrNotFound
+	}
+
+	if !g.located {
+		result.Location = nil
+	}
+	return result, nil
+}
+
+func TestDistance(t *testing.T) {
+	service := newService(t, &gazetteer{name: "Located", located: true})
+
+	distance, err := service.Distance(context.Background(), "01001-000", "20040010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(distance-SÉ_TO_CANDELÁRIA_KM) > 0.5 {
+		t.Errorf("distance = %.2f km, want about %.2f", distance, SÉ_TO_CANDELÁRIA_KM)
+	}
+
+	if same, err := service.Distance(context.Background(), "01001000", "01001000"); err != nil || same != 0 {
+		t.Errorf("distance to itself = %v, %v, want 0", same, err)
+	}
+}
+
+// The winner knows no coordinates, so the registered coordinate provider,
+// which is not raced, is asked for them.
+func TestDistanceFallsBackToACoordinateProvider(t *testing.T) {
+	plain := &gazetteer{name: "Plain"}
+	located := &gazetteer{name: "Located", located: true}
+	service := newService(t, plain, located)
+	if err := service.SetProviders("Plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	distance, err := service.Distance(context.Background(), "01001000", "20040010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(distance-SÉ_TO_CANDELÁRIA_KM) > 0.5 {
+		t.Errorf("distance = %.2f km, want about %.2f", distance, SÉ_TO_CANDELÁRIA_KM)
+	}
+	if plain.calls.Load() != 2 || located.calls.Load() != 2 {
+		t.Errorf("%d lookups and %d fallbacks, want 2 of each", plain.calls.Load(), located.calls.Load())
+	}
+}
+
+func TestDistanceWithoutCoordinates(t *testing.T) {
+	// Claims coordinates, but has none for these CEPs.
+	empty := &gazetteer{name: "Empty"}
+	service := newService(t, &gazetteer{name: "Plain"}, coordinateClaim{empty})
+	if err := service.SetProviders("Plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := service.Distance(context.Background(), "01001000", "20040010")
+	if !errors.Is(err, address.ErrNoCoordinates) || !strings.Contains(err.Error(), "01001000") {
+		t.Errorf("err = %v, want ErrNoCoordinates naming 01001000", err)
+	}
+}
+
+func TestDistanceOfAnUnknownCEP(t *testing.T) {
+	service := newService(t, &gazetteer{name: "Located", located: true})
+
+	if _, err := service.Distance(context.Background(), "01001000", "99999999"); !errors.Is(err, address.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+// coordinateClaim is a provider that says it provides coordinates.
+type coordinateClaim struct {
+	*gazetteer
+}
+
+func (coordinateClaim) ProvidesCoordinates() bool {
+	return true
+}
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		a, b address.Coordinates
+		km   float64
+	}{
+		{a: *séLocation, b: *candeláriaLocation, km: SÉ_TO_CANDELÁRIA_KM},
+		{a: address.Coordinates{}, b: address.Coordinates{Longitude: 180}, km: math.Pi * address.EARTH_RADIUS_KM},
+		{a: address.Coordinates{Latitude: 90}, b: address.Coordinates{Latitude: -90}, km: math.Pi * address.EARTH_RADIUS_KM},
+		{a: *séLocation, b: *séLocation, km: 0},
+	}
+
+	for _, test := range tests {
+		if km := address.Haversine(test.a, test.b); math.Abs(km-test.km) > 0.5 {
+			t.Errorf("Haversine(%v, %v) = %.2f, want %.2f", test.a, test.b, km, test.km)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"strings"
+)
+
+var ErrInvalidHostOverride = errors.New("invalid host override")
+
+// SetHostOverride makes provider requests to host connect to addr instead,
+// as an /etc/hosts entry would, for networks where a provider is reached
+// through an internal address. addr is an IP or host name, with or without
+// a port; without one the request's port is kept. TLS verification and the
+// Host header still use host. An empty addr removes the override. Like
+// SetTransport it replaces a transport or client set earlier.
+func (s *AddressService) SetHostOverride(host string, addr string) error {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrInvalidHostOverride)
+	}
+
+	if addr != "" {
+		target := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			target = h
+		}
+		if target == "" || strings.ContainsAny(addr, "/ ") {
+			return fmt.Errorf("%w: %q for %s", ErrInvalidHostOverride, addr, host)
+		}
+	}
+
+	return s.setTransportOption(func() {
+		// The current transport dials with the old map, so it is replaced
+		// rather than changed.
+		hosts := maps.Clone(s.transport.hosts)
+		if hosts == nil {
+			hosts = make(map[string]string)
+		}
+
+		if addr == "" {
+			delete(hosts, host)
+		} else {
+			hosts[host] = addr
+		}
+		s.transport.hosts = hosts
+	})
+}
+
+// SetResolver makes provider requests resolve host names with resolver, for
+// example one pointed at a specific DNS server. nil goes back to the system
+// resolver. Like SetTransport it replaces a transport or client set earlier.
+func (s *AddressService) SetResolver(resolver *net.Resolver) error {
+	return s.setTransportOption(func() { s.transport.resolver = resolver })
+}
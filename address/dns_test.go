@@ -0,0 +1,178 @@
+package address_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// hostRecorder wraps the ViaCEP fake and keeps the Host header of every
+// request.
+type hostRecorder struct {
+	next http.Handler
+
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (h *hostRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.hosts = append(h.hosts, r.Host)
+	h.mu.Unlock()
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *hostRecorder) seen() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.hosts...)
+}
+
+func TestHostOverride(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(map[string]address.AddressResult{"01001000": sé})
+	t.Cleanup(viaCEP.Close)
+	recorder := &hostRecorder{next: viaCEP.Config.Handler}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+	listener := strings.TrimPrefix(server.URL, "http://")
+	_, port, _ := net.SplitHostPort(listener)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		addr    string
+		host    string
+	}{
+		{name: "with port", baseURL: "http://viacep.test/ws", addr: listener, host: "viacep.test"},
+		{name: "request port kept", baseURL: "http://viacep.test:" + port + "/ws", addr: "127.0.0.1", host: "viacep.test:" + port},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newService(t, address.NewViaCEPProvider(test.baseURL))
+			// Any name still resolved would hang on this resolver.
+			if err := service.SetResolver(blackHole()); err != nil {
+				t.Fatal(err)
+			}
+			if err := service.SetHostOverride("ViaCEP.test", test.addr); err != nil {
+				t.Fatal(err)
+			}
+
+			before := len(recorder.seen())
+			result, err := service.Execute("01001000")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Street != sé.Street {
+				t.Errorf("result = %+v", result)
+			}
+			if hosts := recorder.seen()[before:]; len(hosts) != 1 || hosts[0] != test.host {
+				t.Errorf("Host = %v, want %s", hosts, test.host)
+			}
+		})
+	}
+}
+
+// TLS still verifies the original name, which the test certificate holds.
+func TestHostOverrideKeepsTLSName(t *testing.T) {
+	viaCEP := addresstest.NewViaCEPServer(map[string]address.AddressResult{"01001000": sé})
+	t.Cleanup(viaCEP.Close)
+	secure := httptest.NewUnstartedServer(viaCEP.Config.Handler)
+	// The handshake refused for the wrong name is expected.
+	secure.Config.ErrorLog = log.New(io.Discard, "", 0)
+	secure.StartTLS()
+	t.Cleanup(secure.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(secure.Certificate())
+
+	service := newService(t, address.NewViaCEPProvider("https://example.com/ws"))
+	if err := service.SetTLSConfig(&tls.Config{RootCAs: roots}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetHostOverride("example.com", strings.TrimPrefix(secure.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overridden to a name the certificate does not hold, verification
+	// fails.
+	service = newService(t, address.NewViaCEPProvider("https://viacep.test/ws"))
+	if err := service.SetTLSConfig(&tls.Config{RootCAs: roots}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetHostOverride("viacep.test", strings.TrimPrefix(secure.URL, "https://")); err != nil {
+		t.Fatal(err)
+	}
+	var hostname x509.HostnameError
+	if _, err := service.Execute("01001000"); !errors.As(err, &hostname) {
+		t.Errorf("err = %v, want a hostname mismatch", err)
+	}
+}
+
+func TestHostOverrideRemoved(t *testing.T) {
+	service := newService(t, address.NewViaCEPProvider("http://viacep.test/ws"))
+	if err := service.SetResolver(failingResolver(new(atomic.Int32))); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetHostOverride("viacep.test", "127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetHostOverride("viacep.test", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var dns *net.DNSError
+	if _, err := service.Execute("01001000"); !errors.As(err, &dns) {
+		t.Errorf("err = %v, want the name resolved again", err)
+	}
+}
+
+func TestInvalidHostOverride(t *testing.T) {
+	service := newService(t)
+	for _, override := range [][2]string{{"", "127.0.0.1"}, {"  ", "127.0.0.1"}, {"viacep.test", ":8080"}, {"viacep.test", "http://proxy"}, {"viacep.test", "a b"}} {
+		if err := service.SetHostOverride(override[0], override[1]); !errors.Is(err, address.ErrInvalidHostOverride) {
+			t.Errorf("SetHostOverride(%q, %q) = %v, want ErrInvalidHostOverride", override[0], override[1], err)
+		}
+	}
+}
+
+// failingResolver counts the DNS queries it is asked and fails them.
+func failingResolver(queries *atomic.Int32) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			queries.Add(1)
+			return nil, errors.New("no DNS here")
+		},
+	}
+}
+
+func TestResolverIsUsed(t *testing.T) {
+	var queries atomic.Int32
+	service := newService(t, address.NewViaCEPProvider("http://viacep.test/ws"))
+	if err := service.SetResolver(failingResolver(&queries)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Execute("01001000"); err == nil {
+		t.Fatal("lookup succeeded without DNS")
+	}
+	if queries.Load() == 0 {
+		t.Error("the resolver was never asked")
+	}
+}
@@ -0,0 +1,118 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DEFAULT_ENRICHMENT_TIMEOUT bounds each enricher unless
+// SetEnrichmentTimeout says otherwise.
+const DEFAULT_ENRICHMENT_TIMEOUT = 1 * time.Second
+
+var ErrEnrichmentFailed = errors.New("enrichment failed")
+
+// Enricher adds data the providers cannot supply to a resolved address, such
+// as a delivery zone. Enrich may change any field of result. It should stop
+// when ctx is done; an enricher that does not is abandoned, and whatever it
+// writes afterwards is discarded.
+type Enricher interface {
+	Enrich(ctx context.Context, result *AddressResult) error
+}
+
+// enrichment is the enrichment configuration of a service.
+type enrichment struct {
+	enrichers []Enricher
+	timeout   time.Duration
+	required  bool
+}
+
+// SetEnrichers makes enrichers run, in order, on the winning result of every
+// lookup before Execute returns it and before it is cached. Results taken
+// from the cache are enriched again only when an earlier enrichment did not
+// complete, starting over from the provider's answer. A failing enricher is
+// logged and skipped, unless SetEnrichmentRequired is on. No enrichers turns
+// enrichment off.
+func (s *AddressService) SetEnrichers(enrichers ...Enricher) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enrichment.enrichers = slices.Clone(enrichers)
+	return s
+}
+
+// SetEnrichmentTimeout bounds each enricher by timeout, on top of the
+// lookup's own context.
+func (s *AddressService) SetEnrichmentTimeout(timeout time.Duration) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enrichment.timeout = timeout
+	return s
+}
+
+// SetEnrichmentRequired makes a failing or timed out enricher fail the
+// lookup with ErrEnrichmentFailed instead of only being logged.
+func (s *AddressService) SetEnrichmentRequired(required bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enrichment.required = required
+	return s
+}
+
+// enrich runs the enrichers on result and sets Enriched once all of them
+// succeeded.
+func (e enrichment) enrich(ctx context.Context, config settings, result AddressResult) (AddressResult, error) {
+	if len(e.enrichers) == 0 || result.Enriched {
+		return result, nil
+	}
+
+	complete := true
+	for _, enricher := range e.enrichers {
+		enriched, err := e.run(ctx, enricher, result)
+		if err != nil {
+			err = fmt.Errorf("%w: %T: %w", ErrEnrichmentFailed, enricher, err)
+			if e.required {
+				return result, err
+			}
+
+			config.logger.Warn("enricher failed", "cep", result.ZipCode, "error", err)
+			complete = false
+			continue
+		}
+
+		result = enriched
+	}
+
+	result.Enriched = complete
+	return result, nil
+}
+
+// run calls enricher on a copy of result, apart from the caller so an
+// enricher that ignores its context cannot hold the lookup past the
+// timeout.
+func (e enrichment) run(ctx context.Context, enricher Enricher, result AddressResult) (AddressResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	type enriched struct {
+		result AddressResult
+		err    error
+	}
+
+	done := make(chan enriched, 1)
+	go func() {
+		err := enricher.Enrich(ctx, &result)
+		done <- enriched{result, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return AddressResult{}, ctx.Err()
+	case enriched := <-done:
+		return enriched.result, enriched.err
+	}
+}
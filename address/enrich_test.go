@@ -0,0 +1,154 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// fakeEnricher tags the street with its name, or fails with err, and counts
+// its calls. A blocking one ignores its context.
+type fakeEnricher struct {
+	tag      string
+	err      error
+	blocking bool
+	calls    atomic.Int32
+}
+
+func (e *fakeEnricher) Enrich(ctx context.Context, result *address.AddressResult) error {
+	e.calls.Add(1)
+	if e.blocking {
+		time.Sleep(time.Second)
+	}
+	if e.err != nil {
+		return e.err
+	}
+
+	result.Street += " [" + e.tag + "]"
+	return nil
+}
+
+func TestEnrichers(t *testing.T) {
+	down := errors.New("geocoder down")
+
+	tests := []struct {
+		name      string
+		enrichers []*fakeEnricher
+		required  bool
+		street    string
+		enriched  bool
+		err       error
+	}{
+		{
+			name:      "success",
+			enrichers: []*fakeEnricher{{tag: "geo"}, {tag: "zone"}},
+			street:    sé.Street + " [geo] [zone]",
+			enriched:  true,
+		},
+		{
+			name:      "failure ignored",
+			enrichers: []*fakeEnricher{{tag: "geo", err: down}, {tag: "zone"}},
+			street:    sé.Street + " [zone]",
+		},
+		{
+			name:      "failure fatal",
+			enrichers: []*fakeEnricher{{tag: "geo", err: down}, {tag: "zone"}},
+			required:  true,
+			err:       down,
+		},
+		{
+			name:      "timeout ignored",
+			enrichers: []*fakeEnricher{{tag: "geo"}, {tag: "zone", blocking: true}},
+			street:    sé.Street + " [geo]",
+		},
+		{
+			name:      "timeout fatal",
+			enrichers: []*fakeEnricher{{tag: "geo"}, {tag: "zone", blocking: true}},
+			required:  true,
+			err:       context.DeadlineExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enrichers := make([]address.Enricher, len(test.enrichers))
+			for i, enricher := range test.enrichers {
+				enrichers[i] = enricher
+			}
+			service := newService(t, addresstest.NewMockProvider("A").Returns(sé)).
+				SetEnrichers(enrichers...).
+				SetEnrichmentTimeout(50 * time.Millisecond).
+				SetEnrichmentRequired(test.required)
+
+			start := time.Now()
+			result, err := service.Execute("01001000")
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("lookup took %v, want the enrichment timeout to cut it short", elapsed)
+			}
+
+			if test.err != nil {
+				if !errors.Is(err, address.ErrEnrichmentFailed) || !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want ErrEnrichmentFailed with %v", err, test.err)
+				}
+				if n := test.enrichers[1].calls.Load(); test.enrichers[0].err != nil && n != 0 {
+					t.Errorf("the enricher after a fatal failure ran %d times", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Street != test.street || result.Enriched != test.enriched {
+				t.Errorf("result = %q, enriched %t, want %q, enriched %t", result.Street, result.Enriched, test.street, test.enriched)
+			}
+		})
+	}
+}
+
+func TestEnrichedCacheHitsAreNotEnrichedAgain(t *testing.T) {
+	zone := &fakeEnricher{tag: "zone"}
+	provider := addresstest.NewMockProvider("A").Returns(sé)
+	service := newService(t, provider).SetCache(address.NewMemoryCache(time.Minute)).SetEnrichers(zone)
+
+	for lookup := 1; lookup <= 3; lookup++ {
+		result, err := service.Execute("01001000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Street != sé.Street+" [zone]" || !result.Enriched {
+			t.Fatalf("lookup %d: result = %q, enriched %t", lookup, result.Street, result.Enriched)
+		}
+	}
+	provider.AssertCalls(t, 1)
+	if n := zone.calls.Load(); n != 1 {
+		t.Errorf("enricher ran %d times, want only for the provider's answer", n)
+	}
+}
+
+func TestPartlyEnrichedCacheHitsStartOver(t *testing.T) {
+	geo := &fakeEnricher{tag: "geo", err: errors.New("geocoder down")}
+	zone := &fakeEnricher{tag: "zone"}
+	service := newService(t, addresstest.NewMockProvider("A").Returns(sé)).
+		SetCache(address.NewMemoryCache(time.Minute)).
+		SetEnrichers(geo, zone)
+
+	for lookup := 1; lookup <= 2; lookup++ {
+		result, err := service.Execute("01001000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Starting over from the provider's answer, the street is tagged
+		// once.
+		if result.Street != sé.Street+" [zone]" || result.Enriched {
+			t.Fatalf("lookup %d: result = %q, enriched %t", lookup, result.Street, result.Enriched)
+		}
+	}
+	if geo.calls.Load() != 2 || zone.calls.Load() != 2 {
+		t.Errorf("enrichers ran %d and %d times, want 2 each", geo.calls.Load(), zone.calls.Load())
+	}
+}
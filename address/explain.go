@@ -0,0 +1,110 @@
+package address
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// explainEvent is a line of the timeline. At the same time, launches come
+// before answers and answers before the cancellations they caused.
+type explainEvent struct {
+	at   time.Duration
+	rank int
+	text string
+}
+
+// Explain tells the story of the lookup as a timeline, each event with its
+// time since the lookup started:
+//
+//	0ms launch ViaCEP; 0ms launch BrasilAPI; 120ms BrasilAPI 200 OK (winner); 121ms cancel ViaCEP; total 122ms
+//
+// Retries show up with their attempt number and the backoff waited before
+// them, and the time spent waiting for SetRateLimit and the cache's answer,
+// hit or miss, come first.
+func (r Report) Explain() string {
+	var parts []string
+	if r.RateLimitWait > 0 {
+		parts = append(parts, fmt.Sprintf("waited %s for the rate limit", explainTime(r.RateLimitWait)))
+	}
+	switch {
+	case r.Cached:
+		parts = append(parts, "0ms cache hit")
+	case r.CacheMiss:
+		parts = append(parts, "0ms cache miss")
+	}
+
+	var events []explainEvent
+	ended := make(map[string]time.Duration)
+	for _, attempt := range r.Attempts {
+		launch := "launch " + attempt.Provider
+		if attempt.Number > 1 {
+			launch = fmt.Sprintf("retry %s (attempt %d", attempt.Provider, attempt.Number)
+			if end, ok := ended[attempt.Provider]; ok && attempt.Start > end {
+				launch += ", after " + explainTime(attempt.Start-end) + " backoff"
+			}
+			launch += ")"
+		}
+		events = append(events, explainEvent{at: attempt.Start, text: launch})
+
+		end := attempt.Start + attempt.Duration
+		ended[attempt.Provider] = end
+		switch attempt.Outcome {
+		case OUTCOME_PENDING:
+			events = append(events, explainEvent{at: r.Duration, rank: 2, text: "no answer from " + attempt.Provider})
+			continue
+		case OUTCOME_CANCELLED:
+			// An attempt still running when the lookup returned was
+			// cancelled then.
+			if attempt.Duration == 0 {
+				end = r.Duration
+			}
+			events = append(events, explainEvent{at: end, rank: 2, text: "cancel " + attempt.Provider})
+			continue
+		}
+
+		answer := attempt.Provider
+		if attempt.StatusCode != 0 {
+			answer += fmt.Sprintf(" %d %s", attempt.StatusCode, http.StatusText(attempt.StatusCode))
+		}
+		for _, redirect := range attempt.Redirects {
+			answer += " via " + redirect
+		}
+		switch attempt.Outcome {
+		case OUTCOME_WON:
+			answer += " (winner)"
+		case OUTCOME_LOST:
+			answer += " (lost)"
+		case OUTCOME_FAILED:
+			answer += " (failed"
+			if attempt.Err != nil {
+				answer += ": " + strings.TrimPrefix(attempt.Err.Error(), attempt.Provider+": ")
+			}
+			answer += ")"
+		}
+		events = append(events, explainEvent{at: end, rank: 1, text: answer})
+	}
+
+	slices.SortStableFunc(events, func(a, b explainEvent) int {
+		if at := cmp.Compare(a.at.Round(time.Millisecond), b.at.Round(time.Millisecond)); at != 0 {
+			return at
+		}
+		return cmp.Compare(a.rank, b.rank)
+	})
+	for _, event := range events {
+		parts = append(parts, explainTime(event.at)+" "+event.text)
+	}
+
+	if len(r.Attempts) == 0 && !r.Cached {
+		parts = append(parts, "no provider queried")
+	}
+	parts = append(parts, "total "+explainTime(r.Duration))
+	return strings.Join(parts, "; ")
+}
+
+func explainTime(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Round(time.Millisecond).Milliseconds())
+}
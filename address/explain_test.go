@@ -0,0 +1,97 @@
+package address_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestExplain(t *testing.T) {
+	const ms = time.Millisecond
+
+	tests := []struct {
+		name   string
+		report address.Report
+		want   string
+	}{
+		{
+			name: "race",
+			report: address.Report{
+				Duration: 122 * ms,
+				Attempts: []address.Attempt{
+					{Provider: "ViaCEP", Number: 1, Outcome: address.OUTCOME_CANCELLED},
+					{Provider: "BrasilAPI", Number: 1, Duration: 120 * ms, StatusCode: 200, Outcome: address.OUTCOME_WON},
+				},
+			},
+			want: "0ms launch ViaCEP; 0ms launch BrasilAPI; 120ms BrasilAPI 200 OK (winner); 122ms cancel ViaCEP; total 122ms",
+		},
+		{
+			name: "retry after a failure",
+			report: address.Report{
+				Duration:  400 * ms,
+				CacheMiss: true,
+				Attempts: []address.Attempt{
+					{Provider: "ViaCEP", Number: 1, Duration: 50 * ms, StatusCode: 503, Outcome: address.OUTCOME_FAILED, Err: errors.New("ViaCEP: unexpected status 503")},
+					{Provider: "ViaCEP", Number: 2, Start: 150 * ms, Duration: 250 * ms, StatusCode: 200, Outcome: address.OUTCOME_WON, Redirects: []string{"https://viacep.com.br/ws/01001000/json/"}},
+				},
+			},
+			want: "0ms cache miss; 0ms launch ViaCEP; 50ms ViaCEP 503 Service Unavailable (failed: unexpected status 503); " +
+				"150ms retry ViaCEP (attempt 2, after 100ms backoff); 400ms ViaCEP 200 OK via https://viacep.com.br/ws/01001000/json/ (winner); total 400ms",
+		},
+		{
+			name: "timeout",
+			report: address.Report{
+				Duration:      1000 * ms,
+				RateLimitWait: 30 * ms,
+				Attempts: []address.Attempt{
+					{Provider: "ViaCEP", Number: 1, Outcome: address.OUTCOME_PENDING},
+				},
+			},
+			want: "waited 30ms for the rate limit; 0ms launch ViaCEP; 1000ms no answer from ViaCEP; total 1000ms",
+		},
+		{
+			name:   "cache hit",
+			report: address.Report{Cached: true},
+			want:   "0ms cache hit; total 0ms",
+		},
+		{
+			name:   "nothing asked",
+			report: address.Report{Duration: time.Millisecond},
+			want:   "no provider queried; total 1ms",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.report.Explain(); got != test.want {
+				t.Errorf("Explain() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExplainLiveRace(t *testing.T) {
+	service := newService(t,
+		addresstest.NewMockProvider("Fast").Returns(sé).SetLatency(10*time.Millisecond),
+		addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Hour),
+	)
+
+	_, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explained := report.Explain()
+	for _, want := range []string{"launch Fast", "launch Slow", "Fast (winner)", "cancel Slow", "total "} {
+		if !strings.Contains(explained, want) {
+			t.Errorf("Explain() = %q, want it to contain %q", explained, want)
+		}
+	}
+	if strings.Index(explained, "Fast (winner)") > strings.Index(explained, "cancel Slow") {
+		t.Errorf("Explain() = %q, want the win before the cancellation it caused", explained)
+	}
+}
@@ -0,0 +1,110 @@
+package address_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func FuzzNormalizeCEP(f *testing.F) {
+	for _, seed := range []string{"01001000", "01001-000", " 01001-000 ", "01.001-000", "0100100", "010010000", "01001-00a", "", "-", "０１００１０００", "01001\x00000", "01001 000\n"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, cep string) {
+		normalized, err := address.NormalizeCEP(cep)
+		if err != nil {
+			if !errors.Is(err, address.ErrInvalidCEP) || normalized != "" {
+				t.Fatalf("NormalizeCEP(%q) = %q, %v; want only ErrInvalidCEP", cep, normalized, err)
+			}
+			return
+		}
+
+		if len(normalized) != 8 || strings.Trim(normalized, "0123456789") != "" {
+			t.Fatalf("NormalizeCEP(%q) = %q, not 8 digits", cep, normalized)
+		}
+		if again, err := address.NormalizeCEP(normalized); err != nil || again != normalized {
+			t.Fatalf("NormalizeCEP(%q) = %q, %v; want it unchanged", normalized, again, err)
+		}
+	})
+}
+
+// bodyTransport answers every request with 200 and its body.
+type bodyTransport []byte
+
+func (b bodyTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(b)),
+		Request:    request,
+	}, nil
+}
+
+// weirdPayloads are answers providers were seen or could be expected to
+// send.
+var weirdPayloads = []string{
+	``,
+	`null`,
+	`[]`,
+	`"01001000"`,
+	`{}`,
+	`{"erro": true}`,
+	`{"erro": "true"}`,
+	`{"erro": 1}`,
+	"\xef\xbb\xbf{\"cep\": \"01001-000\", \"localidade\": \"São Paulo\", \"uf\": \"SP\"}",
+	`{"cep": null, "logradouro": null, "localidade": null, "uf": null}`,
+	`{"cep": 1001000, "city": "São Paulo", "state": "SP"}`,
+	`{"cep": "01001000", "city": ["São Paulo"], "state": {"uf": "SP"}}`,
+	`{"cep": "01001-000", "logradouro": `,
+	`{"cep": "01001000", "state": "SP", "city": "São Paulo"} trailing`,
+	`{"cep": "01001000", "state": "SP", "city": "São Paulo", "location": {"coordinates": {"latitude": 1, "longitude": "x"}}}`,
+	"{\"cep\": \"\xff\xfe\", \"uf\": \"\\u0000\"}",
+}
+
+// fuzzDecoding seeds f with the recorded answers named and the weird
+// payloads, and checks that provider maps any body without panicking and
+// fails, if it does, with a typed error.
+func fuzzDecoding(f *testing.F, provider func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error), recorded ...string) {
+	for _, name := range recorded {
+		data, err := os.ReadFile(filepath.Join("addresstest", "testdata", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, payload := range weirdPayloads {
+		f.Add([]byte(payload))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		client := &http.Client{Transport: bodyTransport(body)}
+		result, err := provider(context.Background(), client, "01001000")
+		if err != nil {
+			var drift *address.SchemaDriftError
+			if !errors.Is(err, address.ErrInvalidResponse) && !errors.Is(err, address.ErrNotFound) && !errors.As(err, &drift) {
+				t.Fatalf("untyped error %v for %q", err, body)
+			}
+			return
+		}
+		if result.Source == "" {
+			t.Fatalf("mapped %q to %+v without a source", body, result)
+		}
+	})
+}
+
+func FuzzDecodeViaCEP(f *testing.F) {
+	fuzzDecoding(f, address.ViaCEP, "viacep_01001000.json", "viacep_99999999.json")
+}
+
+func FuzzDecodeBrasilAPI(f *testing.F) {
+	fuzzDecoding(f, address.BrasilAPI, "brasilapi_01001000.json", "brasilapi_99999999.json", "brasilapi_1234.json")
+}
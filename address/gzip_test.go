@@ -0,0 +1,145 @@
+package address_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func gzipped(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// encodedViaCEP serves body under Content-Encoding: gzip, whatever the
+// request asked for, and records the Accept-Encoding of the requests.
+type encodedViaCEP struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	accept []string
+}
+
+func newEncodedViaCEP(t *testing.T, body []byte) *encodedViaCEP {
+	server := &encodedViaCEP{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		server.accept = append(server.accept, r.Header.Get("Accept-Encoding"))
+		server.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *encodedViaCEP) accepted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.accept...)
+}
+
+func TestGzipResponses(t *testing.T) {
+	payload, err := os.ReadFile("testdata/payloads/viacep_big_city.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		compression bool
+		accept      string
+	}{
+		{name: "gzip", body: gzipped(t, payload), compression: true, accept: "gzip"},
+		{name: "lying", body: payload, compression: true, accept: "gzip"},
+		// Unasked-for gzip, as from a client that set Accept-Encoding
+		// itself, is still decoded.
+		{name: "unasked", body: gzipped(t, payload), compression: false, accept: ""},
+		{name: "lying unasked", body: payload, compression: false, accept: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newEncodedViaCEP(t, test.body)
+			service := newService(t, address.NewViaCEPProvider(server.URL+"/ws")).SetCompression(test.compression)
+
+			result, err := service.Execute("01310100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Street != "Avenida Paulista" || result.City != "São Paulo" {
+				t.Errorf("result = %+v", result)
+			}
+			if accepted := server.accepted(); len(accepted) != 1 || accepted[0] != test.accept {
+				t.Errorf("Accept-Encoding = %q, want %q", accepted, test.accept)
+			}
+		})
+	}
+}
+
+func TestGzipCustomProviderReadsPlainBodies(t *testing.T) {
+	payload := `{"cep": "01001-000"}`
+	server := newEncodedViaCEP(t, gzipped(t, []byte(payload)))
+
+	var body string
+	custom := address.NewProvider("Custom", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return address.AddressResult{}, err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return address.AddressResult{}, err
+		}
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		body = string(data)
+		return sé, err
+	})
+
+	if _, err := newService(t, custom).Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if body != payload {
+		t.Errorf("custom provider read %q, want the decompressed %q", body, payload)
+	}
+}
+
+// A small gzip body that decompresses past MAX_RESPONSE_BYTES.
+func TestGzipBombIsCapped(t *testing.T) {
+	bomb := gzipped(t, []byte(`{"cep": "01001-000", "logradouro": "`+strings.Repeat("a", 4*address.MAX_RESPONSE_BYTES)+`"}`))
+	if len(bomb) > address.MAX_RESPONSE_BYTES/10 {
+		t.Fatalf("the bomb is %d bytes compressed, want it small", len(bomb))
+	}
+
+	for _, compression := range []bool{true, false} {
+		server := newEncodedViaCEP(t, bomb)
+		service := newService(t, address.NewViaCEPProvider(server.URL+"/ws")).SetCompression(compression)
+
+		if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrInvalidResponse) {
+			t.Errorf("compression %t: err = %v, want ErrInvalidResponse", compression, err)
+		}
+	}
+}
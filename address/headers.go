@@ -0,0 +1,89 @@
+package address
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// REQUEST_ID_PLACEHOLDER in a SetProviderHeaders value is replaced with the
+// ID of the request the lookup serves (see WithRequestID).
+const REQUEST_ID_PLACEHOLDER = "{request_id}"
+
+const REDACTED = "REDACTED"
+
+// SENSITIVE_HEADERS carry credentials; their values are replaced with
+// REDACTED wherever headers are reported.
+var SENSITIVE_HEADERS = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Auth-Token"}
+
+type requestIDKey struct{}
+
+// WithRequestID tags ctx with the ID of the request a lookup serves, so
+// provider headers can forward it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID set with WithRequestID, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// SetProviderHeaders adds header to every request sent to the provider named
+// name, for the auth or routing headers a single upstream needs. Values may
+// contain REQUEST_ID_PLACEHOLDER. A nil or empty header removes them.
+func (s *AddressService) SetProviderHeaders(name string, header http.Header) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	headers := make(map[string]http.Header, len(s.headers)+1)
+	for provider, header := range s.headers {
+		headers[provider] = header
+	}
+
+	if len(header) == 0 {
+		delete(headers, strings.ToLower(name))
+	} else {
+		headers[strings.ToLower(name)] = header.Clone()
+	}
+
+	// The client's transport holds the old map, so it is replaced rather
+	// than changed.
+	s.headers = headers
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// RedactHeader returns a copy of header with the values of SENSITIVE_HEADERS
+// replaced with REDACTED.
+func RedactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if slices.ContainsFunc(SENSITIVE_HEADERS, func(sensitive string) bool { return strings.EqualFold(sensitive, name) }) {
+			redacted[name] = []string{REDACTED}
+		}
+	}
+
+	return redacted
+}
+
+// providerHeader is the header configured for provider with the request ID
+// of ctx filled in, or nil.
+func providerHeader(headers map[string]http.Header, provider string, ctx context.Context) http.Header {
+	header, ok := headers[strings.ToLower(provider)]
+	if !ok {
+		return nil
+	}
+
+	id := RequestID(ctx)
+	expanded := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			expanded.Add(name, strings.ReplaceAll(value, REQUEST_ID_PLACEHOLDER, id))
+		}
+	}
+
+	return expanded
+}
@@ -0,0 +1,111 @@
+package address_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestProviderHeadersReachTheirUpstream(t *testing.T) {
+	recording := &recordingTransport{}
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers...).
+		SetTransport(recording).
+		SetProviderHeaders("ViaCEP", http.Header{"Authorization": {"Bearer viacep-token"}}).
+		SetProviderHeaders("brasilapi", http.Header{"X-Route": {"mirror-b"}, "X-Api-Key": {"brasilapi-key"}})
+
+	if _, err := service.ExecuteAll("01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	viaCEP, brasilAPI := recording.sent("/ws/"), recording.sent("/api/cep/")
+	if len(viaCEP) != 1 || len(brasilAPI) != 1 {
+		t.Fatalf("%d ViaCEP and %d BrasilAPI requests, want 1 each", len(viaCEP), len(brasilAPI))
+	}
+	if got := viaCEP[0].Get("Authorization"); got != "Bearer viacep-token" {
+		t.Errorf("ViaCEP Authorization = %q", got)
+	}
+	if got := viaCEP[0].Get("X-Route") + viaCEP[0].Get("X-Api-Key"); got != "" {
+		t.Errorf("ViaCEP got BrasilAPI's headers: %v", viaCEP[0])
+	}
+	if brasilAPI[0].Get("X-Route") != "mirror-b" || brasilAPI[0].Get("X-Api-Key") != "brasilapi-key" {
+		t.Errorf("BrasilAPI headers = %v", brasilAPI[0])
+	}
+	if got := brasilAPI[0].Get("Authorization"); got != "" {
+		t.Errorf("BrasilAPI got ViaCEP's Authorization %q", got)
+	}
+}
+
+func TestProviderHeadersForwardTheRequestID(t *testing.T) {
+	recording := &recordingTransport{}
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers...).
+		SetTransport(recording).
+		SetProviderHeaders("ViaCEP", http.Header{
+			"Authorization": {"Bearer viacep-token"},
+			"X-Req":         {"id-" + address.REQUEST_ID_PLACEHOLDER},
+		})
+
+	ctx := address.WithRequestID(context.Background(), "req-42")
+	_, report, err := service.ExecuteWithReportContext(ctx, "01001000", address.WithOnlyProviders("ViaCEP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := recording.sent("/ws/")[0].Get("X-Req"); got != "id-req-42" {
+		t.Errorf("X-Req = %q, want id-req-42", got)
+	}
+
+	// The report, which -vv prints, shows what was sent but not the
+	// credentials.
+	header := attemptOf(t, report, "ViaCEP").Header
+	if got := header.Get("Authorization"); got != address.REDACTED {
+		t.Errorf("reported Authorization = %q, want %s", got, address.REDACTED)
+	}
+	if got := header.Get("X-Req"); got != "id-req-42" {
+		t.Errorf("reported X-Req = %q, want id-req-42", got)
+	}
+}
+
+func TestProviderHeadersRemoved(t *testing.T) {
+	recording := &recordingTransport{}
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers[0]).
+		SetTransport(recording).
+		SetProviderHeaders("ViaCEP", http.Header{"X-Route": {"mirror-a"}}).
+		SetProviderHeaders("ViaCEP", nil)
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if got := recording.sent("/ws/")[0].Get("X-Route"); got != "" {
+		t.Errorf("X-Route = %q after the headers were removed", got)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	header := http.Header{
+		"Authorization":       {"Bearer token"},
+		"Proxy-Authorization": {"Basic dXNlcjpwYXNz"},
+		"X-Api-Key":           {"one", "two"},
+		"x-auth-token":        {"lowercase"},
+		"Cookie":              {"session=1"},
+		"X-Route":             {"mirror-a"},
+	}
+
+	redacted := address.RedactHeader(header)
+	for name, values := range redacted {
+		want := address.REDACTED
+		if name == "X-Route" {
+			want = "mirror-a"
+		}
+		if len(values) != 1 || values[0] != want {
+			t.Errorf("%s = %v, want [%s]", name, values, want)
+		}
+	}
+	if header.Get("Authorization") != "Bearer token" {
+		t.Error("RedactHeader changed the header it was given")
+	}
+}
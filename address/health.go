@@ -0,0 +1,104 @@
+package address
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const HEALTH_CHECK_CEP = "01001000"
+const DEFAULT_HEALTH_CHECK_TIMEOUT = 3 * time.Second
+
+// HealthChecker can be implemented by providers that have a cheaper way to
+// prove they are reachable than resolving HEALTH_CHECK_CEP.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context, client *http.Client) error
+}
+
+type ProviderStatus struct {
+	Name    string
+	Enabled bool
+	BaseURL string
+	Checked bool
+	Latency time.Duration
+	Err     error
+}
+
+func (p ProviderStatus) Healthy() bool {
+	return p.Checked && p.Err == nil
+}
+
+func baseURL(provider Provider) string {
+	if withURL, ok := provider.(interface{ BaseURL() string }); ok {
+		return withURL.BaseURL()
+	}
+
+	return ""
+}
+
+// ProviderStatuses lists every registered provider without contacting them.
+func (s *AddressService) ProviderStatuses() []ProviderStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	enabled := providerNames(s.providers)
+	statuses := make([]ProviderStatus, len(s.registry))
+
+	for i, provider := range s.registry {
+		statuses[i] = ProviderStatus{
+			Name:    provider.Name(),
+			Enabled: slices.Contains(enabled, provider.Name()),
+			BaseURL: baseURL(provider),
+		}
+	}
+
+	return statuses
+}
+
+// CheckProviders probes every enabled provider concurrently, giving each at
+// most timeout to answer.
+func (s *AddressService) CheckProviders(ctx context.Context, timeout time.Duration) []ProviderStatus {
+	statuses := s.ProviderStatuses()
+	config := s.settings()
+	var group errgroup.Group
+
+	for i := range statuses {
+		if !statuses[i].Enabled {
+			continue
+		}
+
+		s.mu.RLock()
+		provider, _ := s.findProvider(statuses[i].Name)
+		s.mu.RUnlock()
+
+		status := &statuses[i]
+		group.Go(func() error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			client, _ := config.clientFor(provider.Name())
+			start := time.Now()
+			status.Err = checkProvider(ctx, provider, client)
+			status.Latency = time.Since(start)
+			status.Checked = true
+			return nil
+		})
+	}
+
+	group.Wait()
+	return statuses
+}
+
+func checkProvider(ctx context.Context, provider Provider, client *http.Client) (err error) {
+	defer recoverProvider(provider.Name(), &err)
+
+	if checker, ok := provider.(HealthChecker); ok {
+		return checker.HealthCheck(ctx, client)
+	}
+
+	_, err = provider.GetAddress(ctx, client, HEALTH_CHECK_CEP)
+	return err
+}
@@ -0,0 +1,162 @@
+package address
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// HTTP2Mode selects how provider requests use HTTP/2.
+type HTTP2Mode int
+
+const (
+	// HTTP2_AUTO negotiates HTTP/2 as net/http does, and falls back to
+	// HTTP/1.1 for hosts that reset streams.
+	HTTP2_AUTO HTTP2Mode = iota
+	// HTTP2_ON always offers HTTP/2, with connection health checks.
+	HTTP2_ON
+	// HTTP2_OFF only speaks HTTP/1.1.
+	HTTP2_OFF
+)
+
+// HTTP2_RESET_LIMIT is how many HTTP/2 stream resets from a host it takes,
+// in HTTP2_AUTO, for the rest of the process to talk HTTP/1.1 to it.
+const HTTP2_RESET_LIMIT = 2
+
+// HTTP2_READ_IDLE_TIMEOUT is how long an HTTP2_ON connection may stay
+// silent before it is pinged, so a dead connection is noticed instead of
+// stalling the lookups multiplexed on it.
+const HTTP2_READ_IDLE_TIMEOUT = 15 * time.Second
+
+var ErrInvalidHTTP2Mode = errors.New("invalid HTTP/2 mode")
+
+func (m HTTP2Mode) String() string {
+	switch m {
+	case HTTP2_AUTO:
+		return "auto"
+	case HTTP2_ON:
+		return "on"
+	case HTTP2_OFF:
+		return "off"
+	}
+
+	return fmt.Sprintf("HTTP2Mode(%d)", int(m))
+}
+
+// SetHTTP2 sets how provider requests use HTTP/2; HTTP2_AUTO is the default.
+// Like SetTransport it replaces a transport or client set earlier.
+func (s *AddressService) SetHTTP2(mode HTTP2Mode) error {
+	if mode < HTTP2_AUTO || mode > HTTP2_OFF {
+		return fmt.Errorf("%w: %s", ErrInvalidHTTP2Mode, mode)
+	}
+
+	return s.setTransportOption(func() {
+		s.transport.http2 = mode
+	})
+}
+
+// configure sets transport up for mode and returns the round tripper to use.
+func (mode HTTP2Mode) configure(transport *http.Transport) http.RoundTripper {
+	switch mode {
+	case HTTP2_OFF:
+		disableHTTP2(transport)
+	case HTTP2_ON:
+		h2, err := http2.ConfigureTransports(transport)
+		if err == nil {
+			h2.ReadIdleTimeout = HTTP2_READ_IDLE_TIMEOUT
+		}
+	case HTTP2_AUTO:
+		h1 := transport.Clone()
+		disableHTTP2(h1)
+		return &http2Fallback{next: transport, h1: h1}
+	}
+
+	return transport
+}
+
+func disableHTTP2(transport *http.Transport) {
+	transport.ForceAttemptHTTP2 = false
+	// A non-nil empty map keeps net/http from adding its HTTP/2 support.
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	// Cloning a transport sets its HTTP/2 defaults up, which offer h2.
+	if transport.TLSClientConfig != nil {
+		transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+		transport.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(transport.TLSClientConfig.NextProtos), func(proto string) bool {
+			return proto == "h2"
+		})
+	}
+}
+
+// http2Fallback sends requests through next, and over HTTP/1.1 again when
+// HTTP/2 resets their stream, which some mirrors do under load.
+type http2Fallback struct {
+	next http.RoundTripper
+	h1   http.RoundTripper
+}
+
+func (t *http2Fallback) RoundTrip(request *http.Request) (*http.Response, error) {
+	host := strings.ToLower(request.URL.Host)
+	if http2Downgrades.downgraded(host) {
+		return t.h1.RoundTrip(request)
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err == nil || !isStreamReset(err) || request.Context().Err() != nil {
+		return response, err
+	}
+
+	if request.Body != nil && request.Body != http.NoBody {
+		if request.GetBody == nil {
+			return nil, err
+		}
+
+		body, bodyErr := request.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+
+		request = request.Clone(request.Context())
+		request.Body = body
+	}
+
+	http2Downgrades.reset(host)
+	return t.h1.RoundTrip(request)
+}
+
+// isStreamReset reports whether err is an HTTP/2 stream error. net/http
+// keeps its own HTTP/2 error types unexported, so those are recognized by
+// their message.
+func isStreamReset(err error) bool {
+	var streamErr http2.StreamError
+	return errors.As(err, &streamErr) || strings.Contains(err.Error(), "stream error: stream ID")
+}
+
+// http2Downgrades counts stream resets by host for the whole process, since
+// a broken HTTP/2 implementation stays broken whichever service talks to it.
+var http2Downgrades = &hostResets{resets: make(map[string]int)}
+
+type hostResets struct {
+	mu     sync.Mutex
+	resets map[string]int
+}
+
+func (r *hostResets) downgraded(host string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.resets[host] >= HTTP2_RESET_LIMIT
+}
+
+func (r *hostResets) reset(host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resets[host]++
+}
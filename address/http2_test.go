@@ -0,0 +1,114 @@
+package address_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// h2ViaCEP serves the ViaCEP fake over TLS with HTTP/2 enabled. A broken
+// server resets every HTTP/2 stream, as the misbehaving mirror does, and
+// answers HTTP/1.1. It returns how many HTTP/2 requests arrived.
+func h2ViaCEP(t *testing.T, broken bool) (*address.AddressService, *atomic.Int32) {
+	t.Helper()
+
+	viaCEP := addresstest.NewViaCEPServer(map[string]address.AddressResult{"01001000": sé})
+	t.Cleanup(viaCEP.Close)
+
+	h2 := new(atomic.Int32)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 {
+			h2.Add(1)
+			if broken {
+				panic(http.ErrAbortHandler)
+			}
+		}
+		viaCEP.Config.Handler.ServeHTTP(w, r)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+	if err := service.SetTLSConfig(&tls.Config{RootCAs: roots}); err != nil {
+		t.Fatal(err)
+	}
+
+	return service, h2
+}
+
+func protocolOf(t *testing.T, service *address.AddressService) string {
+	t.Helper()
+
+	_, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attemptOf(t, report, "ViaCEP").Protocol
+}
+
+func TestHTTP2Modes(t *testing.T) {
+	for _, test := range []struct {
+		mode     address.HTTP2Mode
+		protocol string
+	}{
+		{mode: address.HTTP2_AUTO, protocol: "HTTP/2.0"},
+		{mode: address.HTTP2_ON, protocol: "HTTP/2.0"},
+		{mode: address.HTTP2_OFF, protocol: "HTTP/1.1"},
+	} {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			service, _ := h2ViaCEP(t, false)
+			if err := service.SetHTTP2(test.mode); err != nil {
+				t.Fatal(err)
+			}
+
+			if protocol := protocolOf(t, service); protocol != test.protocol {
+				t.Errorf("protocol = %q, want %q", protocol, test.protocol)
+			}
+		})
+	}
+}
+
+func TestHTTP2FallsBackOnStreamResets(t *testing.T) {
+	service, h2 := h2ViaCEP(t, true)
+
+	// Every reset request is retried over HTTP/1.1, and after
+	// HTTP2_RESET_LIMIT resets the host is only spoken to over HTTP/1.1.
+	for lookup := 1; lookup <= address.HTTP2_RESET_LIMIT+3; lookup++ {
+		if protocol := protocolOf(t, service); protocol != "HTTP/1.1" {
+			t.Fatalf("lookup %d: protocol = %q, want HTTP/1.1", lookup, protocol)
+		}
+	}
+	if n := h2.Load(); n != address.HTTP2_RESET_LIMIT {
+		t.Errorf("%d HTTP/2 requests, want %d before the downgrade", n, address.HTTP2_RESET_LIMIT)
+	}
+}
+
+func TestHTTP2OnDoesNotFallBack(t *testing.T) {
+	service, _ := h2ViaCEP(t, true)
+	if err := service.SetHTTP2(address.HTTP2_ON); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Execute("01001000"); err == nil {
+		t.Error("lookup succeeded against a server resetting every stream")
+	}
+}
+
+func TestSetHTTP2RejectsUnknownModes(t *testing.T) {
+	if err := newService(t).SetHTTP2(address.HTTP2Mode(7)); !errors.Is(err, address.ErrInvalidHTTP2Mode) {
+		t.Errorf("SetHTTP2(7) = %v, want ErrInvalidHTTP2Mode", err)
+	}
+}
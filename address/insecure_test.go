@@ -0,0 +1,97 @@
+package address_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// selfSigned serves the ViaCEP fake over TLS with httptest's self-signed
+// certificate.
+func selfSigned(t *testing.T) string {
+	viaCEP := addresstest.NewViaCEPServer(map[string]address.AddressResult{"01001000": sé})
+	t.Cleanup(viaCEP.Close)
+	server := httptest.NewUnstartedServer(viaCEP.Config.Handler)
+	// The handshakes refused with verification on are expected.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server.URL + "/ws"
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	service := newService(t, address.NewViaCEPProvider(selfSigned(t)))
+
+	var unknown x509.UnknownAuthorityError
+	if _, err := service.Execute("01001000"); !errors.As(err, &unknown) {
+		t.Fatalf("err = %v with verification on, want an unknown authority", err)
+	}
+
+	if err := service.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+	if !service.InsecureSkipVerify() {
+		t.Error("InsecureSkipVerify() = false after turning verification off")
+	}
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.InsecureTransport {
+		t.Errorf("result = %+v, want it marked InsecureTransport", result)
+	}
+
+	if err := service.SetInsecureSkipVerify(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Execute("01001000"); !errors.As(err, &unknown) {
+		t.Errorf("err = %v after turning verification back on, want an unknown authority", err)
+	}
+}
+
+// Clients from SetHTTPClient verify as they are configured to, so their
+// results are not marked.
+func TestInsecureSkipVerifyLeavesInjectedClientsAlone(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers[0])
+	if err := service.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+	service.SetHTTPClient(&http.Client{})
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InsecureTransport {
+		t.Error("result through an injected client marked InsecureTransport")
+	}
+}
+
+func TestInsecureSkipVerifyWarnsOncePerProcess(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	for range 3 {
+		service := newService(t).SetLogger(logger)
+		if err := service.SetInsecureSkipVerify(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An earlier test of this process may have been warned already.
+	if n := strings.Count(logs.String(), "verification is DISABLED"); n > 1 {
+		t.Errorf("warned %d times, want at most once per process:\n%s", n, logs.String())
+	}
+}
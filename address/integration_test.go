@@ -0,0 +1,104 @@
+//go:build integration
+
+package address
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// The live tests hit the real providers, which fixtures cannot keep up
+// with. Run them with
+//
+//	ADDRESS_INTEGRATION=1 go test -tags integration -run Live ./address
+const INTEGRATION_ENV = "ADDRESS_INTEGRATION"
+
+// liveService races the real ViaCEP and BrasilAPI with timeouts and retries
+// generous enough for a slow day.
+func liveService(t *testing.T) *AddressService {
+	t.Helper()
+
+	if os.Getenv(INTEGRATION_ENV) != "1" {
+		t.Skipf("set %s=1 to query the real providers", INTEGRATION_ENV)
+	}
+
+	service := NewAddressService(context.Background()).
+		SetLogger(NopLogger()).
+		SetTimeout(30*time.Second).
+		SetRetries(2, 2*time.Second).
+		SetRawPayload(true)
+	if err := service.SetProviders("ViaCEP", "BrasilAPI"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+
+	return service
+}
+
+var liveSchemas = map[string]responseSchema{
+	"ViaCEP":    {provider: "ViaCEP", fields: viaCEPFields},
+	"BrasilAPI": {provider: "BrasilAPI", fields: brasilAPIFields},
+}
+
+func TestLiveKnownCEPs(t *testing.T) {
+	service := liveService(t)
+
+	tests := []struct {
+		cep   string
+		city  string
+		state string
+	}{
+		{cep: "01001000", city: "São Paulo", state: "SP"},
+		{cep: "20040010", city: "Rio de Janeiro", state: "RJ"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cep, func(t *testing.T) {
+			results, err := service.ExecuteAll(test.cep)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, result := range results {
+				if result.Err != nil {
+					t.Errorf("%s: %v", result.Provider, result.Err)
+					continue
+				}
+
+				// Every field the decoder relies on is still sent, under
+				// the same name and with the same type.
+				schema, ok := liveSchemas[result.Provider]
+				if !ok {
+					t.Fatalf("no schema for %s", result.Provider)
+				}
+				if drift := schema.check(result.Address.Raw); drift != nil {
+					t.Errorf("%s: %v\n%s", result.Provider, drift, result.Address.Raw)
+				}
+
+				address := result.Address
+				if address.ZipCode != test.cep || address.City != test.city || address.State != test.state ||
+					address.Street == "" || address.Neighborhood == "" || address.Source != result.Provider {
+					t.Errorf("%s mapped %s to %+v", result.Provider, test.cep, address)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveNonexistentCEP(t *testing.T) {
+	service := liveService(t)
+
+	results, err := service.ExecuteAll("99999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range results {
+		if !errors.Is(result.Err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", result.Provider, result.Err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package address
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// The languages Labels and Format support.
+const (
+	LANG_EN    = "en"
+	LANG_PT_BR = "pt-BR"
+)
+
+var labels = map[string]map[string]string{
+	LANG_EN: {
+		"street":       "Street",
+		"neighborhood": "Neighborhood",
+		"city":         "City",
+		"state":        "State",
+		"zip":          "CEP",
+		"source":       "Source",
+		"error":        "Error",
+	},
+	LANG_PT_BR: {
+		"street":       "Logradouro",
+		"neighborhood": "Bairro",
+		"city":         "Cidade",
+		"state":        "Estado",
+		"zip":          "CEP",
+		"source":       "Fonte",
+		"error":        "Erro",
+	},
+}
+
+// ParseLanguage maps a language tag or locale, such as "pt_BR.UTF-8", "pt-br"
+// or "en_US", to one of the supported languages. Anything else gives LANG_EN
+// and false.
+func ParseLanguage(tag string) (string, bool) {
+	tag, _, _ = strings.Cut(tag, ".")
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	language, _, _ := strings.Cut(tag, "-")
+
+	switch language {
+	case "pt":
+		return LANG_PT_BR, true
+	case "en":
+		return LANG_EN, true
+	}
+
+	return LANG_EN, false
+}
+
+// Labels returns the labels of the result fields in lang, keyed by the field
+// names used by Diff plus "source" and "error". Unknown languages get English.
+func Labels(lang string) map[string]string {
+	language, _ := ParseLanguage(lang)
+
+	return maps.Clone(labels[language])
+}
+
+// Format renders r as one "Label: value" line per field in lang, such as
+// "Logradouro: Praça da Sé" in pt-BR. Empty fields are left out.
+func (r AddressResult) Format(lang string) string {
+	labels := Labels(lang)
+
+	state := r.State
+	if r.StateName != "" {
+		state = fmt.Sprintf("%s (%s)", r.StateName, r.State)
+	}
+
+	zip := r.ZipCode
+	if cep, err := NormalizeCEP(zip); err == nil {
+		zip = cep[:5] + "-" + cep[5:]
+	}
+
+	var b strings.Builder
+	for _, line := range []struct{ field, value string }{
+		{"street", r.Street},
+		{"neighborhood", r.Neighborhood},
+		{"city", r.City},
+		{"state", state},
+		{"zip", zip},
+		{"source", r.Source},
+	} {
+		if line.value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", labels[line.field], line.value)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package address_test
+
+import (
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		tag       string
+		want      string
+		supported bool
+	}{
+		{"pt-BR", address.LANG_PT_BR, true},
+		{"pt_BR.UTF-8", address.LANG_PT_BR, true},
+		{" pt-br ", address.LANG_PT_BR, true},
+		{"pt", address.LANG_PT_BR, true},
+		{"en_US", address.LANG_EN, true},
+		{"en", address.LANG_EN, true},
+		{"fr_FR.UTF-8", address.LANG_EN, false},
+		{"C", address.LANG_EN, false},
+		{"", address.LANG_EN, false},
+	}
+
+	for _, test := range tests {
+		got, supported := address.ParseLanguage(test.tag)
+		if got != test.want || supported != test.supported {
+			t.Errorf("ParseLanguage(%q) = %q, %t, want %q, %t", test.tag, got, supported, test.want, test.supported)
+		}
+	}
+}
+
+func TestLabelsAreCopies(t *testing.T) {
+	labels := address.Labels(address.LANG_PT_BR)
+	labels["street"] = "Rua"
+
+	if got := address.Labels(address.LANG_PT_BR)["street"]; got != "Logradouro" {
+		t.Errorf("street label = %q after editing a copy, want Logradouro", got)
+	}
+	if got := address.Labels("xx")["street"]; got != "Street" {
+		t.Errorf("street label for an unknown language = %q, want the English one", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	result := address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP"}
+
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{
+			lang: address.LANG_PT_BR,
+			want: "Logradouro: Praça da Sé\nCidade: São Paulo\nEstado: São Paulo (SP)\nCEP: 01001-000\nFonte: ViaCEP\n",
+		},
+		{
+			lang: address.LANG_EN,
+			want: "Street: Praça da Sé\nCity: São Paulo\nState: São Paulo (SP)\nCEP: 01001-000\nSource: ViaCEP\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := result.Format(test.lang); got != test.want {
+			t.Errorf("Format(%q) = %q, want %q", test.lang, got, test.want)
+		}
+	}
+
+	// A zip that is not a CEP is shown as given, and a state without its
+	// name as the code alone.
+	result = address.AddressResult{ZipCode: "123", State: "SP"}
+	if got, want := result.Format(address.LANG_EN), "State: SP\nCEP: 123\n"; got != want {
+		t.Errorf("Format = %q, want %q", got, want)
+	}
+}
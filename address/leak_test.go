@@ -0,0 +1,190 @@
+package address_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// LEAK_RUNS is how many times each leak scenario runs, and SHORT_LEAK_RUNS
+// how many with -short.
+const (
+	LEAK_RUNS       = 2000
+	SHORT_LEAK_RUNS = 300
+)
+
+// leakRuns returns how many times a leak scenario runs in this test binary.
+func leakRuns() int {
+	if testing.Short() {
+		return SHORT_LEAK_RUNS
+	}
+	return LEAK_RUNS
+}
+
+// lingering returns the stacks of the goroutines still running code of the
+// address or race packages.
+func lingering() []string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var stacks []string
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		text := string(stack)
+		if strings.Contains(text, "multithreading-challenge/address.") || strings.Contains(text, "multithreading-challenge/race.") {
+			stacks = append(stacks, text)
+		}
+	}
+	return stacks
+}
+
+// checkNoLeaks fails t when goroutines of the service are still running a
+// little while after the lookups returned, time enough for those the
+// lookups cancelled to notice it.
+func checkNoLeaks(t *testing.T) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stacks := lingering()
+		if len(stacks) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines leaked, the first:\n%s", len(stacks), stacks[0])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNoLeakOnTimeout(t *testing.T) {
+	checkNoLeaks(t)
+
+	a := addresstest.NewMockProvider("A").Returns(sé).SetLatency(time.Hour)
+	b := addresstest.NewMockProvider("B").Returns(sé).SetLatency(time.Hour)
+	service := newService(t, a, b).SetTimeout(time.Millisecond)
+
+	for run := 0; run < leakRuns(); run++ {
+		if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrTimeout) {
+			t.Fatalf("run %d: err = %v, want ErrTimeout", run, err)
+		}
+	}
+	checkNoLeaks(t)
+}
+
+func TestNoLeakOnCallerCancellation(t *testing.T) {
+	checkNoLeaks(t)
+
+	a := addresstest.NewMockProvider("A").Returns(sé).SetLatency(time.Hour)
+	b := addresstest.NewMockProvider("B").Returns(sé).SetLatency(time.Hour)
+	service := newService(t, a, b).SetTimeout(time.Hour).SetRetries(3, time.Hour)
+
+	for run := 0; run < leakRuns(); run++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(time.Millisecond, cancel)
+		if _, err := service.ExecuteContext(ctx, "01001000"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("run %d: err = %v, want context.Canceled", run, err)
+		}
+		cancel()
+	}
+	checkNoLeaks(t)
+}
+
+// hungServer accepts connections and never answers on them. It records the
+// connections the client closed.
+type hungServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	accepted int
+	closed   int
+}
+
+func newHungServer(t *testing.T) *hungServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &hungServer{listener: listener}
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.accepted++
+			server.mu.Unlock()
+
+			server.wg.Add(1)
+			go func() {
+				defer server.wg.Done()
+				defer conn.Close()
+				// Reads the request and waits for the client to hang up.
+				io.Copy(io.Discard, conn)
+				server.mu.Lock()
+				server.closed++
+				server.mu.Unlock()
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		server.wg.Wait()
+	})
+
+	return server
+}
+
+func (s *hungServer) open() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.accepted - s.closed
+}
+
+func TestNoLeakOnHungConnection(t *testing.T) {
+	checkNoLeaks(t)
+
+	server := newHungServer(t)
+	service := newService(t, address.NewViaCEPProvider("http://"+server.listener.Addr().String()+"/ws")).
+		SetTimeout(5 * time.Millisecond)
+
+	for run := 0; run < leakRuns(); run++ {
+		if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrTimeout) {
+			t.Fatalf("run %d: err = %v, want ErrTimeout", run, err)
+		}
+	}
+	checkNoLeaks(t)
+
+	// The requests were cancelled, which closes their connections.
+	deadline := time.Now().Add(5 * time.Second)
+	for server.open() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still open", server.open())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
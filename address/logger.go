@@ -0,0 +1,22 @@
+package address
+
+import (
+	"io"
+	"log/slog"
+)
+
+func NopLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *AddressService) SetLogger(logger *slog.Logger) *AddressService {
+	if logger == nil {
+		logger = NopLogger()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger = logger
+	return s
+}
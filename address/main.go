@@ -2,195 +2,628 @@ package address
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/wendellnd/multithreading-challenge/race"
 )
 
 const DEFAULT_TIMEOUT = 30 * time.Second
+const DEFAULT_CONCURRENCY = 8
+
+// DEFAULT_MAX_IDLE_CONNS_PER_HOST is how many keep-alive connections the
+// service keeps open to each provider. http.DefaultTransport keeps only 2,
+// so any batch wider than that reconnects on almost every lookup.
+const DEFAULT_MAX_IDLE_CONNS_PER_HOST = 64
+
+// DEFAULT_PREFERRED_GRACE is how long SetPreferredWinner waits for the
+// preferred provider once another one has answered.
+const DEFAULT_PREFERRED_GRACE = 100 * time.Millisecond
+
+var (
+	ErrTimeout            = errors.New("request timeout")
+	ErrNotFound           = errors.New("not found")
+	ErrAllProvidersFailed = errors.New("all providers failed")
+	ErrInvalidCEP         = errors.New("invalid CEP")
+	ErrUnknownProvider    = errors.New("unknown provider")
+	ErrNoProviders        = errors.New("no providers configured")
+	ErrInvalidResponse    = errors.New("invalid provider response")
+)
 
 type AddressResult struct {
-	Source       string
-	State        string
-	City         string
-	Street       string
-	ZipCode      string
-	Neighborhood string
+	Source       string `json:"source" xml:"source,attr"`
+	State        string `json:"state" xml:"state"`
+	StateName    string `json:"state_name,omitempty" xml:"state_name,omitempty"`
+	City         string `json:"city" xml:"city"`
+	Street       string `json:"street" xml:"street"`
+	ZipCode      string `json:"cep" xml:"cep"`
+	Neighborhood string `json:"neighborhood" xml:"neighborhood"`
+	// Location is set by the providers that know where the CEP is, see
+	// Distance.
+	Location *Coordinates `json:"location,omitempty" xml:"location,omitempty"`
+	// Enriched is set once every enricher from SetEnrichers succeeded.
+	Enriched bool `json:"enriched,omitempty" xml:"enriched,omitempty"`
+	// Confidence says how far the other providers that answered the same
+	// lookup back the result up, from CONFIDENCE_CONFLICT to
+	// CONFIDENCE_AGREED; Conflicts lists their disagreement when they all
+	// contradict it. A race only counts the answers that arrived before it
+	// returned.
+	Confidence float64    `json:"confidence,omitempty" xml:"confidence,omitempty"`
+	Conflicts  FieldDiffs `json:"conflicts,omitempty" xml:"conflict,omitempty"`
+	// Raw and Header are the body and headers of the response the result
+	// was decoded from, kept only with SetRawPayload. They are left out of
+	// JSON unless marshaled with MarshalWithRaw.
+	Raw    []byte      `json:"-" xml:"-"`
+	Header http.Header `json:"-" xml:"-"`
+	// InsecureTransport is set on results fetched with TLS verification
+	// off, see SetInsecureSkipVerify.
+	InsecureTransport bool `json:"insecure_transport,omitempty" xml:"insecure_transport,omitempty"`
 }
 
-type GetAddressFunc func(ctx context.Context, client http.Client, wg *sync.WaitGroup, ch chan AddressResult, cancel context.CancelFunc, cep string)
+type GetAddressFunc func(ctx context.Context, client *http.Client, cep string) (AddressResult, error)
 
+// AddressService is safe for concurrent use. Every lookup works on a copy of
+// the settings taken when it starts, so the setters may be called while
+// lookups are running and only affect the lookups started afterwards. The
+// exported fields mirror the setters; write them only before the service is
+// shared.
 type AddressService struct {
-	Timeout   time.Duration
-	client    http.Client
-	ctx       context.Context
-	cancel    context.CancelFunc
-	functions []GetAddressFunc
+	Timeout     time.Duration
+	Concurrency int
+	RateLimit   float64
+	Retries     int
+	Backoff     time.Duration
+	Trace       bool
+	preferred   string
+	grace       time.Duration
+	mu          sync.RWMutex
+	client      *http.Client
+	baseClient  *http.Client
+	ownClient   bool
+	userAgent   string
+	compression bool
+	headers     map[string]http.Header
+	middleware  []TransportMiddleware
+	clients     map[string]*http.Client
+	wrapped     map[string]*http.Client
+	ctx         context.Context
+	cancel      context.CancelFunc
+	registry    []Provider
+	providers   []Provider
+	logger      *slog.Logger
+	observer    Observer
+	audit       *auditQueue
+	auditDrops  atomic.Uint64
+	stats       *statsCollector
+	cache       Cache
+	clock       Clock
+	transport   transportOptions
+	redirects   redirectPolicy
+	enrichment  enrichment
+	prefixOff   bool
+	schemaOff   bool
+	canonical   bool
+	redact      bool
+	raw         bool
+	ranking     func(a, b AddressResult) bool
+}
+
+// settings is the configuration one lookup runs with.
+type settings struct {
+	timeout     time.Duration
+	concurrency int
+	rateLimit   float64
+	retries     int
+	backoff     time.Duration
+	trace       bool
+	preferred   string
+	grace       time.Duration
+	client      *http.Client
+	profile     string
+	insecure    bool
+	clients     map[string]*http.Client
+	providers   []Provider
+	filtered    bool
+	logger      *slog.Logger
+	observer    Observer
+	audit       *auditQueue
+	stats       *statsCollector
+	cache       Cache
+	clock       Clock
+	enrichment  enrichment
+	prefixOff   bool
+	schemaOff   bool
+	canonical   bool
+	redact      bool
+	raw         bool
+	ranking     func(a, b AddressResult) bool
+}
+
+type providerResponse struct {
+	address AddressResult
+	attempt *Attempt
+	err     error
 }
 
 func NewAddressService(ctx context.Context) *AddressService {
-	client := http.Client{
-		Timeout: DEFAULT_TIMEOUT,
-	}
 	ctx, cancel := context.WithCancel(ctx)
-
-	return &AddressService{
-		client: client,
-		ctx:    ctx,
-		cancel: cancel,
-		functions: []GetAddressFunc{
-			ViaCEP,
-			BrasilAPI,
-		},
+	providers := defaultProviders()
+
+	service := &AddressService{
+		Timeout:     DEFAULT_TIMEOUT,
+		Concurrency: DEFAULT_CONCURRENCY,
+		userAgent:   DEFAULT_USER_AGENT,
+		compression: true,
+		ctx:         ctx,
+		cancel:      cancel,
+		registry:    providers,
+		providers:   append([]Provider(nil), providers...),
+		logger:      slog.Default(),
+		stats:       newStatsCollector(time.Now()),
+		clock:       RealClock(),
+		enrichment:  enrichment{timeout: DEFAULT_ENRICHMENT_TIMEOUT},
+		redirects:   redirectPolicy{max: DEFAULT_MAX_REDIRECTS},
 	}
+	service.setClient(&http.Client{Timeout: DEFAULT_TIMEOUT, Transport: newTransport(transportOptions{})}, true)
+
+	return service
 }
 
+// SetTimeout bounds every lookup by timeout. The service's own client also
+// uses it as its request timeout; a client from SetHTTPClient is left as it
+// is.
 func (s *AddressService) SetTimeout(timeout time.Duration) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.Timeout = timeout
-	s.client.Timeout = timeout
+	if s.ownClient {
+		// Lookups in flight hold the old client, so it is replaced rather
+		// than changed.
+		s.setClient(&http.Client{Timeout: timeout, Transport: s.baseClient.Transport}, true)
+	}
 	return s
 }
 
-func (s *AddressService) Execute(cep string) (address AddressResult, err error) {
-	defer s.cancel()
-
-	length := len(s.functions)
-	ch := make(chan AddressResult, length)
-	var wg sync.WaitGroup
+// SetTransport makes providers send their requests through transport, for
+// custom dialing or fault injection in tests; it bypasses SetProxy and
+// SetTLSConfig. nil restores the service's default transport. It replaces a
+// client set with SetHTTPClient.
+func (s *AddressService) SetTransport(transport http.RoundTripper) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, f := range s.functions {
-		wg.Add(1)
-		go f(s.ctx, s.client, &wg, ch, s.cancel, cep)
+	if transport == nil {
+		transport = newTransport(s.transport)
 	}
 
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
+	s.setClient(&http.Client{Timeout: s.Timeout, Transport: transport}, true)
+	return s
+}
 
-	select {
-	case <-time.After(s.Timeout):
-		message := "request timeout"
-		return address, errors.New(message)
-	case <-s.ctx.Done():
-		return <-ch, nil
+// SetHTTPClient makes every provider send its requests with client, for
+// instrumented clients or one client shared by several services. The service
+// never changes the client (requests go through a copy that adds the
+// User-Agent), and lookups are still bounded by the service timeout. nil
+// restores the service's own client.
+func (s *AddressService) SetHTTPClient(client *http.Client) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client == nil {
+		s.setClient(&http.Client{Timeout: s.Timeout, Transport: newTransport(s.transport)}, true)
+		return s
 	}
+
+	s.setClient(client, false)
+	return s
+}
+
+func (s *AddressService) SetConcurrency(concurrency int) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Concurrency = concurrency
+	return s
+}
+
+func (s *AddressService) SetRateLimit(lookupsPerSecond float64) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.RateLimit = lookupsPerSecond
+	return s
+}
+
+// SetRetries makes every provider retry a failed request up to retries times,
+// waiting backoff before the first retry and doubling it after each attempt.
+func (s *AddressService) SetRetries(retries int, backoff time.Duration) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Retries = retries
+	s.Backoff = backoff
+	return s
 }
 
-type BrasilAPIResponse struct {
-	CEP          string `json:"cep"`
-	City         string `json:"city"`
-	Neighborhood string `json:"neighborhood"`
-	State        string `json:"state"`
-	Street       string `json:"street"`
+// SetTrace records httptrace connection timings on every attempt of the report.
+func (s *AddressService) SetTrace(trace bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Trace = trace
+	return s
 }
 
-func (r BrasilAPIResponse) ToAddressResult() AddressResult {
-	return AddressResult{
-		Source:       "BrasilAPI",
-		State:        r.State,
-		City:         r.City,
-		Street:       r.Street,
-		ZipCode:      r.CEP,
-		Neighborhood: r.Neighborhood,
+// SetPreferredWinner makes lookups wait up to grace for the provider named
+// name once another provider has answered, and return its answer instead if
+// it succeeds in time. It is meant for tests and bug reproductions that need
+// a deterministic Source; production lookups should leave it unset, since it
+// only ever makes them slower. An empty name turns it off and a grace of 0
+// means DEFAULT_PREFERRED_GRACE.
+func (s *AddressService) SetPreferredWinner(name string, grace time.Duration) *AddressService {
+	if grace <= 0 {
+		grace = DEFAULT_PREFERRED_GRACE
 	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.preferred = name
+	s.grace = grace
+	return s
 }
 
-func BrasilAPI(ctx context.Context, client http.Client, wg *sync.WaitGroup, ch chan AddressResult, cancel context.CancelFunc, cep string) {
-	defer wg.Done()
+// Close cancels the lookups still running, waits for the providers of those
+// that returned to settle, writes the audit entries still queued, see
+// SetAuditWriter, and writes the stats report, see SetStatsReport. Calling
+// it again does nothing.
+func (s *AddressService) Close() {
+	s.cancel()
+	// The cancelled providers settle right away.
+	s.stats.close()
+
+	s.mu.Lock()
+	audit := s.audit
+	s.audit = nil
+	logger := s.logOutput()
+	s.mu.Unlock()
+
+	audit.close(logger)
+
+	if err := s.stats.writeReport(time.Now()); err != nil {
+		logger.Warn("stats report failed", "error", err)
+	}
+}
 
-	result := AddressResult{
-		Source: "BrasilAPI",
+func (s *AddressService) settings() settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return settings{
+		timeout:     s.Timeout,
+		concurrency: s.Concurrency,
+		rateLimit:   s.RateLimit,
+		retries:     s.Retries,
+		backoff:     s.Backoff,
+		trace:       s.Trace,
+		preferred:   s.preferred,
+		grace:       s.grace,
+		client:      s.client,
+		profile:     s.clientProfile(),
+		insecure:    s.transport.insecure,
+		clients:     s.wrapped,
+		providers:   slices.Clone(s.providers),
+		logger:      s.logOutput(),
+		observer:    s.observer,
+		audit:       s.audit,
+		stats:       s.stats,
+		cache:       s.cache,
+		clock:       s.clock,
+		enrichment:  s.enrichment,
+		prefixOff:   s.prefixOff,
+		schemaOff:   s.schemaOff,
+		canonical:   s.canonical,
+		redact:      s.redact,
+		raw:         s.raw,
+		ranking:     s.ranking,
 	}
+}
+
+// Execute returns the first successful answer among the enabled providers,
+// or a *TimeoutError, which wraps ErrTimeout and tells what each provider
+// was doing, once the service timeout has passed. Provider requests,
+// including their response decoding, run apart from the caller, so Execute
+// returns within a few milliseconds of the timeout even when a provider hangs
+// or ignores its context; only a slow Cache or Observer can delay it.
+// Enrichers from SetEnrichers run once a provider has won, each for at most
+// the enrichment timeout. Options, such as WithOnlyProviders, apply to this
+// lookup only.
+func (s *AddressService) Execute(cep string, options ...CallOption) (address AddressResult, err error) {
+	return s.ExecuteContext(context.Background(), cep, options...)
+}
+
+// ExecuteContext is Execute bounded by ctx as well: when ctx is done the
+// provider requests are abandoned and ctx's error is returned.
+func (s *AddressService) ExecuteContext(ctx context.Context, cep string, options ...CallOption) (address AddressResult, err error) {
+	address, _, err = s.ExecuteWithReportContext(ctx, cep, options...)
+	return address, err
+}
 
-	url := fmt.Sprintf("https://brasilapi.com.br/api/cep/v1/%s", cep)
+func (s *AddressService) ExecuteWithReport(cep string, options ...CallOption) (AddressResult, *Report, error) {
+	return s.ExecuteWithReportContext(context.Background(), cep, options...)
+}
 
-	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// ExecuteWithReportContext is ExecuteContext with the report of the lookup.
+// Options that name an unknown provider, or leave none, fail the lookup
+// before it starts, without a report.
+func (s *AddressService) ExecuteWithReportContext(ctx context.Context, cep string, options ...CallOption) (AddressResult, *Report, error) {
+	config, err := s.callSettings(options)
 	if err != nil {
-		log.Println(err)
+		return AddressResult{}, nil, err
+	}
+
+	return s.executeWithReport(ctx, config, cep)
+}
+
+// observe counts a finished lookup in the service's stats and tells the
+// observer, if any, about it.
+func (config settings) observe(report *Report, err error) {
+	config.stats.observe(report, err)
+
+	if config.observer == nil {
 		return
 	}
 
-	response, err := client.Do(request)
-	if err != nil {
-		if os.IsTimeout(err) {
-			log.Println("Timeout, source: ", result.Source)
+	if config.redact {
+		config.observer.ObserveLookup(redactReport(report), redactError(err))
+		return
+	}
+	config.observer.ObserveLookup(report, err)
+}
+
+func (s *AddressService) executeWithReport(parent context.Context, config settings, cep string) (address AddressResult, report *Report, err error) {
+	recorder := newRecorder(config.clock, config.trace, config.raw, config.schemaOff)
+
+	// The timeout starts before the cache is read, so the whole lookup
+	// counts. A stopped timer is released right away; one from time.After
+	// would stay alive until it fired, which under load piles up one per
+	// lookup.
+	timeout := config.clock.NewTimer(config.timeout)
+
+	// settle is set once providers are racing: the stats and the observer
+	// then hear about the lookup after they settled instead of right away.
+	// It takes the timeout over.
+	// requested is kept for the audit log, since cep is emptied when it is
+	// invalid.
+	requested := cep
+	var settle func()
+	defer func() {
+		if cep != "" {
+			requested = cep
+		}
+		config.auditLookup(parent, recorder.start, requested, report, err)
+
+		if settle != nil && config.stats.settle(settle) {
 			return
 		}
+		timeout.Stop()
+		config.observe(report, err)
+	}()
 
-		log.Println(err)
-		return
+	cep, err = NormalizeCEP(cep)
+	if err != nil {
+		return address, recorder.snapshot(cep, nil), err
 	}
-	defer response.Body.Close()
 
-	var brasilAPIResponse BrasilAPIResponse
-	err = json.NewDecoder(response.Body).Decode(&brasilAPIResponse)
-	if err != nil {
-		log.Println(err)
-		return
+	if config.cache != nil {
+		if cached, ok := config.cache.Get(cep); ok && config.serves(cached) {
+			report = recorder.snapshot(cep, nil)
+			report.Cached = true
+			if cached.Enriched || len(config.enrichment.enrichers) == 0 {
+				return cached, report, nil
+			}
+
+			enriched, err := config.enrichment.enrich(parent, config, cached)
+			if err != nil {
+				return address, report, err
+			}
+			if enriched.Enriched {
+				config.cache.Set(cep, enriched)
+			}
+			return enriched, report, nil
+		}
+		recorder.missed = true
 	}
 
-	ch <- brasilAPIResponse.ToAddressResult()
-	cancel()
-}
+	if len(config.providers) == 0 {
+		return address, recorder.snapshot(cep, nil), ErrNoProviders
+	}
 
-type ViaCEPResponse struct {
-	CEP          string `json:"cep"`
-	City         string `json:"localidade"`
-	Neighborhood string `json:"bairro"`
-	State        string `json:"uf"`
-	Street       string `json:"logradouro"`
-}
+	// Providers see the values of parent, such as its request ID, and stop
+	// when either parent or the service is done.
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
 
-func (r ViaCEPResponse) ToAddressResult() AddressResult {
-	return AddressResult{
-		Source:       "ViaCEP",
-		State:        r.State,
-		City:         r.City,
-		Street:       r.Street,
-		ZipCode:      r.CEP,
-		Neighborhood: r.Neighborhood,
+	lookups := make([]func(context.Context) (providerResponse, error), len(config.providers))
+	for i, provider := range config.providers {
+		lookups[i] = func(ctx context.Context) (providerResponse, error) {
+			response := s.getAddress(ctx, config, recorder, provider, cep)
+			return response, response.err
+		}
 	}
-}
 
-func ViaCEP(ctx context.Context, client http.Client, wg *sync.WaitGroup, ch chan AddressResult, cancel context.CancelFunc, cep string) {
-	defer wg.Done()
+	var errs []error
+	// answers are the successful responses so far, which back the winner
+	// up or contradict it.
+	var answers []providerResponse
+
+	// The preferred provider, if any, gets a grace period to beat the
+	// others' answers.
+	contest := race.Race[providerResponse]{
+		Deadline: timeout.C(),
+		Grace:    config.grace,
+		NewTimer: func(d time.Duration) race.Timer {
+			return config.clock.NewTimer(d)
+		},
+		Observe: func(result race.Result[providerResponse]) {
+			if result.Err != nil {
+				config.logger.Warn("provider failed", "cep", cep, "error", result.Err)
+				errs = append(errs, result.Err)
+				return
+			}
+			answers = append(answers, result.Value)
+		},
+	}
+	if config.preferred != "" {
+		contest.Preferred = func(i int) bool {
+			return strings.EqualFold(config.providers[i].Name(), config.preferred)
+		}
+	}
 
-	result := AddressResult{
-		Source: "ViaCEP",
+	// Once the lookup returns, the providers still running are cancelled.
+	// The lookup is counted and observed when they have all returned, or
+	// its timeout passed, so every attempt is seen as it ended rather than
+	// the ones still running as cancelled.
+	var winner *Attempt
+	settle = func() {
+		defer timeout.Stop()
+		contest.Settle()
+
+		settled := recorder.snapshot(cep, winner)
+		settled.Duration = report.Duration
+		config.observe(settled, err)
 	}
-	url := fmt.Sprintf("http://viacep.com.br/ws/%s/json", cep)
 
-	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		log.Println(err)
-		return
+	win := func(response providerResponse) (AddressResult, *Report, error) {
+		winner = response.attempt
+		report := recorder.snapshot(cep, response.attempt)
+
+		var others []AddressResult
+		for _, answer := range answers {
+			if answer.attempt != response.attempt {
+				others = append(others, answer.address)
+			}
+		}
+
+		result, err := config.enrichment.enrich(ctx, config, scoreConfidence(response.address, others))
+		if err != nil {
+			return address, report, err
+		}
+
+		if config.cache != nil {
+			// A partly enriched result is cached as the provider sent it,
+			// so the next hit enriches it from scratch.
+			cached := scoreConfidence(response.address, others)
+			if result.Enriched || len(config.enrichment.enrichers) == 0 {
+				cached = result
+			}
+			config.cache.Set(cep, cached)
+		}
+
+		return result, report, nil
 	}
 
-	response, err := client.Do(request)
-	if err != nil {
-		if os.IsTimeout(err) {
-			log.Println("Timeout, source: ", result.Source)
-			return
+	response, err := contest.First(ctx, lookups...)
+	switch {
+	case err == nil:
+		return win(response)
+	case contest.Expired():
+		report := recorder.snapshot(cep, nil)
+		return address, report, newTimeoutError(config.timeout, config.providers, report)
+	case len(errs) < len(config.providers):
+		// The lookup was stopped by parent or the service.
+		if err := parent.Err(); err != nil {
+			return address, recorder.snapshot(cep, nil), err
 		}
+		return address, recorder.snapshot(cep, nil), ctx.Err()
+	default:
+		return address, recorder.snapshot(cep, nil), joinProviderErrors(errs)
+	}
+}
 
-		log.Println(err)
-		return
+func (s *AddressService) getAddress(ctx context.Context, config settings, recorder *recorder, provider Provider, cep string) providerResponse {
+	backoff := config.backoff
+
+	for number := 1; ; number++ {
+		client, profile := config.clientFor(provider.Name())
+		attemptCtx, attempt := recorder.begin(ctx, provider.Name(), number, profile)
+		result, err := callProvider(attemptCtx, provider, client, cep)
+		var panicked *PanicError
+		if errors.As(err, &panicked) {
+			config.logger.Error("provider panicked", "provider", provider.Name(), "cep", cep, "panic", panicked.Value, "stack", string(panicked.Stack))
+		}
+		if err == nil && !config.prefixOff {
+			if mismatch := checkPrefix(cep, result); mismatch != nil {
+				err = fmt.Errorf("%s: %w", provider.Name(), mismatch)
+			}
+		}
+		recorder.finish(attempt, err)
+		if err == nil && config.canonical {
+			result.Street = CanonicalizeStreet(result.Street)
+		}
+		if err == nil && config.insecure && profile == CLIENT_DEFAULT {
+			result.InsecureTransport = true
+		}
+		if payload, ok := recorder.payload(attempt); ok && err == nil {
+			result.Raw, result.Header = payload.body, payload.header
+		}
+
+		response := providerResponse{address: result, attempt: attempt, err: err}
+		if err == nil || number > config.retries || !retryable(ctx, err) {
+			return response
+		}
+
+		config.logger.Info("retrying provider", "provider", provider.Name(), "attempt", number, "attempts", config.retries+1, "backoff", backoff, "error", err)
+
+		wait := config.clock.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return response
+		case <-wait.C():
+		}
+
+		backoff *= 2
 	}
-	defer response.Body.Close()
+}
 
-	var viaCepResponse ViaCEPResponse
-	err = json.NewDecoder(response.Body).Decode(&viaCepResponse)
-	if err != nil {
-		log.Println(err)
-		return
+func retryable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrStateMismatch) && !errors.Is(err, ErrProviderPanic) &&
+		!errors.Is(err, ErrTooManyRedirects) && !errors.Is(err, ErrCrossHostRedirect) && !errors.Is(err, ErrSchemaDrift)
+}
+
+func joinProviderErrors(errs []error) error {
+	for _, err := range errs {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
 	}
 
-	ch <- viaCepResponse.ToAddressResult()
-	cancel()
+	return fmt.Errorf("%w: %w", ErrAllProvidersFailed, providerErrors(errs))
+}
+
+type providerErrors []error
+
+func (e providerErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e providerErrors) Unwrap() []error {
+	return e
 }
@@ -0,0 +1,70 @@
+package address
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// TransportMiddleware wraps the transport of provider requests, for request
+// signing, mirroring or auditing.
+type TransportMiddleware func(http.RoundTripper) http.RoundTripper
+
+// SetTransportMiddleware wraps the transport of every provider, including
+// clients from SetHTTPClient and SetProviderClient, with middleware. The
+// first middleware is the outermost, so it sees a request first and its
+// response last. Middleware sees each attempt, retries included, once the
+// User-Agent and provider headers are set. Calling it again replaces the
+// chain, and no middleware removes it.
+func (s *AddressService) SetTransportMiddleware(middleware ...TransportMiddleware) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.middleware = slices.Clone(middleware)
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// chain wraps next with middleware, the first one outermost.
+func chain(next http.RoundTripper, middleware []TransportMiddleware) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+
+	return next
+}
+
+// LoggingMiddleware logs every provider request and its outcome at debug
+// level.
+func LoggingMiddleware(logger *slog.Logger) TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			start := time.Now()
+			response, err := next.RoundTrip(request)
+
+			attrs := []any{"method", request.Method, "url", request.URL.Redacted(), "duration", time.Since(start)}
+			if recorded, ok := attemptFromContext(request.Context()); ok {
+				attrs = append(attrs, "provider", recorded.attempt.Provider, "attempt", recorded.attempt.Number)
+			}
+
+			if err != nil {
+				logger.DebugContext(request.Context(), "provider request failed", append(attrs, "error", err)...)
+				return response, err
+			}
+
+			logger.DebugContext(request.Context(), "provider request", append(attrs, "status", response.StatusCode)...)
+			return response, nil
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
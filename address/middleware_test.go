@@ -0,0 +1,109 @@
+package address_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// callLog keeps, in order, which middleware saw which request.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) middleware(name string) address.TransportMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripper(func(request *http.Request) (*http.Response, error) {
+			l.mu.Lock()
+			l.calls = append(l.calls, name+" "+request.Header.Get("User-Agent"))
+			l.mu.Unlock()
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+func (l *callLog) seen() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestTransportMiddlewareSeesEveryAttempt(t *testing.T) {
+	faults := addresstest.NewFaultTransport(nil).On("/ws/",
+		addresstest.Status(http.StatusServiceUnavailable, ""),
+		addresstest.NetworkError(),
+	)
+	service, server := faultyViaCEP(t, faults)
+	calls := &callLog{}
+	service.SetRetries(2, time.Millisecond).
+		SetUserAgent("mine/1").
+		SetTransportMiddleware(calls.middleware("outer"), calls.middleware("inner"))
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first middleware is the outermost, and both run once per
+	// attempt, after the service set its headers.
+	want := []string{"outer mine/1", "inner mine/1", "outer mine/1", "inner mine/1", "outer mine/1", "inner mine/1"}
+	if got := calls.seen(); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	if n := server.Requests(); n != 1 {
+		t.Errorf("server got %d requests, want the last attempt", n)
+	}
+}
+
+func TestTransportMiddlewareReplaced(t *testing.T) {
+	providers, _ := fakeProviders(t)
+	calls := &callLog{}
+	service := newService(t, providers[0]).
+		SetTransportMiddleware(calls.middleware("dropped")).
+		SetTransportMiddleware()
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.seen(); len(got) != 0 {
+		t.Errorf("calls = %q after the middleware was removed", got)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	faults := addresstest.NewFaultTransport(nil).On("/ws/", addresstest.NetworkError())
+	service, _ := faultyViaCEP(t, faults)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	service.SetRetries(1, time.Millisecond).SetTransportMiddleware(address.LoggingMiddleware(logger))
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines logged, want one per attempt:\n%s", len(lines), logs.String())
+	}
+	for i, want := range []string{`msg="provider request failed"`, `msg="provider request"`} {
+		if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "provider=ViaCEP") {
+			t.Errorf("line %d = %s, want %s for ViaCEP", i+1, lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[1], "attempt=2") || !strings.Contains(lines[1], "status=200") {
+		t.Errorf("line 2 = %s, want attempt 2 with status 200", lines[1])
+	}
+}
@@ -0,0 +1,46 @@
+package address
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeForComparison folds s so that spellings of the same address
+// compare equal: accents are stripped, case is folded, runs of whitespace
+// become a single space and hyphens lose the spaces around them. "Praça da
+// Sé" and "PRACA  DA SE" both give "praca da se".
+func NormalizeForComparison(s string) string {
+	var stripped strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			stripped.WriteRune(r)
+		}
+	}
+
+	folded := cases.Fold().String(stripped.String())
+	folded = strings.Join(strings.Fields(strings.ReplaceAll(folded, "-", " - ")), " ")
+	return strings.ReplaceAll(folded, " - ", "-")
+}
+
+// EqualFold reports whether r and other describe the same address, comparing
+// every field with NormalizeForComparison and the CEPs by their digits.
+// Source, which only names the provider, is ignored.
+func (r AddressResult) EqualFold(other AddressResult) bool {
+	return digits(r.ZipCode) == digits(other.ZipCode) &&
+		NormalizeForComparison(r.State) == NormalizeForComparison(other.State) &&
+		NormalizeForComparison(r.City) == NormalizeForComparison(other.City) &&
+		NormalizeForComparison(r.Street) == NormalizeForComparison(other.Street) &&
+		NormalizeForComparison(r.Neighborhood) == NormalizeForComparison(other.Neighborhood)
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,78 @@
+package address_test
+
+import (
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestNormalizeForComparison(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"São Paulo", "sao paulo"},
+		{"SAO PAULO", "sao paulo"},
+		{"Praça da Sé", "praca da se"},
+		{"PRACA  DA SE", "praca da se"},
+		{"  Conceição\tdo  Araguaia ", "conceicao do araguaia"},
+		{"Jardim São João - Zona Leste", "jardim sao joao-zona leste"},
+		{"Jardim São João-Zona Leste", "jardim sao joao-zona leste"},
+		{"Vila Pompéia", "vila pompeia"},
+		{"Guarujá", "guaruja"},
+		{"Ñandú", "nandu"},
+		// Already decomposed: the combining marks are stripped as well.
+		{"Sa\u0303o Jose\u0301", "sao jose"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := address.NormalizeForComparison(test.in); got != test.want {
+			t.Errorf("NormalizeForComparison(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestEqualFold(t *testing.T) {
+	sé := address.AddressResult{ZipCode: "01001-000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", Source: "ViaCEP"}
+
+	tests := []struct {
+		name  string
+		other address.AddressResult
+		equal bool
+	}{
+		{name: "identical", other: sé, equal: true},
+		{
+			name:  "formatting",
+			other: address.AddressResult{ZipCode: "01001000", Street: "PRACA  DA SE", Neighborhood: "se", City: "sao paulo", State: "sp", Source: "BrasilAPI"},
+			equal: true,
+		},
+		{
+			name:  "hyphenated neighborhood",
+			other: address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé - Centro", City: "São Paulo", State: "SP"},
+		},
+		{
+			name:  "another street",
+			other: address.AddressResult{ZipCode: "01001000", Street: "Praça da República", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+		},
+		{
+			name:  "another CEP",
+			other: address.AddressResult{ZipCode: "01001-001", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"},
+		},
+	}
+
+	for _, test := range tests {
+		if equal := sé.EqualFold(test.other); equal != test.equal {
+			t.Errorf("%s: EqualFold = %t, want %t", test.name, equal, test.equal)
+		}
+		if equal := test.other.EqualFold(sé); equal != test.equal {
+			t.Errorf("%s: EqualFold is not symmetric", test.name)
+		}
+	}
+
+	centro := address.AddressResult{ZipCode: "20040010", Neighborhood: "Centro - Zona Sul", City: "Rio de Janeiro", State: "RJ"}
+	spaced := address.AddressResult{ZipCode: "20040-010", Neighborhood: "centro-zona  sul", City: "RIO DE JANEIRO", State: "rj"}
+	if !centro.EqualFold(spaced) {
+		t.Error("hyphens and double spaces should not tell results apart")
+	}
+}
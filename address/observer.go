@@ -0,0 +1,22 @@
+package address
+
+// Observer is told about every finished lookup. The report carries what each
+// provider did, which is enough to derive metrics without the library
+// depending on a metrics package.
+type Observer interface {
+	ObserveLookup(report *Report, err error)
+}
+
+// SetObserver installs observer, or removes the current one when nil.
+// ObserveLookup is called once the lookup settled: right away when no
+// provider was asked, otherwise when every provider it started returned or
+// its timeout passed, which can be after Execute returned. It may run on
+// another goroutine than the lookup, so it has to be safe for concurrent
+// use.
+func (s *AddressService) SetObserver(observer Observer) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observer = observer
+	return s
+}
@@ -0,0 +1,42 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+var ErrProviderPanic = errors.New("provider panicked")
+
+// PanicError is the error of a provider call that panicked. It wraps
+// ErrProviderPanic; the lookup goes on with the other providers and the
+// panic is not retried.
+type PanicError struct {
+	Provider string
+	Value    any
+	Stack    []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Provider, ErrProviderPanic, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrProviderPanic
+}
+
+// recoverProvider turns a panic of provider into a *PanicError stored in
+// err. It has to be deferred directly.
+func recoverProvider(provider string, err *error) {
+	if value := recover(); value != nil {
+		*err = &PanicError{Provider: provider, Value: value, Stack: debug.Stack()}
+	}
+}
+
+// callProvider calls provider.GetAddress, recovering from a panic.
+func callProvider(ctx context.Context, provider Provider, client *http.Client, cep string) (result AddressResult, err error) {
+	defer recoverProvider(provider.Name(), &err)
+	return provider.GetAddress(ctx, client, cep)
+}
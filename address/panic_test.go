@@ -0,0 +1,79 @@
+package address_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// panickingProvider panics on every call, counting them.
+func panickingProvider(name string, calls *atomic.Int32) address.Provider {
+	return address.NewProvider(name, func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		calls.Add(1)
+		panic("index out of range")
+	})
+}
+
+func TestProviderPanicLeavesTheRaceToTheOthers(t *testing.T) {
+	var calls atomic.Int32
+	var logs bytes.Buffer
+	service := newService(t,
+		panickingProvider("Broken", &calls),
+		addresstest.NewMockProvider("Up").Returns(sé).SetLatency(20*time.Millisecond),
+	).SetLogger(slog.New(slog.NewTextHandler(&logs, nil))).SetRetries(2, time.Millisecond)
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "Up" {
+		t.Errorf("won by %q, want Up", result.Source)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Broken called %d times, want a panic not to be retried", n)
+	}
+	if !strings.Contains(logs.String(), "provider panicked") || !strings.Contains(logs.String(), "panic_test.go") {
+		t.Errorf("logs lack the panic with its stack:\n%s", logs.String())
+	}
+}
+
+func TestProviderPanicError(t *testing.T) {
+	var calls atomic.Int32
+	service := newService(t, panickingProvider("Broken", &calls))
+
+	_, err := service.Execute("01001000")
+	if !errors.Is(err, address.ErrAllProvidersFailed) || !errors.Is(err, address.ErrProviderPanic) {
+		t.Fatalf("err = %v, want ErrAllProvidersFailed with ErrProviderPanic", err)
+	}
+
+	var panicked *address.PanicError
+	if !errors.As(err, &panicked) {
+		t.Fatalf("err = %v, want a *PanicError", err)
+	}
+	if panicked.Provider != "Broken" || panicked.Value != "index out of range" || len(panicked.Stack) == 0 {
+		t.Errorf("PanicError = %+v, want Broken's panic with its stack", panicked)
+	}
+}
+
+func TestCheckProvidersRecoversPanics(t *testing.T) {
+	var calls atomic.Int32
+	service := newService(t, panickingProvider("Broken", &calls))
+
+	for _, status := range service.CheckProviders(context.Background(), time.Second) {
+		if status.Name == "Broken" && !errors.Is(status.Err, address.ErrProviderPanic) {
+			t.Errorf("Broken status = %+v, want it failed with ErrProviderPanic", status)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Broken checked %d times, want once", calls.Load())
+	}
+}
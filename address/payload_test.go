@@ -0,0 +1,78 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// The payloads under testdata/payloads are provider answers as they were
+// captured, plus the variants mirrors and older API versions send.
+func TestPayloadMapping(t *testing.T) {
+	paulista := address.AddressResult{ZipCode: "01310100", Street: "Avenida Paulista", Neighborhood: "Bela Vista", City: "São Paulo", State: "SP", StateName: "São Paulo"}
+	engenheiroCoelho := address.AddressResult{ZipCode: "13165000", City: "Engenheiro Coelho", State: "SP", StateName: "São Paulo"}
+	matão := address.AddressResult{ZipCode: "05508090", Street: "Rua do Matão", Neighborhood: "Butantã", City: "São Paulo", State: "SP", StateName: "São Paulo"}
+	praçaDaSé := address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo"}
+	centroRio := address.AddressResult{ZipCode: "20040010", City: "Rio de Janeiro", State: "RJ", StateName: "Rio de Janeiro"}
+	candelária := centroRio
+	candelária.Street, candelária.Neighborhood = "Rua da Candelária", "Centro"
+
+	type provider func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error)
+	tests := []struct {
+		payload  string
+		provider provider
+		want     address.AddressResult
+		err      error
+	}{
+		{"viacep_big_city.json", address.ViaCEP, paulista, nil},
+		{"viacep_general_delivery.json", address.ViaCEP, engenheiroCoelho, nil},
+		{"viacep_accented.json", address.ViaCEP, matão, nil},
+		{"viacep_padded.json", address.ViaCEP, praçaDaSé, nil},
+		{"viacep_missing_fields.json", address.ViaCEP, centroRio, nil},
+		{"viacep_not_found.json", address.ViaCEP, address.AddressResult{}, address.ErrNotFound},
+		{"viacep_not_found_bool.json", address.ViaCEP, address.AddressResult{}, address.ErrNotFound},
+		{"viacep_not_found_padded.json", address.ViaCEP, address.AddressResult{}, address.ErrNotFound},
+		{"brasilapi_big_city.json", address.BrasilAPI, paulista, nil},
+		{"brasilapi_general_delivery.json", address.BrasilAPI, engenheiroCoelho, nil},
+		{"brasilapi_accented.json", address.BrasilAPI, matão, nil},
+		{"brasilapi_dashed_mirror.json", address.BrasilAPI, praçaDaSé, nil},
+		{"brasilapi_missing_fields.json", address.BrasilAPI, centroRio, nil},
+		{"brasilapi_state_name.json", address.BrasilAPI, candelária, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.payload, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "payloads", test.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{Transport: bodyTransport(body)}
+			result, err := test.provider(context.Background(), client, test.want.ZipCode)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Source == "" {
+				t.Error("no source")
+			}
+			want := test.want
+			want.Source = result.Source
+			if !reflect.DeepEqual(result, want) {
+				t.Errorf("mapped to\n%+v\nwant\n%+v", result, want)
+			}
+		})
+	}
+}
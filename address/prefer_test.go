@@ -0,0 +1,91 @@
+package address_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// With both providers equally fast, the preferred one always wins.
+func TestPreferredWinnerWinsEveryTime(t *testing.T) {
+	for _, preferred := range []string{"ViaCEP", "BrasilAPI"} {
+		t.Run(preferred, func(t *testing.T) {
+			service := addresstest.NewService(t, map[string]address.AddressResult{sé.ZipCode: sé}).
+				SetPreferredWinner(preferred, 0)
+
+			for run := 0; run < 100; run++ {
+				result, report, err := service.ExecuteWithReport("01001000")
+				if err != nil {
+					t.Fatalf("run %d: %v", run, err)
+				}
+				if result.Source != preferred || report.Winner != preferred {
+					t.Fatalf("run %d: %s won, want %s", run, result.Source, preferred)
+				}
+			}
+		})
+	}
+}
+
+func TestPreferredWinnerFailing(t *testing.T) {
+	down := errors.New("503 from upstream")
+	preferred := addresstest.NewMockProvider("Preferred").Fails(down).SetLatency(20 * time.Millisecond)
+	other := addresstest.NewMockProvider("Other").Returns(sé)
+	service := newService(t, preferred, other).SetPreferredWinner("Preferred", time.Minute)
+
+	start := time.Now()
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "Other" {
+		t.Errorf("%s won, want the answer held back", result.Source)
+	}
+	// The failure ends the wait, long before the grace period would.
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("lookup took %v", elapsed)
+	}
+}
+
+func TestPreferredWinnerGraceRunsOut(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	preferred := addresstest.NewMockProvider("Preferred").Returns(sé).SetLatency(time.Second).SetClock(clock)
+	other := addresstest.NewMockProvider("Other").Returns(sé).SetLatency(10 * time.Millisecond).SetClock(clock)
+	service := newService(t, preferred, other).SetClock(clock).SetPreferredWinner("Preferred", 100*time.Millisecond)
+
+	done := startLookup(service, "01001000")
+	// The lookup's timeout and both providers.
+	clock.BlockUntilTimers(3)
+	clock.Advance(10 * time.Millisecond)
+	// Other answered; the grace period started.
+	clock.BlockUntilTimers(3)
+	select {
+	case got := <-done:
+		t.Fatalf("lookup returned %s before the grace period ran out", got.result.Source)
+	default:
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	got := <-done
+	if got.err != nil || got.result.Source != "Other" {
+		t.Fatalf("result from %q (%v), want Other once the grace ran out", got.result.Source, got.err)
+	}
+	if got.report.Duration != 110*time.Millisecond {
+		t.Errorf("lookup took %v, want 110ms", got.report.Duration)
+	}
+}
+
+func TestPreferredWinnerUnknownIsIgnored(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	fast := addresstest.NewMockProvider("Fast").Returns(sé).SetLatency(10 * time.Millisecond).SetClock(clock)
+	service := newService(t, fast).SetClock(clock).SetPreferredWinner("Missing", time.Hour)
+
+	done := startLookup(service, "01001000")
+	clock.BlockUntilTimers(2)
+	clock.Advance(10 * time.Millisecond)
+	if got := <-done; got.err != nil || got.result.Source != "Fast" {
+		t.Fatalf("result from %q (%v), want Fast without waiting", got.result.Source, got.err)
+	}
+}
@@ -0,0 +1,98 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrStateMismatch = errors.New("state does not match the CEP")
+
+// cepRanges maps ranges of CEP prefixes, the first five digits, to the UF
+// they were assigned to by the Correios.
+var cepRanges = []struct {
+	from, to int
+	uf       string
+}{
+	{1000, 19999, "SP"},
+	{20000, 28999, "RJ"},
+	{29000, 29999, "ES"},
+	{30000, 39999, "MG"},
+	{40000, 48999, "BA"},
+	{49000, 49999, "SE"},
+	{50000, 56999, "PE"},
+	{57000, 57999, "AL"},
+	{58000, 58999, "PB"},
+	{59000, 59999, "RN"},
+	{60000, 63999, "CE"},
+	{64000, 64999, "PI"},
+	{65000, 65999, "MA"},
+	{66000, 68899, "PA"},
+	{68900, 68999, "AP"},
+	{69000, 69299, "AM"},
+	{69300, 69399, "RR"},
+	{69400, 69899, "AM"},
+	{69900, 69999, "AC"},
+	{70000, 72799, "DF"},
+	{72800, 72999, "GO"},
+	{73000, 73699, "DF"},
+	{73700, 76799, "GO"},
+	{76800, 76999, "RO"},
+	{77000, 77999, "TO"},
+	{78000, 78899, "MT"},
+	{79000, 79999, "MS"},
+	{80000, 87999, "PR"},
+	{88000, 89999, "SC"},
+	{90000, 99999, "RS"},
+}
+
+// StateForCEP returns the UF a CEP belongs to by its prefix, or false for
+// invalid CEPs and prefixes no state was assigned.
+func StateForCEP(cep string) (string, bool) {
+	cep, err := NormalizeCEP(cep)
+	if err != nil {
+		return "", false
+	}
+
+	prefix, _ := strconv.Atoi(cep[:5])
+	for _, r := range cepRanges {
+		if prefix >= r.from && prefix <= r.to {
+			return r.uf, true
+		}
+	}
+
+	return "", false
+}
+
+// SetPrefixCheck controls whether answers whose state does not match the
+// state of the CEP's prefix are rejected, which is on by default. Such an
+// answer almost always means the provider returned garbage; it fails with
+// ErrStateMismatch, so another provider can win, and shows up in the report.
+func (s *AddressService) SetPrefixCheck(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prefixOff = !enabled
+	return s
+}
+
+// checkPrefix returns ErrStateMismatch when result is in another state than
+// cep. Answers without a state, or with one that is not a UF or a state
+// name, and CEPs outside the known ranges pass.
+func checkPrefix(cep string, result AddressResult) error {
+	want, ok := StateForCEP(cep)
+	if !ok || result.State == "" {
+		return nil
+	}
+
+	got := strings.ToUpper(strings.TrimSpace(result.State))
+	if code, ok := StateCode(result.State); ok {
+		got = code
+	}
+	if _, ok := STATES[got]; !ok || got == want {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s is in %s, got %s", ErrStateMismatch, cep, want, got)
+}
@@ -0,0 +1,137 @@
+package address_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestStateForCEPBorders(t *testing.T) {
+	ranges := []struct {
+		from, to int
+		uf       string
+	}{
+		{1000, 19999, "SP"},
+		{20000, 28999, "RJ"},
+		{29000, 29999, "ES"},
+		{30000, 39999, "MG"},
+		{40000, 48999, "BA"},
+		{49000, 49999, "SE"},
+		{50000, 56999, "PE"},
+		{57000, 57999, "AL"},
+		{58000, 58999, "PB"},
+		{59000, 59999, "RN"},
+		{60000, 63999, "CE"},
+		{64000, 64999, "PI"},
+		{65000, 65999, "MA"},
+		{66000, 68899, "PA"},
+		{68900, 68999, "AP"},
+		{69000, 69299, "AM"},
+		{69300, 69399, "RR"},
+		{69400, 69899, "AM"},
+		{69900, 69999, "AC"},
+		{70000, 72799, "DF"},
+		{72800, 72999, "GO"},
+		{73000, 73699, "DF"},
+		{73700, 76799, "GO"},
+		{76800, 76999, "RO"},
+		{77000, 77999, "TO"},
+		{78000, 78899, "MT"},
+		{79000, 79999, "MS"},
+		{80000, 87999, "PR"},
+		{88000, 89999, "SC"},
+		{90000, 99999, "RS"},
+	}
+
+	states := make(map[string]bool)
+	for _, r := range ranges {
+		states[r.uf] = true
+		for _, cep := range []string{fmt.Sprintf("%05d000", r.from), fmt.Sprintf("%05d-999", r.to)} {
+			if uf, ok := address.StateForCEP(cep); !ok || uf != r.uf {
+				t.Errorf("StateForCEP(%s) = %q, %t, want %s", cep, uf, ok, r.uf)
+			}
+		}
+	}
+	if len(states) != len(address.STATES) {
+		t.Errorf("the ranges cover %d states, want all %d", len(states), len(address.STATES))
+	}
+
+	// No state was given the prefixes under 01000.
+	for _, cep := range []string{"00000000", "00999999", "1234", "abcdefgh"} {
+		if uf, ok := address.StateForCEP(cep); ok {
+			t.Errorf("StateForCEP(%s) = %q, want none", cep, uf)
+		}
+	}
+}
+
+func TestPrefixCheck(t *testing.T) {
+	wrong := sé
+	wrong.State = "RJ"
+	byName := sé
+	byName.State = "São Paulo"
+	nowhere := sé
+	nowhere.State = "Província Cisplatina"
+
+	tests := []struct {
+		name   string
+		answer address.AddressResult
+		ok     bool
+	}{
+		{name: "correct", answer: sé, ok: true},
+		{name: "by name", answer: byName, ok: true},
+		{name: "unknown state", answer: nowhere, ok: true},
+		{name: "wrong", answer: wrong},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The garbled provider answers first; a correct one answers
+			// a little later.
+			garbled := addresstest.NewMockProvider("Garbled").Returns(test.answer)
+			correct := addresstest.NewMockProvider("Correct").Returns(sé).SetLatency(20 * time.Millisecond)
+			settled := make(settledReports, 1)
+			service := newService(t, garbled, correct).SetObserver(settled).SetRetries(2, time.Millisecond)
+
+			got := <-startLookup(service, "01001000")
+			if got.err != nil {
+				t.Fatal(got.err)
+			}
+
+			want := "Garbled"
+			if !test.ok {
+				want = "Correct"
+			}
+			if got.report.Winner != want {
+				t.Errorf("winner = %s, want %s", got.report.Winner, want)
+			}
+
+			attempt := attemptOf(t, settled.next(t), "Garbled")
+			if test.ok != (attempt.Outcome == address.OUTCOME_WON) {
+				t.Errorf("Garbled attempt = %s", attempt.Outcome)
+			}
+			if !test.ok && !errors.Is(attempt.Err, address.ErrStateMismatch) {
+				t.Errorf("Garbled attempt failed with %v, want ErrStateMismatch", attempt.Err)
+			}
+			// A mismatch is not retried.
+			garbled.AssertCalls(t, 1)
+		})
+	}
+}
+
+func TestPrefixCheckOff(t *testing.T) {
+	wrong := sé
+	wrong.State = "RJ"
+	service := newService(t, addresstest.NewMockProvider("Garbled").Returns(wrong)).SetPrefixCheck(false)
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.State != "RJ" {
+		t.Errorf("state = %q, want the provider's RJ", result.State)
+	}
+}
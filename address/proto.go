@@ -0,0 +1,95 @@
+package address
+
+import (
+	"net/http"
+
+	"github.com/wendellnd/multithreading-challenge/proto/addresspb"
+)
+
+// ToProto converts result to the protobuf message defined in
+// proto/address.proto, shared by the gRPC API and anything else that needs a
+// binary encoding.
+func ToProto(result AddressResult) *addresspb.Address {
+	message := &addresspb.Address{
+		Cep:               result.ZipCode,
+		State:             result.State,
+		City:              result.City,
+		Neighborhood:      result.Neighborhood,
+		Street:            result.Street,
+		Source:            result.Source,
+		StateName:         result.StateName,
+		Enriched:          result.Enriched,
+		Confidence:        result.Confidence,
+		Raw:               result.Raw,
+		InsecureTransport: result.InsecureTransport,
+	}
+
+	if result.Location != nil {
+		message.Location = &addresspb.Coordinates{
+			Latitude:  result.Location.Latitude,
+			Longitude: result.Location.Longitude,
+		}
+	}
+
+	for _, diff := range result.Conflicts {
+		message.Conflicts = append(message.Conflicts, &addresspb.FieldDiff{
+			Field:       diff.Field,
+			A:           diff.A,
+			B:           diff.B,
+			Substantive: diff.Substantive,
+		})
+	}
+
+	if result.Header != nil {
+		message.Header = make(map[string]*addresspb.HeaderValues, len(result.Header))
+		for name, values := range result.Header {
+			message.Header[name] = &addresspb.HeaderValues{Values: values}
+		}
+	}
+
+	return message
+}
+
+// FromProto converts message back to a result. A nil message gives the zero
+// result, absent optional fields are left empty and fields added to the
+// message after this was built are ignored.
+func FromProto(message *addresspb.Address) AddressResult {
+	result := AddressResult{
+		Source:            message.GetSource(),
+		State:             message.GetState(),
+		StateName:         message.GetStateName(),
+		City:              message.GetCity(),
+		Street:            message.GetStreet(),
+		ZipCode:           message.GetCep(),
+		Neighborhood:      message.GetNeighborhood(),
+		Enriched:          message.GetEnriched(),
+		Confidence:        message.GetConfidence(),
+		Raw:               message.GetRaw(),
+		InsecureTransport: message.GetInsecureTransport(),
+	}
+
+	if location := message.GetLocation(); location != nil {
+		result.Location = &Coordinates{
+			Latitude:  location.GetLatitude(),
+			Longitude: location.GetLongitude(),
+		}
+	}
+
+	for _, diff := range message.GetConflicts() {
+		result.Conflicts = append(result.Conflicts, FieldDiff{
+			Field:       diff.GetField(),
+			A:           diff.GetA(),
+			B:           diff.GetB(),
+			Substantive: diff.GetSubstantive(),
+		})
+	}
+
+	if header := message.GetHeader(); header != nil {
+		result.Header = make(http.Header, len(header))
+		for name, values := range header {
+			result.Header[name] = values.GetValues()
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,117 @@
+package address_test
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/proto/addresspb"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		result address.AddressResult
+	}{
+		{name: "zero"},
+		{name: "plain", result: sé},
+		{
+			name: "every field",
+			result: address.AddressResult{
+				ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP",
+				Location:          &address.Coordinates{Latitude: -23.5503, Longitude: -46.6339},
+				Enriched:          true,
+				Confidence:        address.CONFIDENCE_CONFLICT,
+				Conflicts:         address.FieldDiffs{{Field: "street", A: "Praça da Sé", B: "Rua Direita", Substantive: true}},
+				Raw:               []byte(`{"cep":"01001-000"}`),
+				Header:            http.Header{"Content-Type": {"application/json"}},
+				InsecureTransport: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Through the wire encoding, as the gRPC API sends it.
+			data, err := proto.Marshal(address.ToProto(test.result))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var message addresspb.Address
+			if err := proto.Unmarshal(data, &message); err != nil {
+				t.Fatal(err)
+			}
+
+			got := address.FromProto(&message)
+			if !reflect.DeepEqual(got, test.result) {
+				t.Errorf("round trip gave %+v, want %+v", got, test.result)
+			}
+		})
+	}
+}
+
+func TestFromProtoNil(t *testing.T) {
+	if got := address.FromProto(nil); !reflect.DeepEqual(got, address.AddressResult{}) {
+		t.Errorf("FromProto(nil) = %+v, want the zero result", got)
+	}
+}
+
+// fixture is the result encoded in testdata/address.binpb. The file is
+// never regenerated: it stands for messages already written by older
+// builds, which have to stay readable.
+var fixture = address.AddressResult{
+	ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP",
+	Location:   &address.Coordinates{Latitude: -23.5503, Longitude: -46.6339},
+	Enriched:   true,
+	Confidence: address.CONFIDENCE_CONFLICT,
+	Conflicts:  address.FieldDiffs{{Field: "street", A: "Praça da Sé", B: "Rua Direita", Substantive: true}},
+	Raw:        []byte(`{"cep":"01001-000"}`),
+	Header:     http.Header{"Content-Type": {"application/json"}},
+}
+
+// decode unmarshals data into an Address and converts it.
+func decode(t *testing.T, data []byte) address.AddressResult {
+	t.Helper()
+
+	var message addresspb.Address
+	if err := proto.Unmarshal(data, &message); err != nil {
+		t.Fatal(err)
+	}
+	return address.FromProto(&message)
+}
+
+func TestFromProtoFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/address.binpb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := decode(t, data); !reflect.DeepEqual(got, fixture) {
+		t.Errorf("decoded %+v, want %+v", got, fixture)
+	}
+}
+
+func TestFromProtoIgnoresUnknownFields(t *testing.T) {
+	data, err := os.ReadFile("testdata/address.binpb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields a newer version of the message might add: a string, a number
+	// and a nested message, under numbers this version does not know.
+	data = protowire.AppendTag(data, 1000, protowire.BytesType)
+	data = protowire.AppendString(data, "IBGE 3550308")
+	data = protowire.AppendTag(data, 1001, protowire.VarintType)
+	data = protowire.AppendVarint(data, 42)
+	data = protowire.AppendTag(data, 1002, protowire.BytesType)
+	data = protowire.AppendBytes(data, protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "nested"))
+
+	if got := decode(t, data); !reflect.DeepEqual(got, fixture) {
+		t.Errorf("decoded %+v with unknown fields, want %+v", got, fixture)
+	}
+}
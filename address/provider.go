@@ -0,0 +1,275 @@
+package address
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MAX_RESPONSE_BYTES caps how much of a provider response is decoded, after
+// decompression, so a huge or zip-bomb body cannot exhaust memory.
+const MAX_RESPONSE_BYTES = 1 << 20
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var gzipMagic = []byte{0x1F, 0x8B}
+
+type Provider interface {
+	Name() string
+	GetAddress(ctx context.Context, client *http.Client, cep string) (AddressResult, error)
+}
+
+type funcProvider struct {
+	name string
+	fn   GetAddressFunc
+}
+
+func NewProvider(name string, fn GetAddressFunc) Provider {
+	return funcProvider{name: name, fn: fn}
+}
+
+func (p funcProvider) Name() string {
+	return p.name
+}
+
+func (p funcProvider) GetAddress(ctx context.Context, client *http.Client, cep string) (AddressResult, error) {
+	return p.fn(ctx, client, cep)
+}
+
+func defaultProviders() []Provider {
+	return []Provider{
+		NewViaCEPProvider(VIACEP_BASE_URL),
+		NewBrasilAPIProvider(BRASILAPI_BASE_URL),
+	}
+}
+
+// RegisterProvider adds provider to the registry and enables it. A provider
+// named like one already registered replaces it, so the built-in providers
+// can be pointed at another base URL:
+//
+//	service.RegisterProvider(address.NewViaCEPProvider("http://localhost:8081/ws"))
+func (s *AddressService) RegisterProvider(provider Provider) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, registered := range s.registry {
+		if strings.EqualFold(registered.Name(), provider.Name()) {
+			s.registry[i] = provider
+			s.replaceEnabled(registered, provider)
+			return s
+		}
+	}
+
+	s.registry = append(s.registry, provider)
+	s.providers = append(s.providers, provider)
+	return s
+}
+
+func (s *AddressService) replaceEnabled(old Provider, provider Provider) {
+	for i, enabled := range s.providers {
+		if strings.EqualFold(enabled.Name(), old.Name()) {
+			s.providers[i] = provider
+			return
+		}
+	}
+
+	s.providers = append(s.providers, provider)
+}
+
+func (s *AddressService) ProviderNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return providerNames(s.registry)
+}
+
+func (s *AddressService) EnabledProviderNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return providerNames(s.providers)
+}
+
+func providerNames(providers []Provider) []string {
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		names[i] = provider.Name()
+	}
+
+	return names
+}
+
+// SetProviders restricts the race to the registered providers with the given
+// names, matched case-insensitively.
+func (s *AddressService) SetProviders(names ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selected := make([]Provider, 0, len(names))
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		provider, ok := s.findProvider(name)
+		if !ok {
+			return fmt.Errorf("%w %q (valid: %s)", ErrUnknownProvider, name, strings.Join(providerNames(s.registry), ", "))
+		}
+
+		if seen[provider.Name()] {
+			continue
+		}
+		seen[provider.Name()] = true
+
+		selected = append(selected, provider)
+	}
+
+	s.providers = selected
+	return nil
+}
+
+// findProvider looks name up in the registry. s.mu must be held.
+func (s *AddressService) findProvider(name string) (Provider, bool) {
+	for _, provider := range s.registry {
+		if strings.EqualFold(provider.Name(), name) {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+func doRequest(ctx context.Context, client *http.Client, source string, url string) (*http.Response, error) {
+	recorded, recording := attemptFromContext(ctx)
+	if recording {
+		ctx = recorded.withTrace(ctx)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	response, err := client.Do(request)
+	if err == nil && recording {
+		recorded.setResponse(response)
+	}
+	if err != nil {
+		if os.IsTimeout(err) {
+			return nil, fmt.Errorf("%s: %w", source, ErrTimeout)
+		}
+
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	return response, nil
+}
+
+// decodeResponse decodes a provider's JSON body into v. A gzip body is
+// decompressed when the response says so and the body really is gzip, since
+// some mirrors send plain JSON under Content-Encoding: gzip. A leading UTF-8
+// BOM, which some proxies add, is skipped. Bodies past MAX_RESPONSE_BYTES,
+// counted after decompression, and anything that still fails to decode are
+// reported as ErrInvalidResponse, unless schema is set and the body breaks
+// it, which is a *SchemaDriftError; see SetSchemaCheck.
+func decodeResponse(response *http.Response, v any, schema responseSchema) error {
+	reader := getReader(response.Body)
+	defer putReader(reader)
+
+	var body io.Reader = reader
+	if strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		if magic, err := reader.Peek(2); err == nil && bytes.Equal(magic, gzipMagic) {
+			gzipReader, err := gzip.NewReader(reader)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+			}
+			defer gzipReader.Close()
+
+			body = gzipReader
+		}
+	}
+
+	var raw *bytes.Buffer
+	recorded, recording := attemptFromContext(requestContext(response))
+	keepRaw := recording && recorded.recorder.raw
+	checkSchema := len(schema.fields) > 0 && !(recording && recorded.recorder.noSchema)
+	if keepRaw || checkSchema {
+		raw = new(bytes.Buffer)
+		body = io.TeeReader(body, raw)
+	}
+
+	limited := getReader(&io.LimitedReader{R: body, N: MAX_RESPONSE_BYTES + 1})
+	defer putReader(limited)
+	if bom, err := limited.Peek(3); err == nil && bytes.Equal(bom, utf8BOM) {
+		limited.Discard(len(utf8BOM))
+	}
+
+	counter := &countingReader{reader: limited}
+	if err := json.NewDecoder(counter).Decode(v); err != nil {
+		if counter.n > MAX_RESPONSE_BYTES {
+			return fmt.Errorf("%w: body larger than %d bytes", ErrInvalidResponse, MAX_RESPONSE_BYTES)
+		}
+		if checkSchema {
+			if drift := driftFrom(schema, raw.Bytes(), v, err); drift != nil {
+				return drift
+			}
+		}
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	if checkSchema {
+		if drift := driftFrom(schema, raw.Bytes(), v, nil); drift != nil {
+			return drift
+		}
+	}
+
+	if keepRaw {
+		// The decoder stops at the end of the value, the rest of the body
+		// is kept too.
+		io.Copy(io.Discard, limited)
+		recorded.setPayload(raw.Bytes()[:min(raw.Len(), MAX_RESPONSE_BYTES)], response.Header.Clone())
+	}
+
+	return nil
+}
+
+// readers holds the buffered readers decodeResponse peeks through, which
+// would otherwise cost two 4 KiB buffers per response.
+var readers = sync.Pool{New: func() any { return bufio.NewReader(nil) }}
+
+func getReader(r io.Reader) *bufio.Reader {
+	reader := readers.Get().(*bufio.Reader)
+	reader.Reset(r)
+	return reader
+}
+
+func putReader(reader *bufio.Reader) {
+	reader.Reset(nil)
+	readers.Put(reader)
+}
+
+func requestContext(response *http.Response) context.Context {
+	if response.Request == nil {
+		return context.Background()
+	}
+
+	return response.Request.Context()
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
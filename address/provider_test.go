@@ -0,0 +1,74 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// The provider mapping tests replay testdata/providers.cassette.json. Record
+// it again from the real APIs with
+//
+//	go test -run Mapping -record ./address
+var record = flag.Bool("record", false, "record the provider cassettes from the real APIs")
+
+// cassetteClient returns a client replaying, or with -record recording, the
+// cassette named name.
+func cassetteClient(t *testing.T, name string) *http.Client {
+	t.Helper()
+
+	mode := addresstest.CASSETTE_REPLAY
+	if *record {
+		mode = addresstest.CASSETTE_RECORD
+	}
+
+	cassette, err := addresstest.NewCassette(filepath.Join("testdata", name+".cassette.json"), mode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := cassette.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return &http.Client{Transport: cassette}
+}
+
+func TestProviderMapping(t *testing.T) {
+	client := cassetteClient(t, "providers")
+
+	praçaDaSé := address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo"}
+	tests := []struct {
+		provider func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error)
+		source   string
+	}{
+		{address.ViaCEP, "ViaCEP"},
+		{address.BrasilAPI, "BrasilAPI"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			result, err := test.provider(context.Background(), client, "01001000")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := praçaDaSé
+			want.Source = test.source
+			if diffs := address.Diff(result, want); len(diffs) != 0 {
+				t.Errorf("mapped to %+v: %v", result, diffs)
+			}
+
+			if _, err := test.provider(context.Background(), client, "99999999"); !errors.Is(err, address.ErrNotFound) {
+				t.Errorf("unknown CEP: err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// settledReports hands the tests the report of each lookup once every
+// provider it started returned.
+type settledReports chan *address.Report
+
+func (s settledReports) ObserveLookup(report *address.Report, err error) {
+	s <- report
+}
+
+func (s settledReports) next(t *testing.T) *address.Report {
+	t.Helper()
+
+	select {
+	case report := <-s:
+		return report
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup never settled: a provider is still running")
+		return nil
+	}
+}
+
+type lookup struct {
+	result address.AddressResult
+	report *address.Report
+	err    error
+}
+
+// startLookup runs ExecuteWithReport for cep in the background.
+func startLookup(service *address.AddressService, cep string) <-chan lookup {
+	done := make(chan lookup, 1)
+	go func() {
+		result, report, err := service.ExecuteWithReport(cep)
+		done <- lookup{result: result, report: report, err: err}
+	}()
+	return done
+}
+
+func attemptOf(t *testing.T, report *address.Report, provider string) address.Attempt {
+	t.Helper()
+
+	for _, attempt := range report.Attempts {
+		if attempt.Provider == provider {
+			return attempt
+		}
+	}
+	t.Fatalf("no attempt of %s in %+v", provider, report.Attempts)
+	return address.Attempt{}
+}
+
+func TestFastestProviderWins(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	fast := addresstest.NewMockProvider("Fast").Returns(sé).SetLatency(10 * time.Millisecond).SetClock(clock)
+	slow := addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Second).SetClock(clock)
+	settled := make(settledReports, 1)
+	service := newService(t, slow, fast).SetClock(clock).SetObserver(settled)
+
+	done := startLookup(service, "01001-000")
+	// The lookup's timeout and both providers are waiting.
+	clock.BlockUntilTimers(3)
+	clock.Advance(10 * time.Millisecond)
+	got := <-done
+
+	if got.err != nil {
+		t.Fatalf("lookup failed: %v", got.err)
+	}
+	if got.result.Source != "Fast" || got.result.Street != sé.Street {
+		t.Errorf("result = %+v, want the address from Fast", got.result)
+	}
+	if got.report.Winner != "Fast" {
+		t.Errorf("winner = %q, want Fast", got.report.Winner)
+	}
+	fast.AssertCalls(t, 1)
+	slow.AssertCalls(t, 1)
+	fast.AssertCalledWith(t, "01001000")
+
+	// The slow provider is cancelled rather than left running out its
+	// latency: the clock never reaches it.
+	report := settled.next(t)
+	if attempt := attemptOf(t, report, "Slow"); attempt.Outcome != address.OUTCOME_CANCELLED || !errors.Is(attempt.Err, context.Canceled) {
+		t.Errorf("Slow attempt = %s (%v), want cancelled", attempt.Outcome, attempt.Err)
+	}
+	if attempt := attemptOf(t, report, "Fast"); attempt.Outcome != address.OUTCOME_WON {
+		t.Errorf("Fast attempt = %s, want won", attempt.Outcome)
+	}
+}
+
+func TestProviderRegisteredAsFunctionWins(t *testing.T) {
+	var calls atomic.Int32
+	provider := address.NewProvider("Func", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		calls.Add(1)
+		return address.AddressResult{ZipCode: cep, City: "São Paulo", State: "SP", Source: "Func"}, nil
+	})
+	service := newService(t, provider)
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != "Func" || result.ZipCode != "01001000" {
+		t.Errorf("result = %+v", result)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("provider called %d times, want 1", n)
+	}
+}
+
+func TestAllProvidersFail(t *testing.T) {
+	down := errors.New("503 from upstream")
+	broken := errors.New("garbled body")
+	a := addresstest.NewMockProvider("A").Fails(down)
+	b := addresstest.NewMockProvider("B").Fails(broken)
+	service := newService(t, a, b)
+
+	result, err := service.Execute("01001000")
+	if !errors.Is(err, address.ErrAllProvidersFailed) {
+		t.Fatalf("err = %v, want ErrAllProvidersFailed", err)
+	}
+	if !errors.Is(err, down) || !errors.Is(err, broken) {
+		t.Errorf("err = %v, want both provider errors", err)
+	}
+	if result.Source != "" || result.ZipCode != "" {
+		t.Errorf("result = %+v, want none", result)
+	}
+	a.AssertCalls(t, 1)
+	b.AssertCalls(t, 1)
+}
+
+func TestAllProvidersNotFound(t *testing.T) {
+	a := addresstest.NewMockProvider("A")
+	b := addresstest.NewMockProvider("B")
+	service := newService(t, a, b)
+
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrNotFound) || errors.Is(err, address.ErrAllProvidersFailed) {
+		t.Fatalf("err = %v, want ErrNotFound alone", err)
+	}
+}
+
+func TestTimeoutFires(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	// The providers only return once cancelled, and say with what.
+	started := make(chan struct{}, 2)
+	exits := make(chan error, 2)
+	hang := func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		exits <- ctx.Err()
+		return address.AddressResult{}, ctx.Err()
+	}
+	service := newService(t, address.NewProvider("A", hang), address.NewProvider("B", hang)).
+		SetClock(clock).
+		SetTimeout(50 * time.Millisecond)
+
+	done := startLookup(service, "01001000")
+	<-started
+	<-started
+	clock.Advance(50 * time.Millisecond)
+	got := <-done
+
+	var timeout *address.TimeoutError
+	if !errors.As(got.err, &timeout) || !errors.Is(got.err, address.ErrTimeout) {
+		t.Fatalf("err = %v, want a *TimeoutError", got.err)
+	}
+	if timeout.After != 50*time.Millisecond || len(timeout.Attempts) != 2 {
+		t.Errorf("timeout = %+v, want both providers after 50ms", timeout)
+	}
+	for _, attempt := range timeout.Attempts {
+		if attempt.Outcome != address.OUTCOME_PENDING {
+			t.Errorf("%s attempt = %s, want pending when the timeout fired", attempt.Provider, attempt.Outcome)
+		}
+	}
+	if got.report.Winner != "" {
+		t.Errorf("winner = %q, want none", got.report.Winner)
+	}
+
+	// Both providers are cancelled once the lookup gave up on them.
+	for range 2 {
+		select {
+		case err := <-exits:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("provider stopped with %v, want context.Canceled", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a provider was never cancelled")
+		}
+	}
+}
+
+func TestZeroProviders(t *testing.T) {
+	idle := addresstest.NewMockProvider("Idle").Returns(sé)
+	service := newService(t, idle)
+	if err := service.SetProviders(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrNoProviders) {
+		t.Fatalf("err = %v, want ErrNoProviders", err)
+	}
+	if _, err := service.ExecuteAll("01001000"); !errors.Is(err, address.ErrNoProviders) {
+		t.Fatalf("ExecuteAll err = %v, want ErrNoProviders", err)
+	}
+	idle.AssertCalls(t, 0)
+}
+
+// Both providers answer at the same instant. One wins, and the loser's
+// answer, which nobody reads any more, must not leave it blocked.
+func TestNearSimultaneousAnswers(t *testing.T) {
+	for run := 0; run < 50; run++ {
+		clock := addresstest.NewFakeClock(time.Now())
+		a := addresstest.NewMockProvider("A").Returns(sé).SetLatency(10 * time.Millisecond).SetClock(clock)
+		b := addresstest.NewMockProvider("B").Returns(sé).SetLatency(10 * time.Millisecond).SetClock(clock)
+		settled := make(settledReports, 1)
+		service := newService(t, a, b).SetClock(clock).SetObserver(settled)
+
+		done := startLookup(service, "01001000")
+		clock.BlockUntilTimers(3)
+		clock.Advance(10 * time.Millisecond)
+		got := <-done
+
+		if got.err != nil {
+			t.Fatalf("run %d: lookup failed: %v", run, got.err)
+		}
+		if got.result.Source != got.report.Winner || (got.report.Winner != "A" && got.report.Winner != "B") {
+			t.Fatalf("run %d: result from %q, winner %q", run, got.result.Source, got.report.Winner)
+		}
+
+		won := 0
+		for _, attempt := range settled.next(t).Attempts {
+			switch attempt.Outcome {
+			case address.OUTCOME_WON:
+				won++
+			case address.OUTCOME_LOST, address.OUTCOME_CANCELLED:
+			default:
+				t.Errorf("run %d: %s attempt = %s, want won, lost or cancelled", run, attempt.Provider, attempt.Outcome)
+			}
+		}
+		if won != 1 {
+			t.Fatalf("run %d: %d winners, want 1", run, won)
+		}
+		a.AssertCalls(t, 1)
+		b.AssertCalls(t, 1)
+	}
+}
+
+func TestManyProvidersWithRetriesNeverBlock(t *testing.T) {
+	checkNoLeaks(t)
+
+	const timeout = 20 * time.Millisecond
+	unavailable := errors.New("503 Service Unavailable")
+
+	// Twenty providers, each failing its first attempts, some answering
+	// within the timeout and some not; the last one ignores its context
+	// and only returns well after the deadline.
+	var providers []address.Provider
+	for i := range 19 {
+		provider := addresstest.NewMockProvider(fmt.Sprintf("Mock%02d", i)).Returns(sé).
+			SetLatency(time.Duration(1+i) * 2 * time.Millisecond)
+		for range 100 {
+			provider.Script(addresstest.Fail(unavailable), addresstest.Fail(unavailable), addresstest.Answer(sé))
+		}
+		providers = append(providers, provider)
+	}
+	providers = append(providers, address.NewProvider("Deaf", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		time.Sleep(3 * timeout)
+		return sé, nil
+	}))
+
+	settled := make(settledReports, 1)
+	service := newService(t, providers...).SetTimeout(timeout).SetRetries(3, time.Millisecond).SetObserver(settled)
+
+	for run := range 100 {
+		start := time.Now()
+		_, err := service.Execute("01001000")
+		if err != nil && !errors.Is(err, address.ErrTimeout) {
+			t.Fatalf("run %d: err = %v, want a result or ErrTimeout", run, err)
+		}
+		if elapsed := time.Since(start); elapsed > timeout+50*time.Millisecond {
+			t.Errorf("run %d: Execute took %s with a %s timeout", run, elapsed, timeout)
+		}
+
+		// The lookup settles once its timeout passed, even though Deaf is
+		// still running: nothing waits for its result.
+		if report := settled.next(t); len(report.Attempts) == 0 {
+			t.Fatalf("run %d: report without attempts", run)
+		}
+	}
+
+	// Every late result was dropped rather than left blocking on a send.
+	checkNoLeaks(t)
+}
@@ -0,0 +1,96 @@
+package address
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// SetRanking sets how ExecuteRanked orders results: less reports whether a
+// ranks before b. Results it ranks equally keep the order of the enabled
+// providers. nil restores the default, which ranks the results with more
+// fields filled in first, then the faster providers.
+func (s *AddressService) SetRanking(less func(a, b AddressResult) bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ranking = less
+	return s
+}
+
+// ExecuteRanked queries every enabled provider, like ExecuteAll, and returns
+// the successful answers best first, as ranked by SetRanking. It fails like
+// Execute when no provider answers.
+func (s *AddressService) ExecuteRanked(ctx context.Context, cep string) ([]AddressResult, error) {
+	config := s.settings()
+
+	results, err := s.executeAll(ctx, config, cep)
+	if err != nil {
+		return nil, err
+	}
+
+	type ranked struct {
+		ProviderResult
+		priority int
+	}
+
+	var answers []ranked
+	var errs []error
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+			continue
+		}
+		answers = append(answers, ranked{result, i})
+	}
+
+	if len(answers) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, joinProviderErrors(errs)
+	}
+
+	slices.SortFunc(answers, func(a, b ranked) int {
+		if config.ranking != nil {
+			switch {
+			case config.ranking(a.Address, b.Address):
+				return -1
+			case config.ranking(b.Address, a.Address):
+				return 1
+			}
+		} else {
+			if c := cmp.Compare(completeness(b.Address), completeness(a.Address)); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(a.Latency, b.Latency); c != 0 {
+				return c
+			}
+		}
+
+		return cmp.Compare(a.priority, b.priority)
+	})
+
+	addresses := make([]AddressResult, len(answers))
+	for i, answer := range answers {
+		addresses[i] = answer.Address
+	}
+
+	return addresses, nil
+}
+
+// completeness counts the fields of result that are filled in.
+func completeness(result AddressResult) int {
+	n := 0
+	for _, field := range []string{result.State, result.City, result.Street, result.Neighborhood, result.ZipCode} {
+		if field != "" {
+			n++
+		}
+	}
+
+	if result.Location != nil {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,116 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func sources(results []address.AddressResult) []string {
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Source
+	}
+	return names
+}
+
+// rankedService races three providers: Full is complete but slowest,
+// Partial is fastest but has no street, FullFast is complete and in
+// between.
+func rankedService(t *testing.T) *address.AddressService {
+	partial := sé
+	partial.Street = ""
+
+	return newService(t,
+		addresstest.NewMockProvider("Full").Returns(sé).SetLatency(80*time.Millisecond),
+		addresstest.NewMockProvider("Partial").Returns(partial),
+		addresstest.NewMockProvider("FullFast").Returns(sé).SetLatency(40*time.Millisecond),
+	)
+}
+
+func TestExecuteRankedDefault(t *testing.T) {
+	service := rankedService(t)
+
+	results, err := service.ExecuteRanked(context.Background(), "01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sources(results), []string{"FullFast", "Full", "Partial"}; !equalStrings(got, want) {
+		t.Errorf("ranked %v, want %v", got, want)
+	}
+}
+
+func TestExecuteRankedCustom(t *testing.T) {
+	// Ranks the results without a street first, and ties everything else.
+	service := rankedService(t).SetRanking(func(a, b address.AddressResult) bool {
+		return a.Street == "" && b.Street != ""
+	})
+
+	for run := 0; run < 5; run++ {
+		results, err := service.ExecuteRanked(context.Background(), "01001000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Ties keep the order of the enabled providers, whatever the
+		// latencies.
+		if got, want := sources(results), []string{"Partial", "Full", "FullFast"}; !equalStrings(got, want) {
+			t.Fatalf("run %d: ranked %v, want %v", run, got, want)
+		}
+	}
+
+	service.SetRanking(nil)
+	results, err := service.ExecuteRanked(context.Background(), "01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sources(results), []string{"FullFast", "Full", "Partial"}; !equalStrings(got, want) {
+		t.Errorf("ranked %v after SetRanking(nil), want the default %v", got, want)
+	}
+}
+
+func TestExecuteRankedLeavesFailuresOut(t *testing.T) {
+	down := errors.New("503 from upstream")
+	service := newService(t,
+		addresstest.NewMockProvider("Down").Fails(down),
+		addresstest.NewMockProvider("Up").Returns(sé),
+	)
+
+	results, err := service.ExecuteRanked(context.Background(), "01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sources(results); !equalStrings(got, []string{"Up"}) {
+		t.Errorf("ranked %v, want only Up", got)
+	}
+
+	service = newService(t, addresstest.NewMockProvider("Down").Fails(down))
+	if _, err := service.ExecuteRanked(context.Background(), "01001000"); !errors.Is(err, address.ErrAllProvidersFailed) || !errors.Is(err, down) {
+		t.Errorf("err = %v, want ErrAllProvidersFailed with the provider's error", err)
+	}
+}
+
+func TestExecuteRankedHonoursTheContext(t *testing.T) {
+	service := newService(t, addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := service.ExecuteRanked(ctx, "01001000"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the caller's deadline", err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package address
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// rawPayload is what a provider response looked like before decoding.
+type rawPayload struct {
+	body   []byte
+	header http.Header
+}
+
+// SetRawPayload makes the results of the built-in providers keep the body,
+// up to MAX_RESPONSE_BYTES and decompressed, and the headers of the response
+// they were decoded from, in Raw and Header. It is off by default since
+// every result then holds its whole response.
+func (s *AddressService) SetRawPayload(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.raw = enabled
+	return s
+}
+
+// MarshalWithRaw marshals result like json.Marshal, with its Raw and Header
+// included as "raw", base64-encoded, and "header".
+func MarshalWithRaw(result AddressResult) ([]byte, error) {
+	type plain AddressResult
+
+	return json.Marshal(struct {
+		plain
+		Raw    []byte      `json:"raw,omitempty"`
+		Header http.Header `json:"header,omitempty"`
+	}{plain(result), result.Raw, result.Header})
+}
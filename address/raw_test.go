@@ -0,0 +1,122 @@
+package address_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// fixtureServer answers every request with the payload file, byte for byte,
+// and counts the requests.
+func fixtureServer(t *testing.T, path string) (*httptest.Server, []byte, *atomic.Int32) {
+	t.Helper()
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Fixture", path)
+		w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, payload, requests
+}
+
+func TestRawPayloadIsTheDecodedBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		provider func(baseURL string) address.Provider
+		path     string
+	}{
+		{name: "ViaCEP", payload: "testdata/payloads/viacep_big_city.json", provider: address.NewViaCEPProvider, path: "/ws"},
+		{name: "BrasilAPI", payload: "testdata/payloads/brasilapi_big_city.json", provider: address.NewBrasilAPIProvider, path: "/api/cep/v1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, payload, requests := fixtureServer(t, test.payload)
+			service := newService(t, test.provider(server.URL+test.path)).SetRawPayload(true)
+
+			result, err := service.Execute("01310100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(result.Raw, payload) {
+				t.Errorf("Raw = %q, want the fixture %q", result.Raw, payload)
+			}
+			if got := result.Header.Get("X-Fixture"); got != test.payload {
+				t.Errorf("Header X-Fixture = %q, want %q", got, test.payload)
+			}
+			if result.Street != "Avenida Paulista" {
+				t.Errorf("result = %+v, want the fixture decoded", result)
+			}
+			// The bytes are kept while decoding, not fetched again.
+			if n := requests.Load(); n != 1 {
+				t.Errorf("%d requests, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRawPayloadIsOffByDefault(t *testing.T) {
+	server, _, _ := fixtureServer(t, "testdata/payloads/viacep_big_city.json")
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+
+	result, err := service.Execute("01310100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Raw != nil || result.Header != nil {
+		t.Errorf("Raw = %q, Header = %v, want neither kept", result.Raw, result.Header)
+	}
+}
+
+func TestRawPayloadMarshaling(t *testing.T) {
+	result := address.AddressResult{
+		ZipCode: "01001000",
+		City:    "São Paulo",
+		Raw:     []byte(`{"cep": "01001-000"}`),
+		Header:  http.Header{"Content-Type": {"application/json"}},
+	}
+
+	plain, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "raw") || strings.Contains(string(plain), "header") {
+		t.Errorf("json.Marshal = %s, want Raw and Header left out", plain)
+	}
+
+	withRaw, err := address.MarshalWithRaw(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		ZipCode string      `json:"cep"`
+		Raw     []byte      `json:"raw"`
+		Header  http.Header `json:"header"`
+	}
+	if err := json.Unmarshal(withRaw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Raw, result.Raw) || decoded.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("MarshalWithRaw = %s, want the raw body and headers", withRaw)
+	}
+	if decoded.ZipCode != "01001000" {
+		t.Errorf("MarshalWithRaw = %s, want the address fields too", withRaw)
+	}
+}
@@ -0,0 +1,182 @@
+package address
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// cepPattern matches CEPs written with or without the dash, keeping the
+// first five digits in a group.
+var cepPattern = regexp.MustCompile(`\b(\d{5})-?\d{3}\b`)
+
+// REDACTED_STREET replaces street attributes in redacted logs.
+const REDACTED_STREET = "REDACTED"
+
+// MaskCEPs masks every CEP in s down to its first five digits, so
+// "01001-000" becomes "01001***".
+func MaskCEPs(s string) string {
+	return cepPattern.ReplaceAllString(s, "${1}***")
+}
+
+// SetLogRedaction makes the service mask CEPs, as MaskCEPs does, and drop
+// street names from everything it logs, including the errors it logs, and
+// from what the Observer is given. The results and errors returned to the
+// caller are left untouched. It is off by default.
+func (s *AddressService) SetLogRedaction(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.redact = enabled
+	return s
+}
+
+// logOutput returns the logger lookups write to. s.mu must be held.
+func (s *AddressService) logOutput() *slog.Logger {
+	if s.redact {
+		return RedactLogger(s.logger)
+	}
+
+	return s.logger
+}
+
+// RedactLogger wraps logger so that every message and attribute written
+// through it has its CEPs masked and its street attributes replaced, the way
+// SetLogRedaction does for the service's own logs. Use it for the logger
+// given to LoggingMiddleware, whose URLs carry the CEP.
+func RedactLogger(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(redactingHandler); ok {
+		return logger
+	}
+
+	return slog.New(redactingHandler{next: logger.Handler()})
+}
+
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, MaskCEPs(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+
+	return redactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if attr.Key == "street" {
+		return slog.String(attr.Key, REDACTED_STREET)
+	}
+
+	attr.Value = redactValue(attr.Value.Resolve())
+	return attr
+}
+
+func redactValue(value slog.Value) slog.Value {
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.StringValue(MaskCEPs(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = redactAttr(attr)
+		}
+		return slog.GroupValue(redacted...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case AddressResult:
+			return slog.AnyValue(redactResult(v))
+		case *AddressResult:
+			if v != nil {
+				return slog.AnyValue(redactResult(*v))
+			}
+		case error:
+			return slog.StringValue(MaskCEPs(v.Error()))
+		default:
+			text := fmt.Sprint(v)
+			if masked := MaskCEPs(text); masked != text {
+				return slog.StringValue(masked)
+			}
+		}
+	}
+
+	return value
+}
+
+func redactResult(result AddressResult) AddressResult {
+	result.ZipCode = MaskCEPs(result.ZipCode)
+	if result.Street != "" {
+		result.Street = REDACTED_STREET
+	}
+	result.Raw, result.Header, result.Conflicts = nil, nil, nil
+	return result
+}
+
+// redactReport returns a copy of report with its CEP masked and the errors
+// of its attempts wrapped to print masked.
+func redactReport(report *Report) *Report {
+	if report == nil {
+		return nil
+	}
+
+	redacted := *report
+	redacted.CEP = MaskCEPs(report.CEP)
+	redacted.Attempts = make([]Attempt, len(report.Attempts))
+	for i, attempt := range report.Attempts {
+		attempt.Err = redactError(attempt.Err)
+		if attempt.Redirects != nil {
+			redirects := make([]string, len(attempt.Redirects))
+			for j, redirect := range attempt.Redirects {
+				redirects[j] = MaskCEPs(redirect)
+			}
+			attempt.Redirects = redirects
+		}
+		redacted.Attempts[i] = attempt
+	}
+
+	return &redacted
+}
+
+// redactError wraps err so that its message is masked while errors.Is and
+// errors.As still see it.
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return redactedError{err: err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return MaskCEPs(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,91 @@
+package address_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestMaskCEPs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"01001-000", "01001***"},
+		{"01001000", "01001***"},
+		{"GET https://viacep.com.br/ws/01001000/json/", "GET https://viacep.com.br/ws/01001***/json/"},
+		{"01001-000 and 20040010", "01001*** and 20040***"},
+		{"port 12345", "port 12345"},
+		{"010010001", "010010001"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := address.MaskCEPs(test.s); got != test.want {
+			t.Errorf("MaskCEPs(%q) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestRedactLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := address.RedactLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	if address.RedactLogger(logger) != logger {
+		t.Error("RedactLogger wrapped an already redacting logger again")
+	}
+
+	logger.With("cep", "01001-000").Info("looked up 01001000",
+		"street", "Praça da Sé",
+		"result", sé,
+		"error", fmt.Errorf("ViaCEP: no answer for 01001000"),
+		slog.Group("request", "url", "https://viacep.com.br/ws/01001000/json/"),
+	)
+
+	got := logs.String()
+	for _, leaked := range []string{"01001-000", "01001000", "Praça da Sé"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("log leaks %q:\n%s", leaked, got)
+		}
+	}
+	for _, kept := range []string{"cep=01001***", "looked up 01001***", "street=REDACTED", "request.url=https://viacep.com.br/ws/01001***/json/", "São Paulo"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("log lacks %q:\n%s", kept, got)
+		}
+	}
+}
+
+func TestSetLogRedaction(t *testing.T) {
+	down := fmt.Errorf("no answer for 01001000")
+	reports := make(settledReports, 1)
+
+	var logs bytes.Buffer
+	service := newService(t, addresstest.NewMockProvider("Down").Fails(down)).
+		SetLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		SetObserver(reports).
+		SetLogRedaction(true)
+
+	_, err := service.Execute("01001000")
+	if !errors.Is(err, down) || !strings.Contains(err.Error(), "01001000") {
+		t.Errorf("err = %v, want the provider's error unmasked", err)
+	}
+
+	report := reports.next(t)
+	if report.CEP != "01001***" {
+		t.Errorf("observed CEP %q, want it masked", report.CEP)
+	}
+	for _, attempt := range report.Attempts {
+		if !errors.Is(attempt.Err, down) || strings.Contains(attempt.Err.Error(), "01001000") {
+			t.Errorf("observed attempt error %v, want the provider's error masked", attempt.Err)
+		}
+	}
+
+	if strings.Contains(logs.String(), "01001000") || !strings.Contains(logs.String(), "01001***") {
+		t.Errorf("logs are not masked:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,71 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DEFAULT_MAX_REDIRECTS is how many redirects a provider request follows
+// unless SetRedirectPolicy says otherwise.
+const DEFAULT_MAX_REDIRECTS = 2
+
+var (
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrCrossHostRedirect = errors.New("cross-host redirect refused")
+)
+
+// RedirectError is the error of a provider request redirected to another
+// host while SetRedirectPolicy refuses that. It wraps ErrCrossHostRedirect.
+type RedirectError struct {
+	From string
+	To   string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("%v: %s to %s", ErrCrossHostRedirect, e.From, e.To)
+}
+
+func (e *RedirectError) Unwrap() error {
+	return ErrCrossHostRedirect
+}
+
+type redirectPolicy struct {
+	max       int
+	crossHost bool
+}
+
+// SetRedirectPolicy sets how many redirects a provider request follows,
+// DEFAULT_MAX_REDIRECTS by default, and whether it follows them to another
+// host, which it refuses by default with a *RedirectError. Going from http
+// to https on the same host is not a change of host. A request redirected
+// once more than allowed fails with ErrTooManyRedirects; neither error is
+// retried. The redirects followed show up in the attempt's Redirects. A
+// client from SetHTTPClient or SetProviderClient that has its own
+// CheckRedirect keeps it.
+func (s *AddressService) SetRedirectPolicy(maxRedirects int, followCrossHost bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.redirects = redirectPolicy{max: max(maxRedirects, 0), crossHost: followCrossHost}
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// check implements http.Client.CheckRedirect.
+func (p redirectPolicy) check(request *http.Request, via []*http.Request) error {
+	from := via[0].URL
+	if !p.crossHost && !strings.EqualFold(request.URL.Hostname(), from.Hostname()) {
+		return &RedirectError{From: from.Host, To: request.URL.Host}
+	}
+
+	if len(via) > p.max {
+		return fmt.Errorf("%w: more than %d", ErrTooManyRedirects, p.max)
+	}
+
+	if recorded, ok := attemptFromContext(request.Context()); ok {
+		recorded.addRedirect(request.URL.Redacted())
+	}
+	return nil
+}
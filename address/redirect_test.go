@@ -0,0 +1,103 @@
+package address_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// redirecting serves a ViaCEP answer for sé after redirecting every request
+// hops times, through ?hop=1, ?hop=2 and so on, to the host target returns,
+// or its own when target is nil. It counts the requests it receives.
+func redirecting(t *testing.T, hops int, target func(server *httptest.Server) string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+		if hop < hops {
+			host := server.URL
+			if target != nil {
+				host = target(server)
+			}
+			http.Redirect(w, r, fmt.Sprintf("%s%s?hop=%d", host, r.URL.Path, hop+1), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cep": "01001-000", "logradouro": "Praça da Sé", "localidade": "São Paulo", "uf": "SP"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+// asLocalhost names the server's host localhost instead of 127.0.0.1, which
+// is another host to the redirect policy.
+func asLocalhost(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func TestRedirectPolicyDefault(t *testing.T) {
+	server, _ := redirecting(t, address.DEFAULT_MAX_REDIRECTS, nil)
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+
+	result, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Street != "Praça da Sé" {
+		t.Errorf("street = %q, want the answer after the redirects", result.Street)
+	}
+	redirects := attemptOf(t, report, "ViaCEP").Redirects
+	if len(redirects) != 2 || !strings.HasSuffix(redirects[1], "?hop=2") {
+		t.Errorf("redirects = %v, want both hops in order", redirects)
+	}
+
+	server, requests := redirecting(t, address.DEFAULT_MAX_REDIRECTS+1, nil)
+	service = newService(t, address.NewViaCEPProvider(server.URL+"/ws")).SetRetries(2, time.Millisecond)
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrTooManyRedirects) {
+		t.Errorf("err = %v, want ErrTooManyRedirects", err)
+	}
+	if n := requests.Load(); n != address.DEFAULT_MAX_REDIRECTS+1 {
+		t.Errorf("server got %d requests, want one attempt and no retry", n)
+	}
+}
+
+func TestRedirectPolicyCrossHost(t *testing.T) {
+	server, requests := redirecting(t, 1, asLocalhost)
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws")).SetRetries(2, time.Millisecond)
+
+	_, err := service.Execute("01001000")
+	var redirect *address.RedirectError
+	if !errors.As(err, &redirect) || !errors.Is(err, address.ErrCrossHostRedirect) {
+		t.Fatalf("err = %v, want a *RedirectError", err)
+	}
+	if !strings.HasPrefix(redirect.From, "127.0.0.1:") || !strings.HasPrefix(redirect.To, "localhost:") {
+		t.Errorf("RedirectError = %+v, want from 127.0.0.1 to localhost", redirect)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server got %d requests, want the refusal not to be retried", n)
+	}
+
+	service.SetRedirectPolicy(address.DEFAULT_MAX_REDIRECTS, true)
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Errorf("err = %v with cross-host redirects allowed", err)
+	}
+}
+
+func TestRedirectPolicyNone(t *testing.T) {
+	server, _ := redirecting(t, 1, nil)
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws")).SetRedirectPolicy(0, false)
+
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrTooManyRedirects) {
+		t.Errorf("err = %v, want ErrTooManyRedirects with no redirect allowed", err)
+	}
+}
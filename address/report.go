@@ -0,0 +1,242 @@
+package address
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	OUTCOME_WON       = "won"
+	OUTCOME_LOST      = "lost"
+	OUTCOME_FAILED    = "failed"
+	OUTCOME_CANCELLED = "cancelled"
+	OUTCOME_PENDING   = "pending"
+)
+
+type TraceTimings struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration
+	Reused    bool
+}
+
+type Attempt struct {
+	Provider   string
+	Number     int
+	Client     string
+	Start      time.Duration
+	Duration   time.Duration
+	StatusCode int
+	Protocol   string
+	Outcome    string
+	Err        error
+	Trace      *TraceTimings
+	// Header holds the headers set with SetProviderHeaders, credentials
+	// redacted.
+	Header http.Header
+	// Redirects lists the URLs the request was redirected to, in order.
+	Redirects []string
+}
+
+// Report describes what every provider did during a single lookup, as seen
+// at the moment Execute returned. Cached lookups have no attempts.
+type Report struct {
+	CEP      string
+	Duration time.Duration
+	Winner   string
+	Cached   bool
+	Attempts []Attempt
+	// CacheMiss is set when the cache was asked first and had no answer
+	// the lookup could use.
+	CacheMiss bool
+	// RateLimitWait is how long a streamed or batch lookup waited for
+	// SetRateLimit before it started.
+	RateLimitWait time.Duration
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	clock    Clock
+	start    time.Time
+	trace    bool
+	raw      bool
+	noSchema bool
+	missed   bool
+	attempts []*Attempt
+	payloads map[*Attempt]rawPayload
+}
+
+type attemptKey struct{}
+
+type recordedAttempt struct {
+	recorder *recorder
+	attempt  *Attempt
+}
+
+func newRecorder(clock Clock, trace bool, raw bool, noSchema bool) *recorder {
+	return &recorder{clock: clock, start: clock.Now(), trace: trace, raw: raw, noSchema: noSchema}
+}
+
+func (r *recorder) elapsed() time.Duration {
+	return r.clock.Now().Sub(r.start)
+}
+
+func (r *recorder) begin(ctx context.Context, provider string, number int, client string) (context.Context, *Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt := &Attempt{
+		Provider: provider,
+		Number:   number,
+		Client:   client,
+		Start:    r.elapsed(),
+		Outcome:  OUTCOME_PENDING,
+	}
+	r.attempts = append(r.attempts, attempt)
+
+	return context.WithValue(ctx, attemptKey{}, recordedAttempt{recorder: r, attempt: attempt}), attempt
+}
+
+func (r *recorder) finish(attempt *Attempt, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt.Duration = r.elapsed() - attempt.Start
+	attempt.Err = err
+	switch {
+	case errors.Is(err, context.Canceled):
+		attempt.Outcome = OUTCOME_CANCELLED
+	case err != nil:
+		attempt.Outcome = OUTCOME_FAILED
+	default:
+		attempt.Outcome = OUTCOME_LOST
+	}
+}
+
+func (r *recorder) snapshot(cep string, winner *Attempt) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		CEP:       cep,
+		Duration:  r.elapsed(),
+		Attempts:  make([]Attempt, len(r.attempts)),
+		CacheMiss: r.missed,
+	}
+
+	for i, attempt := range r.attempts {
+		report.Attempts[i] = *attempt
+
+		switch {
+		case attempt == winner:
+			report.Attempts[i].Outcome = OUTCOME_WON
+			report.Winner = attempt.Provider
+		case attempt.Outcome == OUTCOME_PENDING && winner != nil:
+			report.Attempts[i].Outcome = OUTCOME_CANCELLED
+		}
+
+		if attempt.Trace != nil {
+			trace := *attempt.Trace
+			report.Attempts[i].Trace = &trace
+		}
+		report.Attempts[i].Redirects = slices.Clone(attempt.Redirects)
+	}
+
+	return report
+}
+
+func attemptFromContext(ctx context.Context) (recordedAttempt, bool) {
+	recorded, ok := ctx.Value(attemptKey{}).(recordedAttempt)
+	return recorded, ok
+}
+
+func (a recordedAttempt) setResponse(response *http.Response) {
+	a.recorder.mu.Lock()
+	defer a.recorder.mu.Unlock()
+
+	a.attempt.StatusCode = response.StatusCode
+	a.attempt.Protocol = response.Proto
+}
+
+func (a recordedAttempt) setHeader(header http.Header) {
+	a.recorder.mu.Lock()
+	defer a.recorder.mu.Unlock()
+
+	a.attempt.Header = header
+}
+
+func (a recordedAttempt) addRedirect(url string) {
+	a.recorder.mu.Lock()
+	defer a.recorder.mu.Unlock()
+
+	a.attempt.Redirects = append(a.attempt.Redirects, url)
+}
+
+// setPayload keeps the body and headers an attempt decoded, see
+// SetRawPayload.
+func (a recordedAttempt) setPayload(body []byte, header http.Header) {
+	a.recorder.mu.Lock()
+	defer a.recorder.mu.Unlock()
+
+	if a.recorder.payloads == nil {
+		a.recorder.payloads = make(map[*Attempt]rawPayload)
+	}
+	a.recorder.payloads[a.attempt] = rawPayload{body: body, header: header}
+}
+
+func (r *recorder) payload(attempt *Attempt) (rawPayload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payload, ok := r.payloads[attempt]
+	return payload, ok
+}
+
+// withTrace attaches an httptrace.ClientTrace that records connection phase
+// timings on the attempt when tracing is enabled.
+func (a recordedAttempt) withTrace(ctx context.Context) context.Context {
+	if !a.recorder.trace {
+		return ctx
+	}
+
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+
+	update := func(fn func(trace *TraceTimings)) {
+		a.recorder.mu.Lock()
+		defer a.recorder.mu.Unlock()
+
+		if a.attempt.Trace == nil {
+			a.attempt.Trace = &TraceTimings{}
+		}
+		fn(a.attempt.Trace)
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			update(func(trace *TraceTimings) { trace.DNS = time.Since(dnsStart) })
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			update(func(trace *TraceTimings) { trace.Connect = time.Since(connectStart) })
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			update(func(trace *TraceTimings) { trace.TLS = time.Since(tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			update(func(trace *TraceTimings) { trace.Reused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			update(func(trace *TraceTimings) { trace.FirstByte = time.Since(start) })
+		},
+	})
+}
@@ -0,0 +1,128 @@
+package address_test
+
+import (
+	"errors"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// faultyViaCEP returns a service querying a fake ViaCEP that knows sé,
+// through faults.
+func faultyViaCEP(t *testing.T, faults *addresstest.FaultTransport) (*address.AddressService, *addresstest.Server) {
+	t.Helper()
+
+	server := addresstest.NewViaCEPServer(map[string]address.AddressResult{sé.ZipCode: sé})
+	t.Cleanup(server.Close)
+
+	service := newService(t, address.NewViaCEPProvider(server.BaseURL())).SetTransport(faults)
+	return service, server
+}
+
+func TestRetryRecoversFromTransientFaults(t *testing.T) {
+	faults := addresstest.NewFaultTransport(nil).On("/ws/",
+		addresstest.Status(http.StatusServiceUnavailable, ""),
+		addresstest.NetworkError(),
+		addresstest.CutAfter(`{"cep": "01001-000", "logradouro": "Praça da Sé"}`, 10),
+		addresstest.MalformedJSON(),
+	)
+	service, server := faultyViaCEP(t, faults)
+	service.SetRetries(4, time.Millisecond)
+
+	result, report, err := service.ExecuteWithReport("01001000")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if result.Street != sé.Street {
+		t.Errorf("result = %+v", result)
+	}
+	if len(report.Attempts) != 5 {
+		t.Fatalf("%d attempts, want 5: %+v", len(report.Attempts), report.Attempts)
+	}
+	for i, attempt := range report.Attempts[:4] {
+		if attempt.Number != i+1 || attempt.Outcome != address.OUTCOME_FAILED {
+			t.Errorf("attempt %d = #%d %s, want failed", i+1, attempt.Number, attempt.Outcome)
+		}
+	}
+	if last := report.Attempts[4]; last.Number != 5 || last.Outcome != address.OUTCOME_WON {
+		t.Errorf("last attempt = #%d %s, want #5 won", last.Number, last.Outcome)
+	}
+	if n := server.Requests(); n != 1 {
+		t.Errorf("server got %d requests, want only the last attempt", n)
+	}
+}
+
+func TestRetriesRunOut(t *testing.T) {
+	unavailable := addresstest.Status(http.StatusServiceUnavailable, "")
+	faults := addresstest.NewFaultTransport(nil).On("/ws/", unavailable, unavailable, unavailable, unavailable)
+	service, server := faultyViaCEP(t, faults)
+	service.SetRetries(2, time.Millisecond)
+
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrAllProvidersFailed) {
+		t.Fatalf("err = %v, want ErrAllProvidersFailed", err)
+	}
+	if n := faults.Requests("/ws/"); n != 3 {
+		t.Errorf("%d requests, want the first and 2 retries", n)
+	}
+	if n := server.Requests(); n != 0 {
+		t.Errorf("server got %d requests, want none", n)
+	}
+}
+
+func TestNoRetriesByDefault(t *testing.T) {
+	faults := addresstest.NewFaultTransport(nil).On("/ws/", addresstest.NetworkError())
+	service, _ := faultyViaCEP(t, faults)
+
+	if _, err := service.Execute("01001000"); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("err = %v, want the refused connection", err)
+	}
+	if n := faults.Requests("/ws/"); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestNotFoundIsNotRetried(t *testing.T) {
+	faults := addresstest.NewFaultTransport(nil).On("/ws/")
+	service, server := faultyViaCEP(t, faults)
+	service.SetRetries(3, time.Millisecond)
+
+	if _, err := service.Execute("20040010"); !errors.Is(err, address.ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if n := server.Requests(); n != 1 {
+		t.Errorf("server got %d requests, want 1", n)
+	}
+}
+
+func TestRetryBackoffDoubles(t *testing.T) {
+	clock := addresstest.NewFakeClock(time.Now())
+	unavailable := addresstest.Status(http.StatusServiceUnavailable, "")
+	faults := addresstest.NewFaultTransport(nil).On("/ws/", unavailable, unavailable)
+	service, _ := faultyViaCEP(t, faults)
+	service.SetClock(clock).SetTimeout(time.Hour).SetRetries(2, 100*time.Millisecond)
+
+	done := startLookup(service, "01001000")
+	// The lookup's timeout and the first backoff.
+	clock.BlockUntilTimers(2)
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimers(2)
+	if n := faults.Requests("/ws/"); n != 2 {
+		t.Fatalf("%d requests after the first backoff, want 2", n)
+	}
+	// The second backoff is twice as long.
+	clock.Advance(100 * time.Millisecond)
+	clock.BlockUntilTimers(2)
+	if n := faults.Requests("/ws/"); n != 2 {
+		t.Fatalf("%d requests 100ms into the second backoff, want 2", n)
+	}
+	clock.Advance(100 * time.Millisecond)
+
+	got := <-done
+	if got.err != nil || len(got.report.Attempts) != 3 {
+		t.Fatalf("err = %v, attempts %+v; want a win on the third", got.err, got.report.Attempts)
+	}
+}
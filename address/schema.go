@@ -0,0 +1,169 @@
+package address
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrSchemaDrift = errors.New("provider response schema drift")
+
+// SchemaDriftError is the error of a provider answer that no longer has the
+// shape the provider is decoded with, which usually means the provider
+// changed its API. It wraps ErrSchemaDrift, and neither ErrInvalidResponse
+// nor ErrNotFound, so it can be told apart from a garbled body.
+type SchemaDriftError struct {
+	Provider string
+	// MissingFields are the required keys absent from the answer.
+	MissingFields []string
+	// WrongTypes describe the keys whose value has another JSON type than
+	// expected, null included, as "key (got, want expected)".
+	WrongTypes []string
+}
+
+func (e *SchemaDriftError) Error() string {
+	var problems []string
+	if len(e.MissingFields) > 0 {
+		problems = append(problems, "missing "+strings.Join(e.MissingFields, ", "))
+	}
+	if len(e.WrongTypes) > 0 {
+		problems = append(problems, "wrong types "+strings.Join(e.WrongTypes, ", "))
+	}
+
+	return fmt.Sprintf("%v: %s", ErrSchemaDrift, strings.Join(problems, "; "))
+}
+
+func (e *SchemaDriftError) Unwrap() error {
+	return ErrSchemaDrift
+}
+
+// SetSchemaCheck controls whether the answers of the built-in providers are
+// checked against the shape they are expected to have, which is on by
+// default. Checking keeps a copy of each body while it is decoded; the body
+// is only parsed again when decoding failed on a type or left a field the
+// provider always sends empty, and a broken contract then fails with a
+// *SchemaDriftError rather than the decoder's error or an empty address.
+// Turning it off saves the copy for maximum throughput.
+func (s *AddressService) SetSchemaCheck(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schemaOff = !enabled
+	return s
+}
+
+// schemaField is a top-level key of a provider answer and the JSON type of
+// its value: "string", "number", "bool", "object" or "array".
+type schemaField struct {
+	name     string
+	kind     string
+	required bool
+	nullable bool
+}
+
+// responseSchema is the contract of the answers of provider. A schema
+// without fields checks nothing.
+type responseSchema struct {
+	provider string
+	fields   []schemaField
+}
+
+var viaCEPFields = []schemaField{
+	{name: "cep", kind: "string", required: true},
+	{name: "logradouro", kind: "string", required: true},
+	{name: "bairro", kind: "string", required: true},
+	{name: "localidade", kind: "string", required: true},
+	{name: "uf", kind: "string", required: true},
+}
+
+var brasilAPIFields = []schemaField{
+	{name: "cep", kind: "string", required: true},
+	{name: "state", kind: "string", required: true},
+	{name: "city", kind: "string", required: true},
+	{name: "neighborhood", kind: "string", required: true},
+	{name: "street", kind: "string", required: true},
+	{name: "location", kind: "object"},
+}
+
+// suspectAnswer is implemented by decoded answers that can tell when a field
+// the provider always fills came out empty, as it does when its key was
+// renamed or sent as null.
+type suspectAnswer interface {
+	incomplete() bool
+}
+
+// check returns a *SchemaDriftError when body breaks the schema, or nil
+// when it keeps to it or is not JSON at all.
+func (s responseSchema) check(body []byte) error {
+	var answer any
+	// The body may go on past the answer, which is all that is checked.
+	if err := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(body, utf8BOM))).Decode(&answer); err != nil {
+		return nil
+	}
+
+	drift := &SchemaDriftError{Provider: s.provider}
+	object, ok := answer.(map[string]any)
+	if !ok {
+		drift.WrongTypes = append(drift.WrongTypes, fmt.Sprintf("body (%s, want object)", jsonKind(answer)))
+		return drift
+	}
+
+	for _, field := range s.fields {
+		value, ok := object[field.name]
+		switch {
+		case !ok:
+			if field.required {
+				drift.MissingFields = append(drift.MissingFields, field.name)
+			}
+		case value == nil:
+			if !field.nullable {
+				drift.WrongTypes = append(drift.WrongTypes, fmt.Sprintf("%s (null, want %s)", field.name, field.kind))
+			}
+		case jsonKind(value) != field.kind:
+			drift.WrongTypes = append(drift.WrongTypes, fmt.Sprintf("%s (%s, want %s)", field.name, jsonKind(value), field.kind))
+		}
+	}
+
+	if len(drift.MissingFields) == 0 && len(drift.WrongTypes) == 0 {
+		return nil
+	}
+	return drift
+}
+
+// jsonKind names the JSON type of a value decoded into an any.
+func jsonKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// driftFrom returns the *SchemaDriftError of body, an answer that decoded
+// with decodeErr, or into v, when it breaks schema. Only a decode that
+// failed on a type or left v incomplete makes body be parsed again.
+func driftFrom(schema responseSchema, body []byte, v any, decodeErr error) error {
+	var typeErr *json.UnmarshalTypeError
+	if decodeErr != nil && !errors.As(decodeErr, &typeErr) {
+		return nil
+	}
+
+	if suspect, ok := v.(suspectAnswer); decodeErr == nil && (!ok || !suspect.incomplete()) {
+		return nil
+	}
+
+	return schema.check(body)
+}
@@ -0,0 +1,95 @@
+package address_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// answering returns a ViaCEP provider whose server answers body.
+func answering(t *testing.T, body string) address.Provider {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return address.NewViaCEPProvider(server.URL + "/ws")
+}
+
+func TestSchemaDrift(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		missing []string
+		wrong   []string
+	}{
+		{
+			name:    "renamed field",
+			body:    `{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "cidade": "São Paulo", "uf": "SP"}`,
+			missing: []string{"localidade"},
+		},
+		{
+			name:  "wrong type",
+			body:  `{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "localidade": "São Paulo", "uf": 35}`,
+			wrong: []string{"uf (number, want string)"},
+		},
+		{
+			name:  "null",
+			body:  `{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "localidade": null, "uf": "SP"}`,
+			wrong: []string{"localidade (null, want string)"},
+		},
+		{
+			name:  "not an object",
+			body:  `["01001-000"]`,
+			wrong: []string{"body (array, want object)"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newService(t, answering(t, test.body)).Execute("01001000")
+
+			var drift *address.SchemaDriftError
+			if !errors.As(err, &drift) || !errors.Is(err, address.ErrSchemaDrift) {
+				t.Fatalf("err = %v, want a *SchemaDriftError", err)
+			}
+			if errors.Is(err, address.ErrInvalidResponse) || errors.Is(err, address.ErrNotFound) {
+				t.Errorf("err = %v, want drift told apart from a garbled or unknown CEP", err)
+			}
+			if drift.Provider != "ViaCEP" || !reflect.DeepEqual(drift.MissingFields, test.missing) || !reflect.DeepEqual(drift.WrongTypes, test.wrong) {
+				t.Errorf("drift = %+v, want missing %v and wrong types %v", drift, test.missing, test.wrong)
+			}
+		})
+	}
+}
+
+func TestSchemaCheckLeavesOtherAnswersAlone(t *testing.T) {
+	service := newService(t, answering(t, `{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "localidade": "São Paulo", "uf": "SP", "ddd": "11"}`))
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Errorf("answer with an extra field: %v, want it accepted", err)
+	}
+
+	service = newService(t, answering(t, `{"erro": "true"}`))
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrNotFound) || errors.Is(err, address.ErrSchemaDrift) {
+		t.Errorf("unknown CEP: %v, want ErrNotFound", err)
+	}
+
+	service = newService(t, answering(t, `{"cep": "01001-000", "logr`))
+	if _, err := service.Execute("01001000"); !errors.Is(err, address.ErrInvalidResponse) || errors.Is(err, address.ErrSchemaDrift) {
+		t.Errorf("truncated body: %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestSetSchemaCheckOff(t *testing.T) {
+	service := newService(t, answering(t, `{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "cidade": "São Paulo", "uf": "SP"}`)).
+		SetSchemaCheck(false)
+
+	if _, err := service.Execute("01001000"); errors.Is(err, address.ErrSchemaDrift) {
+		t.Errorf("err = %v with the schema check off", err)
+	}
+}
@@ -0,0 +1,76 @@
+package address
+
+import "strings"
+
+// STATES maps the UF of every Brazilian state, and of the Distrito Federal,
+// to its name.
+var STATES = map[string]string{
+	"AC": "Acre",
+	"AL": "Alagoas",
+	"AP": "Amapá",
+	"AM": "Amazonas",
+	"BA": "Bahia",
+	"CE": "Ceará",
+	"DF": "Distrito Federal",
+	"ES": "Espírito Santo",
+	"GO": "Goiás",
+	"MA": "Maranhão",
+	"MT": "Mato Grosso",
+	"MS": "Mato Grosso do Sul",
+	"MG": "Minas Gerais",
+	"PA": "Pará",
+	"PB": "Paraíba",
+	"PR": "Paraná",
+	"PE": "Pernambuco",
+	"PI": "Piauí",
+	"RJ": "Rio de Janeiro",
+	"RN": "Rio Grande do Norte",
+	"RS": "Rio Grande do Sul",
+	"RO": "Rondônia",
+	"RR": "Roraima",
+	"SC": "Santa Catarina",
+	"SP": "São Paulo",
+	"SE": "Sergipe",
+	"TO": "Tocantins",
+}
+
+// stateCodes maps the state names, normalized for comparison, to their UF.
+var stateCodes = func() map[string]string {
+	codes := make(map[string]string, len(STATES))
+	for code, name := range STATES {
+		codes[NormalizeForComparison(name)] = code
+	}
+	return codes
+}()
+
+// StateName returns the name of the state whose UF is uf, in any case.
+func StateName(uf string) (string, bool) {
+	name, ok := STATES[strings.ToUpper(strings.TrimSpace(uf))]
+	return name, ok
+}
+
+// StateCode returns the UF of the state named name, ignoring case, accents
+// and extra spaces, so "SAO PAULO" and "são  paulo" both give "SP".
+func StateCode(name string) (string, bool) {
+	code, ok := stateCodes[NormalizeForComparison(name)]
+	return code, ok
+}
+
+// normalizeState writes the state of result as its UF, when it was given as
+// a name, and fills StateName in. A state that is neither is kept as it is.
+func normalizeState(result AddressResult) AddressResult {
+	// Most providers already answer with the UF, which needs no
+	// normalizing.
+	if _, ok := StateName(result.State); !ok {
+		if code, ok := StateCode(result.State); ok {
+			result.State = code
+		}
+	}
+
+	if name, ok := StateName(result.State); ok {
+		result.State = strings.ToUpper(result.State)
+		result.StateName = name
+	}
+
+	return result
+}
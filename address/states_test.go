@@ -0,0 +1,122 @@
+package address_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestStates(t *testing.T) {
+	states := []struct{ uf, name string }{
+		{"AC", "Acre"},
+		{"AL", "Alagoas"},
+		{"AP", "Amapá"},
+		{"AM", "Amazonas"},
+		{"BA", "Bahia"},
+		{"CE", "Ceará"},
+		{"DF", "Distrito Federal"},
+		{"ES", "Espírito Santo"},
+		{"GO", "Goiás"},
+		{"MA", "Maranhão"},
+		{"MT", "Mato Grosso"},
+		{"MS", "Mato Grosso do Sul"},
+		{"MG", "Minas Gerais"},
+		{"PA", "Pará"},
+		{"PB", "Paraíba"},
+		{"PR", "Paraná"},
+		{"PE", "Pernambuco"},
+		{"PI", "Piauí"},
+		{"RJ", "Rio de Janeiro"},
+		{"RN", "Rio Grande do Norte"},
+		{"RS", "Rio Grande do Sul"},
+		{"RO", "Rondônia"},
+		{"RR", "Roraima"},
+		{"SC", "Santa Catarina"},
+		{"SP", "São Paulo"},
+		{"SE", "Sergipe"},
+		{"TO", "Tocantins"},
+	}
+	if len(address.STATES) != len(states) {
+		t.Errorf("STATES has %d entries, want %d", len(address.STATES), len(states))
+	}
+
+	for _, state := range states {
+		if name, ok := address.StateName(state.uf); !ok || name != state.name {
+			t.Errorf("StateName(%q) = %q, %t, want %q", state.uf, name, ok, state.name)
+		}
+		if code, ok := address.StateCode(state.name); !ok || code != state.uf {
+			t.Errorf("StateCode(%q) = %q, %t, want %q", state.name, code, ok, state.uf)
+		}
+	}
+}
+
+func TestStateCodeMatching(t *testing.T) {
+	tests := []struct {
+		name string
+		uf   string
+		ok   bool
+	}{
+		{"SAO PAULO", "SP", true},
+		{"são  paulo", "SP", true},
+		{" Espirito Santo ", "ES", true},
+		{"rio grande do norte", "RN", true},
+		{"Rio Grande do Sul", "RS", true},
+		{"Mato Grosso", "MT", true},
+		{"mato grosso do sul", "MS", true},
+		{"PARA", "PA", true},
+		{"paraiba", "PB", true},
+		{"Paraná", "PR", true},
+		{"Rio Grande", "", false},
+		{"SP", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		if uf, ok := address.StateCode(test.name); uf != test.uf || ok != test.ok {
+			t.Errorf("StateCode(%q) = %q, %t, want %q, %t", test.name, uf, ok, test.uf, test.ok)
+		}
+	}
+
+	for _, uf := range []string{"sp", " rj ", "Df"} {
+		if _, ok := address.StateName(uf); !ok {
+			t.Errorf("StateName(%q) not found", uf)
+		}
+	}
+	if name, ok := address.StateName("XX"); ok || name != "" {
+		t.Errorf("StateName(XX) = %q, %t, want nothing", name, ok)
+	}
+}
+
+// ViaCEP results are mapped whichever way the mirror wrote the state, and
+// states nobody knows pass through.
+func TestMappedStateNormalized(t *testing.T) {
+	tests := []struct {
+		uf        string
+		want      string
+		stateName string
+	}{
+		{uf: "SP", want: "SP", stateName: "São Paulo"},
+		{uf: "sp", want: "SP", stateName: "São Paulo"},
+		{uf: " SAO PAULO ", want: "SP", stateName: "São Paulo"},
+		{uf: "Província Cisplatina", want: "Província Cisplatina"},
+		{uf: "", want: ""},
+	}
+
+	for _, test := range tests {
+		body, err := json.Marshal(map[string]string{"cep": "01001-000", "logradouro": "Praça da Sé", "bairro": "Sé", "localidade": "São Paulo", "uf": test.uf})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := address.ViaCEP(context.Background(), &http.Client{Transport: bodyTransport(body)}, "01001000")
+		if err != nil {
+			t.Fatalf("%q: %v", test.uf, err)
+		}
+		if result.State != test.want || result.StateName != test.stateName {
+			t.Errorf("%q mapped to %q (%q), want %q (%q)", test.uf, result.State, result.StateName, test.want, test.stateName)
+		}
+	}
+}
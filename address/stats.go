@@ -0,0 +1,308 @@
+package address
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/internal/atomicfile"
+)
+
+// STATS_SAMPLES bounds how many latencies the service keeps, per provider
+// and for the lookups, to compute percentiles from. Past it, the latencies
+// kept are a uniform sample of all of them.
+const STATS_SAMPLES = 10000
+
+// LatencyStats is a latency distribution, in milliseconds.
+type LatencyStats struct {
+	P50MS float64 `json:"p50_ms"`
+	P90MS float64 `json:"p90_ms"`
+	P99MS float64 `json:"p99_ms"`
+	MaxMS float64 `json:"max_ms"`
+}
+
+// ProviderStats counts the requests made to one provider. Errors are the
+// requests that failed, whether or not a retry followed; latencies are those
+// of the requests that finished.
+type ProviderStats struct {
+	Requests  int64        `json:"requests"`
+	Wins      int64        `json:"wins"`
+	Errors    int64        `json:"errors"`
+	Cancelled int64        `json:"cancelled"`
+	Latency   LatencyStats `json:"latency"`
+}
+
+// ServiceStats is what the service did since it was created. A lookup is
+// counted when it returns or, with an observer, once it settled, see
+// SetObserver.
+type ServiceStats struct {
+	Lookups   int64 `json:"lookups"`
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+	// CacheHitRatio is CacheHits over the lookups the cache was asked
+	// for, zero when it never was.
+	CacheHits     int64   `json:"cache_hits"`
+	CacheMisses   int64   `json:"cache_misses"`
+	CacheHitRatio float64 `json:"cache_hit_ratio"`
+	// Retries are the requests made after a provider's first one.
+	Retries int64 `json:"retries"`
+	// RateLimitWaits are the streamed and batch lookups held back by
+	// SetRateLimit, for RateLimitWaitMS in total.
+	RateLimitWaits  int64                    `json:"rate_limit_waits"`
+	RateLimitWaitMS float64                  `json:"rate_limit_wait_ms"`
+	Latency         LatencyStats             `json:"latency"`
+	Providers       map[string]ProviderStats `json:"providers"`
+}
+
+// StatsReport is the file SetStatsReport writes.
+type StatsReport struct {
+	Version     string       `json:"version"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  time.Time    `json:"finished_at"`
+	Interrupted bool         `json:"interrupted"`
+	Stats       ServiceStats `json:"stats"`
+}
+
+// latencySample keeps at most STATS_SAMPLES latencies, replacing them at
+// random once full so that they stay a uniform sample (reservoir sampling).
+type latencySample struct {
+	seen      int64
+	latencies []time.Duration
+	max       time.Duration
+}
+
+func (l *latencySample) add(latency time.Duration) {
+	l.seen++
+	l.max = max(l.max, latency)
+	if len(l.latencies) < STATS_SAMPLES {
+		l.latencies = append(l.latencies, latency)
+		return
+	}
+	if i := rand.Int64N(l.seen); i < STATS_SAMPLES {
+		l.latencies[i] = latency
+	}
+}
+
+func (l *latencySample) stats() LatencyStats {
+	sorted := slices.Clone(l.latencies)
+	slices.Sort(sorted)
+
+	return LatencyStats{
+		P50MS: milliseconds(statsPercentile(sorted, 50)),
+		P90MS: milliseconds(statsPercentile(sorted, 90)),
+		P99MS: milliseconds(statsPercentile(sorted, 99)),
+		MaxMS: milliseconds(l.max),
+	}
+}
+
+// statsPercentile is the nearest-rank percentile p of sorted.
+func statsPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type providerCounts struct {
+	ProviderStats
+	latency latencySample
+}
+
+// statsCollector accumulates the ServiceStats of a service and the settings
+// of its stats report.
+type statsCollector struct {
+	mu        sync.Mutex
+	stats     ServiceStats
+	wait      time.Duration
+	latency   latencySample
+	providers map[string]*providerCounts
+	// settling counts the lookups that returned but are only observed
+	// once their providers settle. closed is set, under mu, once Close
+	// waits for them, and then no more are added.
+	settling sync.WaitGroup
+	closed   bool
+
+	started     time.Time
+	path        string
+	version     string
+	interrupted bool
+}
+
+func newStatsCollector(started time.Time) *statsCollector {
+	return &statsCollector{started: started, providers: make(map[string]*providerCounts)}
+}
+
+func (c *statsCollector) observe(report *Report, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Lookups++
+	if err != nil {
+		c.stats.Failed++
+	} else {
+		c.stats.Succeeded++
+	}
+
+	if report == nil {
+		return
+	}
+	c.latency.add(report.Duration)
+
+	switch {
+	case report.Cached:
+		c.stats.CacheHits++
+	case report.CacheMiss:
+		c.stats.CacheMisses++
+	}
+
+	for _, attempt := range report.Attempts {
+		provider, ok := c.providers[attempt.Provider]
+		if !ok {
+			provider = &providerCounts{}
+			c.providers[attempt.Provider] = provider
+		}
+
+		provider.Requests++
+		if attempt.Number > 1 {
+			c.stats.Retries++
+		}
+
+		switch attempt.Outcome {
+		case OUTCOME_WON:
+			provider.Wins++
+		case OUTCOME_FAILED:
+			provider.Errors++
+		case OUTCOME_CANCELLED, OUTCOME_PENDING:
+			provider.Cancelled++
+			continue
+		}
+		provider.latency.add(attempt.Duration)
+	}
+}
+
+// settle calls fn, which observes a lookup once its providers settled, in
+// its own goroutine, and tells whether it did: after close, the lookup is
+// to be observed right away.
+func (c *statsCollector) settle(fn func()) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	c.settling.Add(1)
+	go func() {
+		defer c.settling.Done()
+		fn()
+	}()
+	return true
+}
+
+// close waits for the lookups settling, and stops settle from starting
+// more.
+func (c *statsCollector) close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.settling.Wait()
+}
+
+func (c *statsCollector) rateLimitWait(wait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.RateLimitWaits++
+	c.wait += wait
+}
+
+func (c *statsCollector) snapshot() ServiceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	if asked := stats.CacheHits + stats.CacheMisses; asked > 0 {
+		stats.CacheHitRatio = float64(stats.CacheHits) / float64(asked)
+	}
+	stats.RateLimitWaitMS = milliseconds(c.wait)
+	stats.Latency = c.latency.stats()
+
+	stats.Providers = make(map[string]ProviderStats, len(c.providers))
+	for name, provider := range c.providers {
+		counts := provider.ProviderStats
+		counts.Latency = provider.latency.stats()
+		stats.Providers[name] = counts
+	}
+
+	return stats
+}
+
+// Stats returns what the service did since it was created.
+func (s *AddressService) Stats() ServiceStats {
+	return s.stats.snapshot()
+}
+
+// SetStatsReport makes Close write the service's stats to path as a JSON
+// StatsReport, replacing the file in one step so that it is either absent
+// or complete, for runs too short-lived to be scraped. version is recorded
+// in the report; when empty, the version of this module is. An empty path
+// writes no report.
+func (s *AddressService) SetStatsReport(path string, version string) *AddressService {
+	if version == "" {
+		version = moduleVersion()
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.path = path
+	s.stats.version = version
+	return s
+}
+
+// MarkInterrupted records in the stats report that the run was cut short,
+// for instance by a signal, so its counts are partial.
+func (s *AddressService) MarkInterrupted() *AddressService {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.interrupted = true
+	return s
+}
+
+// writeReport writes the stats report, once, if SetStatsReport asked for
+// one.
+func (c *statsCollector) writeReport(finished time.Time) error {
+	c.mu.Lock()
+	path := c.path
+	c.path = ""
+	report := StatsReport{
+		Version:     c.version,
+		StartedAt:   c.started.UTC(),
+		FinishedAt:  finished.UTC(),
+		Interrupted: c.interrupted,
+	}
+	c.mu.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	report.Stats = c.snapshot()
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, append(data, '\n'))
+}
@@ -0,0 +1,168 @@
+package address_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func readStatsReport(t *testing.T, path string) address.StatsReport {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report address.StatsReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("stats report is not a StatsReport: %v\n%s", err, data)
+	}
+	return report
+}
+
+func TestStatsReportIsWrittenOnClose(t *testing.T) {
+	fast := addresstest.NewMockProvider("Fast").Returns(sé).SetLatency(time.Millisecond)
+	slow := addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Second)
+	service := newService(t, fast, slow).SetCache(address.NewMemoryCache(time.Minute))
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	service.SetStatsReport(path, "1.2.3")
+
+	for _, cep := range []string{"01001000", "01001000", "01001-000", "123"} {
+		service.Execute(cep)
+	}
+	service.Close()
+
+	report := readStatsReport(t, path)
+	if report.Version != "1.2.3" || report.Interrupted {
+		t.Errorf("report metadata = %q, interrupted %v", report.Version, report.Interrupted)
+	}
+	if report.StartedAt.IsZero() || report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("report ran from %v to %v", report.StartedAt, report.FinishedAt)
+	}
+
+	stats := report.Stats
+	if stats.Lookups != 4 || stats.Succeeded != 3 || stats.Failed != 1 {
+		t.Errorf("lookups = %d (%d ok, %d failed), want 4 (3 ok, 1 failed)", stats.Lookups, stats.Succeeded, stats.Failed)
+	}
+	if stats.CacheHits != 2 || stats.CacheMisses != 1 || stats.CacheHitRatio < 0.66 || stats.CacheHitRatio > 0.67 {
+		t.Errorf("cache hits %d, misses %d, ratio %v, want 2, 1, 2/3", stats.CacheHits, stats.CacheMisses, stats.CacheHitRatio)
+	}
+
+	want := map[string]address.ProviderStats{
+		"Fast": {Requests: 1, Wins: 1},
+		"Slow": {Requests: 1, Cancelled: 1},
+	}
+	for name, counts := range want {
+		got := stats.Providers[name]
+		got.Latency = address.LatencyStats{}
+		if got != counts {
+			t.Errorf("%s stats = %+v, want %+v", name, got, counts)
+		}
+	}
+	if stats.Providers["Fast"].Latency.P50MS <= 0 {
+		t.Errorf("Fast latency = %+v, want a p50", stats.Providers["Fast"].Latency)
+	}
+
+	// The second Close, as a deferred one after the grace period, keeps
+	// the report.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	service.Close()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("second Close rewrote the report: %v", err)
+	}
+}
+
+func TestStatsReportMarksInterruptedRuns(t *testing.T) {
+	service := newService(t, addresstest.NewMockProvider("Fast").Returns(sé))
+	path := filepath.Join(t.TempDir(), "stats.json")
+	service.SetStatsReport(path, "")
+
+	service.Execute("01001000")
+	service.MarkInterrupted().Close()
+
+	report := readStatsReport(t, path)
+	if !report.Interrupted || report.Stats.Lookups != 1 {
+		t.Errorf("report interrupted %v with %d lookups, want interrupted with 1", report.Interrupted, report.Stats.Lookups)
+	}
+	if report.Version == "" {
+		t.Error("report has no version")
+	}
+}
+
+func TestStatsCountLosersOnceTheySettle(t *testing.T) {
+	// Slow answers after the lookup returned, ignoring its cancellation,
+	// so it lost rather than was cancelled. No observer is set: the stats
+	// wait for the providers to settle on their own.
+	release := make(chan struct{})
+	slow := address.NewProvider("Slow", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		<-release
+		return sé, nil
+	})
+	fast := addresstest.NewMockProvider("Fast").Returns(sé)
+	service := newService(t, fast, slow).SetTimeout(time.Minute)
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	service.SetStatsReport(path, "1.2.3")
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	service.Close()
+
+	stats := readStatsReport(t, path).Stats
+	if stats.Lookups != 1 || stats.Succeeded != 1 {
+		t.Errorf("lookups = %d (%d ok), want 1 (1 ok)", stats.Lookups, stats.Succeeded)
+	}
+
+	slowStats := stats.Providers["Slow"]
+	if slowStats.Requests != 1 || slowStats.Wins != 0 || slowStats.Errors != 0 || slowStats.Cancelled != 0 {
+		t.Errorf("Slow stats = %+v, want one request that lost", slowStats)
+	}
+	if slowStats.Latency.P50MS <= 0 {
+		t.Errorf("Slow latency = %+v, want the loser's latency", slowStats.Latency)
+	}
+	if fastStats := stats.Providers["Fast"]; fastStats.Requests != 1 || fastStats.Wins != 1 {
+		t.Errorf("Fast stats = %+v, want one win", fastStats)
+	}
+}
+
+func TestStatsCountLookupsReturningWhileClosing(t *testing.T) {
+	const GOROUTINES, LOOKUPS = 50, 20
+
+	fast := addresstest.NewMockProvider("Fast").Returns(sé)
+	slow := addresstest.NewMockProvider("Slow").Returns(sé).SetLatency(time.Millisecond)
+	service := newService(t, fast, slow)
+
+	var lookups sync.WaitGroup
+	for range GOROUTINES {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			for range LOOKUPS {
+				service.Execute("01001000")
+			}
+		}()
+	}
+	service.Close()
+	lookups.Wait()
+
+	// Every lookup is counted, whether it settled before Close or returned
+	// after it.
+	if stats := service.Stats(); stats.Lookups != GOROUTINES*LOOKUPS {
+		t.Errorf("stats counted %d lookups, want %d", stats.Lookups, GOROUTINES*LOOKUPS)
+	}
+}
@@ -0,0 +1,73 @@
+package address
+
+import "strings"
+
+// streetTypes maps the lower-case abbreviations of street types to the full
+// word.
+var streetTypes = map[string]string{
+	"r":    "Rua",
+	"av":   "Avenida",
+	"avda": "Avenida",
+	"trav": "Travessa",
+	"tv":   "Travessa",
+	"al":   "Alameda",
+	"pça":  "Praça",
+	"pca":  "Praça",
+	"pç":   "Praça",
+	"rod":  "Rodovia",
+	"estr": "Estrada",
+	"est":  "Estrada",
+	"lgo":  "Largo",
+	"lg":   "Largo",
+	"pq":   "Parque",
+	"pque": "Parque",
+	"vl":   "Vila",
+	"bc":   "Beco",
+	"lad":  "Ladeira",
+	"jd":   "Jardim",
+	"qd":   "Quadra",
+	"vd":   "Viaduto",
+	"vdto": "Viaduto",
+	"conj": "Conjunto",
+	"cj":   "Conjunto",
+	"pte":  "Ponte",
+	"ptg":  "Passagem",
+	"psg":  "Passagem",
+}
+
+// CanonicalizeStreet writes out the abbreviated street type of s, so "R. XV
+// de Novembro" and "Av Paulista" become "Rua XV de Novembro" and "Avenida
+// Paulista", and collapses its whitespace. Only a first word followed by a
+// dot or a space is expanded, so names that merely start with the same
+// letters, like "Rodrigues Alves", are left alone.
+func CanonicalizeStreet(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+
+	end := strings.IndexAny(s, ". ")
+	if end <= 0 {
+		return s
+	}
+
+	street, ok := streetTypes[strings.ToLower(s[:end])]
+	if !ok {
+		return s
+	}
+
+	rest := strings.TrimLeft(s[end+1:], " ")
+	if rest == "" {
+		return s
+	}
+
+	return street + " " + rest
+}
+
+// SetCanonicalStreets makes lookups expand the abbreviated street types
+// providers answer with, see CanonicalizeStreet. It is off by default, so
+// results keep the provider's spelling.
+func (s *AddressService) SetCanonicalStreets(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.canonical = enabled
+	return s
+}
@@ -0,0 +1,57 @@
+package address_test
+
+import (
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestCanonicalizeStreet(t *testing.T) {
+	tests := []struct {
+		street string
+		want   string
+	}{
+		{"R. XV de Novembro", "Rua XV de Novembro"},
+		{"Av Paulista", "Avenida Paulista"},
+		{"AV. PAULISTA", "Avenida PAULISTA"},
+		{"Pça. da Sé", "Praça da Sé"},
+		{"Tv.Barão de Itapetininga", "Travessa Barão de Itapetininga"},
+		{"  Al.   Santos  ", "Alameda Santos"},
+		{"Rua XV de Novembro", "Rua XV de Novembro"},
+		{"Rodrigues Alves", "Rodrigues Alves"},
+		{"Jd América", "Jardim América"},
+		{"R.", "R."},
+		{"Av", "Av"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := address.CanonicalizeStreet(test.street); got != test.want {
+			t.Errorf("CanonicalizeStreet(%q) = %q, want %q", test.street, got, test.want)
+		}
+	}
+}
+
+func TestSetCanonicalStreets(t *testing.T) {
+	abbreviated := sé
+	abbreviated.Street = "Pça. da Sé"
+	service := newService(t, addresstest.NewMockProvider("A").Returns(abbreviated))
+
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Street != "Pça. da Sé" {
+		t.Errorf("street = %q by default, want the provider's spelling", result.Street)
+	}
+
+	service.SetCanonicalStreets(true)
+	result, err = service.Execute("01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Street != "Praça da Sé" {
+		t.Errorf("street = %q with canonical streets, want Praça da Sé", result.Street)
+	}
+}
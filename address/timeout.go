@@ -0,0 +1,86 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeoutError is the error of a lookup whose timeout passed before any
+// provider answered. It wraps ErrTimeout and tells what every provider was
+// doing at that instant, e.g.
+//
+//	request timeout after 1s: ViaCEP pending (no response), BrasilAPI failed (status 500 at 230ms)
+type TimeoutError struct {
+	After time.Duration
+	// Attempts holds the last attempt of every provider, in the order the
+	// providers were tried; a provider that never started has a zero
+	// Attempt with only Provider set.
+	Attempts []Attempt
+}
+
+func (e *TimeoutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v after %v", ErrTimeout, e.After)
+
+	for i, attempt := range e.Attempts {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(attempt.Provider)
+		b.WriteByte(' ')
+		b.WriteString(describeAttempt(attempt))
+	}
+
+	return b.String()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return ErrTimeout
+}
+
+// newTimeoutError builds the TimeoutError of a lookup over providers from
+// report, keeping the last attempt of each.
+func newTimeoutError(after time.Duration, providers []Provider, report *Report) *TimeoutError {
+	attempts := make([]Attempt, len(providers))
+	for i, provider := range providers {
+		attempts[i] = Attempt{Provider: provider.Name()}
+		for _, attempt := range report.Attempts {
+			if attempt.Provider == provider.Name() {
+				attempts[i] = attempt
+			}
+		}
+	}
+
+	return &TimeoutError{After: after, Attempts: attempts}
+}
+
+func describeAttempt(attempt Attempt) string {
+	var state string
+	switch attempt.Outcome {
+	case "":
+		return "not started"
+	case OUTCOME_PENDING:
+		if attempt.StatusCode != 0 {
+			state = fmt.Sprintf("pending (status %d, reading body)", attempt.StatusCode)
+		} else {
+			state = "pending (no response)"
+		}
+	case OUTCOME_FAILED:
+		at := (attempt.Start + attempt.Duration).Round(time.Millisecond)
+		if attempt.StatusCode != 0 {
+			state = fmt.Sprintf("failed (status %d at %v)", attempt.StatusCode, at)
+		} else {
+			state = fmt.Sprintf("failed (%v at %v)", attempt.Err, at)
+		}
+	default:
+		state = fmt.Sprintf("%s at %v", attempt.Outcome, (attempt.Start + attempt.Duration).Round(time.Millisecond))
+	}
+
+	if attempt.Number > 1 {
+		state += fmt.Sprintf(" on attempt %d", attempt.Number)
+	}
+	return state
+}
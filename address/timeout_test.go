@@ -0,0 +1,56 @@
+package address_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestTimeoutErrorNamesPendingProviders(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+
+	service := newService(t,
+		stubborn(t, "Stubborn"),
+		trickling(t),
+		address.NewBrasilAPIProvider(failing.URL),
+	).SetTimeout(100 * time.Millisecond)
+
+	_, err := service.Execute("01001000")
+	var timeout *address.TimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, address.ErrTimeout) {
+		t.Fatalf("err = %v, want a *TimeoutError", err)
+	}
+	if timeout.After != 100*time.Millisecond {
+		t.Errorf("After = %v, want the 100ms timeout", timeout.After)
+	}
+
+	want := regexp.MustCompile(`^request timeout after 100ms: ` +
+		`Stubborn pending \(no response\), ` +
+		`ViaCEP pending \(status 200, reading body\), ` +
+		`BrasilAPI failed \(status 500 at \d+(\.\d+)?m?s\)$`)
+	if !want.MatchString(err.Error()) {
+		t.Errorf("err = %q, want it to match %s", err, want)
+	}
+}
+
+func TestTimeoutErrorDescribesAttempts(t *testing.T) {
+	err := &address.TimeoutError{
+		After: time.Second,
+		Attempts: []address.Attempt{
+			{Provider: "A"},
+			{Provider: "B", Outcome: address.OUTCOME_FAILED, Err: errors.New("connection refused"), Start: 200 * time.Millisecond, Duration: 30 * time.Millisecond, Number: 2},
+		},
+	}
+
+	if got, want := err.Error(), "request timeout after 1s: A not started, B failed (connection refused at 230ms) on attempt 2"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var ErrInvalidTimeouts = errors.New("invalid timeouts")
+
+// TimeoutConfig splits the time a provider request may take by phase, so a
+// lookup can fail fast when a provider cannot even be reached while still
+// allowing a slow body. Zero leaves a phase as it is: Dial and TLSHandshake
+// keep the net/http defaults, ResponseHeader stays unbounded and Overall
+// keeps the service timeout.
+type TimeoutConfig struct {
+	// Dial bounds establishing the TCP connection.
+	Dial time.Duration
+	// TLSHandshake bounds the TLS handshake.
+	TLSHandshake time.Duration
+	// ResponseHeader bounds the wait for the response headers once the
+	// request is written.
+	ResponseHeader time.Duration
+	// Overall is the lookup timeout, as set by SetTimeout.
+	Overall time.Duration
+}
+
+// Validate rejects negative phases and phases longer than the overall
+// timeout they are part of.
+func (c TimeoutConfig) Validate(overall time.Duration) error {
+	if c.Overall != 0 {
+		overall = c.Overall
+	}
+
+	for _, phase := range []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"dial", c.Dial},
+		{"TLS handshake", c.TLSHandshake},
+		{"response header", c.ResponseHeader},
+		{"overall", c.Overall},
+	} {
+		if phase.timeout < 0 {
+			return fmt.Errorf("%w: %s timeout must not be negative, got %s", ErrInvalidTimeouts, phase.name, phase.timeout)
+		}
+
+		if phase.timeout > overall {
+			return fmt.Errorf("%w: %s timeout %s exceeds the overall %s", ErrInvalidTimeouts, phase.name, phase.timeout, overall)
+		}
+	}
+
+	return nil
+}
+
+// apply sets the timeouts of transport; Dial is applied by newTransport's
+// dialer.
+func (c TimeoutConfig) apply(transport *http.Transport) {
+	if c.TLSHandshake > 0 {
+		transport.TLSHandshakeTimeout = c.TLSHandshake
+	}
+
+	if c.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = c.ResponseHeader
+	}
+}
+
+// SetTimeouts sets per-phase timeouts for provider requests. It fails with
+// ErrInvalidTimeouts when a phase is negative or longer than the overall
+// timeout. Like SetTransport it replaces a transport or client set earlier.
+func (s *AddressService) SetTimeouts(config TimeoutConfig) error {
+	s.mu.RLock()
+	overall := s.Timeout
+	s.mu.RUnlock()
+
+	if err := config.Validate(overall); err != nil {
+		return err
+	}
+
+	if err := s.setTransportOption(func() { s.transport.timeouts = config }); err != nil {
+		return err
+	}
+
+	if config.Overall != 0 {
+		s.SetTimeout(config.Overall)
+	}
+
+	return nil
+}
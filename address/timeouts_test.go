@@ -0,0 +1,167 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// blackHole returns a resolver whose DNS server never answers.
+func blackHole() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+}
+
+// silentListener accepts connections and never writes to them.
+func silentListener(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns []net.Conn
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		<-done
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	return listener.Addr().String()
+}
+
+// The lookup is allowed 5s, but each phase fails the request well before.
+func TestPhaseTimeoutsFailFast(t *testing.T) {
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	hung.Config.ErrorLog = log.New(io.Discard, "", 0)
+	t.Cleanup(hung.Close)
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		resolver *net.Resolver
+		config   address.TimeoutConfig
+	}{
+		{
+			name:     "dial",
+			baseURL:  "http://viacep.test/ws",
+			resolver: blackHole(),
+			config:   address.TimeoutConfig{Dial: 100 * time.Millisecond},
+		},
+		{
+			name:    "TLS handshake",
+			baseURL: "https://" + silentListener(t) + "/ws",
+			config:  address.TimeoutConfig{TLSHandshake: 100 * time.Millisecond},
+		},
+		{
+			name:    "response header",
+			baseURL: hung.URL + "/ws",
+			config:  address.TimeoutConfig{ResponseHeader: 100 * time.Millisecond},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newService(t, address.NewViaCEPProvider(test.baseURL)).SetTimeout(5 * time.Second)
+			if test.resolver != nil {
+				if err := service.SetResolver(test.resolver); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := service.SetTimeouts(test.config); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			_, err := service.Execute("01001000")
+			elapsed := time.Since(start)
+			// The provider failed; the lookup did not time out.
+			var lookupTimeout *address.TimeoutError
+			if !errors.Is(err, address.ErrAllProvidersFailed) || errors.As(err, &lookupTimeout) {
+				t.Fatalf("err = %v, want the request to fail before the lookup timeout", err)
+			}
+			if elapsed > time.Second {
+				t.Errorf("lookup failed after %v, want about 100ms", elapsed)
+			}
+		})
+	}
+}
+
+func TestTimeoutsKeepTheDefaults(t *testing.T) {
+	service := newService(t, address.NewViaCEPProvider("http://viacep.test/ws")).SetTimeout(200 * time.Millisecond)
+	if err := service.SetResolver(blackHole()); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetTimeouts(address.TimeoutConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// No dial timeout of its own: only the lookup's stops the request.
+	var lookupTimeout *address.TimeoutError
+	if _, err := service.Execute("01001000"); !errors.As(err, &lookupTimeout) {
+		t.Errorf("err = %v, want the lookup timeout", err)
+	}
+}
+
+func TestSetTimeoutsValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		config address.TimeoutConfig
+		valid  bool
+	}{
+		{name: "empty", config: address.TimeoutConfig{}, valid: true},
+		{name: "within", config: address.TimeoutConfig{Dial: time.Second, TLSHandshake: time.Second, ResponseHeader: 2 * time.Second}, valid: true},
+		{name: "longer overall", config: address.TimeoutConfig{ResponseHeader: 8 * time.Second, Overall: 10 * time.Second}, valid: true},
+		{name: "negative", config: address.TimeoutConfig{Dial: -time.Second}},
+		{name: "dial too long", config: address.TimeoutConfig{Dial: 4 * time.Second}},
+		{name: "header past overall", config: address.TimeoutConfig{ResponseHeader: 2 * time.Second, Overall: time.Second}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newService(t).SetTimeout(3 * time.Second)
+
+			err := service.SetTimeouts(test.config)
+			if test.valid && err != nil {
+				t.Fatalf("SetTimeouts = %v", err)
+			}
+			if !test.valid && !errors.Is(err, address.ErrInvalidTimeouts) {
+				t.Fatalf("SetTimeouts = %v, want ErrInvalidTimeouts", err)
+			}
+
+			want := 3 * time.Second
+			if test.valid && test.config.Overall != 0 {
+				want = test.config.Overall
+			}
+			if service.Timeout != want {
+				t.Errorf("timeout = %v, want %v", service.Timeout, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package address
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrInvalidCACert = errors.New("invalid CA certificate file")
+
+// SetTLSConfig makes provider requests use config, for client certificates
+// or a private trust store. It applies to every provider, including the ones
+// registered later, and nil goes back to the system defaults. Like
+// SetTransport it replaces a transport or client set earlier.
+func (s *AddressService) SetTLSConfig(config *tls.Config) error {
+	return s.setTransportOption(func() { s.transport.tlsConfig = config })
+}
+
+// SetCACertFile trusts the PEM certificates in path on top of the system
+// roots, for networks that re-encrypt traffic with an internal CA. An
+// unreadable file or one without any certificate fails with
+// ErrInvalidCACert.
+func (s *AddressService) SetCACertFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCACert, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("%w: no PEM certificates in %s", ErrInvalidCACert, path)
+	}
+
+	s.mu.RLock()
+	config := s.transport.tlsConfig.Clone()
+	s.mu.RUnlock()
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.RootCAs = pool
+
+	return s.SetTLSConfig(config)
+}
+
+// insecureWarning makes SetInsecureSkipVerify warn once per process rather
+// than once per service.
+var insecureWarning sync.Once
+
+// SetInsecureSkipVerify turns TLS certificate verification of provider
+// requests off, like curl -k, for debugging behind an intercepting proxy.
+// Never use it in production: anyone on the path can answer for the
+// providers. Results fetched this way have InsecureTransport set. Like
+// SetTransport it replaces a transport or client set earlier, and it does not
+// apply to clients from SetHTTPClient or SetProviderClient.
+func (s *AddressService) SetInsecureSkipVerify(skip bool) error {
+	if err := s.setTransportOption(func() { s.transport.insecure = skip }); err != nil {
+		return err
+	}
+
+	if skip {
+		s.mu.RLock()
+		logger := s.logOutput()
+		s.mu.RUnlock()
+
+		insecureWarning.Do(func() {
+			logger.Warn("TLS certificate verification is DISABLED for provider requests; responses may be forged, use only for local debugging")
+		})
+	}
+	return nil
+}
+
+// InsecureSkipVerify reports whether SetInsecureSkipVerify turned
+// certificate verification off.
+func (s *AddressService) InsecureSkipVerify() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.transport.insecure
+}
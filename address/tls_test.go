@@ -0,0 +1,160 @@
+package address_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// internalCA starts the fake ViaCEP behind TLS with a certificate issued by
+// a freshly generated CA, as a re-encrypting corporate proxy would, and
+// returns the server and the path of the CA's PEM file.
+func internalCA(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Internal CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "viacep"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viaCEP := addresstest.NewViaCEPServer(map[string]address.AddressResult{"01001000": sé})
+	t.Cleanup(viaCEP.Close)
+	server := httptest.NewUnstartedServer(viaCEP.Config.Handler)
+	// The handshakes refused without the CA are expected.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leafDER}, PrivateKey: leafKey}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, path
+}
+
+func TestCACertFileTrustsTheInternalCA(t *testing.T) {
+	server, ca := internalCA(t)
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+
+	var unknown x509.UnknownAuthorityError
+	if _, err := service.Execute("01001000"); !errors.As(err, &unknown) {
+		t.Fatalf("err = %v without the CA, want an unknown authority", err)
+	}
+
+	if err := service.SetCACertFile(ca); err != nil {
+		t.Fatal(err)
+	}
+	result, err := service.Execute("01001000")
+	if err != nil {
+		t.Fatalf("with the CA: %v", err)
+	}
+	if result.Street != sé.Street {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestCACertFileAppliesToProvidersRegisteredLater(t *testing.T) {
+	server, ca := internalCA(t)
+	service := newService(t)
+	if err := service.SetCACertFile(ca); err != nil {
+		t.Fatal(err)
+	}
+	// Other transport settings keep the CA.
+	if err := service.SetProxy(""); err != nil {
+		t.Fatal(err)
+	}
+
+	service.RegisterProvider(address.NewViaCEPProvider(server.URL + "/ws"))
+	if err := service.SetProviders("ViaCEP"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	server, _ := internalCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+
+	if err := service.SetTLSConfig(&tls.Config{RootCAs: roots}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	// nil goes back to the system roots.
+	if err := service.SetTLSConfig(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Execute("01001000"); err == nil {
+		t.Fatal("lookup trusted the internal CA after SetTLSConfig(nil)")
+	}
+}
+
+func TestInvalidCACertFile(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.pem"), garbage, dir} {
+		if err := newService(t).SetCACertFile(path); !errors.Is(err, address.ErrInvalidCACert) {
+			t.Errorf("SetCACertFile(%s) = %v, want ErrInvalidCACert", filepath.Base(path), err)
+		}
+	}
+}
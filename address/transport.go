@@ -0,0 +1,282 @@
+package address
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const MODULE_PATH = "github.com/wendellnd/multithreading-challenge"
+
+const USER_AGENT_NAME = "multithreading-challenge-address"
+
+// DEFAULT_USER_AGENT identifies the library to the providers, as ViaCEP asks
+// automated clients to do.
+var DEFAULT_USER_AGENT = USER_AGENT_NAME + "/" + moduleVersion()
+
+// moduleVersion is the version of this module in the running binary, or
+// "devel" when it was built from a checkout.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+
+	module := &info.Main
+	for _, dependency := range info.Deps {
+		if dependency.Path == MODULE_PATH {
+			module = dependency
+		}
+	}
+
+	if module.Path != MODULE_PATH || module.Version == "" || module.Version == "(devel)" {
+		return "devel"
+	}
+
+	return module.Version
+}
+
+// transportOptions configure the service's default transport.
+type transportOptions struct {
+	proxy     *url.URL
+	tlsConfig *tls.Config
+	timeouts  TimeoutConfig
+	resolver  *net.Resolver
+	http2     HTTP2Mode
+	insecure  bool
+	// hosts maps lower-case host names to the addresses dialed instead.
+	hosts map[string]string
+}
+
+// newTransport is http.DefaultTransport keeping enough idle connections per
+// provider for concurrent lookups to reuse them, with options applied. A
+// DefaultTransport replaced by the program is used as is; the setters of
+// transport options refuse to work with one.
+func newTransport(options transportOptions) http.RoundTripper {
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+
+	transport := defaultTransport.Clone()
+	transport.MaxIdleConnsPerHost = DEFAULT_MAX_IDLE_CONNS_PER_HOST
+	// Providers decompress gzip themselves, see SetCompression.
+	transport.DisableCompression = true
+	if options.proxy != nil {
+		transport.Proxy = http.ProxyURL(options.proxy)
+	}
+	if options.tlsConfig != nil {
+		transport.TLSClientConfig = options.tlsConfig.Clone()
+	}
+	if options.insecure {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	options.timeouts.apply(transport)
+	if options.timeouts.Dial > 0 || options.resolver != nil || len(options.hosts) > 0 {
+		transport.DialContext = options.dialContext()
+	}
+	return options.http2.configure(transport)
+}
+
+// dialContext dials like net/http's default dialer, with the dial timeout,
+// the resolver and the host overrides of options. Only the dialed address
+// changes, so TLS still verifies and the Host header still names the
+// original host.
+func (options transportOptions) dialContext() func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: DEFAULT_DIAL_TIMEOUT, KeepAlive: 30 * time.Second, Resolver: options.resolver}
+	if options.timeouts.Dial > 0 {
+		dialer.Timeout = options.timeouts.Dial
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(address); err == nil {
+			if override, ok := options.hosts[strings.ToLower(host)]; ok {
+				address = override
+				if _, _, err := net.SplitHostPort(override); err != nil {
+					address = net.JoinHostPort(override, port)
+				}
+			}
+		}
+
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// DEFAULT_DIAL_TIMEOUT is the dial timeout of http.DefaultTransport, kept when
+// the service dials by itself.
+const DEFAULT_DIAL_TIMEOUT = 30 * time.Second
+
+// ErrTransportReplaced is returned by the setters that configure the default
+// transport when the program replaced http.DefaultTransport.
+var ErrTransportReplaced = errors.New("http.DefaultTransport is not an *http.Transport")
+
+// setTransportOption runs apply under the lock and installs a default
+// transport built from the result. It fails when there is no
+// *http.Transport to configure.
+func (s *AddressService) setTransportOption(apply func()) error {
+	if _, ok := http.DefaultTransport.(*http.Transport); !ok {
+		return ErrTransportReplaced
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apply()
+	s.setClient(&http.Client{Timeout: s.Timeout, Transport: newTransport(s.transport)}, true)
+	return nil
+}
+
+// SetUserAgent sets the User-Agent header sent with every provider request,
+// including retries and requests of custom providers that use the client
+// they are given. A provider that sets its own User-Agent keeps it, and an
+// empty agent falls back to Go's default.
+func (s *AddressService) SetUserAgent(agent string) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.userAgent = agent
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// SetCompression controls whether provider requests ask for gzip, which is
+// on by default. The service decompresses responses itself instead of
+// leaving it to net/http, so bodies stay capped at MAX_RESPONSE_BYTES once
+// decompressed and mirrors that claim gzip but send plain JSON still work.
+// Turned off, providers are asked for uncompressed bodies.
+func (s *AddressService) SetCompression(enabled bool) *AddressService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.compression = enabled
+	s.setClient(s.baseClient, s.ownClient)
+	return s
+}
+
+// setClient makes base the client providers use, behind the transport that
+// adds the User-Agent, Accept-Encoding and provider headers and the transport
+// middleware. A client from SetHTTPClient is copied rather than changed. s.mu must be held.
+func (s *AddressService) setClient(base *http.Client, own bool) {
+	s.baseClient = base
+	s.ownClient = own
+	s.client = s.wrapClient(base)
+
+	wrapped := make(map[string]*http.Client, len(s.clients))
+	for provider, client := range s.clients {
+		wrapped[provider] = s.wrapClient(client)
+	}
+	s.wrapped = wrapped
+}
+
+// wrapClient returns base behind the transport middleware and the
+// requestTransport of the service, with the redirect policy unless base has
+// its own. s.mu must be held.
+func (s *AddressService) wrapClient(base *http.Client) *http.Client {
+	client := *base
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = s.redirects.check
+	}
+
+	if s.userAgent != "" || s.compression || len(s.headers) > 0 || len(s.middleware) > 0 {
+		client.Transport = requestTransport{next: chain(base.Transport, s.middleware), agent: s.userAgent, compression: s.compression, headers: s.headers}
+	}
+	return &client
+}
+
+// requestTransport sets the User-Agent, Accept-Encoding and the headers
+// configured for the provider making the request, which it finds in the
+// request context.
+type requestTransport struct {
+	next        http.RoundTripper
+	agent       string
+	compression bool
+	headers     map[string]http.Header
+}
+
+func (t requestTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var header http.Header
+	recorded, recording := attemptFromContext(request.Context())
+	if recording {
+		header = providerHeader(t.headers, recorded.attempt.Provider, request.Context())
+	}
+
+	setAgent := t.agent != "" && request.Header.Get("User-Agent") == ""
+	setEncoding := t.compression && request.Header.Get("Accept-Encoding") == ""
+	if !setAgent && !setEncoding && header == nil {
+		return next.RoundTrip(request)
+	}
+
+	request = request.Clone(request.Context())
+	if setAgent {
+		request.Header.Set("User-Agent", t.agent)
+	}
+	if setEncoding {
+		request.Header.Set("Accept-Encoding", "gzip")
+	}
+	for name, values := range header {
+		request.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if header != nil {
+		recorded.setHeader(RedactHeader(header))
+	}
+
+	response, err := next.RoundTrip(request)
+	if err != nil || !setEncoding {
+		return response, err
+	}
+
+	return gunzipResponse(response)
+}
+
+// gunzipResponse decompresses a response to a request that asked for gzip,
+// as net/http does for the requests it compresses, so custom providers read
+// plain bodies too. A body that only claims to be gzip is left alone.
+func gunzipResponse(response *http.Response) (*http.Response, error) {
+	if !strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		return response, nil
+	}
+
+	reader := bufio.NewReader(response.Body)
+	response.Body = readCloser{Reader: reader, Closer: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+
+	if magic, err := reader.Peek(2); err != nil || !bytes.Equal(magic, gzipMagic) {
+		return response, nil
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		response.Body.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	response.Body = readCloser{Reader: gzipReader, Closer: response.Body}
+	return response, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
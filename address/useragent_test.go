@@ -0,0 +1,149 @@
+package address_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// recordingTransport keeps the headers of every request it passes on to
+// next, keyed by the request's path.
+type recordingTransport struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	headers map[string][]http.Header
+}
+
+func (r *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	if r.headers == nil {
+		r.headers = make(map[string][]http.Header)
+	}
+	r.headers[request.URL.Path] = append(r.headers[request.URL.Path], request.Header.Clone())
+	r.mu.Unlock()
+
+	next := r.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(request)
+}
+
+// sent returns the headers of the requests whose path starts with prefix.
+func (r *recordingTransport) sent(prefix string) []http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var headers []http.Header
+	for path, sent := range r.headers {
+		if strings.HasPrefix(path, prefix) {
+			headers = append(headers, sent...)
+		}
+	}
+	return headers
+}
+
+// headerService returns a service racing ViaCEP, BrasilAPI and a custom
+// provider that fetches /custom/ from the ViaCEP fake, all through
+// recording.
+func headerService(t *testing.T, recording *recordingTransport) *address.AddressService {
+	t.Helper()
+
+	providers, servers := fakeProviders(t)
+	custom := address.NewProvider("Custom", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, servers[0].URL+"/custom/"+cep, nil)
+		if err != nil {
+			return address.AddressResult{}, err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return address.AddressResult{}, err
+		}
+		response.Body.Close()
+		return sé, nil
+	})
+
+	return newService(t, append(providers, custom)...).SetTransport(recording)
+}
+
+func TestUserAgentOnEveryProvider(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		agent string
+		want  string
+	}{
+		{name: "default", want: address.DEFAULT_USER_AGENT},
+		{name: "custom", agent: "ops-lookups/2.1 (ops@example.com)", want: "ops-lookups/2.1 (ops@example.com)"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recording := &recordingTransport{}
+			service := headerService(t, recording)
+			if test.agent != "" {
+				service.SetUserAgent(test.agent)
+			}
+
+			if _, err := service.ExecuteAll("01001000"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, prefix := range []string{"/ws/", "/api/cep/", "/custom/"} {
+				sent := recording.sent(prefix)
+				if len(sent) != 1 {
+					t.Fatalf("%d requests to %s, want 1", len(sent), prefix)
+				}
+				if agent := sent[0].Get("User-Agent"); agent != test.want {
+					t.Errorf("%s User-Agent = %q, want %q", prefix, agent, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultUserAgentNamesTheLibrary(t *testing.T) {
+	if !strings.HasPrefix(address.DEFAULT_USER_AGENT, address.USER_AGENT_NAME+"/") {
+		t.Errorf("DEFAULT_USER_AGENT = %q, want %s/<version>", address.DEFAULT_USER_AGENT, address.USER_AGENT_NAME)
+	}
+}
+
+func TestUserAgentOnRetries(t *testing.T) {
+	recording := &recordingTransport{next: addresstest.NewFaultTransport(nil).On("/ws/", addresstest.Status(http.StatusServiceUnavailable, ""))}
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers[0]).SetTransport(recording).SetRetries(1, time.Millisecond)
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	sent := recording.sent("/ws/")
+	if len(sent) != 2 {
+		t.Fatalf("%d requests, want the first and its retry", len(sent))
+	}
+	for i, header := range sent {
+		if agent := header.Get("User-Agent"); agent != address.DEFAULT_USER_AGENT {
+			t.Errorf("request %d User-Agent = %q", i+1, agent)
+		}
+	}
+}
+
+func TestUserAgentLeavesTheInjectedClientAlone(t *testing.T) {
+	recording := &recordingTransport{}
+	client := &http.Client{Transport: recording}
+	providers, _ := fakeProviders(t)
+	service := newService(t, providers[0]).SetHTTPClient(client).SetUserAgent("mine/1")
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if agent := recording.sent("/ws/")[0].Get("User-Agent"); agent != "mine/1" {
+		t.Errorf("User-Agent = %q, want mine/1", agent)
+	}
+	if client.Transport != recording {
+		t.Error("SetUserAgent replaced the injected client's transport")
+	}
+}
@@ -0,0 +1,59 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnassignedPrefix = errors.New("CEP prefix not assigned to any state")
+
+// ValidationResult is what Validate found out about one CEP.
+type ValidationResult struct {
+	Index int
+	// CEP is the CEP as it was given.
+	CEP string
+	// Normalized is the CEP as 8 digits, empty when it is invalid.
+	Normalized string
+	// State is the UF the CEP's prefix belongs to, empty when it is invalid
+	// or the prefix check is off and no state has the prefix.
+	State string
+	Err   error
+}
+
+// Validate checks ceps the way lookups would before sending any request: it
+// normalizes each one and, unless SetPrefixCheck turned the check off, maps
+// its prefix to a state, failing CEPs whose prefix no state was assigned
+// with ErrInvalidCEP and ErrUnassignedPrefix. It never queries a provider,
+// so it tells how many of a batch's CEPs are even plausible before the
+// batch is run.
+func (s *AddressService) Validate(ceps []string) []ValidationResult {
+	s.mu.RLock()
+	prefixCheck := !s.prefixOff
+	s.mu.RUnlock()
+
+	results := make([]ValidationResult, len(ceps))
+	for i, cep := range ceps {
+		results[i] = validateCEP(i, cep, prefixCheck)
+	}
+
+	return results
+}
+
+func validateCEP(index int, cep string, prefixCheck bool) ValidationResult {
+	result := ValidationResult{Index: index, CEP: cep}
+
+	normalized, err := NormalizeCEP(cep)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	state, ok := StateForCEP(normalized)
+	if !ok && prefixCheck {
+		result.Err = fmt.Errorf("%w %q: %w", ErrInvalidCEP, cep, ErrUnassignedPrefix)
+		return result
+	}
+
+	result.Normalized, result.State = normalized, state
+	return result
+}
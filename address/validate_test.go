@@ -0,0 +1,33 @@
+package address_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestValidate(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé)
+	service := newService(t, provider)
+
+	results := service.Validate([]string{"01001-000", "123", "00000000"})
+	if got := results[0]; got.Index != 0 || got.CEP != "01001-000" || got.Normalized != "01001000" || got.State != "SP" || got.Err != nil {
+		t.Errorf("01001-000: %+v, want it normalized to SP", got)
+	}
+	if got := results[1]; got.Index != 1 || !errors.Is(got.Err, address.ErrInvalidCEP) || got.Normalized != "" {
+		t.Errorf("123: %+v, want ErrInvalidCEP", got)
+	}
+	if got := results[2]; !errors.Is(got.Err, address.ErrInvalidCEP) || !errors.Is(got.Err, address.ErrUnassignedPrefix) {
+		t.Errorf("00000000: %+v, want ErrUnassignedPrefix", got)
+	}
+
+	// Without the prefix check, an unassigned prefix is valid but stateless.
+	service.SetPrefixCheck(false)
+	if got := service.Validate([]string{"00000000"})[0]; got.Err != nil || got.Normalized != "00000000" || got.State != "" {
+		t.Errorf("00000000 without the prefix check: %+v, want it valid with no state", got)
+	}
+
+	provider.AssertCalls(t, 0)
+}
@@ -0,0 +1,93 @@
+package address
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const VIACEP_BASE_URL = "http://viacep.com.br/ws"
+
+type ViaCEPResponse struct {
+	CEP          string `json:"cep"`
+	City         string `json:"localidade"`
+	Neighborhood string `json:"bairro"`
+	State        string `json:"uf"`
+	Street       string `json:"logradouro"`
+	Error        any    `json:"erro"`
+}
+
+func (r ViaCEPResponse) NotFound() bool {
+	switch value := r.Error.(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(strings.TrimSpace(value), "true")
+	}
+
+	return false
+}
+
+// incomplete reports an answer that is neither "not found" nor has the
+// fields ViaCEP always fills.
+func (r ViaCEPResponse) incomplete() bool {
+	return !r.NotFound() && (r.CEP == "" || r.City == "" || r.State == "")
+}
+
+func (r ViaCEPResponse) ToAddressResult() AddressResult {
+	return cleanResult(AddressResult{
+		Source:       "ViaCEP",
+		State:        r.State,
+		City:         r.City,
+		Street:       r.Street,
+		ZipCode:      r.CEP,
+		Neighborhood: r.Neighborhood,
+	})
+}
+
+type viaCEPProvider struct {
+	baseURL string
+}
+
+func NewViaCEPProvider(baseURL string) Provider {
+	return viaCEPProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p viaCEPProvider) Name() string {
+	return "ViaCEP"
+}
+
+func (p viaCEPProvider) BaseURL() string {
+	return p.baseURL
+}
+
+func (p viaCEPProvider) GetAddress(ctx context.Context, client *http.Client, cep string) (AddressResult, error) {
+	source := p.Name()
+	url := p.baseURL + "/" + cep + "/json"
+
+	response, err := doRequest(ctx, client, source, url)
+	if err != nil {
+		return AddressResult{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return AddressResult{}, fmt.Errorf("%s: unexpected status %d", source, response.StatusCode)
+	}
+
+	var viaCepResponse ViaCEPResponse
+	if err := decodeResponse(response, &viaCepResponse, responseSchema{provider: source, fields: viaCEPFields}); err != nil {
+		return AddressResult{}, fmt.Errorf("%s: %w", source, err)
+	}
+
+	if viaCepResponse.NotFound() {
+		return AddressResult{}, fmt.Errorf("%s: %w", source, ErrNotFound)
+	}
+
+	return viaCepResponse.ToAddressResult(), nil
+}
+
+func ViaCEP(ctx context.Context, client *http.Client, cep string) (AddressResult, error) {
+	return NewViaCEPProvider(VIACEP_BASE_URL).GetAddress(ctx, client, cep)
+}
@@ -0,0 +1,89 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DEFAULT_WARM_UP_TIMEOUT bounds how long WarmUp waits for each provider.
+const DEFAULT_WARM_UP_TIMEOUT = 2 * time.Second
+
+var ErrWarmUpFailed = errors.New("warm-up failed")
+
+// WarmUp sends a HEAD request to the base URL of every enabled provider,
+// concurrently, so the DNS lookup and the TCP and TLS handshakes are done
+// and the connection is left idle for the first lookups to reuse. Each
+// provider gets at most DEFAULT_WARM_UP_TIMEOUT and any HTTP answer counts,
+// whatever its status. The outcome for each provider is logged; providers
+// without a base URL are skipped. A host that is down does not stop the
+// others: WarmUp only fails, with ErrWarmUpFailed, when no provider could be
+// warmed up.
+func (s *AddressService) WarmUp(ctx context.Context) error {
+	config := s.settings()
+
+	var mu sync.Mutex
+	var errs []error
+	warmed := 0
+
+	var group errgroup.Group
+	for _, provider := range config.providers {
+		url := baseURL(provider)
+		if url == "" {
+			config.logger.Debug("provider has no base URL, not warmed up", "provider", provider.Name())
+			continue
+		}
+
+		group.Go(func() error {
+			client, _ := config.clientFor(provider.Name())
+			start := time.Now()
+			err := warmUp(ctx, client, url)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				config.logger.Warn("provider warm-up failed", "provider", provider.Name(), "error", err)
+				errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+				return nil
+			}
+
+			config.logger.Info("provider warmed up", "provider", provider.Name(), "duration", time.Since(start))
+			warmed++
+			return nil
+		})
+	}
+	group.Wait()
+
+	if warmed == 0 && len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrWarmUpFailed, providerErrors(errs))
+	}
+
+	return nil
+}
+
+func warmUp(ctx context.Context, client *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_WARM_UP_TIMEOUT)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	// The body has to be read to the end for the connection to go back to
+	// the idle pool.
+	io.Copy(io.Discard, response.Body)
+	return response.Body.Close()
+}
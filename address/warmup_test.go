@@ -0,0 +1,81 @@
+package address_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// connectionCounting serves ViaCEP answers for sé, and 405 to the HEAD of
+// the warm-up, counting the connections clients open.
+func connectionCounting(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cep": "01001-000", "logradouro": "Praça da Sé", "localidade": "São Paulo", "uf": "SP"}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return server, &connections
+}
+
+func TestWarmUpLeavesAConnectionForTheLookup(t *testing.T) {
+	server, connections := connectionCounting(t)
+	service := newService(t, address.NewViaCEPProvider(server.URL+"/ws"))
+
+	if err := service.WarmUp(context.Background()); err != nil {
+		t.Fatalf("WarmUp: %v, want any HTTP answer to count", err)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Fatalf("warm-up opened %d connections, want 1", n)
+	}
+
+	if _, err := service.Execute("01001000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("%d connections after the lookup, want it to reuse the warm one", n)
+	}
+}
+
+func TestWarmUpFailsOnlyWhenNoProviderIsWarmed(t *testing.T) {
+	up, _ := connectionCounting(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	service := newService(t,
+		address.NewViaCEPProvider(down.URL+"/ws"),
+		address.NewBrasilAPIProvider(up.URL+"/api/cep/v1"),
+	)
+	if err := service.WarmUp(context.Background()); err != nil {
+		t.Errorf("WarmUp with one provider up: %v, want nil", err)
+	}
+
+	service = newService(t, address.NewViaCEPProvider(down.URL+"/ws"))
+	if err := service.WarmUp(context.Background()); !errors.Is(err, address.ErrWarmUpFailed) {
+		t.Errorf("WarmUp with every provider down: %v, want ErrWarmUpFailed", err)
+	}
+
+	// Providers without a base URL are skipped, not failed.
+	service = newService(t, addresstest.NewMockProvider("Mock").Returns(sé))
+	if err := service.WarmUp(context.Background()); err != nil {
+		t.Errorf("WarmUp without base URLs: %v, want nil", err)
+	}
+}
@@ -0,0 +1,49 @@
+package address
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// MarshalXML encodes the result as an <address> element, whatever element
+// name it is encoded under, with its source as an attribute.
+func (r AddressResult) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plain AddressResult
+
+	start.Name = xml.Name{Local: "address"}
+	return e.EncodeElement(plain(r), start)
+}
+
+func (r *AddressResult) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain AddressResult
+
+	var decoded plain
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+
+	*r = AddressResult(decoded)
+	return nil
+}
+
+type xmlAddresses struct {
+	XMLName   xml.Name        `xml:"addresses"`
+	Addresses []AddressResult `xml:"address"`
+}
+
+// WriteXML writes results to w as an <addresses> document, with the XML
+// header and one <address> element per result.
+func WriteXML(w io.Writer, results []AddressResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(xmlAddresses{Addresses: results}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
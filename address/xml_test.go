@@ -0,0 +1,74 @@
+package address_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func TestXMLRoundTrip(t *testing.T) {
+	result := address.AddressResult{
+		ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP",
+		Location:   &address.Coordinates{Latitude: -23.5503, Longitude: -46.6339},
+		Confidence: address.CONFIDENCE_CONFLICT,
+		Conflicts:  address.FieldDiffs{{Field: "street", A: "Praça da Sé", B: "Rua Direita", Substantive: true}},
+	}
+
+	data, err := xml.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`<address source="ViaCEP">`)) {
+		t.Errorf("marshaled %s, want an <address> element with the source attribute", data)
+	}
+
+	var decoded address.AddressResult
+	if err := xml.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, result) {
+		t.Errorf("round trip gave %+v, want %+v", decoded, result)
+	}
+}
+
+func TestXMLLeavesOutRawAndHeader(t *testing.T) {
+	result := sé
+	result.Raw = []byte(`{"cep":"01001-000"}`)
+	result.Header = http.Header{"X-Secret": {"s3cret"}}
+
+	var b strings.Builder
+	// Whatever name the caller picks, the element is <address>.
+	if err := xml.NewEncoder(&b).EncodeElement(result, xml.StartElement{Name: xml.Name{Local: "result"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.HasPrefix(got, "<address") || strings.Contains(got, "01001-000") || strings.Contains(got, "s3cret") {
+		t.Errorf("encoded %s, want an <address> without the raw payload or headers", got)
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	var b strings.Builder
+	if err := address.WriteXML(&b, []address.AddressResult{sé}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := b.String()
+	if !strings.HasPrefix(got, xml.Header+"<addresses>\n  <address") || !strings.HasSuffix(got, "</addresses>\n") {
+		t.Errorf("WriteXML wrote\n%s", got)
+	}
+
+	var decoded struct {
+		Addresses []address.AddressResult `xml:"address"`
+	}
+	if err := xml.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Addresses) != 1 || decoded.Addresses[0].Street != sé.Street {
+		t.Errorf("decoded %+v, want sé", decoded.Addresses)
+	}
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+const API_KEYS_ENV = "ADDRESS_API_KEYS"
+
+// loadAPIKeys reads the keys file, if any, and the comma-separated
+// "label:key" pairs of ADDRESS_API_KEYS. ok is false when neither is set,
+// meaning authentication stays off.
+func loadAPIKeys(path string, lookupEnv func(string) (string, bool)) (keys []httpapi.APIKey, ok bool, err error) {
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+
+		keys, err = httpapi.ParseAPIKeys(file)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", path, err)
+		}
+		ok = true
+	}
+
+	if value, set := lookupEnv(API_KEYS_ENV); set && value != "" {
+		envKeys, err := httpapi.ParseAPIKeyList(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", API_KEYS_ENV, err)
+		}
+		keys = append(keys, envKeys...)
+		ok = true
+	}
+
+	return keys, ok, nil
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// BENCH_ITERATIONS is how many lookups bench sends each provider by default,
+// waiting BENCH_DELAY between two lookups to the same provider.
+const (
+	BENCH_ITERATIONS = 50
+	BENCH_DELAY      = 100 * time.Millisecond
+)
+
+// MAX_HEDGE_ERROR_RATE is the error rate above which bench does not
+// recommend a provider to be queried first.
+const MAX_HEDGE_ERROR_RATE = 0.2
+
+// benchStats is the latency distribution of one provider, over the lookups
+// that succeeded.
+type benchStats struct {
+	Provider  string  `json:"provider"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	MinMS     int64   `json:"min_ms"`
+	P50MS     int64   `json:"p50_ms"`
+	P95MS     int64   `json:"p95_ms"`
+	MaxMS     int64   `json:"max_ms"`
+	// LastError is the error of the last failed lookup.
+	LastError string `json:"last_error,omitempty"`
+
+	min, p50, p95, max time.Duration
+}
+
+// benchReport is what bench prints. HedgeDelayMS is zero, and Primary empty,
+// when no recommendation could be made.
+type benchReport struct {
+	CEP          string       `json:"cep"`
+	Iterations   int          `json:"iterations"`
+	Cold         bool         `json:"cold"`
+	Interrupted  bool         `json:"interrupted,omitempty"`
+	Providers    []benchStats `json:"providers"`
+	Primary      string       `json:"recommended_primary,omitempty"`
+	HedgeDelayMS int64        `json:"recommended_hedge_delay_ms,omitempty"`
+}
+
+func runBench(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "bench")
+	var global globalOptions
+	global.register(flags)
+	iterations := flags.Int("iterations", BENCH_ITERATIONS, "number of lookups sent to each provider")
+	delay := flags.Duration("delay", BENCH_DELAY, "time to wait between two lookups to the same provider")
+	cold := flags.Bool("cold", false, "open a new connection for every lookup instead of reusing one")
+	jsonOutput := flags.Bool("json", false, "print the results as JSON")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	if err := global.resolve(env, flags); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	if *iterations < 1 {
+		fmt.Fprintf(env.stderr, "--iterations must be positive, got %d\n", *iterations)
+		return EXIT_USAGE
+	}
+
+	if *delay < 0 {
+		fmt.Fprintf(env.stderr, "--delay must not be negative, got %s\n", *delay)
+		return EXIT_USAGE
+	}
+
+	cep := address.HEALTH_CHECK_CEP
+	if flags.NArg() == 1 {
+		cep = flags.Arg(0)
+	}
+	if _, err := address.NormalizeCEP(cep); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_INVALID_CEP
+	}
+
+	// bench measures every provider by default, and takes "all" for that
+	// too.
+	if strings.EqualFold(strings.TrimSpace(global.providers), "all") {
+		global.providers = ""
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Each provider gets a service of its own, so the providers are measured
+	// at the same time without one's lookups waiting for another's.
+	var services []*address.AddressService
+	defer func() {
+		for _, service := range services {
+			service.Close()
+		}
+	}()
+
+	probe, err := global.newService(ctx, env)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+	services = append(services, probe)
+
+	names := probe.EnabledProviderNames()
+	for i, name := range names {
+		service := probe
+		if i > 0 {
+			if service, err = global.newService(ctx, env); err != nil {
+				fmt.Fprintln(env.stderr, err.Error())
+				return EXIT_USAGE
+			}
+			services = append(services, service)
+		}
+
+		if err := service.SetProviders(name); err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+		if *cold {
+			service.SetTransportMiddleware(closeConnections)
+		}
+	}
+
+	report := benchReport{CEP: cep, Iterations: *iterations, Cold: *cold, Providers: make([]benchStats, len(names))}
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Providers[i] = benchProvider(ctx, services[i], name, cep, *iterations, *delay, *cold)
+		}()
+	}
+	wg.Wait()
+
+	report.Interrupted = ctx.Err() != nil
+	report.Primary, report.HedgeDelayMS = recommendHedge(report.Providers)
+
+	if *jsonOutput {
+		if err := json.NewEncoder(env.stdout).Encode(report); err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+	} else {
+		writeBench(env.stdout, report)
+	}
+
+	if report.Interrupted {
+		return EXIT_INTERRUPTED
+	}
+	if !slices.ContainsFunc(report.Providers, func(stats benchStats) bool { return stats.Errors < stats.Requests }) {
+		return EXIT_PROVIDERS_FAILED
+	}
+	return EXIT_SUCCESS
+}
+
+// benchProvider looks cep up iterations times, one lookup after the other,
+// on a service that only queries the provider name, and returns the latency
+// distribution. Unless cold, the connection is opened before the first
+// lookup, so only steady-state lookups are measured.
+func benchProvider(ctx context.Context, service *address.AddressService, name string, cep string, iterations int, delay time.Duration, cold bool) benchStats {
+	if !cold {
+		// A failed warm-up shows up as failed lookups.
+		service.WarmUp(ctx)
+	}
+
+	latencies := make([]time.Duration, 0, iterations)
+	stats := benchStats{Provider: name}
+	for i := 0; i < iterations; i++ {
+		if i > 0 && delay > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		start := time.Now()
+		_, err := service.ExecuteContext(ctx, cep)
+		latency := time.Since(start)
+
+		// A lookup cut short by an interrupt measured nothing.
+		if ctx.Err() != nil {
+			break
+		}
+
+		stats.Requests++
+		if err != nil {
+			stats.Errors++
+			stats.LastError = err.Error()
+			continue
+		}
+		latencies = append(latencies, latency)
+	}
+
+	return summarizeLatencies(stats, latencies)
+}
+
+// summarizeLatencies fills the distribution of stats from latencies.
+func summarizeLatencies(stats benchStats, latencies []time.Duration) benchStats {
+	if stats.Requests > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	stats.min, stats.max = sorted[0], sorted[len(sorted)-1]
+	stats.p50, stats.p95 = percentile(sorted, 50), percentile(sorted, 95)
+
+	stats.MinMS, stats.P50MS = stats.min.Milliseconds(), stats.p50.Milliseconds()
+	stats.P95MS, stats.MaxMS = stats.p95.Milliseconds(), stats.max.Milliseconds()
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted by the nearest-rank
+// method: the smallest value at least p percent of the values are less than
+// or equal to.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// recommendHedge picks the provider with the lowest median among those that
+// failed at most MAX_HEDGE_ERROR_RATE of their lookups, to be queried first,
+// and its p95 as the delay before querying the others: past it, the primary
+// is having one of its slowest 5% of lookups and a second provider is worth
+// its load. There is no recommendation unless another provider answered too.
+func recommendHedge(providers []benchStats) (string, int64) {
+	answered := 0
+	var primary *benchStats
+	for i := range providers {
+		stats := &providers[i]
+		if stats.Errors == stats.Requests {
+			continue
+		}
+		answered++
+
+		if stats.ErrorRate > MAX_HEDGE_ERROR_RATE {
+			continue
+		}
+		if primary == nil || stats.p50 < primary.p50 {
+			primary = stats
+		}
+	}
+
+	if primary == nil || answered < 2 {
+		return "", 0
+	}
+
+	return primary.Provider, max(primary.p95.Round(time.Millisecond).Milliseconds(), 1)
+}
+
+func writeBench(w io.Writer, report benchReport) {
+	rows := [][]string{{"PROVIDER", "REQUESTS", "ERRORS", "MIN", "P50", "P95", "MAX"}}
+	for _, stats := range report.Providers {
+		row := []string{stats.Provider, strconv.Itoa(stats.Requests), fmt.Sprintf("%d (%.0f%%)", stats.Errors, stats.ErrorRate*100)}
+		if stats.Errors == stats.Requests {
+			row = append(row, "-", "-", "-", "-")
+		} else {
+			row = append(row, formatDuration(stats.min), formatDuration(stats.p50), formatDuration(stats.p95), formatDuration(stats.max))
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, value := range row {
+			widths[i] = max(widths[i], len(value))
+		}
+	}
+
+	for _, row := range rows {
+		writeTableLine(w, row, widths, "", false)
+	}
+
+	fmt.Fprintln(w)
+	for _, stats := range report.Providers {
+		if stats.LastError != "" {
+			fmt.Fprintf(w, "%s last error: %s\n", stats.Provider, stats.LastError)
+		}
+	}
+
+	mode := "reused connections"
+	if report.Cold {
+		mode = "a new connection per lookup"
+	}
+	fmt.Fprintf(w, "CEP %s, %d lookups per provider over %s", report.CEP, report.Iterations, mode)
+	if report.Interrupted {
+		fmt.Fprint(w, " (interrupted)")
+	}
+	fmt.Fprintln(w)
+
+	if report.Primary == "" {
+		fmt.Fprintf(w, "no hedge delay recommended: it needs at least two providers that answered, one failing at most %.0f%% of its lookups\n", MAX_HEDGE_ERROR_RATE*100)
+		return
+	}
+	fmt.Fprintf(w, "recommended hedge delay: %s (p95 of %s, the fastest provider by median; query it first)\n",
+		formatDuration(time.Duration(report.HedgeDelayMS)*time.Millisecond), report.Primary)
+}
+
+// closeConnections makes every provider request use a connection of its own,
+// closed once the response is read, for bench --cold.
+func closeConnections(next http.RoundTripper) http.RoundTripper {
+	return benchTransport{next: next}
+}
+
+type benchTransport struct {
+	next http.RoundTripper
+}
+
+func (t benchTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Close = true
+	return t.next.RoundTrip(request)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 20; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		values []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{sorted, 50, 10 * time.Millisecond},
+		{sorted, 95, 19 * time.Millisecond},
+		{sorted, 100, 20 * time.Millisecond},
+		{sorted, 0, time.Millisecond},
+		{sorted[:1], 95, time.Millisecond},
+		{sorted[:3], 50, 2 * time.Millisecond},
+		{nil, 50, 0},
+	}
+
+	for _, test := range tests {
+		if got := percentile(test.values, test.p); got != test.want {
+			t.Errorf("p%v of %d values = %v, want %v", test.p, len(test.values), got, test.want)
+		}
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	latencies := []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond}
+	stats := summarizeLatencies(benchStats{Provider: "A", Requests: 4, Errors: 1}, latencies)
+
+	if stats.ErrorRate != 0.25 || stats.MinMS != 10 || stats.P50MS != 20 || stats.P95MS != 30 || stats.MaxMS != 30 {
+		t.Errorf("stats = %+v, want 25%% errors and 10/20/30/30ms", stats)
+	}
+	if latencies[0] != 30*time.Millisecond {
+		t.Error("summarizeLatencies sorted its argument in place")
+	}
+
+	stats = summarizeLatencies(benchStats{Provider: "A", Requests: 2, Errors: 2}, nil)
+	if stats.ErrorRate != 1 || stats.MaxMS != 0 {
+		t.Errorf("stats = %+v, want every lookup failed and no latencies", stats)
+	}
+}
+
+func TestRecommendHedge(t *testing.T) {
+	provider := func(name string, requests, errors int, p50, p95 time.Duration) benchStats {
+		stats := benchStats{Provider: name, Requests: requests, Errors: errors, p50: p50, p95: p95}
+		stats.ErrorRate = float64(errors) / float64(requests)
+		return stats
+	}
+
+	tests := []struct {
+		name      string
+		providers []benchStats
+		primary   string
+		delay     int64
+	}{
+		{
+			name: "fastest median",
+			providers: []benchStats{
+				provider("Slow", 10, 0, 80*time.Millisecond, 120*time.Millisecond),
+				provider("Fast", 10, 0, 40*time.Millisecond, 95*time.Millisecond),
+			},
+			primary: "Fast",
+			delay:   95,
+		},
+		{
+			name: "fastest fails too often",
+			providers: []benchStats{
+				provider("Slow", 10, 0, 80*time.Millisecond, 120*time.Millisecond),
+				provider("Flaky", 10, 3, 40*time.Millisecond, 95*time.Millisecond),
+			},
+			primary: "Slow",
+			delay:   120,
+		},
+		{
+			name: "only one answered",
+			providers: []benchStats{
+				provider("Up", 10, 0, 40*time.Millisecond, 95*time.Millisecond),
+				provider("Down", 10, 10, 0, 0),
+			},
+		},
+		{
+			name: "sub-millisecond",
+			providers: []benchStats{
+				provider("A", 10, 0, 100*time.Microsecond, 200*time.Microsecond),
+				provider("B", 10, 0, time.Millisecond, 2*time.Millisecond),
+			},
+			primary: "A",
+			delay:   1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary, delay := recommendHedge(test.providers)
+			if primary != test.primary || delay != test.delay {
+				t.Errorf("recommendHedge = %q, %dms, want %q, %dms", primary, delay, test.primary, test.delay)
+			}
+		})
+	}
+}
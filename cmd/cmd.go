@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const PROGRAM_NAME = "cep-lookup"
+
+// environment is what a command runs against. configDirs lists the
+// directories searched for CONFIG_FILE_NAME when --config is not given, in
+// order; nil searches none.
+type environment struct {
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	lookupEnv  func(string) (string, bool)
+	configDirs func() []string
+	newService func(ctx context.Context) *address.AddressService
+}
+
+type command struct {
+	name    string
+	summary string
+	usage   string
+	run     func(ctx context.Context, env *environment, args []string) int
+}
+
+func commandList() []command {
+	return []command{
+		{name: "lookup", summary: "resolve one or more CEPs", usage: "lookup [flags] <cep> [cep...] | -", run: runLookup},
+		{name: "batch", summary: "resolve CEPs read from a file or stdin", usage: "batch [flags] [--input file]", run: runBatch},
+		{name: "serve", summary: "run the HTTP server", usage: "serve [flags]", run: runServe},
+		{name: "providers", summary: "list registered providers", usage: "providers [flags]", run: runProviders},
+		{name: "interactive", summary: "start an interactive prompt (also -i)", usage: "interactive [flags]", run: runInteractive},
+		{name: "version", summary: "print version and build information", usage: "version [--json]", run: runVersion},
+		{name: "compare", summary: "compare what each provider returns for a CEP", usage: "compare [flags] <cep>", run: runCompare},
+		{name: "bench", summary: "measure the latency of each provider", usage: "bench [flags] [cep]", run: runBench},
+		{name: "doctor", summary: "diagnose connectivity and configuration problems", usage: "doctor [flags]", run: runDoctor},
+	}
+}
+
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	env := &environment{
+		stdin:      stdin,
+		stdout:     stdout,
+		stderr:     stderr,
+		lookupEnv:  os.LookupEnv,
+		configDirs: defaultConfigDirs,
+		newService: address.NewAddressService,
+	}
+
+	return env.run(ctx, args)
+}
+
+func (env *environment) run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		printHelp(env.stderr)
+		return EXIT_USAGE
+	}
+
+	switch args[0] {
+	case "help", "-h", "--help":
+		return env.help(ctx, args[1:])
+	case "--version", "-version":
+		return runVersion(ctx, env, args[1:])
+	case "-i", "--interactive":
+		return runInteractive(ctx, env, args[1:])
+	}
+
+	command, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n\n", args[0])
+		printHelp(env.stderr)
+		return EXIT_USAGE
+	}
+
+	return command.run(ctx, env, args[1:])
+}
+
+func (env *environment) help(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		printHelp(env.stdout)
+		return EXIT_SUCCESS
+	}
+
+	command, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(env.stderr, "unknown command %q\n", args[0])
+		return EXIT_USAGE
+	}
+
+	helpEnv := *env
+	helpEnv.stderr = env.stdout
+	return command.run(ctx, &helpEnv, []string{"-h"})
+}
+
+func findCommand(name string) (command, bool) {
+	for _, command := range commandList() {
+		if command.name == name {
+			return command, true
+		}
+	}
+
+	return command{}, false
+}
+
+func printHelp(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n       %s -i [flags]\n\ncommands:\n", PROGRAM_NAME, PROGRAM_NAME)
+	for _, command := range commandList() {
+		fmt.Fprintf(w, "  %-12s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintf(w, "\nrun \"%s help <command>\" for the flags of a command\n", PROGRAM_NAME)
+}
+
+func newFlagSet(env *environment, command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(PROGRAM_NAME+" "+command, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		if c, ok := findCommand(command); ok {
+			fmt.Fprintf(flags.Output(), "usage: %s %s\n\n%s\n\nflags:\n", PROGRAM_NAME, c.usage, c.summary)
+		}
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+// parseFlags returns the exit code to stop with when parsing does not
+// succeed; -h is a successful stop.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return EXIT_SUCCESS, false
+	}
+
+	if err != nil {
+		return EXIT_USAGE, false
+	}
+
+	return EXIT_SUCCESS, true
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestSubcommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		// stdout and stderr are what each must contain.
+		stdout, stderr string
+	}{
+		{name: "no command", code: EXIT_USAGE, stderr: "usage: cep-lookup <command> [flags]"},
+		{name: "unknown command", args: []string{"resolve"}, code: EXIT_USAGE, stderr: `unknown command "resolve"`},
+		{name: "help", args: []string{"help"}, code: EXIT_SUCCESS, stdout: "  batch        resolve CEPs read from a file or stdin"},
+		{name: "help of a command", args: []string{"help", "batch"}, code: EXIT_SUCCESS, stdout: "usage: cep-lookup batch [flags] [--input file]"},
+		{name: "help of an unknown command", args: []string{"help", "resolve"}, code: EXIT_USAGE, stderr: `unknown command "resolve"`},
+		{name: "-h of a command", args: []string{"serve", "-h"}, code: EXIT_SUCCESS, stderr: "usage: cep-lookup serve [flags]"},
+		{name: "unknown flag", args: []string{"lookup", "--bogus", "01001000"}, code: EXIT_USAGE, stderr: "flag provided but not defined: -bogus"},
+		{name: "lookup", args: []string{"lookup", "01001000"}, code: EXIT_SUCCESS, stdout: "Praça da Sé"},
+		{name: "lookup from stdin", args: []string{"lookup", "-"}, stdin: "01001000\n", code: EXIT_SUCCESS, stdout: "Praça da Sé"},
+		{name: "batch", args: []string{"batch"}, stdin: "01001000\n", code: EXIT_SUCCESS, stdout: "Praça da Sé"},
+		{name: "batch with arguments", args: []string{"batch", "01001000"}, code: EXIT_USAGE, stderr: "batch does not accept CEP arguments"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(test.stdin, addresstest.NewMockProvider("Mock").Returns(sé.Address))
+
+			if code := env.run(context.Background(), test.args); code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s", code, test.code, env.stderr.String())
+			}
+			if !strings.Contains(env.stdout.String(), test.stdout) {
+				t.Errorf("stdout = %q, want %q", env.stdout.String(), test.stdout)
+			}
+			if !strings.Contains(env.stderr.String(), test.stderr) {
+				t.Errorf("stderr = %q, want %q", env.stderr.String(), test.stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+var compareFields = []string{"street", "neighborhood", "city", "state", "zip"}
+
+type fieldComparison struct {
+	Field  string            `json:"field"`
+	Agree  bool              `json:"agree"`
+	Values map[string]string `json:"values"`
+}
+
+type providerComparison struct {
+	Provider string                 `json:"provider"`
+	Address  *address.AddressResult `json:"address,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	// diffs are the differences from the reference provider.
+	diffs address.FieldDiffs
+}
+
+type comparison struct {
+	CEP       string               `json:"cep"`
+	Agree     bool                 `json:"agree"`
+	Providers []providerComparison `json:"providers"`
+	Fields    []fieldComparison    `json:"fields"`
+	// reference is the first provider that answered, the others are
+	// diffed against it.
+	reference string
+}
+
+func runCompare(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "compare")
+	var global globalOptions
+	global.register(flags)
+	jsonOutput := flags.Bool("json", false, "print the comparison as JSON")
+	noColor := flags.Bool("no-color", false, "disable colored output")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	if err := global.resolve(env, flags); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	service, err := global.newService(ctx, env)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+	defer service.Close()
+
+	cep := flags.Arg(0)
+	results, err := service.ExecuteAll(cep)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return exitCodeFor(err)
+	}
+
+	result := compareResults(cep, results)
+
+	if *jsonOutput {
+		if err := json.NewEncoder(env.stdout).Encode(result); err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+	} else {
+		writeComparison(env.stdout, result, useColor(env.stdout, *noColor))
+	}
+
+	succeeded := 0
+	for _, provider := range result.Providers {
+		if provider.Address != nil {
+			succeeded++
+		}
+	}
+
+	switch {
+	case succeeded == 0:
+		return EXIT_PROVIDERS_FAILED
+	case !result.Agree:
+		return EXIT_DISAGREE
+	}
+
+	return EXIT_SUCCESS
+}
+
+func fieldValue(result address.AddressResult, field string) string {
+	switch field {
+	case "street":
+		return result.Street
+	case "neighborhood":
+		return result.Neighborhood
+	case "city":
+		return result.City
+	case "state":
+		return result.State
+	case "zip":
+		return result.ZipCode
+	}
+
+	return ""
+}
+
+func compareResults(cep string, results []address.ProviderResult) comparison {
+	result := comparison{CEP: cep, Agree: true}
+
+	for _, providerResult := range results {
+		provider := providerComparison{Provider: providerResult.Provider}
+		if providerResult.Err != nil {
+			provider.Error = providerResult.Err.Error()
+		} else {
+			addressResult := providerResult.Address
+			provider.Address = &addressResult
+		}
+
+		result.Providers = append(result.Providers, provider)
+	}
+
+	var reference *address.AddressResult
+	disagree := make(map[string]bool)
+	for i, provider := range result.Providers {
+		if provider.Address == nil {
+			continue
+		}
+
+		if reference == nil {
+			reference, result.reference = provider.Address, provider.Provider
+			continue
+		}
+
+		diffs := address.Diff(*reference, *provider.Address)
+		result.Providers[i].diffs = diffs
+		for _, diff := range diffs {
+			if diff.Substantive {
+				disagree[diff.Field] = true
+			}
+		}
+	}
+
+	for _, field := range compareFields {
+		comparison := fieldComparison{Field: field, Agree: !disagree[field], Values: make(map[string]string)}
+
+		for _, provider := range result.Providers {
+			if provider.Address != nil {
+				comparison.Values[provider.Provider] = fieldValue(*provider.Address, field)
+			}
+		}
+
+		if !comparison.Agree {
+			result.Agree = false
+		}
+
+		result.Fields = append(result.Fields, comparison)
+	}
+
+	return result
+}
+
+func writeComparison(w io.Writer, result comparison, color bool) {
+	header := []string{"", "FIELD"}
+	for _, provider := range result.Providers {
+		header = append(header, provider.Provider)
+	}
+
+	rows := [][]string{header}
+	for _, field := range result.Fields {
+		marker := ""
+		if !field.Agree {
+			marker = "*"
+		}
+
+		row := []string{marker, field.Field}
+		for _, provider := range result.Providers {
+			if provider.Address == nil {
+				row = append(row, "-")
+				continue
+			}
+
+			row = append(row, truncate(field.Values[provider.Provider], MAX_COLUMN_WIDTH))
+		}
+
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, value := range row {
+			widths[i] = max(widths[i], len([]rune(value)))
+		}
+	}
+
+	for i, row := range rows {
+		switch {
+		case i == 0:
+			writeTableLine(w, row, widths, colorHeader, color)
+		case row[0] == "*":
+			writeTableLine(w, row, widths, colorError, color)
+		default:
+			writeTableLine(w, row, widths, "", color)
+		}
+	}
+
+	for _, provider := range result.Providers {
+		if provider.Error != "" {
+			fmt.Fprintf(w, "%s: error: %s\n", provider.Provider, provider.Error)
+		}
+	}
+
+	for _, provider := range result.Providers {
+		if len(provider.diffs) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s vs %s:\n", result.reference, provider.Provider)
+		for _, line := range strings.Split(strings.TrimSuffix(provider.diffs.String(), "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestCompare(t *testing.T) {
+	elsewhere := sé.Address
+	elsewhere.Street = "Rua Direita"
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		alpha, beta *addresstest.MockProvider
+		code        int
+		// want are patterns of stdout lines.
+		want []string
+	}{
+		{
+			name:  "agree",
+			alpha: addresstest.NewMockProvider("Alpha").Returns(sé.Address),
+			beta:  addresstest.NewMockProvider("Beta").Returns(sé.Address),
+			code:  EXIT_SUCCESS,
+			want:  []string{`^ +street +Praça da Sé +Praça da Sé$`},
+		},
+		{
+			name:  "differ",
+			alpha: addresstest.NewMockProvider("Alpha").Returns(sé.Address),
+			beta:  addresstest.NewMockProvider("Beta").Returns(elsewhere),
+			code:  EXIT_DISAGREE,
+			want:  []string{`^\* +street +Praça da Sé +Rua Direita$`, `^ +city +São Paulo +São Paulo$`, `^Alpha vs Beta:$`},
+		},
+		{
+			name:  "one fails",
+			alpha: addresstest.NewMockProvider("Alpha").Fails(down),
+			beta:  addresstest.NewMockProvider("Beta").Returns(sé.Address),
+			code:  EXIT_SUCCESS,
+			want:  []string{`^ +street +- +Praça da Sé$`, `^Alpha: error: .*connection refused$`},
+		},
+		{
+			name:  "all fail",
+			alpha: addresstest.NewMockProvider("Alpha").Fails(down),
+			beta:  addresstest.NewMockProvider("Beta").Fails(down),
+			code:  EXIT_PROVIDERS_FAILED,
+			want:  []string{`^ +street +- +-$`, `^Alpha: error: .*connection refused$`, `^Beta: error: .*connection refused$`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv("", test.alpha, test.beta)
+
+			if code := env.run(context.Background(), []string{"compare", "01001000"}); code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s%s", code, test.code, env.stdout.String(), env.stderr.String())
+			}
+			for _, want := range test.want {
+				if !regexp.MustCompile(`(?m)` + want).MatchString(env.stdout.String()) {
+					t.Errorf("stdout has no line matching %s:\n%s", want, env.stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCompareJSON(t *testing.T) {
+	elsewhere := sé.Address
+	elsewhere.Street = "Rua Direita"
+	env := newTestEnv("",
+		addresstest.NewMockProvider("Alpha").Returns(sé.Address),
+		addresstest.NewMockProvider("Beta").Returns(elsewhere),
+	)
+
+	if code := env.run(context.Background(), []string{"compare", "--json", "01001000"}); code != EXIT_DISAGREE {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_DISAGREE, env.stderr.String())
+	}
+
+	var got comparison
+	if err := json.Unmarshal(env.stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, env.stdout.String())
+	}
+	if got.CEP != "01001000" || got.Agree || len(got.Providers) != 2 {
+		t.Fatalf("comparison = %+v", got)
+	}
+	for _, field := range got.Fields {
+		if want := field.Field != "street"; field.Agree != want {
+			t.Errorf("%s agree = %v, want %v", field.Field, field.Agree, want)
+		}
+	}
+}
+
+func TestCompareInvalidCEP(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	env := newTestEnv("", provider)
+
+	if code := env.run(context.Background(), []string{"compare", "123"}); code != EXIT_INVALID_CEP {
+		t.Fatalf("exit code %d, want %d", code, EXIT_INVALID_CEP)
+	}
+	if !strings.Contains(env.stderr.String(), address.ErrInvalidCEP.Error()) {
+		t.Errorf("stderr = %q, want the invalid CEP", env.stderr.String())
+	}
+	provider.AssertCalls(t, 0)
+}
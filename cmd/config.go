@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+type envBinding struct {
+	name  string
+	flag  string
+	skips []string
+}
+
+// envBindings maps environment variables onto the flags they configure. A
+// variable is ignored when its flag, or any flag in skips, was set explicitly.
+var envBindings = []envBinding{
+	{name: "ADDRESS_TIMEOUT", flag: "timeout"},
+	{name: "ADDRESS_PROVIDERS", flag: "providers"},
+	{name: "ADDRESS_CONCURRENCY", flag: "concurrency"},
+	{name: "ADDRESS_OUTPUT_FORMAT", flag: "output-format", skips: []string{"json", "jsonl", "csv", "xml", "format"}},
+	{name: "ADDRESS_RETRIES", flag: "retries"},
+	{name: "ADDRESS_NOTIFY_SECRET", flag: "notify-secret"},
+}
+
+// settingNames remembers where each setting came from so validation errors
+// name the flag or the environment variable the user actually wrote.
+type settingNames map[string]string
+
+func (n settingNames) name(flagName string) string {
+	if name, ok := n[flagName]; ok {
+		return name
+	}
+
+	return "--" + flagName
+}
+
+// resolveConfig applies the environment and the config file over the flag
+// defaults, keeping the precedence flag > env > file > default. Unknown file
+// keys are reported to warn.
+func resolveConfig(flags *flag.FlagSet, lookupEnv func(string) (string, bool), file *configFile, warn io.Writer) (settingNames, error) {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make(settingNames)
+
+	for _, binding := range envBindings {
+		value, ok := lookupEnv(binding.name)
+		if !ok || flags.Lookup(binding.flag) == nil || explicit[binding.flag] || anyExplicit(explicit, binding.skips) {
+			continue
+		}
+
+		if err := flags.Set(binding.flag, value); err != nil {
+			return names, fmt.Errorf("invalid %s=%q: %w", binding.name, value, err)
+		}
+
+		names[binding.flag] = binding.name
+		explicit[binding.flag] = true
+	}
+
+	if file == nil {
+		return names, nil
+	}
+
+	for _, entry := range file.entries {
+		flagName, ok := fileBindings[entry.key]
+		if !ok {
+			fmt.Fprintf(warn, "warning: %s:%d: unknown key %q\n", file.path, entry.line, entry.key)
+			continue
+		}
+
+		if flags.Lookup(flagName) == nil || explicit[flagName] || (flagName == "output-format" && anyExplicit(explicit, []string{"json", "jsonl", "csv", "xml", "format"})) {
+			continue
+		}
+
+		if err := flags.Set(flagName, entry.value); err != nil {
+			return names, fmt.Errorf("%s:%d: invalid %s %q: %w", file.path, entry.line, entry.key, entry.value, err)
+		}
+
+		names[flagName] = fmt.Sprintf("%s:%d: %s", file.path, entry.line, entry.key)
+	}
+
+	return names, nil
+}
+
+func anyExplicit(explicit map[string]bool, flagNames []string) bool {
+	for _, name := range flagNames {
+		if explicit[name] {
+			return true
+		}
+	}
+
+	return false
+}
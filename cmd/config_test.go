@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// writeConfig writes content to a config file in a temporary directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), CONFIG_FILE_NAME)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, `# every key lookup reads
+timeout: 3s
+providers:
+  - ViaCEP
+  - BrasilAPI
+output_format: "jsonl"
+concurrency: 8
+retries: 2
+retry_backoff: 50ms
+rate_limit: 10
+proxy: http://proxy.local:3128
+ca_cert: `+filepath.Join(dir, "ca.pem")+`
+audit_log: `+filepath.Join(dir, "audit.jsonl")+`
+cache: `+filepath.Join(dir, "cache.db")+` # a SQLite file
+cache_ttl: 12h
+cors_origins: [https://example.com]
+colour: always
+`)
+	file, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &environment{stderr: &bytes.Buffer{}}
+	flags := newFlagSet(env, "lookup")
+	var o lookupOptions
+	o.register(flags)
+	if err := flags.Parse([]string{"--retries", "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	lookupEnv := func(name string) (string, bool) {
+		if name == "ADDRESS_CONCURRENCY" {
+			return "4", true
+		}
+		return "", false
+	}
+	names, err := resolveConfig(flags, lookupEnv, file, &warnings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := o.global
+	if g.timeout != 3*time.Second || g.providers != "ViaCEP,BrasilAPI" || o.outputFormat != "jsonl" || g.retryBackoff != 50*time.Millisecond || o.rateLimit != 10 {
+		t.Errorf("timeout %s, providers %q, output format %q, retry backoff %s, rate limit %g, want the file's", g.timeout, g.providers, o.outputFormat, g.retryBackoff, o.rateLimit)
+	}
+	if g.proxy != "http://proxy.local:3128" || g.caCert != filepath.Join(dir, "ca.pem") || g.auditLog != filepath.Join(dir, "audit.jsonl") {
+		t.Errorf("proxy %q, CA cert %q, audit log %q, want the file's", g.proxy, g.caCert, g.auditLog)
+	}
+	if o.cachePath != filepath.Join(dir, "cache.db") || o.cacheTTL != 12*time.Hour {
+		t.Errorf("cache %q for %s, want the file's", o.cachePath, o.cacheTTL)
+	}
+
+	// flag > env > file.
+	if g.retries != 1 {
+		t.Errorf("retries %d, want the flag's 1", g.retries)
+	}
+	if o.concurrency != 4 || names.name("concurrency") != "ADDRESS_CONCURRENCY" {
+		t.Errorf("concurrency %d from %s, want ADDRESS_CONCURRENCY's 4", o.concurrency, names.name("concurrency"))
+	}
+	if want := path + ":15: cache_ttl"; names.name("cache-ttl") != want {
+		t.Errorf("cache-ttl named %q, want %q", names.name("cache-ttl"), want)
+	}
+
+	// Keys of serve are skipped quietly, unknown keys are named.
+	if got := warnings.String(); got != "warning: "+path+":17: unknown key \"colour\"\n" {
+		t.Errorf("warnings = %q, want colour named", got)
+	}
+}
+
+func TestConfigFileIsFound(t *testing.T) {
+	const JSON, CSV = "output_format: json\n", "output_format: csv\n"
+
+	tests := []struct {
+		name      string
+		cwd, home string
+		want      string
+	}{
+		{"in the current directory", JSON, "", `"street"`},
+		{"in the home directory", "", CSV, "cep,street"},
+		{"in both", JSON, CSV, `"street"`},
+		{"in neither", "", "", "Praça da Sé"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cwd, home := t.TempDir(), t.TempDir()
+			for dir, content := range map[string]string{cwd: test.cwd, home: test.home} {
+				if content == "" {
+					continue
+				}
+				if err := os.WriteFile(filepath.Join(dir, CONFIG_FILE_NAME), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			env := newTestEnv("", addresstest.NewMockProvider("Mock").Returns(sé.Address))
+			env.configDirs = func() []string { return []string{cwd, home} }
+
+			if code := env.run(context.Background(), []string{"lookup", "01001000"}); code != EXIT_SUCCESS {
+				t.Fatalf("exit code %d\n%s", code, env.stderr.String())
+			}
+			if !strings.Contains(env.stdout.String(), test.want) {
+				t.Errorf("stdout = %q, want %q", env.stdout.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"list item without a key", "- ViaCEP\n", ":1: list item without a key"},
+		{"no colon", "timeout: 1s\ntimeout 2s\n", `:2: expected "key: value"`},
+		{"indented key", "timeout: 1s\n  retries: 2\n", `:2: expected "key: value"`},
+		{"wrong type", "retries: 2\ncache_ttl: a day\n", `:2: invalid cache_ttl "a day"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeConfig(t, test.content)
+			var stderr bytes.Buffer
+			env := &environment{
+				stdin:     strings.NewReader(""),
+				stdout:    &bytes.Buffer{},
+				stderr:    &stderr,
+				lookupEnv: func(string) (string, bool) { return "", false },
+			}
+
+			if code := env.run(context.Background(), []string{"lookup", "--config", path, "01001000"}); code != EXIT_USAGE {
+				t.Fatalf("exit code %d, want %d\n%s", code, EXIT_USAGE, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), path+test.want) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), path+test.want)
+			}
+		})
+	}
+}
+
+func TestCacheFromConfigFile(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	path := writeConfig(t, "cache: "+filepath.Join(t.TempDir(), "cache.db")+"\ncache_ttl: 1h\n")
+
+	for run := range 2 {
+		var stdout, stderr bytes.Buffer
+		env := &environment{
+			stdin:     strings.NewReader(""),
+			stdout:    &stdout,
+			stderr:    &stderr,
+			lookupEnv: func(string) (string, bool) { return "", false },
+			newService: func(ctx context.Context) *address.AddressService {
+				service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(provider)
+				service.SetProviders("Mock")
+				return service
+			},
+		}
+
+		if code := env.run(context.Background(), []string{"lookup", "--config", path, "--quiet", "01001000"}); code != EXIT_SUCCESS {
+			t.Fatalf("run %d: exit code %d\n%s", run, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "Praça da Sé") {
+			t.Errorf("run %d: stdout = %q, want the address", run, stdout.String())
+		}
+	}
+
+	// The second run answered from the file the first one wrote.
+	provider.AssertCalls(t, 1)
+}
+
+func TestSQLiteCacheStoreExpires(t *testing.T) {
+	store, err := newSQLiteCacheStore(filepath.Join(t.TempDir(), "cache.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Set("01001000", []byte("first"))
+	store.Set("01001000", []byte("second"))
+	if got, ok := store.Get("01001000"); !ok || string(got) != "second" {
+		t.Errorf("Get = %q, %t, want the replaced entry", got, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if got, ok := store.Get("01001000"); ok {
+		t.Errorf("Get = %q an hour later, want a miss", got)
+	}
+	if _, ok := store.Get("20040010"); ok {
+		t.Error("Get of an absent key hit")
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const CONFIG_FILE_NAME = ".cep-lookup.yaml"
+
+// fileBindings maps config file keys onto the flags they configure.
+var fileBindings = map[string]string{
+	"timeout":          "timeout",
+	"providers":        "providers",
+	"output_format":    "output-format",
+	"concurrency":      "concurrency",
+	"retries":          "retries",
+	"retry_backoff":    "retry-backoff",
+	"rate_limit":       "rate-limit",
+	"proxy":            "proxy",
+	"ca_cert":          "ca-cert",
+	"audit_log":        "audit-log",
+	"cache":            "cache",
+	"cache_ttl":        "cache-ttl",
+	"cors_origins":     "cors-origins",
+	"cors_methods":     "cors-methods",
+	"cors_headers":     "cors-headers",
+	"cors_max_age":     "cors-max-age",
+	"cors_credentials": "cors-credentials",
+}
+
+type configEntry struct {
+	key   string
+	value string
+	line  int
+}
+
+type configFile struct {
+	path    string
+	entries []configEntry
+}
+
+// defaultConfigDirs is the current directory, then the home directory.
+func defaultConfigDirs() []string {
+	dirs := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+
+	return dirs
+}
+
+// findConfigFile returns the config file in the first of dirs that has one,
+// or "" when none does.
+func findConfigFile(dirs []string) string {
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, CONFIG_FILE_NAME)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func loadConfigFile(path string) (*configFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseConfig(path, file)
+}
+
+// parseConfig reads the small YAML subset the config file needs: top-level
+// "key: value" pairs, comments, and lists written either inline ([a, b]) or
+// as "- item" lines below their key.
+func parseConfig(path string, r io.Reader) (*configFile, error) {
+	config := &configFile{path: path}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	listKey := -1
+
+	for scanner.Scan() {
+		lineNumber++
+		line := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey < 0 || !indented {
+				return nil, fmt.Errorf("%s:%d: list item without a key", path, lineNumber)
+			}
+
+			item := unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			entry := &config.entries[listKey]
+			if entry.value != "" {
+				entry.value += ","
+			}
+			entry.value += item
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || indented {
+			return nil, fmt.Errorf("%s:%d: expected \"key: value\"", path, lineNumber)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		listKey = -1
+		if value == "" {
+			listKey = len(config.entries)
+		} else if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			items := strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"), ",")
+			for i, item := range items {
+				items[i] = unquote(strings.TrimSpace(item))
+			}
+			value = strings.Join(items, ",")
+		} else {
+			value = unquote(value)
+		}
+
+		config.entries = append(config.entries, configEntry{key: key, value: value, line: lineNumber})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func stripComment(line string) string {
+	inQuote := rune(0)
+	for i, r := range line {
+		switch {
+		case inQuote != 0 && r == inQuote:
+			inQuote = 0
+		case inQuote == 0 && (r == '"' || r == '\''):
+			inQuote = r
+		case inQuote == 0 && r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
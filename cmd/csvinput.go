@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// csvInput picks the CEP out of the rows of a CSV file, by header name or by
+// 1-based index. With a name the first row must be the header; with an index
+// the first row is taken for a header only when the selected cell has no
+// digit in it.
+type csvInput struct {
+	reader *csv.Reader
+	column int
+	// name is the --cep-column value, for errors.
+	name   string
+	header []string
+	// first is the first row when it is not the header, read ahead while
+	// looking for one.
+	first     []string
+	firstLine int
+	width     int
+	// keep makes the rows be kept for --passthrough until their result is
+	// written.
+	keep    bool
+	records sync.Map
+}
+
+func newCSVInput(r io.Reader, column string, keep bool) (*csvInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	input := &csvInput{reader: reader, name: column, keep: keep}
+
+	first, err := reader.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(first) > 0 {
+		// A byte order mark, as spreadsheets write, is not part of the
+		// first column's name.
+		first[0] = strings.TrimPrefix(first[0], "\ufeff")
+		input.firstLine, _ = reader.FieldPos(0)
+		input.width = len(first)
+	}
+
+	if index, err := strconv.Atoi(column); err == nil {
+		if index < 1 {
+			return nil, fmt.Errorf("--cep-column must be a header name or a 1-based index, got %q", column)
+		}
+		input.column = index - 1
+
+		if input.column < len(first) && !strings.ContainsAny(first[input.column], "0123456789") {
+			input.header = first
+		} else {
+			input.first = first
+		}
+		return input, nil
+	}
+
+	input.header = first
+	input.column = -1
+	for i, name := range first {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+			input.column = i
+			break
+		}
+	}
+	if input.column < 0 && first != nil {
+		return nil, fmt.Errorf("--cep-column: no column %q in header %q", column, strings.Join(first, ","))
+	}
+
+	return input, nil
+}
+
+// countCSVRows counts the rows in r, the header included.
+func countCSVRows(r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	count := 0
+	for {
+		_, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// next returns the next data row, the line it starts on and the CEP in it;
+// a row without the CEP column comes with an error.
+func (c *csvInput) next() (record []string, line int, cep string, err error) {
+	if c.first != nil {
+		record, line, c.first = c.first, c.firstLine, nil
+	} else {
+		record, err = c.reader.Read()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		line, _ = c.reader.FieldPos(0)
+	}
+
+	if c.column >= len(record) {
+		return record, line, "", fmt.Errorf("%w: row has no column %s", address.ErrInvalidCEP, c.name)
+	}
+
+	return record, line, strings.TrimSpace(record[c.column]), nil
+}
+
+// passthrough returns what --passthrough adds to the output: the names of
+// the input columns, column_1 and so on when the file has no header, and
+// the row of each result.
+func (c *csvInput) passthrough() *passthrough {
+	header := c.header
+	if header == nil {
+		header = make([]string, c.width)
+		for i := range header {
+			header[i] = "column_" + strconv.Itoa(i+1)
+		}
+	}
+
+	return &passthrough{header: header, row: c.row}
+}
+
+func (c *csvInput) store(index int, record []string) {
+	if c.keep {
+		c.records.Store(index, record)
+	}
+}
+
+func (c *csvInput) row(index int) []string {
+	record, _ := c.records.LoadAndDelete(index)
+	row, _ := record.([]string)
+	return row
+}
+
+// passthrough carries the input rows --passthrough copies into the output.
+type passthrough struct {
+	header []string
+	row    func(index int) []string
+}
+
+// values returns the row of the result at index, cut or padded to the
+// width of the header.
+func (p *passthrough) values(index int) []string {
+	values := make([]string, len(p.header))
+	copy(values, p.row(index))
+	return values
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// readCEPs reads every row of input, returning the CEPs and the lines they
+// are on; rows that fail are kept as "!" and their line.
+func readCEPs(t *testing.T, input *csvInput) (ceps []string, lines []int) {
+	t.Helper()
+
+	for {
+		_, line, cep, err := input.next()
+		if errors.Is(err, io.EOF) {
+			return ceps, lines
+		}
+		if err != nil {
+			if !errors.Is(err, address.ErrInvalidCEP) {
+				t.Fatal(err)
+			}
+			cep = "!"
+		}
+		ceps = append(ceps, cep)
+		lines = append(lines, line)
+	}
+}
+
+func TestCSVInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		csv    string
+		column string
+		ceps   []string
+		lines  []int
+		header []string
+	}{
+		{
+			name:   "header name",
+			csv:    "id,CEP\n1,01001-000\n2, 20040010 \n",
+			column: "cep",
+			ceps:   []string{"01001-000", "20040010"},
+			lines:  []int{2, 3},
+			header: []string{"id", "CEP"},
+		},
+		{
+			name:   "byte order mark",
+			csv:    "\ufeffcep,id\n01001000,1\n",
+			column: "cep",
+			ceps:   []string{"01001000"},
+			lines:  []int{2},
+			header: []string{"cep", "id"},
+		},
+		{
+			name:   "index with header",
+			csv:    "id,cep\n1,01001000\n",
+			column: "2",
+			ceps:   []string{"01001000"},
+			lines:  []int{2},
+			header: []string{"id", "cep"},
+		},
+		{
+			name:   "index without header",
+			csv:    "1,01001000,x\n2,20040010,y\n",
+			column: "2",
+			ceps:   []string{"01001000", "20040010"},
+			lines:  []int{1, 2},
+			header: []string{"column_1", "column_2", "column_3"},
+		},
+		{
+			name:   "short row",
+			csv:    "id,cep\n1,01001000\n2\n3,20040010\n",
+			column: "cep",
+			ceps:   []string{"01001000", "!", "20040010"},
+			lines:  []int{2, 3, 4},
+			header: []string{"id", "cep"},
+		},
+		{
+			name:   "empty",
+			column: "1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := newCSVInput(strings.NewReader(test.csv), test.column, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ceps, lines := readCEPs(t, input)
+			if !reflect.DeepEqual(ceps, test.ceps) || !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("read %q on lines %v, want %q on lines %v", ceps, lines, test.ceps, test.lines)
+			}
+			if header := input.passthrough().header; test.header != nil && !reflect.DeepEqual(header, test.header) {
+				t.Errorf("passthrough header %q, want %q", header, test.header)
+			}
+		})
+	}
+}
+
+func TestCSVInputBadColumn(t *testing.T) {
+	for _, column := range []string{"0", "-1", "zip"} {
+		if _, err := newCSVInput(strings.NewReader("id,cep\n1,01001000\n"), column, false); err == nil || !strings.Contains(err.Error(), "--cep-column") {
+			t.Errorf("column %q: err = %v, want a --cep-column error", column, err)
+		}
+	}
+}
+
+func TestCSVInputPassthrough(t *testing.T) {
+	input, err := newCSVInput(strings.NewReader("id,cep,name\n1,01001000,Sé\n2,20040010\n"), "cep", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := 0; ; index++ {
+		record, _, _, err := input.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.store(index, record)
+	}
+
+	pass := input.passthrough()
+	if got, want := pass.values(1), []string{"2", "20040010", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("values(1) = %q, want %q padded to the header", got, want)
+	}
+	if got, want := pass.values(0), []string{"1", "01001000", "Sé"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("values(0) = %q, want %q", got, want)
+	}
+	// A row is handed out once, then forgotten.
+	if got := pass.values(0); !reflect.DeepEqual(got, []string{"", "", ""}) {
+		t.Errorf("values(0) again = %q, want it forgotten", got)
+	}
+}
+
+func TestCountCSVRows(t *testing.T) {
+	count, err := countCSVRows(strings.NewReader("id,cep\n1,01001000\n2\n\"3\",\"20040010\"\n"))
+	if err != nil || count != 4 {
+		t.Errorf("countCSVRows = %d, %v, want 4 rows", count, err)
+	}
+}
@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const (
+	DOCTOR_PASS = "pass"
+	DOCTOR_WARN = "warn"
+	DOCTOR_FAIL = "fail"
+)
+
+// MAX_CLOCK_SKEW is how far the local clock may drift from the providers'
+// before doctor warns about it.
+const MAX_CLOCK_SKEW = time.Minute
+
+// doctorCheck is the outcome of one doctor check. A failed critical check
+// makes doctor exit non-zero.
+type doctorCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Detail    string `json:"detail,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+}
+
+func passed(name string, detail string) doctorCheck {
+	return doctorCheck{Name: name, Status: DOCTOR_PASS, Critical: true, Detail: detail}
+}
+
+func failed(name string, detail string, hint string) doctorCheck {
+	return doctorCheck{Name: name, Status: DOCTOR_FAIL, Critical: true, Detail: detail, Hint: hint}
+}
+
+func runDoctor(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "doctor")
+	var global globalOptions
+	global.register(flags)
+	jsonOutput := flags.Bool("json", false, "print the checks as JSON")
+	checkTimeout := flags.Duration("check-timeout", address.DEFAULT_HEALTH_CHECK_TIMEOUT, "maximum time each DNS and connection check may take")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	checks := []doctorCheck{checkConfig(env, &global, flags)}
+
+	proxyCheck, proxyFor := checkProxy(env.lookupEnv, global.proxy)
+	checks = append(checks, proxyCheck)
+
+	service, err := global.newService(ctx, env)
+	if err != nil {
+		checks = append(checks, failed("service", err.Error(), "fix the setting named above and run doctor again"))
+		return writeDoctor(env, checks, *jsonOutput)
+	}
+	defer service.Close()
+
+	tlsConfig, err := doctorTLSConfig(global.caCert, global.insecure)
+	if err != nil {
+		checks = append(checks, failed("ca-cert", err.Error(), "point --ca-cert at a readable PEM file"))
+	}
+
+	dialer := &net.Dialer{Timeout: *checkTimeout}
+	for _, status := range service.ProviderStatuses() {
+		if !status.Enabled || status.BaseURL == "" {
+			continue
+		}
+
+		target, err := url.Parse(status.BaseURL)
+		if err != nil {
+			checks = append(checks, failed("url "+status.Name, err.Error(), "fix the provider's base URL"))
+			continue
+		}
+
+		proxy := proxyFor(target)
+		checkCtx, cancel := context.WithTimeout(ctx, *checkTimeout)
+		dns := checkDNS(checkCtx, net.DefaultResolver, status.Name, target, proxy)
+		checks = append(checks, dns)
+		// A host that does not resolve cannot be connected to either.
+		if dns.Status == DOCTOR_PASS {
+			checks = append(checks, checkConnection(checkCtx, dialer, tlsConfig, status.Name, target, proxy))
+		}
+		cancel()
+	}
+
+	// Raw payloads keep the response headers, whose Date the clock is
+	// checked against.
+	service.SetRawPayload(true)
+	results, err := service.ExecuteAll(address.HEALTH_CHECK_CEP)
+	if err != nil {
+		checks = append(checks, failed("lookup", err.Error(), "enable at least one provider with --providers"))
+	} else {
+		checks = append(checks, checkLookups(results)...)
+		if clock, ok := checkClock(time.Now(), results); ok {
+			checks = append(checks, clock)
+		}
+	}
+
+	return writeDoctor(env, checks, *jsonOutput)
+}
+
+func writeDoctor(env *environment, checks []doctorCheck, jsonOutput bool) int {
+	if jsonOutput {
+		if err := json.NewEncoder(env.stdout).Encode(checks); err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+	} else {
+		writeDoctorText(env.stdout, checks)
+	}
+
+	if slices.ContainsFunc(checks, func(check doctorCheck) bool { return check.Critical && check.Status == DOCTOR_FAIL }) {
+		return EXIT_ERROR
+	}
+	return EXIT_SUCCESS
+}
+
+func writeDoctorText(w io.Writer, checks []doctorCheck) {
+	width := 0
+	for _, check := range checks {
+		width = max(width, len(check.Name))
+	}
+
+	for _, check := range checks {
+		fmt.Fprintf(w, "%-4s  %-*s  %s\n", strings.ToUpper(check.Status), width, check.Name, check.Detail)
+		if check.Hint != "" && check.Status != DOCTOR_PASS {
+			fmt.Fprintf(w, "      %-*s  hint: %s\n", width, "", check.Hint)
+		}
+	}
+}
+
+// checkConfig resolves the config file and environment variables over the
+// flags, as every command does, and reports where the settings came from.
+func checkConfig(env *environment, global *globalOptions, flags *flag.FlagSet) doctorCheck {
+	if err := global.resolve(env, flags); err != nil {
+		return failed("config", err.Error(), "fix or unset the setting named above; flags override the environment, which overrides the config file")
+	}
+
+	var sources []string
+	if global.configPath != "" {
+		sources = append(sources, "config file "+global.configPath)
+	}
+	for _, binding := range envBindings {
+		if _, ok := env.lookupEnv(binding.name); ok {
+			sources = append(sources, binding.name)
+		}
+	}
+
+	if len(sources) == 0 {
+		return passed("config", "no config file or ADDRESS_* variables, using flags and defaults")
+	}
+	return passed("config", "valid: "+strings.Join(sources, ", "))
+}
+
+// checkProxy reports the proxy provider requests go through, from --proxy
+// or the proxy environment variables, and returns which proxy, if any, a
+// given URL is reached through.
+func checkProxy(lookupEnv func(string) (string, bool), proxyFlag string) (doctorCheck, func(*url.URL) *url.URL) {
+	direct := func(*url.URL) *url.URL { return nil }
+
+	if proxyFlag != "" {
+		proxy, err := parseDoctorProxy(proxyFlag)
+		if err != nil {
+			return failed("proxy", err.Error(), "--proxy takes a URL such as http://proxy:3128 or socks5://proxy:1080"), direct
+		}
+		return passed("proxy", "every provider request goes through --proxy "+proxy.Redacted()), func(*url.URL) *url.URL { return proxy }
+	}
+
+	getenv := func(names ...string) (string, string) {
+		for _, name := range names {
+			if value, ok := lookupEnv(name); ok && value != "" {
+				return name, value
+			}
+		}
+		return "", ""
+	}
+
+	config := httpproxy.Config{}
+	var found []string
+	for _, variable := range []struct {
+		names []string
+		value *string
+	}{
+		{[]string{"HTTPS_PROXY", "https_proxy"}, &config.HTTPSProxy},
+		{[]string{"HTTP_PROXY", "http_proxy"}, &config.HTTPProxy},
+		{[]string{"NO_PROXY", "no_proxy"}, &config.NoProxy},
+	} {
+		name, value := getenv(variable.names...)
+		if name == "" {
+			continue
+		}
+		*variable.value = value
+
+		if variable.value != &config.NoProxy {
+			proxy, err := parseDoctorProxy(value)
+			if err != nil {
+				return failed("proxy", name+": "+err.Error(), "fix or unset "+name), direct
+			}
+			value = proxy.Redacted()
+		}
+		found = append(found, name+"="+value)
+	}
+
+	if len(found) == 0 {
+		return passed("proxy", "no proxy configured, providers are reached directly"), direct
+	}
+
+	proxyFunc := config.ProxyFunc()
+	return passed("proxy", strings.Join(found, ", ")), func(target *url.URL) *url.URL {
+		proxy, _ := proxyFunc(target)
+		return proxy
+	}
+}
+
+func parseDoctorProxy(value string) (*url.URL, error) {
+	proxy, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(address.PROXY_SCHEMES, proxy.Scheme) || proxy.Host == "" {
+		return nil, fmt.Errorf("%q is not a proxy URL, want one of the schemes %v", proxy.Redacted(), address.PROXY_SCHEMES)
+	}
+	return proxy, nil
+}
+
+// doctorTLSConfig trusts what provider requests trust with --ca-cert and
+// --insecure.
+func doctorTLSConfig(caCert string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caCert == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(caCert)
+	if err != nil {
+		return config, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return config, fmt.Errorf("no PEM certificates in %s", caCert)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// checkDNS resolves the host of target, or of proxy when target is reached
+// through one, since the proxy then resolves target itself.
+func checkDNS(ctx context.Context, resolver *net.Resolver, provider string, target *url.URL, proxy *url.URL) doctorCheck {
+	name := "dns " + provider
+	host := target.Hostname()
+	if proxy != nil {
+		host = proxy.Hostname()
+	}
+
+	if net.ParseIP(host) != nil {
+		return passed(name, host+" is an IP address, nothing to resolve")
+	}
+
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		hint := "check the DNS servers in /etc/resolv.conf or your network settings"
+		if proxy != nil {
+			hint = "the proxy host does not resolve; check HTTPS_PROXY or --proxy"
+		}
+		return failed(name, err.Error(), hint)
+	}
+
+	check := passed(name, fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", ")))
+	check.LatencyMS = time.Since(start).Milliseconds()
+	return check
+}
+
+// checkConnection opens a TCP connection to target, and completes a TLS
+// handshake for https, or only connects to proxy when target is reached
+// through one.
+func checkConnection(ctx context.Context, dialer *net.Dialer, tlsConfig *tls.Config, provider string, target *url.URL, proxy *url.URL) doctorCheck {
+	name := "connect " + provider
+	endpoint := target
+	if proxy != nil {
+		endpoint = proxy
+	}
+	addr := net.JoinHostPort(endpoint.Hostname(), defaultPort(endpoint))
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		hint := "a firewall may block outbound connections to port " + defaultPort(endpoint) + "; if the network needs a proxy, set HTTPS_PROXY or --proxy"
+		if proxy != nil {
+			hint = "the proxy does not accept connections; check HTTPS_PROXY or --proxy"
+		}
+		return failed(name, err.Error(), hint)
+	}
+	defer conn.Close()
+
+	if proxy != nil {
+		check := passed(name, "proxy "+addr+" accepts connections")
+		check.LatencyMS = time.Since(start).Milliseconds()
+		return check
+	}
+
+	if target.Scheme != "https" {
+		check := passed(name, addr+" accepts connections")
+		check.LatencyMS = time.Since(start).Milliseconds()
+		return check
+	}
+
+	config := tlsConfig.Clone()
+	config.ServerName = target.Hostname()
+	if err := tls.Client(conn, config).HandshakeContext(ctx); err != nil {
+		return failed(name, "TLS handshake with "+addr+": "+err.Error(), tlsHint(err))
+	}
+
+	check := passed(name, addr+" accepts connections, TLS certificate verified")
+	if config.InsecureSkipVerify {
+		check.Detail = addr + " accepts connections, TLS certificate NOT verified (--insecure)"
+	}
+	check.LatencyMS = time.Since(start).Milliseconds()
+	return check
+}
+
+func defaultPort(target *url.URL) string {
+	if port := target.Port(); port != "" {
+		return port
+	}
+
+	switch target.Scheme {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	}
+	return "80"
+}
+
+func tlsHint(err error) string {
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+		return "the certificate looks expired or not yet valid, which usually means the local clock is wrong; check the system date"
+	}
+
+	var unknown x509.UnknownAuthorityError
+	if errors.As(err, &unknown) {
+		return "the certificate is signed by an unknown authority, as happens behind a proxy that re-encrypts traffic; trust its CA with --ca-cert"
+	}
+
+	return "something between you and the provider interferes with TLS; try from another network"
+}
+
+// checkLookups reports how each provider answered a live lookup.
+func checkLookups(results []address.ProviderResult) []doctorCheck {
+	checks := make([]doctorCheck, len(results))
+	for i, result := range results {
+		name := "lookup " + result.Provider
+		if result.Err != nil {
+			hint := "see the DNS and connection checks above for this provider"
+			if errors.Is(result.Err, address.ErrTimeout) {
+				hint = "the provider did not answer within --timeout; if the checks above pass, raise --timeout"
+			}
+			checks[i] = failed(name, result.Err.Error(), hint)
+			checks[i].LatencyMS = result.Latency.Milliseconds()
+			continue
+		}
+
+		checks[i] = passed(name, fmt.Sprintf("%s resolved to %s/%s", address.HEALTH_CHECK_CEP, result.Address.City, result.Address.State))
+		checks[i].LatencyMS = result.Latency.Milliseconds()
+	}
+
+	return checks
+}
+
+// checkClock compares now with the Date header of the responses in
+// results, reporting false when none had one.
+func checkClock(now time.Time, results []address.ProviderResult) (doctorCheck, bool) {
+	var skew time.Duration
+	found := false
+	for _, result := range results {
+		date, err := http.ParseTime(result.Address.Header.Get("Date"))
+		if err != nil {
+			continue
+		}
+
+		// Date has a resolution of one second and was sent during the
+		// lookup, so only the largest drift counts.
+		if drift := now.Sub(date); !found || drift.Abs() > skew.Abs() {
+			skew = drift
+		}
+		found = true
+	}
+
+	if !found {
+		return doctorCheck{}, false
+	}
+
+	detail := fmt.Sprintf("local clock is %s off the providers'", skew.Round(time.Second).Abs())
+	if skew.Abs() <= MAX_CLOCK_SKEW {
+		return doctorCheck{Name: "clock", Status: DOCTOR_PASS, Detail: detail}, true
+	}
+
+	return doctorCheck{Name: "clock", Status: DOCTOR_WARN, Detail: detail, Hint: "sync the system clock, for example by enabling NTP; TLS certificates fail to verify when it is far off"}, true
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// validateStream stands in for ExecuteStreamContext under --dry-run: it only
+// validates each CEP, and the results of valid ones carry the normalized CEP
+// and the state of its prefix. The channel must be drained.
+func validateStream(ctx context.Context, service *address.AddressService, ceps <-chan string) <-chan address.BatchResult {
+	results := make(chan address.BatchResult)
+
+	go func() {
+		defer close(results)
+
+		index := 0
+		for cep := range ceps {
+			validation := service.Validate([]string{cep})[0]
+
+			result := address.BatchResult{Index: index, CEP: cep, Err: validation.Err}
+			if validation.Err == nil {
+				result.CEP = validation.Normalized
+				result.Address = address.AddressResult{ZipCode: validation.Normalized, State: validation.State}
+				result.Address.StateName, _ = address.StateName(validation.State)
+			}
+			index++
+
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+func writeDryRunSummary(w io.Writer, summary batchSummary) {
+	fmt.Fprintf(w, "dry run: %d CEPs, %d valid, %d invalid, no requests sent\n", summary.Processed, summary.Succeeded, summary.Failed)
+}
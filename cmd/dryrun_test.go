@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestDryRunSendsNoRequest(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	var stdout, stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader("01001-000\n123\n00000000\n 20040010 \n"),
+		stdout:    &stdout,
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(provider)
+			service.SetProviders("Mock")
+			return service
+		},
+	}
+
+	code := env.run(context.Background(), []string{"batch", "--dry-run", "--jsonl"})
+	if code == EXIT_SUCCESS {
+		t.Errorf("exit code %d with invalid CEPs in the input, want a failure", code)
+	}
+	provider.AssertCalls(t, 0)
+
+	var states []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var result struct {
+			CEP     string                 `json:"cep"`
+			Address *address.AddressResult `json:"address"`
+			Error   string                 `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if result.Address != nil {
+			states = append(states, result.CEP+"/"+result.Address.State)
+		} else if result.Error != "" {
+			states = append(states, result.CEP+"!")
+		}
+	}
+	if got, want := strings.Join(states, " "), "01001000/SP 123! 00000000! 20040010/RJ"; got != want {
+		t.Errorf("results %q, want %q", got, want)
+	}
+
+	if !strings.Contains(stderr.String(), "dry run: 4 CEPs, 2 valid, 2 invalid, no requests sent") {
+		t.Errorf("stderr = %q, want the dry run summary", stderr.String())
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const (
+	EXIT_SUCCESS          = 0
+	EXIT_ERROR            = 1
+	EXIT_USAGE            = 2
+	EXIT_INVALID_CEP      = 3
+	EXIT_NOT_FOUND        = 4
+	EXIT_TIMEOUT          = 5
+	EXIT_PROVIDERS_FAILED = 6
+	EXIT_DISAGREE         = 7
+	EXIT_INTERRUPTED      = 130
+)
+
+// exitSeverity ranks exit codes so multi-CEP runs report the worst outcome.
+// Unexpected errors rank above every known lookup failure.
+var exitSeverity = map[int]int{
+	EXIT_SUCCESS:          0,
+	EXIT_INVALID_CEP:      1,
+	EXIT_NOT_FOUND:        2,
+	EXIT_TIMEOUT:          3,
+	EXIT_PROVIDERS_FAILED: 4,
+	EXIT_USAGE:            5,
+	EXIT_ERROR:            6,
+}
+
+func exitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return EXIT_SUCCESS
+	case errors.Is(err, address.ErrInvalidCEP):
+		return EXIT_INVALID_CEP
+	case errors.Is(err, address.ErrNotFound):
+		return EXIT_NOT_FOUND
+	case errors.Is(err, address.ErrAllProvidersFailed):
+		return EXIT_PROVIDERS_FAILED
+	case errors.Is(err, address.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return EXIT_TIMEOUT
+	}
+
+	return EXIT_ERROR
+}
+
+func mostSevere(a int, b int) int {
+	if exitSeverity[b] > exitSeverity[a] {
+		return b
+	}
+
+	return a
+}
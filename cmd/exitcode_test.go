@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, EXIT_SUCCESS},
+		{"invalid CEP", fmt.Errorf("%w: 123", address.ErrInvalidCEP), EXIT_INVALID_CEP},
+		{"unassigned prefix", fmt.Errorf("%w: %w", address.ErrInvalidCEP, address.ErrUnassignedPrefix), EXIT_INVALID_CEP},
+		{"not found", fmt.Errorf("ViaCEP: %w", address.ErrNotFound), EXIT_NOT_FOUND},
+		{"timeout", &address.TimeoutError{After: time.Second}, EXIT_TIMEOUT},
+		{"deadline", fmt.Errorf("BrasilAPI: %w", context.DeadlineExceeded), EXIT_TIMEOUT},
+		{"all providers failed", fmt.Errorf("%w: %w", address.ErrAllProvidersFailed, down), EXIT_PROVIDERS_FAILED},
+		{"failed with a timeout among them", fmt.Errorf("%w: %w", address.ErrAllProvidersFailed, address.ErrTimeout), EXIT_PROVIDERS_FAILED},
+		{"unexpected", down, EXIT_ERROR},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCodeFor(test.err); got != test.want {
+				t.Errorf("exitCodeFor(%v) = %d, want %d", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMostSevere(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		want  int
+	}{
+		{"all resolved", []int{EXIT_SUCCESS, EXIT_SUCCESS}, EXIT_SUCCESS},
+		{"one invalid", []int{EXIT_SUCCESS, EXIT_INVALID_CEP, EXIT_SUCCESS}, EXIT_INVALID_CEP},
+		{"not found over invalid", []int{EXIT_INVALID_CEP, EXIT_NOT_FOUND}, EXIT_NOT_FOUND},
+		{"failed over timeout", []int{EXIT_PROVIDERS_FAILED, EXIT_TIMEOUT}, EXIT_PROVIDERS_FAILED},
+		{"unexpected over every lookup failure", []int{EXIT_PROVIDERS_FAILED, EXIT_ERROR, EXIT_NOT_FOUND}, EXIT_ERROR},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := EXIT_SUCCESS
+			for _, code := range test.codes {
+				got = mostSevere(got, code)
+			}
+			if got != test.want {
+				t.Errorf("most severe of %v = %d, want %d", test.codes, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPartialBatchExitCode(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").
+		Script(addresstest.Answer(sé.Address), addresstest.Fail(address.ErrNotFound), addresstest.Answer(candelária.Address))
+	var stdout, stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader(""),
+		stdout:    &stdout,
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(provider)
+			service.SetProviders("Mock")
+			return service
+		},
+	}
+
+	code := env.run(context.Background(), []string{"lookup", "--concurrency", "1", "--jsonl", "01001000", "123", "99999999", "20040010"})
+	if code != EXIT_NOT_FOUND {
+		t.Errorf("exit code %d, want %d for a batch with an invalid and an unknown CEP\n%s", code, EXIT_NOT_FOUND, stderr.String())
+	}
+
+	// The resolved CEPs are still written.
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 4 {
+		t.Errorf("%d lines written, want one per CEP:\n%s", len(lines), stdout.String())
+	}
+	for _, street := range []string{sé.Address.Street, candelária.Address.Street} {
+		if !strings.Contains(stdout.String(), street) {
+			t.Errorf("stdout = %q, want %q", stdout.String(), street)
+		}
+	}
+}
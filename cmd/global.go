@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const LOOKUP_TIMEOUT = 1 * time.Second
+
+// AUDIT_LOG_MAX_SIZE and AUDIT_LOG_BACKUPS set how --audit-log rotates.
+const (
+	AUDIT_LOG_MAX_SIZE = 10 << 20
+	AUDIT_LOG_BACKUPS  = 5
+)
+
+// globalOptions are the flags every subcommand accepts.
+type globalOptions struct {
+	timeout      time.Duration
+	providers    string
+	retries      int
+	retryBackoff time.Duration
+	logLevel     string
+	logFormat    string
+	configPath   string
+	proxy        string
+	caCert       string
+	insecure     bool
+	redactLogs   bool
+	auditLog     string
+	headers      providerHeaders
+	settings     settingNames
+}
+
+func (g *globalOptions) register(flags *flag.FlagSet) {
+	flags.DurationVar(&g.timeout, "timeout", LOOKUP_TIMEOUT, "maximum time to wait for a lookup")
+	flags.StringVar(&g.providers, "providers", "", "comma-separated list of providers to query (default: all)")
+	flags.IntVar(&g.retries, "retries", 0, "number of times each provider retries a failed request")
+	flags.DurationVar(&g.retryBackoff, "retry-backoff", 200*time.Millisecond, "wait before the first retry, doubled after each attempt")
+	flags.StringVar(&g.logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	flags.StringVar(&g.logFormat, "log-format", "text", "log format: text or json")
+	flags.StringVar(&g.proxy, "proxy", "", "send provider requests through this http, https or socks5 proxy URL (default: HTTP_PROXY/HTTPS_PROXY)")
+	flags.StringVar(&g.caCert, "ca-cert", "", "also trust the PEM CA certificates in this file for provider requests")
+	flags.BoolVar(&g.insecure, "insecure", false, "do not verify provider TLS certificates, for local debugging only (refused by serve)")
+	flags.BoolVar(&g.redactLogs, "redact-logs", false, "mask CEPs (01001***) and street names in logs")
+	flags.StringVar(&g.auditLog, "audit-log", "", "append a JSON line per lookup to this file, rotated at 10 MiB; CEPs are masked with --redact-logs")
+	flags.Var(&g.headers, "provider-header", "add a header to one provider's requests as Provider:Name=Value; repeatable, {request_id} expands to the request ID")
+	flags.StringVar(&g.configPath, "config", "", "read settings from a YAML file (default: ./"+CONFIG_FILE_NAME+" or ~/"+CONFIG_FILE_NAME+")")
+}
+
+// resolve layers the config file and the environment under the parsed flags
+// and validates the result.
+func (g *globalOptions) resolve(env *environment, flags *flag.FlagSet) error {
+	var file *configFile
+	if g.configPath == "" && env.configDirs != nil {
+		g.configPath = findConfigFile(env.configDirs())
+	}
+	if g.configPath != "" {
+		var err error
+		file, err = loadConfigFile(g.configPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	settings, err := resolveConfig(flags, env.lookupEnv, file, env.stderr)
+	if err != nil {
+		return err
+	}
+	g.settings = settings
+
+	if g.timeout <= 0 {
+		return fmt.Errorf("%s must be positive, got %s", settings.name("timeout"), g.timeout)
+	}
+
+	if g.retries < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", settings.name("retries"), g.retries)
+	}
+
+	if g.retryBackoff < 0 || g.retryBackoff > g.timeout {
+		return fmt.Errorf("%s must be between 0 and the %s timeout, got %s", settings.name("retry-backoff"), g.timeout, g.retryBackoff)
+	}
+
+	if _, err := parseLogLevel(g.logLevel); err != nil {
+		return fmt.Errorf("%s: %w", settings.name("log-level"), err)
+	}
+
+	if g.logFormat != "text" && g.logFormat != "json" {
+		return fmt.Errorf("%s must be text or json, got %q", settings.name("log-format"), g.logFormat)
+	}
+
+	return nil
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	err := parsed.UnmarshalText([]byte(level))
+	return parsed, err
+}
+
+func (g *globalOptions) logger(w io.Writer) *slog.Logger {
+	level, _ := parseLogLevel(g.logLevel)
+	options := &slog.HandlerOptions{Level: level}
+
+	var logger *slog.Logger
+	if g.logFormat == "json" {
+		logger = slog.New(slog.NewJSONHandler(w, options))
+	} else {
+		logger = slog.New(slog.NewTextHandler(w, options))
+	}
+
+	if g.redactLogs {
+		return address.RedactLogger(logger)
+	}
+	return logger
+}
+
+// newService builds an AddressService configured from the global options.
+func (g *globalOptions) newService(ctx context.Context, env *environment) (*address.AddressService, error) {
+	service := env.newService(ctx)
+	service.SetTimeout(g.timeout)
+	service.SetRetries(g.retries, g.retryBackoff)
+	service.SetLogger(g.logger(env.stderr))
+	service.SetLogRedaction(g.redactLogs)
+	service.SetUserAgent(address.USER_AGENT_NAME + "/" + currentVersion().Version)
+
+	if g.proxy != "" {
+		if err := service.SetProxy(g.proxy); err != nil {
+			service.Close()
+			return nil, fmt.Errorf("%s: %w", g.settings.name("proxy"), err)
+		}
+	}
+
+	if g.caCert != "" {
+		if err := service.SetCACertFile(g.caCert); err != nil {
+			service.Close()
+			return nil, fmt.Errorf("%s: %w", g.settings.name("ca-cert"), err)
+		}
+	}
+
+	if g.insecure {
+		if err := service.SetInsecureSkipVerify(true); err != nil {
+			service.Close()
+			return nil, fmt.Errorf("--insecure: %w", err)
+		}
+	}
+
+	for provider, header := range g.headers {
+		if !slices.ContainsFunc(service.ProviderNames(), func(name string) bool { return strings.EqualFold(name, provider) }) {
+			service.Close()
+			return nil, fmt.Errorf("--provider-header: %w %q", address.ErrUnknownProvider, provider)
+		}
+		service.SetProviderHeaders(provider, header)
+	}
+
+	if g.providers != "" {
+		if strings.Trim(g.providers, ", ") == "" {
+			service.Close()
+			return nil, fmt.Errorf("%s: %w", g.settings.name("providers"), address.ErrNoProviders)
+		}
+		if err := service.SetProviders(strings.Split(g.providers, ",")...); err != nil {
+			service.Close()
+			return nil, fmt.Errorf("%s: %w", g.settings.name("providers"), err)
+		}
+	}
+
+	if g.auditLog != "" {
+		writer, err := address.NewFileAuditWriter(g.auditLog, AUDIT_LOG_MAX_SIZE, AUDIT_LOG_BACKUPS)
+		if err != nil {
+			service.Close()
+			return nil, fmt.Errorf("%s: %w", g.settings.name("audit-log"), err)
+		}
+		service.SetAuditWriter(writer)
+	}
+
+	return service, nil
+}
+
+// providerHeaders collects repeated --provider-header flags by provider.
+type providerHeaders map[string]http.Header
+
+func (h *providerHeaders) String() string {
+	return ""
+}
+
+func (h *providerHeaders) Set(value string) error {
+	provider, header, _ := strings.Cut(value, ":")
+	name, headerValue, ok := strings.Cut(header, "=")
+	provider, name = strings.TrimSpace(provider), strings.TrimSpace(name)
+	if provider == "" || name == "" || !ok {
+		// The value is left out of the error, it may be a credential.
+		return fmt.Errorf("want Provider:Name=Value")
+	}
+
+	if *h == nil {
+		*h = make(providerHeaders)
+	}
+	if (*h)[provider] == nil {
+		(*h)[provider] = make(http.Header)
+	}
+	(*h)[provider].Add(name, strings.TrimSpace(headerValue))
+	return nil
+}
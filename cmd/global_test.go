@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestProvidersFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers string
+		code      int
+		// calls are how many requests Alpha and Beta get.
+		calls [2]int
+		want  string
+	}{
+		{"one", "beta", EXIT_SUCCESS, [2]int{0, 1}, "Praça da Sé"},
+		{"any case, spaced", " ALPHA ", EXIT_SUCCESS, [2]int{1, 0}, "Praça da Sé"},
+		{"unknown", "alpha,gamma", EXIT_USAGE, [2]int{0, 0}, `--providers: unknown provider "gamma" (valid: ViaCEP, BrasilAPI, Alpha, Beta)`},
+		{"empty list", ",", EXIT_USAGE, [2]int{0, 0}, "--providers: no providers configured"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			alpha := addresstest.NewMockProvider("Alpha").Returns(sé.Address)
+			beta := addresstest.NewMockProvider("Beta").Returns(sé.Address)
+			env := newTestEnv("", alpha, beta)
+
+			code := env.run(context.Background(), []string{"lookup", "--providers", test.providers, "01001000"})
+			if code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s", code, test.code, env.stderr.String())
+			}
+			if output := env.stdout.String() + env.stderr.String(); !strings.Contains(output, test.want) {
+				t.Errorf("output = %q, want %q", output, test.want)
+			}
+			alpha.AssertCalls(t, test.calls[0])
+			beta.AssertCalls(t, test.calls[1])
+		})
+	}
+}
+
+func TestRetriesFlag(t *testing.T) {
+	unavailable := errors.New("503 Service Unavailable")
+
+	tests := []struct {
+		retries string
+		code    int
+		calls   int
+		want    string
+	}{
+		{"2", EXIT_SUCCESS, 3, "Praça da Sé"},
+		{"1", EXIT_PROVIDERS_FAILED, 2, "503 Service Unavailable"},
+		{"0", EXIT_PROVIDERS_FAILED, 1, "503 Service Unavailable"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.retries, func(t *testing.T) {
+			// Mock fails twice, then succeeds.
+			provider := addresstest.NewMockProvider("Mock").Script(
+				addresstest.Fail(unavailable),
+				addresstest.Fail(unavailable),
+				addresstest.Answer(sé.Address),
+			)
+			env := newTestEnv("", provider)
+
+			code := env.run(context.Background(), []string{"lookup", "--retries", test.retries, "--retry-backoff", "1ms", "01001000"})
+			if code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s", code, test.code, env.stderr.String())
+			}
+			if output := env.stdout.String() + env.stderr.String(); !strings.Contains(output, test.want) {
+				t.Errorf("output = %q, want %q", output, test.want)
+			}
+			provider.AssertCalls(t, test.calls)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// cepSource reads newline-separated CEPs, skipping blank lines and '#'
+// comments, and remembers the line each CEP came from until its result is
+// reported. total is the number of CEPs when known up front, zero otherwise.
+type cepSource struct {
+	name  string
+	total int
+	lines sync.Map
+	// errs holds, by index, the rows that have no CEP to look up.
+	errs sync.Map
+	// csv, when set, reads the CEPs from a column of CSV rows instead.
+	csv *csvInput
+}
+
+func cepLine(text string) (string, bool) {
+	line := strings.TrimSpace(text)
+	return line, line != "" && !strings.HasPrefix(line, "#")
+}
+
+// countCEPs counts the CEPs scan would read from r.
+func countCEPs(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	count := 0
+
+	for scanner.Scan() {
+		if _, ok := cepLine(scanner.Text()); ok {
+			count++
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+func (c *cepSource) scan(ctx context.Context, r io.Reader, ceps chan<- string) error {
+	defer close(ceps)
+
+	if c.csv != nil {
+		return c.scanCSV(ctx, ceps)
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	index := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line, ok := cepLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		c.lines.Store(index, lineNumber)
+		index++
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ceps <- line:
+		}
+	}
+
+	return scanner.Err()
+}
+
+// scanCSV sends the CEP of every row of c.csv. A row without the CEP column
+// is sent as an empty CEP, whose error rowError replaces.
+func (c *cepSource) scanCSV(ctx context.Context, ceps chan<- string) error {
+	for index := 0; ; index++ {
+		record, line, cep, err := c.csv.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if record == nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+		if err != nil {
+			c.errs.Store(index, err)
+		}
+
+		c.lines.Store(index, line)
+		c.csv.store(index, record)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ceps <- cep:
+		}
+	}
+}
+
+// rowError returns the error of the row at index when it had no CEP.
+func (c *cepSource) rowError(index int) error {
+	err, _ := c.errs.LoadAndDelete(index)
+	rowErr, _ := err.(error)
+	return rowErr
+}
+
+func (c *cepSource) location(index int) string {
+	lineNumber, ok := c.lines.LoadAndDelete(index)
+	if !ok {
+		return c.name
+	}
+
+	return fmt.Sprintf("%s:%d", c.name, lineNumber)
+}
+
+func sliceCEPs(values []string) <-chan string {
+	ceps := make(chan string)
+	go func() {
+		defer close(ceps)
+		for _, cep := range values {
+			ceps <- cep
+		}
+	}()
+
+	return ceps
+}
+
+// orderResults buffers out-of-order results and emits them in input order.
+// Results still waiting for an earlier index when the input closes, as
+// happens when a batch is interrupted, are emitted in index order at the end.
+func orderResults(results <-chan address.BatchResult) <-chan address.BatchResult {
+	ordered := make(chan address.BatchResult)
+
+	go func() {
+		defer close(ordered)
+
+		pending := make(map[int]address.BatchResult)
+		next := 0
+
+		for result := range results {
+			pending[result.Index] = result
+
+			for {
+				result, ok := pending[next]
+				if !ok {
+					break
+				}
+
+				delete(pending, next)
+				ordered <- result
+				next++
+			}
+		}
+
+		indexes := make([]int, 0, len(pending))
+		for index := range pending {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		for _, index := range indexes {
+			ordered <- pending[index]
+		}
+	}()
+
+	return ordered
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// LINES is how many lines bigInput writes, enough to cross bufio's buffer
+// many times over.
+const LINES = 5000
+
+// bigInput is LINES lines of CEPs, all in São Paulo, with comments, blank
+// lines and malformed CEPs among them. It returns the CEPs a scan reads, the
+// line each one is on, and the lines of the malformed ones.
+func bigInput() (input string, ceps []string, lines []int, malformed []int) {
+	var b strings.Builder
+	for line := 1; line <= LINES; line++ {
+		switch {
+		case line%7 == 0:
+			fmt.Fprintf(&b, "  # comment %d\n", line)
+			continue
+		case line%11 == 0:
+			b.WriteString(" \t\n")
+			continue
+		}
+
+		cep := fmt.Sprintf("0%07d", 1000000+line)
+		if line%13 == 0 {
+			cep = fmt.Sprintf("%dx", line)
+			malformed = append(malformed, line)
+		}
+		fmt.Fprintf(&b, "  %s \n", cep)
+		ceps = append(ceps, cep)
+		lines = append(lines, line)
+	}
+
+	return b.String(), ceps, lines, malformed
+}
+
+func TestCEPSourceReadsThousandsOfLines(t *testing.T) {
+	input, want, lines, _ := bigInput()
+
+	total, err := countCEPs(strings.NewReader(input))
+	if err != nil || total != len(want) {
+		t.Fatalf("countCEPs = (%d, %v), want %d", total, err, len(want))
+	}
+
+	source := &cepSource{name: "ceps.txt", total: total}
+	ch := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- source.scan(context.Background(), strings.NewReader(input), ch)
+	}()
+
+	var got []string
+	for cep := range ch {
+		got = append(got, cep)
+	}
+	if err := <-scanErr; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("scan read %d CEPs, want %d", len(got), len(want))
+	}
+
+	for index, line := range lines {
+		if location, want := source.location(index), fmt.Sprintf("ceps.txt:%d", line); location != want {
+			t.Fatalf("location(%d) = %q, want %q", index, location, want)
+		}
+	}
+}
+
+func TestBatchReportsTheLineOfEachFailure(t *testing.T) {
+	input, ceps, _, malformed := bigInput()
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	env := newTestEnv(input, provider)
+
+	if code := env.run(context.Background(), []string{"batch", "--jsonl", "--concurrency", "64"}); code != EXIT_INVALID_CEP {
+		t.Fatalf("exit code %d, want %d", code, EXIT_INVALID_CEP)
+	}
+
+	results := strings.Split(strings.TrimSpace(env.stdout.String()), "\n")
+	if len(results) != len(ceps) {
+		t.Fatalf("%d results, want %d", len(results), len(ceps))
+	}
+	failed := 0
+	for _, line := range results {
+		var result struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("result %q is not JSON: %v", line, err)
+		}
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed != len(malformed) {
+		t.Errorf("%d results failed, want %d", failed, len(malformed))
+	}
+	provider.AssertCalls(t, len(ceps)-len(malformed))
+
+	var got []int
+	for _, match := range regexp.MustCompile(`(?m)^stdin:(\d+): `).FindAllStringSubmatch(env.stderr.String(), -1) {
+		line, _ := strconv.Atoi(match[1])
+		got = append(got, line)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, malformed) {
+		t.Errorf("errors reported on lines %v, want %v", got, malformed)
+	}
+}
+
+func TestBatchInputFile(t *testing.T) {
+	// byCEP knows every CEP but 99999999.
+	byCEP := address.NewProvider("ByCEP", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		if cep == "99999999" {
+			return address.AddressResult{}, address.ErrNotFound
+		}
+		return sé.Address, nil
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		code    int
+		// failed are the lines whose errors are reported.
+		failed []int
+	}{
+		{
+			name:    "good lines",
+			content: "# CEPs\n01001000\n\n01001-000\n",
+			code:    EXIT_SUCCESS,
+		},
+		{
+			name:    "good and bad lines",
+			content: "# CEPs\n01001000\n\n123\n99999999\n01001-000\n   # indented\nabc\n",
+			code:    EXIT_NOT_FOUND,
+			failed:  []int{4, 5, 8},
+		},
+		{
+			name:    "only malformed lines",
+			content: "123\n\nabc\n",
+			code:    EXIT_INVALID_CEP,
+			failed:  []int{1, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ceps.txt")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			env := newTestEnv("", byCEP)
+
+			if code := env.run(context.Background(), []string{"batch", "--input", path, "--jsonl"}); code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s", code, test.code, env.stderr.String())
+			}
+
+			var got []int
+			pattern := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(path) + `:(\d+): `)
+			for _, match := range pattern.FindAllStringSubmatch(env.stderr.String(), -1) {
+				line, _ := strconv.Atoi(match[1])
+				got = append(got, line)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.failed) {
+				t.Errorf("errors reported on lines %v, want %v\n%s", got, test.failed, env.stderr.String())
+			}
+		})
+	}
+}
+
+func TestBatchInputFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	env := newTestEnv("", provider)
+
+	if code := env.run(context.Background(), []string{"batch", "--input", path}); code != EXIT_ERROR {
+		t.Fatalf("exit code %d, want %d", code, EXIT_ERROR)
+	}
+	if !strings.Contains(env.stderr.String(), path) {
+		t.Errorf("stderr = %q, want the missing path", env.stderr.String())
+	}
+	provider.AssertCalls(t, 0)
+}
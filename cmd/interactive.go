@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const PROMPT = "cep> "
+
+const replHelp = `enter a CEP to look it up, or one of:
+  :providers          list enabled providers
+  :timeout <duration> change the lookup timeout
+  :json on|off        toggle JSON output
+  :help               show this help (also help)
+  :quit               exit (also quit or exit)
+`
+
+type repl struct {
+	service    *address.AddressService
+	out        io.Writer
+	jsonOutput bool
+	// table prints its header once, before the first row of the session.
+	table *tableWriter
+}
+
+func runInteractive(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "interactive")
+	var global globalOptions
+	global.register(flags)
+	noColor := flags.Bool("no-color", false, "disable colored output")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if err := global.resolve(env, flags); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	service, err := global.newService(ctx, env)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+	defer service.Close()
+
+	session := &repl{
+		service: service,
+		out:     env.stdout,
+		table:   newTableWriter(env.stdout, useColor(env.stdout, *noColor), true),
+	}
+	if err := session.run(ctx, env.stdin); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_ERROR
+	}
+
+	return EXIT_SUCCESS
+}
+
+// run reads commands from in until EOF, :quit or ctx is cancelled.
+func (r *repl) run(ctx context.Context, in io.Reader) error {
+	lines := make(chan string)
+	scanErr := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	for {
+		fmt.Fprint(r.out, PROMPT)
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(r.out)
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(r.out)
+				select {
+				case err := <-scanErr:
+					return err
+				default:
+					return nil
+				}
+			}
+
+			if quit := r.handle(strings.TrimSpace(line)); quit {
+				return nil
+			}
+		}
+	}
+}
+
+func (r *repl) handle(line string) bool {
+	switch line {
+	case "":
+		return false
+	case "help", "quit", "exit":
+		// No CEP is a word, so these need no colon.
+		line = ":" + line
+	}
+
+	if !strings.HasPrefix(line, ":") {
+		r.lookup(line)
+		return false
+	}
+
+	command, argument, _ := strings.Cut(line[1:], " ")
+	argument = strings.TrimSpace(argument)
+
+	switch command {
+	case "quit", "q", "exit":
+		return true
+	case "help", "h":
+		fmt.Fprint(r.out, replHelp)
+	case "providers":
+		fmt.Fprintln(r.out, strings.Join(r.service.EnabledProviderNames(), ", "))
+	case "timeout":
+		timeout, err := time.ParseDuration(argument)
+		if err != nil || timeout <= 0 {
+			fmt.Fprintf(r.out, "invalid timeout %q\n", argument)
+			return false
+		}
+		r.service.SetTimeout(timeout)
+		fmt.Fprintf(r.out, "timeout set to %s\n", timeout)
+	case "json":
+		switch argument {
+		case "on":
+			r.jsonOutput = true
+		case "off":
+			r.jsonOutput = false
+		default:
+			fmt.Fprintln(r.out, "usage: :json on|off")
+		}
+	default:
+		fmt.Fprintf(r.out, "unknown command %q, type :help\n", command)
+	}
+
+	return false
+}
+
+func (r *repl) lookup(cep string) {
+	start := time.Now()
+	result, err := r.service.Execute(cep)
+	row := address.BatchResult{CEP: cep, Address: result, Err: err, Latency: time.Since(start)}
+
+	if r.jsonOutput {
+		writeJSON(r.out, []address.BatchResult{row})
+		return
+	}
+
+	r.table.Write(row)
+}
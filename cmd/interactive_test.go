@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestInteractiveSession(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	env := newTestEnv(strings.Join([]string{
+		"help",
+		"01001000",
+		"",
+		"01001-000",
+		"123",
+		":json on",
+		"01001000",
+		":json off",
+		":timeout soon",
+		":timeout 2s",
+		":providers",
+		":nope",
+		"quit",
+		"30140071",
+	}, "\n")+"\n", provider)
+
+	if code := env.run(context.Background(), []string{"interactive"}); code != EXIT_SUCCESS {
+		t.Fatalf("exit code %d\n%s", code, env.stderr.String())
+	}
+	out := env.stdout.String()
+
+	for _, want := range []string{
+		replHelp,
+		`"street":"Praça da Sé"`,
+		`invalid timeout "soon"`,
+		"timeout set to 2s",
+		"123         error: invalid CEP",
+		"Mock\n",
+		`unknown command "nope", type :help`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("session output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// The table rows of the session share one header.
+	if headers := strings.Count(out, "CEP         STREET"); headers != 1 {
+		t.Errorf("%d table headers, want 1:\n%s", headers, out)
+	}
+	if rows := strings.Count(out, "Praça da Sé"); rows != 3 {
+		t.Errorf("%d results, want 3:\n%s", rows, out)
+	}
+
+	// Nothing is looked up after quit.
+	provider.AssertCalls(t, 3)
+	if strings.Contains(out, "30140071") {
+		t.Errorf("the CEP after quit was looked up:\n%s", out)
+	}
+}
@@ -0,0 +1,660 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/internal/atomicfile"
+)
+
+const MAX_CONCURRENCY = 256
+
+// INTERRUPT_GRACE is how long an interrupted batch waits for lookups already
+// in flight before abandoning them and flushing what it has.
+const INTERRUPT_GRACE = 5 * time.Second
+
+// lookupOptions are the flags shared by the lookup and batch subcommands.
+type lookupOptions struct {
+	global       globalOptions
+	jsonOutput   bool
+	jsonlOutput  bool
+	csvOutput    bool
+	xmlOutput    bool
+	noColor      bool
+	outputFormat string
+	format       string
+	lang         string
+	ordered      bool
+	outputFile   string
+	appendOutput bool
+	dryRun       bool
+	passthrough  bool
+	notifyURL    string
+	notifySecret string
+	statsReport  string
+	summary      batchSummary
+	runID        string
+	sqliteBatch  int
+	cachePath    string
+	cacheTTL     time.Duration
+	concurrency  int
+	rateLimit    float64
+	verbose      bool
+	veryVerbose  bool
+	quiet        bool
+	explain      bool
+	progress     bool
+}
+
+func (o *lookupOptions) register(flags *flag.FlagSet) {
+	o.global.register(flags)
+	flags.BoolVar(&o.jsonOutput, "json", false, "print results as JSON")
+	flags.BoolVar(&o.jsonlOutput, "jsonl", false, "print one JSON object per line as each lookup completes")
+	flags.BoolVar(&o.csvOutput, "csv", false, "print results as CSV")
+	flags.BoolVar(&o.xmlOutput, "xml", false, "print results as an XML document")
+	flags.BoolVar(&o.noColor, "no-color", false, "disable colored output")
+	flags.StringVar(&o.outputFormat, "output-format", "", "output format: table, text, json, jsonl, csv, xml or sqlite (needs --output)")
+	flags.StringVar(&o.format, "format", "", "render each result with a Go template, e.g. '{{.City}} - {{.State}}' (helpers: upper, lower, zipdash)")
+	flags.StringVar(&o.lang, "lang", "", "language of the text output, en or pt-BR; pt-BR replaces the default table with it (default: from LANG)")
+	flags.BoolVar(&o.ordered, "ordered", false, "print streamed results in input order instead of as they complete")
+	flags.StringVar(&o.outputFile, "output", "", "write results to a file (format inferred from .json, .jsonl, .csv, .xml, .db or .sqlite)")
+	flags.BoolVar(&o.appendOutput, "append", false, "append to the --output file instead of replacing it (JSONL and CSV only)")
+	flags.StringVar(&o.runID, "run-id", "", "identify this run in sqlite output, where rows of a CEP already written with the same ID are replaced, and in --notify-url payloads (default: the run's start time)")
+	flags.IntVar(&o.sqliteBatch, "sqlite-batch-size", SQLITE_BATCH_SIZE, "sqlite output: results written per transaction")
+	flags.StringVar(&o.cachePath, "cache", "", "keep successful lookups in this SQLite file and answer later runs from it")
+	flags.DurationVar(&o.cacheTTL, "cache-ttl", DEFAULT_CACHE_TTL, "how long --cache entries are answered from")
+	flags.BoolVar(&o.dryRun, "dry-run", false, "only normalize and validate the CEPs and map them to their state, without sending any request")
+	flags.IntVar(&o.concurrency, "concurrency", defaultConcurrency(), "number of CEPs resolved in parallel (1-256)")
+	flags.Float64Var(&o.rateLimit, "rate-limit", 0, "maximum lookups started per second (0 = unlimited)")
+	flags.BoolVar(&o.verbose, "verbose", false, "print a per-provider breakdown of each lookup to stderr")
+	flags.BoolVar(&o.verbose, "v", false, "shorthand for --verbose")
+	flags.BoolVar(&o.veryVerbose, "vv", false, "like --verbose, including connection phase timings")
+	flags.BoolVar(&o.quiet, "quiet", false, "print only the address (or nothing on failure), without logs or warnings")
+	flags.BoolVar(&o.quiet, "q", false, "shorthand for --quiet")
+	flags.StringVar(&o.statsReport, "stats-report", "", "write the run's lookup statistics to this JSON file on exit, even when interrupted")
+	flags.BoolVar(&o.explain, "explain", false, "print a timeline of each lookup's provider race to stderr")
+}
+
+func (o *lookupOptions) verbosity() int {
+	switch {
+	case o.veryVerbose:
+		return 2
+	case o.verbose:
+		return 1
+	}
+
+	return 0
+}
+
+func runLookup(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "lookup")
+	var options lookupOptions
+	options.register(flags)
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	args = flags.Args()
+	if (len(args) == 1 && args[0] == "-") || (len(args) == 0 && !isTerminal(env.stdin)) {
+		return options.run(ctx, env, flags, nil, &cepSource{name: "stdin"}, env.stdin)
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	return options.run(ctx, env, flags, args, nil, nil)
+}
+
+func runBatch(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "batch")
+	var options lookupOptions
+	options.register(flags)
+	inputFile := flags.String("input", "", "read CEPs from a file, one per line (default: stdin)")
+	cepColumn := flags.String("cep-column", "", "read the input as CSV and take the CEP from this column, by header name or 1-based index")
+	flags.BoolVar(&options.passthrough, "passthrough", false, "copy the columns of each --cep-column input row into the CSV or JSONL output")
+	flags.StringVar(&options.notifyURL, "notify-url", "", "POST a JSON summary of the run to this URL once the batch finishes, even when it failed or was interrupted")
+	flags.StringVar(&options.notifySecret, "notify-secret", "", "sign --notify-url payloads with HMAC-SHA256 in the "+NOTIFY_SIGNATURE_HEADER+" header")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(env.stderr, "batch does not accept CEP arguments, use --input or stdin")
+		return EXIT_USAGE
+	}
+
+	if options.passthrough && *cepColumn == "" {
+		fmt.Fprintln(env.stderr, "--passthrough requires --cep-column")
+		return EXIT_USAGE
+	}
+
+	if options.notifyURL != "" && !validNotifyURL(options.notifyURL) {
+		fmt.Fprintln(env.stderr, "--notify-url must be an http or https URL")
+		return EXIT_USAGE
+	}
+
+	if options.notifySecret != "" && options.notifyURL == "" {
+		fmt.Fprintln(env.stderr, "--notify-secret requires --notify-url")
+		return EXIT_USAGE
+	}
+
+	started := time.Now()
+	if options.runID == "" {
+		options.runID = started.UTC().Format(time.RFC3339)
+	}
+
+	exitCode := options.runBatch(ctx, env, flags, *inputFile, *cepColumn)
+	// A usage error means the batch never ran.
+	if exitCode != EXIT_USAGE {
+		logger := options.global.logger(env.stderr)
+		if options.quiet {
+			logger = address.NopLogger()
+		}
+		options.notifyBatch(ctx, logger, started, exitCode)
+	}
+
+	return exitCode
+}
+
+// runBatch resolves the CEPs of inputFile, or stdin, read from cepColumn
+// when it is set.
+func (o *lookupOptions) runBatch(ctx context.Context, env *environment, flags *flag.FlagSet, inputFile string, cepColumn string) int {
+	o.progress = true
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		// Restoring the default handlers lets a second Ctrl-C kill the
+		// process while the partial results are being flushed.
+		<-ctx.Done()
+		stop()
+	}()
+
+	if inputFile == "" || inputFile == "-" {
+		source := &cepSource{name: "stdin"}
+		if cepColumn != "" {
+			input, err := newCSVInput(env.stdin, cepColumn, o.passthrough)
+			if err != nil {
+				fmt.Fprintf(env.stderr, "stdin: %s\n", err.Error())
+				return EXIT_USAGE
+			}
+			source.csv = input
+		}
+		return o.run(ctx, env, flags, nil, source, env.stdin)
+	}
+
+	file, err := os.Open(inputFile)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_ERROR
+	}
+	defer file.Close()
+
+	count := countCEPs
+	if cepColumn != "" {
+		count = countCSVRows
+	}
+	total, err := count(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "%s: %s\n", inputFile, err.Error())
+		return EXIT_ERROR
+	}
+
+	source := &cepSource{name: inputFile, total: total}
+	if cepColumn != "" {
+		input, err := newCSVInput(file, cepColumn, o.passthrough)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "%s: %s\n", inputFile, err.Error())
+			return EXIT_USAGE
+		}
+		if input.header != nil {
+			source.total--
+		}
+		source.csv = input
+	}
+
+	return o.run(ctx, env, flags, nil, source, file)
+}
+
+// run resolves either the CEPs in args or, when source is set, the CEPs
+// streamed from sourceReader, and writes the results in the selected format.
+func (o *lookupOptions) run(ctx context.Context, env *environment, flags *flag.FlagSet, args []string, source *cepSource, sourceReader io.Reader) int {
+	stdout := env.stdout
+	stderr := env.stderr
+
+	if err := o.global.resolve(env, flags); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return EXIT_USAGE
+	}
+	settings := o.global.settings
+
+	verbosity := o.verbosity()
+	if o.quiet && verbosity > 0 {
+		fmt.Fprintln(stderr, "--quiet and --verbose are mutually exclusive")
+		return EXIT_USAGE
+	}
+	if o.quiet && o.explain {
+		fmt.Fprintln(stderr, "--quiet and --explain are mutually exclusive")
+		return EXIT_USAGE
+	}
+
+	// --quiet silences the logs and warnings of the lookups; usage and run
+	// errors are still reported.
+	logs := stderr
+	if o.quiet {
+		logs = io.Discard
+	}
+
+	streaming := source != nil
+	if streaming {
+		o.summary.Total = source.total
+	}
+
+	if o.concurrency < 1 || o.concurrency > MAX_CONCURRENCY {
+		fmt.Fprintf(stderr, "%s must be between 1 and %d, got %d\n", settings.name("concurrency"), MAX_CONCURRENCY, o.concurrency)
+		return EXIT_USAGE
+	}
+
+	if o.rateLimit < 0 {
+		fmt.Fprintf(stderr, "%s must not be negative, got %g\n", settings.name("rate-limit"), o.rateLimit)
+		return EXIT_USAGE
+	}
+
+	if o.cacheTTL <= 0 {
+		fmt.Fprintf(stderr, "%s must be positive, got %s\n", settings.name("cache-ttl"), o.cacheTTL)
+		return EXIT_USAGE
+	}
+
+	if countTrue(o.jsonOutput, o.jsonlOutput, o.csvOutput, o.xmlOutput, o.format != "", o.outputFormat != "") > 1 {
+		fmt.Fprintln(stderr, "--json, --jsonl, --csv, --xml, --format and --output-format are mutually exclusive")
+		return EXIT_USAGE
+	}
+
+	outputFormat := "table"
+	switch {
+	case o.outputFormat != "":
+		outputFormat = o.outputFormat
+		if !validOutputFormat(outputFormat) {
+			fmt.Fprintf(stderr, "%s must be one of table, text, json, jsonl, csv, xml or sqlite, got %q\n", settings.name("output-format"), outputFormat)
+			return EXIT_USAGE
+		}
+	case o.jsonOutput:
+		outputFormat = "json"
+	case o.jsonlOutput:
+		outputFormat = "jsonl"
+	case o.csvOutput:
+		outputFormat = "csv"
+	case o.xmlOutput:
+		outputFormat = "xml"
+	case o.format != "":
+		outputFormat = "template"
+	case o.outputFile != "":
+		var ok bool
+		outputFormat, ok = formatForPath(o.outputFile)
+		if !ok {
+			fmt.Fprintf(stderr, "cannot infer output format from %q, use --json or --csv\n", o.outputFile)
+			return EXIT_USAGE
+		}
+	}
+
+	lang, ok := address.ParseLanguage(o.lang)
+	if o.lang == "" {
+		locale, _ := env.lookupEnv("LANG")
+		lang, _ = address.ParseLanguage(locale)
+	} else if !ok {
+		fmt.Fprintf(logs, "warning: unknown --lang %q, using English\n", o.lang)
+	}
+
+	if outputFormat == "table" && o.outputFormat == "" && lang != address.LANG_EN {
+		outputFormat = "text"
+	}
+
+	if o.quiet && (outputFormat == "table" || outputFormat == "text") {
+		outputFormat = "quiet"
+	}
+
+	if o.appendOutput && (o.outputFile == "" || (outputFormat != "jsonl" && outputFormat != "csv")) {
+		fmt.Fprintln(stderr, "--append requires an --output file in JSONL or CSV format")
+		return EXIT_USAGE
+	}
+
+	if o.passthrough && outputFormat != "csv" && outputFormat != "jsonl" {
+		fmt.Fprintln(stderr, "--passthrough requires CSV or JSONL output")
+		return EXIT_USAGE
+	}
+
+	if outputFormat == "sqlite" && o.outputFile == "" {
+		fmt.Fprintln(stderr, "the sqlite output format requires an --output file")
+		return EXIT_USAGE
+	}
+
+	if o.sqliteBatch < 1 {
+		fmt.Fprintf(stderr, "--sqlite-batch-size must be positive, got %d\n", o.sqliteBatch)
+		return EXIT_USAGE
+	}
+
+	out := stdout
+	var target *atomicfile.File
+	skipHeader := false
+	if o.outputFile != "" && outputFormat != "sqlite" {
+		if o.appendOutput {
+			file, err := os.OpenFile(o.outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if err != nil {
+				fmt.Fprintln(stderr, err.Error())
+				return EXIT_ERROR
+			}
+			defer file.Close()
+
+			info, err := file.Stat()
+			if err != nil {
+				fmt.Fprintln(stderr, err.Error())
+				return EXIT_ERROR
+			}
+
+			skipHeader = info.Size() > 0
+			out = file
+		} else {
+			var err error
+			target, err = atomicfile.Create(o.outputFile)
+			if err != nil {
+				fmt.Fprintln(stderr, err.Error())
+				return EXIT_ERROR
+			}
+			defer target.Abort()
+
+			out = target
+		}
+	}
+
+	options := renderOptions{
+		format:     outputFormat,
+		multiple:   streaming || len(args) > 1,
+		streaming:  streaming,
+		color:      out == stdout && useColor(stdout, o.noColor),
+		skipHeader: skipHeader,
+		lang:       lang,
+		errw:       logs,
+	}
+	if o.passthrough {
+		options.passthrough = source.csv.passthrough()
+	}
+	if outputFormat == "template" {
+		tmpl, err := parseFormat(o.format)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return EXIT_USAGE
+		}
+		options.template = tmpl
+	}
+
+	var writer resultWriter
+	if outputFormat == "sqlite" {
+		// The database is written in place, committed a transaction at a
+		// time, rather than through an atomic file.
+		sqlite, err := newSQLiteWriter(o.outputFile, o.runID, time.Now(), o.sqliteBatch)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return EXIT_ERROR
+		}
+		// Closed below to report its error; this commits what was written
+		// and releases the database on the early returns.
+		defer sqlite.Close()
+		writer = sqlite
+	} else {
+		writer = newResultWriter(out, options)
+	}
+
+	// Opened before the service so it is closed after it, once no lookup
+	// can still be writing to it.
+	var cache address.Cache
+	if o.cachePath != "" {
+		store, err := newSQLiteCacheStore(o.cachePath, o.cacheTTL)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", settings.name("cache"), err.Error())
+			return EXIT_ERROR
+		}
+		defer store.Close()
+		cache = address.NewStoreCache(store)
+	}
+
+	// Interrupting ctx only stops new lookups from starting; the service
+	// keeps its own lifetime so in-flight lookups can finish.
+	addressService, err := o.global.newService(context.WithoutCancel(ctx), env)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return EXIT_USAGE
+	}
+	defer addressService.Close()
+
+	if o.statsReport != "" {
+		addressService.SetStatsReport(o.statsReport, currentVersion().Version)
+	}
+	if cache != nil {
+		addressService.SetCache(cache)
+	}
+	addressService.SetConcurrency(o.concurrency)
+	addressService.SetRateLimit(o.rateLimit)
+	addressService.SetTrace(verbosity > 1)
+	if o.quiet {
+		addressService.SetLogger(address.NopLogger())
+	}
+
+	if o.rateLimit > 0 {
+		saturated := int(math.Ceil(o.rateLimit * o.global.timeout.Seconds()))
+		if o.concurrency > saturated {
+			fmt.Fprintf(logs, "warning: --rate-limit %g keeps at most %d lookups in flight, --concurrency %d will not be saturated\n", o.rateLimit, saturated, o.concurrency)
+		}
+	}
+
+	// streamCtx is cancelled when a write fails, which stops the scan and
+	// the lookups not yet started.
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var ceps <-chan string
+	scanErr := make(chan error, 1)
+	if streaming {
+		input := make(chan string)
+		go func() {
+			scanErr <- source.scan(streamCtx, sourceReader, input)
+		}()
+		ceps = input
+	} else {
+		scanErr <- nil
+		ceps = sliceCEPs(args)
+	}
+
+	var results <-chan address.BatchResult
+	if o.dryRun {
+		results = validateStream(streamCtx, addressService, ceps)
+	} else {
+		results = addressService.ExecuteStreamContext(streamCtx, ceps)
+	}
+	if o.ordered || !streaming {
+		results = orderResults(results)
+	}
+
+	var meter *progress
+	sharesTerminal := false
+	if o.progress && !o.quiet {
+		meter = newProgress(stderr, isTerminal(env.stderr), source.total, time.Now())
+		sharesTerminal = meter.tty && out == stdout && isTerminal(stdout)
+	}
+
+	exitCode := EXIT_SUCCESS
+	written := 0
+	abandoned := false
+	interrupted := ctx.Done()
+	var grace <-chan time.Time
+
+	for results != nil {
+		select {
+		case <-interrupted:
+			if meter != nil {
+				meter.clear()
+			}
+			interrupted = nil
+			// Before the grace period can close the service, which writes
+			// the stats report.
+			addressService.MarkInterrupted()
+			grace = time.After(INTERRUPT_GRACE)
+			fmt.Fprintf(logs, "interrupted, waiting up to %s for lookups in flight (press Ctrl-C again to quit now)\n", INTERRUPT_GRACE)
+			continue
+		case <-grace:
+			grace = nil
+			abandoned = true
+			addressService.Close()
+			continue
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+
+			location := ""
+			if streaming {
+				location = source.location(result.Index)
+				if err := source.rowError(result.Index); err != nil {
+					result.Err = err
+				}
+			}
+
+			if abandoned {
+				continue
+			}
+
+			exitCode = mostSevere(exitCode, exitCodeFor(result.Err))
+
+			if meter != nil && (sharesTerminal || verbosity > 0 || o.explain || (result.Err != nil && streaming)) {
+				meter.clear()
+			}
+
+			if verbosity > 0 {
+				writeReport(stderr, result.Report, verbosity)
+			}
+
+			if o.explain && result.Report != nil {
+				fmt.Fprintf(stderr, "[%s] %s\n", result.Report.CEP, result.Report.Explain())
+			}
+
+			if result.Err != nil && streaming {
+				fmt.Fprintf(logs, "%s: %s\n", location, result.Err.Error())
+			}
+
+			if err := writer.Write(result); err != nil {
+				fmt.Fprintln(stderr, err.Error())
+				// No more CEPs are taken; the deferred Close abandons the
+				// lookups in flight, whose results are drained so the
+				// stream can finish.
+				cancel()
+				go func(results <-chan address.BatchResult) {
+					for range results {
+					}
+				}(results)
+				return EXIT_ERROR
+			}
+			written++
+			o.summary.record(result)
+
+			if meter != nil {
+				meter.record(result, time.Now())
+			}
+		}
+	}
+
+	if meter != nil {
+		meter.finish(time.Now())
+	}
+
+	if err := writer.Close(); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return EXIT_ERROR
+	}
+
+	// An interrupted scan may still be blocked reading its input.
+	if ctx.Err() == nil {
+		if err := <-scanErr; err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return EXIT_ERROR
+		}
+	}
+
+	if target != nil {
+		if err := target.Commit(); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return EXIT_ERROR
+		}
+	}
+
+	if o.dryRun {
+		writeDryRunSummary(logs, o.summary)
+	}
+
+	if ctx.Err() != nil {
+		addressService.MarkInterrupted()
+		fmt.Fprintf(logs, "interrupted: %d results written\n", written)
+		return EXIT_INTERRUPTED
+	}
+
+	return exitCode
+}
+
+func countTrue(values ...bool) int {
+	count := 0
+	for _, value := range values {
+		if value {
+			count++
+		}
+	}
+
+	return count
+}
+
+func formatForPath(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json", true
+	case ".jsonl", ".ndjson":
+		return "jsonl", true
+	case ".csv":
+		return "csv", true
+	case ".xml":
+		return "xml", true
+	case ".db", ".sqlite", ".sqlite3":
+		return "sqlite", true
+	}
+
+	return "", false
+}
+
+func defaultConcurrency() int {
+	return min(runtime.NumCPU(), address.DEFAULT_CONCURRENCY)
+}
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case "table", "text", "json", "jsonl", "csv", "xml", "sqlite":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// testEnv runs commands against the given providers, reading stdin and
+// collecting what they print.
+type testEnv struct {
+	*environment
+	stdout, stderr bytes.Buffer
+}
+
+func newTestEnv(stdin string, providers ...address.Provider) *testEnv {
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		names[i] = provider.Name()
+	}
+
+	env := &testEnv{}
+	env.environment = &environment{
+		stdin:     strings.NewReader(stdin),
+		stdout:    &env.stdout,
+		stderr:    &env.stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger())
+			for _, provider := range providers {
+				service.RegisterProvider(provider)
+			}
+			service.SetProviders(names...)
+			return service
+		},
+	}
+	return env
+}
+
+// endlessCEPs reads as a never-ending list of CEPs.
+type endlessCEPs struct{}
+
+func (endlessCEPs) Read(p []byte) (int, error) {
+	const line = "01001000\n"
+	n := 0
+	for n+len(line) <= len(p) {
+		n += copy(p[n:], line)
+	}
+	return n, nil
+}
+
+// brokenWriter fails every write, like a closed pipe.
+type brokenWriter struct{}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write |1: broken pipe")
+}
+
+// streaming reports whether a goroutine is still scanning the input or
+// resolving it.
+func streaming() bool {
+	buf := make([]byte, 1<<20)
+	stacks := buf[:runtime.Stack(buf, true)]
+	return bytes.Contains(stacks, []byte("cmd.(*cepSource).scan")) || bytes.Contains(stacks, []byte("address.(*AddressService).stream"))
+}
+
+func TestWriteErrorStopsTheLookups(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	var stderr bytes.Buffer
+	env := &environment{
+		stdin:     endlessCEPs{},
+		stdout:    brokenWriter{},
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(provider)
+			service.SetProviders("Mock")
+			return service
+		},
+	}
+
+	if code := env.run(context.Background(), []string{"batch", "--format", "text"}); code != EXIT_ERROR {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_ERROR, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "broken pipe") {
+		t.Errorf("stderr = %q, want the write error", stderr.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for streaming() {
+		if time.Now().After(deadline) {
+			t.Fatal("still reading or resolving the input after the write error")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInterruptedBatchFlushesItsResults(t *testing.T) {
+	const TOTAL = 20
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "ceps.txt")
+	var ceps strings.Builder
+	for i := range TOTAL {
+		fmt.Fprintf(&ceps, "0100%04d\n", i)
+	}
+	if err := os.WriteFile(input, []byte(ceps.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address).SetLatency(20 * time.Millisecond)
+	var stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader(""),
+		stdout:    &bytes.Buffer{},
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(provider)
+			service.SetProviders("Mock")
+			return service
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(70*time.Millisecond, cancel)
+
+	output := filepath.Join(dir, "results.json")
+	code := env.run(ctx, []string{"batch", "--input", input, "--output", output, "--concurrency", "1"})
+	if code != EXIT_INTERRUPTED {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_INTERRUPTED, stderr.String())
+	}
+
+	match := regexp.MustCompile(`interrupted: (\d+) results written`).FindStringSubmatch(stderr.String())
+	if match == nil {
+		t.Fatalf("stderr = %q, want the interrupted summary", stderr.String())
+	}
+	written, _ := strconv.Atoi(match[1])
+	if written == 0 || written >= TOTAL {
+		t.Errorf("%d results written, want the batch cut short", written)
+	}
+	if want := fmt.Sprintf("%d/%d", written, TOTAL); !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr = %q, want the progress to end at %s", stderr.String(), want)
+	}
+
+	// The file holds exactly the results written, as a complete document.
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []struct {
+		CEP    string `json:"cep"`
+		Street string `json:"street"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, data)
+	}
+	if len(results) != written {
+		t.Errorf("%d results in the file, want the %d written", len(results), written)
+	}
+	for _, result := range results {
+		if result.Error != "" || result.Street != sé.Address.Street {
+			t.Errorf("result %+v, want a resolved lookup", result)
+		}
+	}
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".results.json.tmp-*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestQuietStillReportsUsageErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"lookup", "--quiet", "--concurrency", "0", "01001000"}, "--concurrency must be between 1 and 256, got 0"},
+		{[]string{"lookup", "-q", "--output-format", "yaml", "01001000"}, "--output-format must be one of"},
+		{[]string{"batch", "--quiet", "--json", "--csv"}, "mutually exclusive"},
+		{[]string{"batch", "--quiet", "--sqlite-batch-size", "0"}, "--sqlite-batch-size must be positive"},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+			env := newTestEnv("01001000\n", provider)
+
+			if code := env.run(context.Background(), test.args); code != EXIT_USAGE {
+				t.Fatalf("exit code %d, want %d", code, EXIT_USAGE)
+			}
+			if !strings.Contains(env.stderr.String(), test.want) {
+				t.Errorf("stderr = %q, want %q", env.stderr.String(), test.want)
+			}
+			provider.AssertCalls(t, 0)
+		})
+	}
+}
+
+func TestQuietSilencesTheLookups(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address)
+	env := newTestEnv("01001000\n123\n", provider)
+
+	code := env.run(context.Background(), []string{"batch", "--quiet", "--lang", "xx"})
+	if code != EXIT_INVALID_CEP {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_INVALID_CEP, env.stderr.String())
+	}
+	if env.stderr.Len() > 0 {
+		t.Errorf("stderr = %q, want nothing under --quiet", env.stderr.String())
+	}
+	if got := env.stdout.String(); !strings.Contains(got, sé.Address.Street) {
+		t.Errorf("stdout = %q, want the address", got)
+	}
+}
+
+// peakProvider answers after a short delay and records how many of its
+// calls were ever in flight at once.
+type peakProvider struct {
+	mu             sync.Mutex
+	inFlight, peak int
+}
+
+func (p *peakProvider) provider() address.Provider {
+	return address.NewProvider("Peak", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		p.mu.Lock()
+		p.inFlight++
+		p.peak = max(p.peak, p.inFlight)
+		p.mu.Unlock()
+
+		defer func() {
+			p.mu.Lock()
+			p.inFlight--
+			p.mu.Unlock()
+		}()
+
+		time.Sleep(2 * time.Millisecond)
+		return sé.Address, nil
+	})
+}
+
+func TestConcurrencyBoundsLookupsInFlight(t *testing.T) {
+	var input strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&input, "0100%04d\n", i)
+	}
+
+	for _, concurrency := range []int{1, 4, 16} {
+		t.Run(strconv.Itoa(concurrency), func(t *testing.T) {
+			var peak peakProvider
+			env := newTestEnv(input.String(), peak.provider())
+
+			code := env.run(context.Background(), []string{"batch", "--jsonl", "--concurrency", strconv.Itoa(concurrency)})
+			if code != EXIT_SUCCESS {
+				t.Fatalf("exit code %d\n%s", code, env.stderr.String())
+			}
+
+			if peak.peak > concurrency {
+				t.Errorf("%d lookups in flight at once, want at most %d", peak.peak, concurrency)
+			}
+			// A batch this long overlaps its lookups whenever it may.
+			if concurrency > 1 && peak.peak < 2 {
+				t.Errorf("%d lookups in flight at once, want them concurrent", peak.peak)
+			}
+		})
+	}
+}
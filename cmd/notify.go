@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// NOTIFY_RETRIES is how many times a failed --notify-url request is retried,
+// waiting NOTIFY_BACKOFF before the first retry and twice as long before
+// each next one. NOTIFY_TIMEOUT bounds each request.
+const (
+	NOTIFY_RETRIES = 3
+	NOTIFY_BACKOFF = 500 * time.Millisecond
+	NOTIFY_TIMEOUT = 10 * time.Second
+)
+
+// NOTIFY_SIGNATURE_HEADER carries the hex HMAC-SHA256 of the body, keyed
+// with --notify-secret, as sha256=<hex>.
+const NOTIFY_SIGNATURE_HEADER = "X-Signature-256"
+
+// batchSummary tallies a batch run.
+type batchSummary struct {
+	// Total is the number of CEPs in the input, zero when it was not known
+	// up front.
+	Total       int  `json:"total,omitempty"`
+	Processed   int  `json:"processed"`
+	Succeeded   int  `json:"succeeded"`
+	Failed      int  `json:"failed"`
+	Interrupted bool `json:"interrupted"`
+	ExitCode    int  `json:"exit_code"`
+}
+
+func (s *batchSummary) record(result address.BatchResult) {
+	s.Processed++
+	if result.Err != nil {
+		s.Failed++
+	} else {
+		s.Succeeded++
+	}
+}
+
+type notification struct {
+	RunID      string       `json:"run_id"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Summary    batchSummary `json:"summary"`
+	// Output is the --output file, empty when the results went to stdout.
+	Output string `json:"output,omitempty"`
+}
+
+func validNotifyURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// notify POSTs payload to target as JSON, signed with secret unless it is
+// empty, retrying failures and 5xx or 429 answers.
+func notify(ctx context.Context, client *http.Client, target string, secret string, payload notification) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var signature string
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	backoff := NOTIFY_BACKOFF
+	for attempt := 0; ; attempt++ {
+		err = postNotification(ctx, client, target, body, signature)
+		if err == nil || attempt == NOTIFY_RETRIES {
+			return err
+		}
+
+		var status statusError
+		if errors.As(err, &status) && status < 500 && status != http.StatusTooManyRequests {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func postNotification(ctx context.Context, client *http.Client, target string, body []byte, signature string) error {
+	ctx, cancel := context.WithTimeout(ctx, NOTIFY_TIMEOUT)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", address.USER_AGENT_NAME+"/"+currentVersion().Version)
+	if signature != "" {
+		request.Header.Set(NOTIFY_SIGNATURE_HEADER, signature)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		// The error would repeat the URL, token included.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return statusError(response.StatusCode)
+	}
+	return nil
+}
+
+// statusError is the error of a notification answered with a non-2xx status.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", int(e))
+}
+
+// notifyBatch sends the --notify-url notification of a finished batch,
+// logging a failure rather than returning it: the exit code stays the
+// batch's own.
+func (o *lookupOptions) notifyBatch(ctx context.Context, logger *slog.Logger, started time.Time, exitCode int) {
+	if o.notifyURL == "" {
+		return
+	}
+
+	o.summary.ExitCode = exitCode
+	o.summary.Interrupted = exitCode == EXIT_INTERRUPTED
+	payload := notification{
+		RunID:      o.runID,
+		StartedAt:  started.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Summary:    o.summary,
+		Output:     o.outputFile,
+	}
+
+	// An interrupted batch still reports how far it got.
+	ctx = context.WithoutCancel(ctx)
+	if err := notify(ctx, http.DefaultClient, o.notifyURL, o.notifySecret, payload); err != nil {
+		logger.Warn("batch notification failed", "url", redactURL(o.notifyURL), "error", err)
+	}
+}
+
+// redactURL hides the password and query of a webhook URL, which often
+// carry a token.
+func redactURL(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+
+	parsed.RawQuery = ""
+	return parsed.Redacted()
+}
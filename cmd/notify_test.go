@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhook answers each notification with the next of statuses, repeating
+// the last one, and hands the requests it got to received.
+func webhook(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32, chan *http.Request) {
+	var calls atomic.Int32
+	received := make(chan *http.Request, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		received <- r
+
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls, received
+}
+
+var finished = notification{
+	RunID:      "run-1",
+	StartedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	FinishedAt: time.Date(2024, 5, 1, 12, 0, 3, 0, time.UTC),
+	Summary:    batchSummary{Total: 3, Processed: 3, Succeeded: 2, Failed: 1, ExitCode: 1},
+}
+
+func TestNotifySignsTheBody(t *testing.T) {
+	server, _, received := webhook(t, http.StatusNoContent)
+
+	if err := notify(context.Background(), server.Client(), server.URL, "s3cret", finished); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-received
+	body, _ := io.ReadAll(request.Body)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if got, want := request.Header.Get(NOTIFY_SIGNATURE_HEADER), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+	if request.Method != http.MethodPost || request.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("%s with Content-Type %q, want a JSON POST", request.Method, request.Header.Get("Content-Type"))
+	}
+
+	var got notification
+	if err := json.Unmarshal(body, &got); err != nil || got != finished {
+		t.Errorf("body %s, want the notification", body)
+	}
+
+	// Without a secret the request is not signed.
+	if err := notify(context.Background(), server.Client(), server.URL, "", finished); err != nil {
+		t.Fatal(err)
+	}
+	if signature := (<-received).Header.Get(NOTIFY_SIGNATURE_HEADER); signature != "" {
+		t.Errorf("unsigned notification carries %q", signature)
+	}
+}
+
+func TestNotifyRetries(t *testing.T) {
+	server, calls, _ := webhook(t, http.StatusServiceUnavailable, http.StatusOK)
+	if err := notify(context.Background(), server.Client(), server.URL, "", finished); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("%d requests, want a 503 retried once", n)
+	}
+
+	server, calls, _ = webhook(t, http.StatusBadRequest)
+	var status statusError
+	if err := notify(context.Background(), server.Client(), server.URL, "", finished); !errors.As(err, &status) || status != http.StatusBadRequest {
+		t.Errorf("err = %v, want the 400", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d requests, want a 400 not retried", n)
+	}
+}
+
+func TestNotifyErrorLeavesTheURLOut(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	// The first backoff is cut short, so notify returns the refused
+	// connection.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(NOTIFY_BACKOFF/5, cancel)
+
+	err := notify(ctx, http.DefaultClient, server.URL+"/hook?token=s3cret", "", finished)
+	if err == nil || errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("err = %v, want the connection failure without the URL", err)
+	}
+}
+
+func TestValidNotifyURL(t *testing.T) {
+	for value, want := range map[string]bool{
+		"https://hooks.example.com/batch": true,
+		"http://localhost:8080/":          true,
+		"ftp://example.com/":              false,
+		"hooks.example.com/batch":         false,
+		"https://":                        false,
+	} {
+		if got := validNotifyURL(value); got != want {
+			t.Errorf("validNotifyURL(%q) = %t, want %t", value, got, want)
+		}
+	}
+}
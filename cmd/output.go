@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+type resultWriter interface {
+	Write(result address.BatchResult) error
+	Close() error
+}
+
+// renderOptions says how results are rendered. The rendering itself only
+// depends on these and the results, never on flags or the terminal.
+type renderOptions struct {
+	// format is one of table, text, json, jsonl, csv, xml, template or
+	// quiet.
+	format string
+	// multiple wraps JSON output in an array even for a single result.
+	multiple bool
+	// streaming prints table rows as they arrive, at fixed widths.
+	streaming bool
+	color     bool
+	// skipHeader leaves out the CSV header, for appending to a file.
+	skipHeader bool
+	template   *template.Template
+	// lang is the language of the text format.
+	lang string
+	// passthrough, set with --passthrough, puts the input row of each
+	// result before it in the CSV and JSONL formats.
+	passthrough *passthrough
+	// errw receives the failures the template and XML formats do not
+	// render.
+	errw io.Writer
+}
+
+func newResultWriter(w io.Writer, options renderOptions) resultWriter {
+	switch options.format {
+	case "json":
+		return newJSONWriter(w, options.multiple)
+	case "jsonl":
+		jsonlWriter := newJSONLWriter(w)
+		jsonlWriter.passthrough = options.passthrough
+		return jsonlWriter
+	case "csv":
+		csvWriter := newCSVWriter(w)
+		csvWriter.wroteHeader = options.skipHeader
+		csvWriter.passthrough = options.passthrough
+		return csvWriter
+	case "xml":
+		return &xmlWriter{w: w, errw: options.errw}
+	case "template":
+		return &templateWriter{w: w, errw: options.errw, tmpl: options.template}
+	case "text":
+		return &textWriter{w: w, lang: options.lang}
+	case "quiet":
+		return &quietWriter{w: w}
+	default:
+		return newTableWriter(w, options.color, options.streaming)
+	}
+}
+
+// render writes results to w as described by options.
+func render(w io.Writer, results []address.BatchResult, options renderOptions) error {
+	return writeResults(newResultWriter(w, options), results)
+}
+
+func writeResults(writer resultWriter, results []address.BatchResult) error {
+	for _, result := range results {
+		if err := writer.Write(result); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+type jsonError struct {
+	CEP   string `json:"cep"`
+	Error string `json:"error"`
+}
+
+func jsonValue(result address.BatchResult) any {
+	if result.Err != nil {
+		return jsonError{CEP: result.CEP, Error: result.Err.Error()}
+	}
+
+	return result.Address
+}
+
+type jsonWriter struct {
+	w        io.Writer
+	multiple bool
+	count    int
+}
+
+func newJSONWriter(w io.Writer, multiple bool) *jsonWriter {
+	return &jsonWriter{w: w, multiple: multiple}
+}
+
+func (j *jsonWriter) Write(result address.BatchResult) error {
+	data, err := json.Marshal(jsonValue(result))
+	if err != nil {
+		return err
+	}
+
+	if !j.multiple {
+		_, err = fmt.Fprintf(j.w, "%s\n", data)
+		return err
+	}
+
+	separator := ","
+	if j.count == 0 {
+		separator = "["
+	}
+	j.count++
+
+	_, err = fmt.Fprintf(j.w, "%s%s", separator, data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if !j.multiple {
+		return nil
+	}
+
+	closing := "]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+func writeJSON(w io.Writer, results []address.BatchResult) error {
+	return render(w, results, renderOptions{format: "json", multiple: len(results) != 1})
+}
+
+type jsonlLine struct {
+	Row       map[string]string      `json:"row,omitempty"`
+	CEP       string                 `json:"cep"`
+	Address   *address.AddressResult `json:"address"`
+	Error     string                 `json:"error,omitempty"`
+	Source    string                 `json:"source,omitempty"`
+	LatencyMS int64                  `json:"latency_ms"`
+}
+
+// jsonlWriter emits one self-contained JSON object per line. Each line is a
+// single Write on the underlying writer, so nothing is held back between
+// results.
+type jsonlWriter struct {
+	w           io.Writer
+	passthrough *passthrough
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	return &jsonlWriter{w: w}
+}
+
+func (j *jsonlWriter) Write(result address.BatchResult) error {
+	line := jsonlLine{CEP: result.CEP, LatencyMS: result.Latency.Milliseconds()}
+	if j.passthrough != nil {
+		line.Row = make(map[string]string, len(j.passthrough.header))
+		for i, value := range j.passthrough.values(result.Index) {
+			line.Row[j.passthrough.header[i]] = value
+		}
+	}
+
+	if result.Err != nil {
+		line.Error = result.Err.Error()
+	} else {
+		addressResult := result.Address
+		line.Address = &addressResult
+		line.Source = addressResult.Source
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.w.Write(append(data, '\n'))
+	return err
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+type quietWriter struct {
+	w io.Writer
+}
+
+func quietLine(result address.AddressResult) string {
+	parts := make([]string, 0, 4)
+	for _, part := range []string{result.Street, result.Neighborhood} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	cityState := result.City
+	if result.State != "" {
+		cityState += "/" + result.State
+	}
+	if cityState != "" {
+		parts = append(parts, cityState)
+	}
+
+	if result.ZipCode != "" {
+		parts = append(parts, zipDash(result.ZipCode))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (q *quietWriter) Write(result address.BatchResult) error {
+	if result.Err != nil {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(q.w, quietLine(result.Address))
+	return err
+}
+
+func (q *quietWriter) Close() error {
+	return nil
+}
+
+// textWriter prints each result as a block of labeled lines, see
+// address.AddressResult.Format.
+type textWriter struct {
+	w     io.Writer
+	lang  string
+	count int
+}
+
+func (t *textWriter) Write(result address.BatchResult) error {
+	separator := ""
+	if t.count > 0 {
+		separator = "\n"
+	}
+	t.count++
+
+	if result.Err != nil {
+		labels := address.Labels(t.lang)
+		_, err := fmt.Fprintf(t.w, "%s%s: %s\n%s: %s\n", separator, labels["zip"], result.CEP, labels["error"], result.Err.Error())
+		return err
+	}
+
+	_, err := fmt.Fprintf(t.w, "%s%s", separator, result.Address.Format(t.lang))
+	return err
+}
+
+func (t *textWriter) Close() error {
+	return nil
+}
+
+var csvHeader = []string{"cep", "street", "neighborhood", "city", "state", "source", "error"}
+
+type csvWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+	passthrough *passthrough
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+
+	if c.passthrough != nil {
+		return c.writer.Write(append(slices.Clip(c.passthrough.header), csvHeader...))
+	}
+	return c.writer.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(result address.BatchResult) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	record := []string{result.CEP, "", "", "", "", "", ""}
+
+	if result.Err != nil {
+		record[6] = result.Err.Error()
+	} else {
+		record[1] = result.Address.Street
+		record[2] = result.Address.Neighborhood
+		record[3] = result.Address.City
+		record[4] = result.Address.State
+		record[5] = result.Address.Source
+	}
+
+	if c.passthrough != nil {
+		record = append(c.passthrough.values(result.Index), record...)
+	}
+
+	if err := c.writer.Write(record); err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func writeCSV(w io.Writer, results []address.BatchResult) error {
+	return render(w, results, renderOptions{format: "csv"})
+}
+
+// xmlWriter writes the successful results as one document when closed,
+// since the document cannot be streamed; failures go to errw.
+type xmlWriter struct {
+	w         io.Writer
+	errw      io.Writer
+	addresses []address.AddressResult
+}
+
+func (x *xmlWriter) Write(result address.BatchResult) error {
+	if result.Err != nil {
+		fmt.Fprintf(x.errw, "[%s] error: %s\n", result.CEP, result.Err.Error())
+		return nil
+	}
+
+	x.addresses = append(x.addresses, result.Address)
+	return nil
+}
+
+func (x *xmlWriter) Close() error {
+	return address.WriteXML(x.w, x.addresses)
+}
+
+// Helpers available to --format templates:
+//
+//	upper   converts a value to upper case
+//	lower   converts a value to lower case
+//	zipdash formats an 8-digit CEP as 00000-000
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"zipdash": zipDash,
+}
+
+func zipDash(cep string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, cep)
+
+	if len(digits) != 8 {
+		return cep
+	}
+
+	return digits[:5] + "-" + digits[5:]
+}
+
+func parseFormat(text string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Parse(text)
+}
+
+type templateWriter struct {
+	w    io.Writer
+	errw io.Writer
+	tmpl *template.Template
+}
+
+func (t *templateWriter) Write(result address.BatchResult) error {
+	if result.Err != nil {
+		fmt.Fprintf(t.errw, "[%s] error: %s\n", result.CEP, result.Err.Error())
+		return nil
+	}
+
+	var line strings.Builder
+	if err := t.tmpl.Execute(&line, result.Address); err != nil {
+		fmt.Fprintf(t.errw, "[%s] format error: %s\n", result.CEP, err.Error())
+		return nil
+	}
+
+	_, err := fmt.Fprintln(t.w, line.String())
+	return err
+}
+
+func (t *templateWriter) Close() error {
+	return nil
+}
+
+func writeTemplate(w io.Writer, errw io.Writer, tmpl *template.Template, results []address.BatchResult) error {
+	return render(w, results, renderOptions{format: "template", template: tmpl, errw: errw})
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// Run with -update to rewrite the golden files from the current output.
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+var (
+	sé = address.BatchResult{
+		CEP:     "01001-000",
+		Address: address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP", StateName: "São Paulo", Source: "ViaCEP"},
+		Latency: 120 * time.Millisecond,
+	}
+	candelária = address.BatchResult{
+		Index:   1,
+		CEP:     "20040010",
+		Address: address.AddressResult{ZipCode: "20040010", Street: "Rua da Candelária", Neighborhood: "Centro", City: "Rio de Janeiro", State: "RJ", StateName: "Rio de Janeiro", Source: "BrasilAPI"},
+		Latency: 85 * time.Millisecond,
+	}
+	notFound = address.BatchResult{
+		Index:   2,
+		CEP:     "99999999",
+		Err:     fmt.Errorf("ViaCEP: %w", address.ErrNotFound),
+		Latency: 40 * time.Millisecond,
+	}
+	accented = address.BatchResult{
+		CEP: "88010400",
+		Address: address.AddressResult{
+			ZipCode:      "88010400",
+			Street:       "Avenida Governador Jorge Lacerda da Conceição Araújo",
+			Neighborhood: "Centro Histórico de São José",
+			City:         "Florianópolis",
+			State:        "SC",
+			StateName:    "Santa Catarina",
+			Source:       "BrasilAPI",
+		},
+		Latency: 230 * time.Millisecond,
+	}
+)
+
+// goldenCases are the result sets every format is rendered for.
+var goldenCases = []struct {
+	name    string
+	results []address.BatchResult
+}{
+	{"single", []address.BatchResult{sé}},
+	{"multiple", []address.BatchResult{sé, candelária, notFound}},
+	{"accented", []address.BatchResult{accented}},
+	{"empty", nil},
+}
+
+// checkGolden compares got with testdata/name.golden, or rewrites it with
+// -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the test with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (-want +got):\n%s", path, lineDiff(string(want), string(got)))
+	}
+}
+
+// lineDiff shows the lines of want and got that differ, from their longest
+// common subsequence of lines.
+func lineDiff(want, got string) string {
+	lines := func(text string) []string {
+		return strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	a, b := lines(want), lines(got)
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	line := func(prefix, text string) {
+		fmt.Fprintf(&diff, "%s %q\n", prefix, text)
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			line(" ", a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			line("-", a[i])
+			i++
+		default:
+			line("+", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
+
+func TestRenderGolden(t *testing.T) {
+	tmpl, err := parseFormat(`{{.ZipCode | zipdash}} {{upper .City}}/{{.State}} ({{.Source}})`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formats := []struct {
+		name    string
+		options renderOptions
+	}{
+		{"table", renderOptions{format: "table"}},
+		{"table-color", renderOptions{format: "table", color: true}},
+		{"table-streaming", renderOptions{format: "table", streaming: true}},
+		{"text", renderOptions{format: "text"}},
+		{"text-pt", renderOptions{format: "text", lang: "pt"}},
+		{"json", renderOptions{format: "json"}},
+		{"json-multiple", renderOptions{format: "json", multiple: true}},
+		{"jsonl", renderOptions{format: "jsonl"}},
+		{"csv", renderOptions{format: "csv"}},
+		{"xml", renderOptions{format: "xml"}},
+		{"template", renderOptions{format: "template", template: tmpl}},
+		{"quiet", renderOptions{format: "quiet"}},
+	}
+
+	for _, format := range formats {
+		for _, test := range goldenCases {
+			name := format.name + "/" + test.name
+			t.Run(name, func(t *testing.T) {
+				var stdout, stderr bytes.Buffer
+				options := format.options
+				options.errw = &stderr
+				if err := render(&stdout, test.results, options); err != nil {
+					t.Fatal(err)
+				}
+
+				// What the template and XML formats leave out goes to
+				// stderr, kept in the same file.
+				got := stdout.Bytes()
+				if stderr.Len() > 0 {
+					got = append(got, "-- stderr --\n"...)
+					got = append(got, stderr.Bytes()...)
+				}
+				checkGolden(t, name, got)
+			})
+		}
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	tmpl, err := parseFormat(`{{.Missing}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := render(&stdout, []address.BatchResult{sé}, renderOptions{format: "template", template: tmpl, errw: &stderr}); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 || !strings.HasPrefix(stderr.String(), "[01001-000] format error: ") {
+		t.Errorf("stdout %q, stderr %q; want the format error on stderr only", stdout.String(), stderr.String())
+	}
+
+	if _, err := parseFormat(`{{.City`); err == nil {
+		t.Error("an unterminated template parsed")
+	}
+}
+
+// failingWriter fails every write, as a closed pipe would.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRenderReportsWriteErrors(t *testing.T) {
+	for _, format := range []string{"table", "text", "json", "jsonl", "csv", "xml", "quiet"} {
+		if err := render(failingWriter{}, []address.BatchResult{sé}, renderOptions{format: format}); err == nil {
+			t.Errorf("%s: write error not reported", format)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// PROGRESS_INTERVAL throttles the in-place progress line on a terminal;
+// PROGRESS_PLAIN_INTERVAL spaces out the plain lines written to logs.
+const (
+	PROGRESS_INTERVAL       = 250 * time.Millisecond
+	PROGRESS_PLAIN_INTERVAL = 10 * time.Second
+)
+
+const clearLine = "\r\033[K"
+
+type progressStats struct {
+	total     int
+	processed int
+	succeeded int
+	failed    int
+}
+
+// progress renders batch progress to stderr from the results it is fed. On a
+// terminal it redraws a single line in place, otherwise it writes a plain
+// line every PROGRESS_PLAIN_INTERVAL. It is not safe for concurrent use; the
+// batch loop owns it.
+type progress struct {
+	w        io.Writer
+	tty      bool
+	interval time.Duration
+	stats    progressStats
+	start    time.Time
+	last     time.Time
+	drawn    bool
+	cleared  bool
+}
+
+// newProgress reports against total CEPs, or only counts them when total is
+// zero because the input size is not known up front.
+func newProgress(w io.Writer, tty bool, total int, now time.Time) *progress {
+	interval := PROGRESS_PLAIN_INTERVAL
+	if tty {
+		interval = PROGRESS_INTERVAL
+	}
+
+	return &progress{
+		w:        w,
+		tty:      tty,
+		interval: interval,
+		stats:    progressStats{total: total},
+		start:    now,
+		last:     now,
+	}
+}
+
+func (p *progress) record(result address.BatchResult, now time.Time) {
+	p.stats.processed++
+	if result.Err != nil {
+		p.stats.failed++
+	} else {
+		p.stats.succeeded++
+	}
+
+	if p.due(now) {
+		p.render(now)
+	}
+}
+
+// due reports whether enough time has passed since the last render, or the
+// line was cleared and has to come back.
+func (p *progress) due(now time.Time) bool {
+	return p.cleared || now.Sub(p.last) >= p.interval
+}
+
+func (p *progress) render(now time.Time) {
+	line := formatProgress(p.stats, now.Sub(p.start))
+	p.last = now
+	p.cleared = false
+
+	if p.tty {
+		fmt.Fprint(p.w, clearLine+line)
+		p.drawn = true
+		return
+	}
+
+	fmt.Fprintln(p.w, "progress: "+line)
+}
+
+// clear removes the in-place line so other output written to the terminal
+// does not land on it. The line comes back with the next recorded result.
+func (p *progress) clear() {
+	if p.drawn {
+		fmt.Fprint(p.w, clearLine)
+		p.drawn = false
+		p.cleared = true
+	}
+}
+
+// finish writes the final tally and leaves it on its own line.
+func (p *progress) finish(now time.Time) {
+	p.render(now)
+	if p.tty {
+		fmt.Fprintln(p.w)
+		p.drawn = false
+	}
+}
+
+func formatProgress(stats progressStats, elapsed time.Duration) string {
+	var b strings.Builder
+
+	if stats.total > 0 {
+		fmt.Fprintf(&b, "%d/%d (%.1f%%)", stats.processed, stats.total, 100*float64(stats.processed)/float64(stats.total))
+	} else {
+		fmt.Fprintf(&b, "%d processed", stats.processed)
+	}
+
+	fmt.Fprintf(&b, ", %d ok, %d failed", stats.succeeded, stats.failed)
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(stats.processed) / elapsed.Seconds()
+	}
+	fmt.Fprintf(&b, ", %.1f/s", rate)
+
+	if remaining := stats.total - stats.processed; remaining > 0 && rate > 0 {
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+		fmt.Fprintf(&b, ", ETA %s", eta.Round(time.Second))
+	}
+
+	return b.String()
+}
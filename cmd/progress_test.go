@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		name    string
+		stats   progressStats
+		elapsed time.Duration
+		want    string
+	}{
+		{"known total", progressStats{total: 200, processed: 50, succeeded: 48, failed: 2}, 10 * time.Second, "50/200 (25.0%), 48 ok, 2 failed, 5.0/s, ETA 30s"},
+		{"done", progressStats{total: 4, processed: 4, succeeded: 4}, 2 * time.Second, "4/4 (100.0%), 4 ok, 0 failed, 2.0/s"},
+		{"unknown total", progressStats{processed: 7, succeeded: 6, failed: 1}, time.Second, "7 processed, 6 ok, 1 failed, 7.0/s"},
+		{"nothing yet", progressStats{total: 10}, 0, "0/10 (0.0%), 0 ok, 0 failed, 0.0/s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatProgress(test.stats, test.elapsed); got != test.want {
+				t.Errorf("formatProgress = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProgressThrottles(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	failed := address.BatchResult{Err: errors.New("failed")}
+
+	t.Run("terminal", func(t *testing.T) {
+		var out bytes.Buffer
+		meter := newProgress(&out, true, 10, start)
+
+		// Results inside PROGRESS_INTERVAL of the last render are only
+		// counted.
+		meter.record(address.BatchResult{}, start.Add(PROGRESS_INTERVAL/2))
+		if out.Len() > 0 {
+			t.Fatalf("rendered %q before PROGRESS_INTERVAL passed", out.String())
+		}
+		meter.record(failed, start.Add(PROGRESS_INTERVAL))
+		if want := clearLine + "2/10 (20.0%), 1 ok, 1 failed, 8.0/s, ETA 1s"; out.String() != want {
+			t.Fatalf("output = %q, want %q", out.String(), want)
+		}
+
+		// A cleared line comes back with the next result, however soon.
+		out.Reset()
+		meter.clear()
+		meter.record(address.BatchResult{}, start.Add(PROGRESS_INTERVAL+time.Millisecond))
+		if !strings.HasPrefix(out.String(), clearLine+clearLine+"3/10") {
+			t.Errorf("output = %q, want the line cleared and redrawn", out.String())
+		}
+
+		out.Reset()
+		meter.finish(start.Add(PROGRESS_INTERVAL + 2*time.Millisecond))
+		if !strings.HasSuffix(out.String(), "\n") {
+			t.Errorf("finish wrote %q, want the tally on its own line", out.String())
+		}
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		var out bytes.Buffer
+		meter := newProgress(&out, false, 0, start)
+
+		for i := range 5 {
+			meter.record(address.BatchResult{}, start.Add(time.Duration(i)*time.Second))
+		}
+		if out.Len() > 0 {
+			t.Fatalf("rendered %q before PROGRESS_PLAIN_INTERVAL passed", out.String())
+		}
+		meter.record(address.BatchResult{}, start.Add(PROGRESS_PLAIN_INTERVAL))
+		meter.finish(start.Add(PROGRESS_PLAIN_INTERVAL + time.Second))
+
+		want := "progress: 6 processed, 6 ok, 0 failed, 0.6/s\nprogress: 6 processed, 6 ok, 0 failed, 0.5/s\n"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+}
+
+func TestBatchReportsProgress(t *testing.T) {
+	env := newTestEnv("01001000\n123\n01001-000\n", addresstest.NewMockProvider("Mock").Returns(sé.Address))
+
+	if code := env.run(context.Background(), []string{"batch", "--jsonl"}); code != EXIT_INVALID_CEP {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_INVALID_CEP, env.stderr.String())
+	}
+
+	// Stdin's size is not known up front, so the batch only counts.
+	pattern := regexp.MustCompile(`(?m)^progress: 3 processed, 2 ok, 1 failed, [\d.]+/s$`)
+	if !pattern.MatchString(env.stderr.String()) {
+		t.Errorf("stderr = %q, want the final tally", env.stderr.String())
+	}
+}
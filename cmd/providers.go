@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+type providerJSON struct {
+	Name      string `json:"name"`
+	Enabled   bool   `json:"enabled"`
+	BaseURL   string `json:"base_url,omitempty"`
+	Checked   bool   `json:"checked"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func runProviders(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "providers")
+	var global globalOptions
+	global.register(flags)
+	check := flags.Bool("check", false, "probe every enabled provider with a live lookup")
+	checkTimeout := flags.Duration("check-timeout", address.DEFAULT_HEALTH_CHECK_TIMEOUT, "maximum time each --check probe may take")
+	jsonOutput := flags.Bool("json", false, "print providers as JSON")
+	only := flags.String("only", "", "comma-separated list of providers to show")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if err := global.resolve(env, flags); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	service, err := global.newService(ctx, env)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+	defer service.Close()
+
+	var statuses []address.ProviderStatus
+	if *check {
+		statuses = service.CheckProviders(ctx, *checkTimeout)
+	} else {
+		statuses = service.ProviderStatuses()
+	}
+
+	statuses, err = filterStatuses(statuses, *only)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	if *jsonOutput {
+		if err := writeProvidersJSON(env.stdout, statuses); err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+	} else {
+		writeProvidersTable(env.stdout, statuses, *check)
+	}
+
+	for _, status := range statuses {
+		if status.Checked && status.Err != nil {
+			return EXIT_PROVIDERS_FAILED
+		}
+	}
+
+	return EXIT_SUCCESS
+}
+
+func filterStatuses(statuses []address.ProviderStatus, only string) ([]address.ProviderStatus, error) {
+	if only == "" {
+		return statuses, nil
+	}
+
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = status.Name
+	}
+
+	var filtered []address.ProviderStatus
+	for _, name := range strings.Split(only, ",") {
+		name = strings.TrimSpace(name)
+		index := slices.IndexFunc(statuses, func(status address.ProviderStatus) bool {
+			return strings.EqualFold(status.Name, name)
+		})
+
+		if index < 0 {
+			return nil, fmt.Errorf("%w %q (valid: %s)", address.ErrUnknownProvider, name, strings.Join(names, ", "))
+		}
+
+		filtered = append(filtered, statuses[index])
+	}
+
+	return filtered, nil
+}
+
+func writeProvidersTable(w io.Writer, statuses []address.ProviderStatus, check bool) {
+	rows := [][]string{{"NAME", "STATUS", "BASE URL"}}
+	if check {
+		rows[0] = append(rows[0], "CHECK")
+	}
+
+	for _, status := range statuses {
+		state := "disabled"
+		if status.Enabled {
+			state = "enabled"
+		}
+
+		row := []string{status.Name, state, status.BaseURL}
+		if check {
+			row = append(row, checkResult(status))
+		}
+
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, value := range row {
+			widths[i] = max(widths[i], len(value))
+		}
+	}
+
+	for _, row := range rows {
+		writeTableLine(w, row, widths, "", false)
+	}
+}
+
+func checkResult(status address.ProviderStatus) string {
+	switch {
+	case !status.Checked:
+		return "-"
+	case status.Err != nil:
+		return "error: " + status.Err.Error()
+	}
+
+	return "OK " + formatDuration(status.Latency)
+}
+
+func writeProvidersJSON(w io.Writer, statuses []address.ProviderStatus) error {
+	values := make([]providerJSON, len(statuses))
+
+	for i, status := range statuses {
+		values[i] = providerJSON{
+			Name:    status.Name,
+			Enabled: status.Enabled,
+			BaseURL: status.BaseURL,
+			Checked: status.Checked,
+			Healthy: status.Healthy(),
+		}
+
+		if status.Checked {
+			values[i].LatencyMS = status.Latency.Milliseconds()
+		}
+
+		if status.Err != nil {
+			values[i].Error = status.Err.Error()
+		}
+	}
+
+	return json.NewEncoder(w).Encode(values)
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// providersEnv has a healthy ViaCEP, a BrasilAPI answering 500 and a
+// disabled Spare, which is never contacted.
+func providersEnv(t *testing.T) (*testEnv, *addresstest.Server, *httptest.Server, *addresstest.MockProvider) {
+	t.Helper()
+
+	healthy := addresstest.NewViaCEPServer(map[string]address.AddressResult{sé.Address.ZipCode: sé.Address})
+	t.Cleanup(healthy.Close)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+	spare := addresstest.NewMockProvider("Spare").Returns(sé.Address)
+
+	env := newTestEnv("", address.NewViaCEPProvider(healthy.BaseURL()), address.NewBrasilAPIProvider(broken.URL), spare)
+	return env, healthy, broken, spare
+}
+
+func TestProvidersTable(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want []string
+	}{
+		{
+			name: "listed",
+			args: nil,
+			code: EXIT_SUCCESS,
+			want: []string{
+				`^NAME +STATUS +BASE URL$`,
+				`^ViaCEP +enabled +http://127\.0\.0\.1:\d+/ws$`,
+				`^BrasilAPI +enabled +http://127\.0\.0\.1:\d+$`,
+				`^Spare +disabled$`,
+			},
+		},
+		{
+			name: "checked",
+			args: []string{"--check"},
+			code: EXIT_PROVIDERS_FAILED,
+			want: []string{
+				`^NAME +STATUS +BASE URL +CHECK$`,
+				`^ViaCEP +enabled +\S+ +OK \S+$`,
+				`^BrasilAPI +enabled +\S+ +error: .*500`,
+				`^Spare +disabled +-$`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, _, _, spare := providersEnv(t)
+
+			args := append([]string{"providers", "--providers", "viacep,brasilapi"}, test.args...)
+			if code := env.run(context.Background(), args); code != test.code {
+				t.Fatalf("exit code %d, want %d\n%s", code, test.code, env.stderr.String())
+			}
+
+			lines := strings.Split(strings.TrimRight(env.stdout.String(), "\n"), "\n")
+			if len(lines) != len(test.want) {
+				t.Fatalf("%d lines, want %d:\n%s", len(lines), len(test.want), env.stdout.String())
+			}
+			for i, pattern := range test.want {
+				if !regexp.MustCompile(pattern).MatchString(lines[i]) {
+					t.Errorf("line %d = %q, want it to match %s", i+1, lines[i], pattern)
+				}
+			}
+			spare.AssertCalls(t, 0)
+		})
+	}
+}
+
+func TestProvidersJSON(t *testing.T) {
+	env, healthy, broken, spare := providersEnv(t)
+
+	code := env.run(context.Background(), []string{"providers", "--providers", "viacep,brasilapi", "--check", "--json"})
+	if code != EXIT_PROVIDERS_FAILED {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_PROVIDERS_FAILED, env.stderr.String())
+	}
+
+	var got []providerJSON
+	if err := json.Unmarshal(env.stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, env.stdout.String())
+	}
+	if len(got) != 3 {
+		t.Fatalf("%d providers, want 3: %+v", len(got), got)
+	}
+
+	// Latency and the error text vary; the rest is checked as a whole.
+	if got[1].Error == "" || !strings.Contains(got[1].Error, "500") {
+		t.Errorf("BrasilAPI error = %q, want the 500", got[1].Error)
+	}
+	for i := range got {
+		got[i].LatencyMS = 0
+		got[i].Error = ""
+	}
+	want := []providerJSON{
+		{Name: "ViaCEP", Enabled: true, BaseURL: healthy.BaseURL(), Checked: true, Healthy: true},
+		{Name: "BrasilAPI", Enabled: true, BaseURL: broken.URL, Checked: true},
+		{Name: "Spare"},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("provider %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	spare.AssertCalls(t, 0)
+}
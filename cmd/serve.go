@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/grpcapi"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+const DEFAULT_SHUTDOWN_TIMEOUT = 15 * time.Second
+const DEFAULT_CACHE_TTL = 24 * time.Hour
+
+// REQUEST_TIMEOUT_MARGIN keeps the default request deadline just above the
+// lookup timeout, so lookups normally time out on their own first.
+const REQUEST_TIMEOUT_MARGIN = 1 * time.Second
+
+func runServe(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "serve")
+	var global globalOptions
+	global.register(flags)
+	config := httpapi.DefaultServerConfig()
+	flags.StringVar(&config.Addr, "addr", config.Addr, "address to listen on")
+	flags.StringVar(&config.TLSCertFile, "tls-cert", "", "serve HTTPS with this certificate file (needs --tls-key)")
+	flags.StringVar(&config.TLSKeyFile, "tls-key", "", "private key of --tls-cert")
+	flags.DurationVar(&config.ReadHeaderTimeout, "read-header-timeout", config.ReadHeaderTimeout, "maximum time to read request headers")
+	flags.DurationVar(&config.ReadTimeout, "read-timeout", config.ReadTimeout, "maximum time to read a whole request")
+	flags.DurationVar(&config.WriteTimeout, "write-timeout", config.WriteTimeout, "maximum time to write a response, must exceed --request-timeout")
+	flags.DurationVar(&config.IdleTimeout, "idle-timeout", config.IdleTimeout, "how long idle keep-alive connections stay open")
+	flags.IntVar(&config.MaxHeaderBytes, "max-header-bytes", config.MaxHeaderBytes, "maximum size of request headers")
+	grpcAddr := flags.String("grpc-addr", "", "also serve the gRPC API on this address (default: gRPC disabled)")
+	metricsAddr := flags.String("metrics-addr", "", "serve GET /metrics on this separate address instead of --addr")
+	shutdownTimeout := flags.Duration("shutdown-timeout", DEFAULT_SHUTDOWN_TIMEOUT, "how long in-flight requests may take to finish after SIGTERM or SIGINT")
+	trustProxy := flags.Bool("trust-proxy", false, "take the client IP from X-Forwarded-For (only behind a trusted proxy)")
+	quiet := flags.Bool("quiet", false, "disable access and library logs")
+	rateLimit := flags.Float64("rate-limit", 0, "requests per second allowed per client (0 disables)")
+	rateBurst := flags.Int("rate-burst", 20, "requests a client may make in a burst above --rate-limit")
+	rateLimitHeader := flags.String("rate-limit-header", "", "identify clients by this header (e.g. X-API-Key) instead of by IP")
+	cacheTTL := flags.Duration("cache-ttl", DEFAULT_CACHE_TTL, "keep resolved CEPs in memory and let HTTP caches keep them for this long (0 disables)")
+	apiKeysFile := flags.String("api-keys-file", "", "require one of the label:key pairs in this file (reloaded on SIGHUP)")
+	corsOrigins := flags.String("cors-origins", "", "comma-separated origins allowed to call the API from a browser, e.g. https://*.example.com (default: CORS disabled)")
+	corsMethods := flags.String("cors-methods", strings.Join(httpapi.DEFAULT_CORS_METHODS, ","), "comma-separated methods allowed in CORS requests")
+	corsHeaders := flags.String("cors-headers", strings.Join(httpapi.DEFAULT_CORS_HEADERS, ","), "comma-separated request headers allowed in CORS requests")
+	corsMaxAge := flags.Duration("cors-max-age", httpapi.DEFAULT_CORS_MAX_AGE, "how long browsers may cache a preflight response")
+	corsCredentials := flags.Bool("cors-credentials", false, "allow CORS requests with credentials")
+	gzipMinSize := flags.Int("gzip-min-size", httpapi.DEFAULT_GZIP_MIN_SIZE, "compress responses of at least this many bytes for clients that accept gzip (-1 disables)")
+	requestTimeout := flags.Duration("request-timeout", 0, "answer 504 and cancel the lookups of requests taking longer than this (default: --timeout plus 1s)")
+	maxBatch := flags.Int("max-batch", httpapi.DEFAULT_MAX_BATCH_SIZE, "maximum number of CEPs accepted by POST /cep/batch")
+	warmUp := flags.Bool("warm-up", false, "connect to every provider before listening, so the first lookups skip DNS, TCP and TLS setup")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if err := global.resolve(env, flags); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	if global.insecure {
+		fmt.Fprintln(env.stderr, "--insecure is for local debugging and cannot be used with serve")
+		return EXIT_USAGE
+	}
+
+	if *maxBatch < 1 {
+		fmt.Fprintf(env.stderr, "--max-batch must be at least 1, got %d\n", *maxBatch)
+		return EXIT_USAGE
+	}
+
+	if *rateLimit < 0 || *rateBurst < 1 {
+		fmt.Fprintf(env.stderr, "--rate-limit must not be negative and --rate-burst must be at least 1, got %g and %d\n", *rateLimit, *rateBurst)
+		return EXIT_USAGE
+	}
+
+	if *cacheTTL < 0 {
+		fmt.Fprintf(env.stderr, "--cache-ttl must not be negative, got %s\n", *cacheTTL)
+		return EXIT_USAGE
+	}
+
+	if *requestTimeout < 0 {
+		fmt.Fprintf(env.stderr, "--request-timeout must not be negative, got %s\n", *requestTimeout)
+		return EXIT_USAGE
+	}
+
+	if *requestTimeout == 0 {
+		*requestTimeout = global.timeout + REQUEST_TIMEOUT_MARGIN
+	}
+
+	if config.WriteTimeout <= *requestTimeout {
+		fmt.Fprintf(env.stderr, "--write-timeout (%s) must be greater than the request timeout (%s)\n", config.WriteTimeout, *requestTimeout)
+		return EXIT_USAGE
+	}
+
+	if *shutdownTimeout < 0 {
+		fmt.Fprintf(env.stderr, "--shutdown-timeout must not be negative, got %s\n", *shutdownTimeout)
+		return EXIT_USAGE
+	}
+
+	keys, authenticate, err := loadAPIKeys(*apiKeysFile, env.lookupEnv)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The service outlives ctx so requests still being served during the
+	// grace period can complete their lookups; the deferred Close cancels
+	// whatever is left once shutdown is over.
+	service, err := global.newService(context.WithoutCancel(ctx), env)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+	defer service.Close()
+
+	logger := global.logger(env.stderr)
+	if *quiet {
+		logger = address.NopLogger()
+	}
+	service.SetLogger(logger)
+
+	if *cacheTTL > 0 {
+		service.SetCache(address.NewMemoryCache(*cacheTTL))
+	}
+
+	server, err := httpapi.NewServer(config, service)
+	if err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_USAGE
+	}
+
+	server.ErrorLog = slog.NewLogLogger(logger.Handler(), slog.LevelWarn)
+
+	handler := server.API.
+		SetMaxBatchSize(*maxBatch).
+		SetLogger(logger).
+		SetTrustProxy(*trustProxy).
+		SetRateLimit(*rateLimit, *rateBurst).
+		SetRateLimitHeader(*rateLimitHeader).
+		SetCacheMaxAge(*cacheTTL).
+		SetGzipMinSize(*gzipMinSize).
+		SetRequestTimeout(*requestTimeout)
+
+	if *corsOrigins != "" {
+		handler.SetCORS(&httpapi.CORS{
+			Origins:          splitList(*corsOrigins),
+			Methods:          splitList(strings.ToUpper(*corsMethods)),
+			Headers:          splitList(*corsHeaders),
+			MaxAge:           *corsMaxAge,
+			AllowCredentials: *corsCredentials,
+		})
+	}
+
+	if authenticate {
+		apiKeys := httpapi.NewAPIKeys(keys)
+		handler.SetAPIKeys(apiKeys)
+		logger.Info("API key authentication enabled", "keys", apiKeys.Len())
+
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		defer signal.Stop(reload)
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-reload:
+					keys, _, err := loadAPIKeys(*apiKeysFile, env.lookupEnv)
+					if err != nil {
+						logger.Error("reloading API keys failed, keeping the current ones", "error", err)
+						continue
+					}
+
+					apiKeys.Set(keys)
+					logger.Info("API keys reloaded", "keys", apiKeys.Len())
+				}
+			}
+		}()
+	}
+
+	// The gRPC listener is opened before any server starts, so a bad
+	// --grpc-addr fails the command at startup instead of after the HTTP
+	// server is already up.
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if *grpcAddr != "" {
+		grpcListener, err = net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintln(env.stderr, err.Error())
+			return EXIT_ERROR
+		}
+
+		var options []grpc.ServerOption
+		if config.TLS() {
+			options = append(options, grpc.Creds(credentials.NewTLS(server.TLSConfig)))
+		}
+
+		grpcServer = grpcapi.NewServer(service).
+			SetLogger(logger).
+			SetMaxBatchSize(*maxBatch).
+			RegisterMetrics(handler.Registry()).
+			GRPCServer(options...)
+	}
+
+	var metricsServer *http.Server
+	if *metricsAddr == "" {
+		handler.HandleMetrics()
+	} else {
+		metricsServer = &http.Server{
+			Addr:              *metricsAddr,
+			Handler:           handler.Metrics(),
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+		}
+	}
+
+	// One slot per server that may fail, so none of them blocks sending.
+	serveErrs := make(chan error, 3)
+	serve := func(listenAndServe func() error) {
+		if err := listenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErrs <- err
+		}
+	}
+
+	if metricsServer != nil {
+		fmt.Fprintf(env.stderr, "serving metrics on %s\n", *metricsAddr)
+		go serve(metricsServer.ListenAndServe)
+	}
+
+	if *warmUp {
+		if err := service.WarmUp(ctx); err != nil {
+			fmt.Fprintf(env.stderr, "warning: %s\n", err.Error())
+		}
+	}
+
+	scheme := "http"
+	if config.TLS() {
+		scheme = "https"
+	}
+	fmt.Fprintf(env.stderr, "listening on %s (%s)\n", config.Addr, scheme)
+	go serve(server.ListenAndServe)
+
+	if grpcServer != nil {
+		fmt.Fprintf(env.stderr, "serving gRPC on %s\n", *grpcAddr)
+		go serve(func() error {
+			return grpcServer.Serve(grpcListener)
+		})
+	}
+
+	select {
+	case err := <-serveErrs:
+		fmt.Fprintln(env.stderr, err.Error())
+		server.Close()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		return EXIT_ERROR
+	case <-ctx.Done():
+	}
+
+	stop()
+	fmt.Fprintf(env.stderr, "shutting down, waiting up to %s for in-flight requests\n", *shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if metricsServer != nil {
+		defer metricsServer.Close()
+	}
+
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+
+	code := EXIT_SUCCESS
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(env.stderr, "shutdown: %s, closing remaining connections\n", err.Error())
+		server.Close()
+		code = EXIT_ERROR
+	}
+
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-shutdownCtx.Done():
+			fmt.Fprintln(env.stderr, "shutdown: gRPC calls still running, closing them")
+			grpcServer.Stop()
+			code = EXIT_ERROR
+		}
+	}
+
+	return code
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestServeRefusesInsecure(t *testing.T) {
+	var stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader(""),
+		stdout:    &bytes.Buffer{},
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+	}
+
+	if code := env.run(context.Background(), []string{"serve", "--insecure", "--addr", "127.0.0.1:0"}); code != EXIT_USAGE {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_USAGE, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "--insecure") {
+		t.Errorf("stderr = %q, want it to name --insecure", stderr.String())
+	}
+}
+
+func TestServeFailsOnATakenGRPCAddress(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	var stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader(""),
+		stdout:    &bytes.Buffer{},
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			return address.NewAddressService(ctx).SetLogger(address.NopLogger())
+		},
+	}
+
+	code := env.run(context.Background(), []string{"serve", "--quiet", "--addr", "127.0.0.1:0", "--grpc-addr", taken.Addr().String()})
+	if code != EXIT_ERROR {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_ERROR, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "listening on") {
+		t.Errorf("stderr = %q, want the command to fail before any server starts", stderr.String())
+	}
+}
+
+// freeAddr returns a loopback address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// waitForListener waits until addr accepts connections.
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("nothing listening on %s: %v", addr, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to write from several goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// runningServer is a serve command running in the background.
+type runningServer struct {
+	addr     string
+	provider *addresstest.MockProvider
+	stderr   *syncBuffer
+	// stop cancels the command's context, as SIGTERM does.
+	stop context.CancelFunc
+	code chan int
+}
+
+// serveSlowly runs serve on a free address with a provider answering after
+// latency.
+func serveSlowly(t *testing.T, latency time.Duration, args ...string) *runningServer {
+	t.Helper()
+
+	provider := addresstest.NewMockProvider("Mock").Returns(sé.Address).SetLatency(latency)
+	stderr := &syncBuffer{}
+	env := &environment{
+		stdin:     strings.NewReader(""),
+		stdout:    &bytes.Buffer{},
+		stderr:    stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).RegisterProvider(provider)
+			service.SetProviders("Mock")
+			return service
+		},
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	t.Cleanup(stop)
+
+	server := &runningServer{addr: freeAddr(t), provider: provider, stderr: stderr, stop: stop, code: make(chan int, 1)}
+	go func() {
+		server.code <- env.run(ctx, append([]string{"serve", "--quiet", "--addr", server.addr, "--timeout", "5s"}, args...))
+	}()
+	waitForListener(t, server.addr)
+
+	return server
+}
+
+// get requests path in the background and sends its status, or the error,
+// once the response is read.
+func (s *runningServer) get(path string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		response, err := http.Get("http://" + s.addr + path)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer response.Body.Close()
+
+		if _, err := io.Copy(io.Discard, response.Body); err != nil {
+			done <- err
+			return
+		}
+		if response.StatusCode != http.StatusOK {
+			done <- fmt.Errorf("status %d", response.StatusCode)
+			return
+		}
+		done <- nil
+	}()
+
+	return done
+}
+
+// stopWhileLookingUp stops the server once the provider is called.
+func (s *runningServer) stopWhileLookingUp() {
+	for s.provider.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.stop()
+}
+
+func TestServeDrainsInFlightRequestsOnShutdown(t *testing.T) {
+	server := serveSlowly(t, 300*time.Millisecond)
+
+	slow := server.get("/cep/01001000")
+	server.stopWhileLookingUp()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", server.addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("new connections are still accepted after shutdown began")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := <-slow; err != nil {
+		t.Errorf("in-flight request: %v, want it to complete with 200", err)
+	}
+	if code := <-server.code; code != EXIT_SUCCESS {
+		t.Errorf("exit code %d, want %d\n%s", code, EXIT_SUCCESS, server.stderr.String())
+	}
+}
+
+func TestServeShutdownDeadline(t *testing.T) {
+	server := serveSlowly(t, time.Hour, "--shutdown-timeout", "50ms")
+
+	server.get("/cep/01001000")
+	server.stopWhileLookingUp()
+
+	select {
+	case code := <-server.code:
+		if code != EXIT_ERROR {
+			t.Errorf("exit code %d, want %d\n%s", code, EXIT_ERROR, server.stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve still running long after the shutdown deadline")
+	}
+	if !strings.Contains(server.stderr.String(), "closing remaining connections") {
+		t.Errorf("stderr = %q, want the forced close reported", server.stderr.String())
+	}
+}
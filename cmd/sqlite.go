@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+
+	// Pure Go, so the binary still cross-compiles with CGO_ENABLED=0.
+	_ "modernc.org/sqlite"
+)
+
+// SQLITE_BATCH_SIZE is how many results --output-format sqlite writes per
+// transaction by default.
+const SQLITE_BATCH_SIZE = 500
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS lookups (
+	run_id       TEXT NOT NULL,
+	run_at       TEXT NOT NULL,
+	input_cep    TEXT NOT NULL,
+	cep          TEXT NOT NULL,
+	street       TEXT,
+	neighborhood TEXT,
+	city         TEXT,
+	state        TEXT,
+	state_name   TEXT,
+	latitude     REAL,
+	longitude    REAL,
+	confidence   REAL,
+	source       TEXT,
+	error        TEXT,
+	latency_ms   INTEGER NOT NULL,
+	PRIMARY KEY (cep, run_id)
+)`
+
+var sqliteColumns = []string{
+	"run_id", "run_at", "input_cep", "cep", "street", "neighborhood", "city", "state", "state_name",
+	"latitude", "longitude", "confidence", "source", "error", "latency_ms",
+}
+
+// sqliteUpsert replaces the row of a CEP already written in the same run, so
+// running again with the same --run-id does not duplicate it.
+var sqliteUpsert = func() string {
+	updates := make([]string, 0, len(sqliteColumns))
+	for _, column := range sqliteColumns {
+		if column != "cep" && column != "run_id" {
+			updates = append(updates, column+" = excluded."+column)
+		}
+	}
+
+	return fmt.Sprintf("INSERT INTO lookups (%s) VALUES (?%s) ON CONFLICT (cep, run_id) DO UPDATE SET %s",
+		strings.Join(sqliteColumns, ", "), strings.Repeat(", ?", len(sqliteColumns)-1), strings.Join(updates, ", "))
+}()
+
+// sqliteWriter writes results to the lookups table of a SQLite database,
+// creating it if needed, batchSize results per transaction.
+type sqliteWriter struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	insert    *sql.Stmt
+	runID     string
+	runAt     string
+	batchSize int
+	pending   int
+}
+
+func newSQLiteWriter(path string, runID string, runAt time.Time, batchSize int) (*sqliteWriter, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	runAtText := runAt.UTC().Format(time.RFC3339)
+	if runID == "" {
+		runID = runAtText
+	}
+
+	return &sqliteWriter{db: db, runID: runID, runAt: runAtText, batchSize: batchSize}, nil
+}
+
+func (s *sqliteWriter) Write(result address.BatchResult) error {
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		insert, err := tx.Prepare(sqliteUpsert)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		s.tx, s.insert = tx, insert
+	}
+
+	// Invalid CEPs have no normalized form and are keyed by what was read.
+	cep, err := address.NormalizeCEP(result.CEP)
+	if err != nil {
+		cep = strings.TrimSpace(result.CEP)
+	}
+
+	values := []any{s.runID, s.runAt, result.CEP, cep}
+	if result.Err != nil {
+		values = append(values, nil, nil, nil, nil, nil, nil, nil, nil, nil, result.Err.Error())
+	} else {
+		a := result.Address
+		var latitude, longitude any
+		if a.Location != nil {
+			latitude, longitude = a.Location.Latitude, a.Location.Longitude
+		}
+		var confidence any
+		if a.Confidence != 0 {
+			confidence = a.Confidence
+		}
+		values = append(values, a.Street, a.Neighborhood, a.City, a.State, a.StateName, latitude, longitude, confidence, a.Source, nil)
+	}
+	values = append(values, result.Latency.Milliseconds())
+
+	if _, err := s.insert.Exec(values...); err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= s.batchSize {
+		return s.commit()
+	}
+	return nil
+}
+
+func (s *sqliteWriter) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	s.insert.Close()
+	err := s.tx.Commit()
+	s.tx, s.insert, s.pending = nil, nil, 0
+	return err
+}
+
+// Close commits the pending results and closes the database. Calling it
+// again does nothing.
+func (s *sqliteWriter) Close() error {
+	if s.db == nil {
+		return nil
+	}
+
+	err := s.commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	s.db = nil
+	return err
+}
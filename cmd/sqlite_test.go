@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestSQLiteWriterCloseCommitsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lookups.db")
+	writer, err := newSQLiteWriter(path, "run", time.Now(), SQLITE_BATCH_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := address.BatchResult{CEP: "01001000", Address: address.AddressResult{ZipCode: "01001000", City: "São Paulo", State: "SP"}}
+	if err := writer.Write(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The deferred Close of an early return runs after this one.
+	if err := writer.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM lookups").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Errorf("%d rows committed, want 1", rows)
+	}
+}
+
+func TestSQLiteOutputUpsertsReruns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	renamed := sé.Address
+	renamed.Street = "Praça da Sé, renamed"
+
+	// batch runs the same three lines, one of them malformed, with answer
+	// from the provider.
+	batch := func(runID string, answer address.AddressResult) {
+		t.Helper()
+
+		env := newTestEnv("01001000\n123\n01001-001\n", addresstest.NewMockProvider("Mock").Returns(answer))
+		args := []string{"batch", "--output", path, "--output-format", "sqlite", "--run-id", runID, "--sqlite-batch-size", "2"}
+		if code := env.run(context.Background(), args); code != EXIT_INVALID_CEP {
+			t.Fatalf("run %s: exit code %d, want %d\n%s", runID, code, EXIT_INVALID_CEP, env.stderr.String())
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// rows returns the street, or error, of every row of runID by CEP.
+	rows := func(runID string) map[string]string {
+		t.Helper()
+
+		result, err := db.Query("SELECT cep, COALESCE(street, ''), COALESCE(error, '') FROM lookups WHERE run_id = ?", runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer result.Close()
+
+		streets := make(map[string]string)
+		for result.Next() {
+			var cep, street, failure string
+			if err := result.Scan(&cep, &street, &failure); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := streets[cep]; ok {
+				t.Errorf("CEP %s written twice in run %s", cep, runID)
+			}
+			streets[cep] = street + failure
+		}
+		if err := result.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return streets
+	}
+
+	check := func(runID string, street string) {
+		t.Helper()
+
+		got := rows(runID)
+		if len(got) != 3 || got["01001000"] != street || got["01001001"] != street || got["123"] == "" {
+			t.Errorf("run %s rows = %q, want 3 with street %q and an error", runID, got, street)
+		}
+	}
+
+	batch("nightly", sé.Address)
+	check("nightly", sé.Address.Street)
+
+	// The rerun replaces the rows of the same run ID.
+	batch("nightly", renamed)
+	check("nightly", renamed.Street)
+
+	// Another run ID keeps its own rows.
+	batch("weekly", sé.Address)
+	check("weekly", sé.Address.Street)
+	check("nightly", renamed.Street)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM lookups").Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != 6 {
+		t.Errorf("%d rows in lookups, want 6", total)
+	}
+}
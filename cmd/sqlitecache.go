@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const sqliteCacheSchema = `CREATE TABLE IF NOT EXISTS cache (
+	cep       TEXT PRIMARY KEY,
+	entry     BLOB NOT NULL,
+	stored_at INTEGER NOT NULL
+)`
+
+// sqliteCacheStore is the address.CacheStore behind --cache: entries are
+// kept in the cache table of a SQLite database, so later runs answer from
+// it, and are misses once older than ttl.
+type sqliteCacheStore struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+func newSQLiteCacheStore(path string, ttl time.Duration) (*sqliteCacheStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookups store their results concurrently; one connection serializes
+	// the writes instead of failing them with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteCacheSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &sqliteCacheStore{db: db, ttl: ttl, now: time.Now}, nil
+}
+
+// Get returns the entry of key, unless it expired. A failed query is a miss.
+func (s *sqliteCacheStore) Get(key string) ([]byte, bool) {
+	var entry []byte
+	oldest := s.now().Add(-s.ttl).UnixMilli()
+	err := s.db.QueryRow("SELECT entry FROM cache WHERE cep = ? AND stored_at > ?", key, oldest).Scan(&entry)
+	if err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Set stores value under key, replacing an older entry. A failed write only
+// costs a later lookup, so it is dropped.
+func (s *sqliteCacheStore) Set(key string, value []byte) {
+	s.db.Exec("INSERT INTO cache (cep, entry, stored_at) VALUES (?, ?, ?) ON CONFLICT (cep) DO UPDATE SET entry = excluded.entry, stored_at = excluded.stored_at",
+		key, value, s.now().UnixMilli())
+}
+
+func (s *sqliteCacheStore) Close() error {
+	return s.db.Close()
+}
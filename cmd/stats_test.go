@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestStatsReportOfAbandonedBatchIsInterrupted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out INTERRUPT_GRACE")
+	}
+
+	stuck := addresstest.NewMockProvider("Stuck").Returns(address.AddressResult{ZipCode: "01001000"}).SetLatency(time.Minute)
+	var stderr bytes.Buffer
+	env := &environment{
+		stdin:     strings.NewReader("01001000\n20040010\n"),
+		stdout:    &bytes.Buffer{},
+		stderr:    &stderr,
+		lookupEnv: func(string) (string, bool) { return "", false },
+		newService: func(ctx context.Context) *address.AddressService {
+			service := address.NewAddressService(ctx).SetLogger(address.NopLogger()).RegisterProvider(stuck)
+			service.SetProviders("Stuck")
+			return service
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if code := env.run(ctx, []string{"batch", "--timeout", "2m", "--stats-report", path}); code != EXIT_INTERRUPTED {
+		t.Fatalf("exit code %d, want %d\n%s", code, EXIT_INTERRUPTED, stderr.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report address.StatsReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Interrupted {
+		t.Errorf("report of an abandoned batch is not interrupted:\n%s", data)
+	}
+}
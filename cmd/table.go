@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const MAX_COLUMN_WIDTH = 40
+
+const (
+	colorReset  = "\033[0m"
+	colorHeader = "\033[1;36m"
+	colorError  = "\033[31m"
+)
+
+var tableHeader = []string{"CEP", "STREET", "NEIGHBORHOOD", "CITY/STATE", "SOURCE", "LATENCY"}
+
+func isTerminal(stream any) bool {
+	file, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func useColor(w io.Writer, noColor bool) bool {
+	if noColor {
+		return false
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	return isTerminal(w)
+}
+
+func truncate(value string, width int) string {
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+
+	runes := []rune(value)
+	return string(runes[:width-1]) + "…"
+}
+
+func pad(value string, width int) string {
+	return value + strings.Repeat(" ", width-utf8.RuneCountInString(value))
+}
+
+func tableRow(result address.BatchResult) []string {
+	latency := result.Latency.Round(time.Millisecond).String()
+
+	if result.Err != nil {
+		return []string{result.CEP, "", "", "", "", latency}
+	}
+
+	cityState := result.Address.City
+	if result.Address.State != "" {
+		cityState += "/" + result.Address.State
+	}
+
+	return []string{
+		result.CEP,
+		result.Address.Street,
+		result.Address.Neighborhood,
+		cityState,
+		result.Address.Source,
+		latency,
+	}
+}
+
+func writeTable(w io.Writer, results []address.BatchResult, color bool) error {
+	rows := make([][]string, len(results))
+	widths := make([]int, len(tableHeader))
+
+	for i, column := range tableHeader {
+		widths[i] = utf8.RuneCountInString(column)
+	}
+
+	for i, result := range results {
+		rows[i] = tableRow(result)
+		for j, value := range rows[i] {
+			value = truncate(value, MAX_COLUMN_WIDTH)
+			rows[i][j] = value
+			widths[j] = max(widths[j], utf8.RuneCountInString(value))
+		}
+	}
+
+	if err := writeTableLine(w, tableHeader, widths, colorHeader, color); err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		var err error
+		if result.Err != nil {
+			line := pad(rows[i][0], widths[0]) + "  error: " + result.Err.Error()
+			err = writeColored(w, line, colorError, color)
+		} else {
+			err = writeTableLine(w, rows[i], widths, "", color)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTableLine(w io.Writer, values []string, widths []int, colorCode string, color bool) error {
+	columns := make([]string, len(values))
+	for i, value := range values {
+		columns[i] = pad(value, widths[i])
+	}
+
+	line := strings.TrimRight(strings.Join(columns, "  "), " ")
+	return writeColored(w, line, colorCode, color)
+}
+
+func writeColored(w io.Writer, line string, colorCode string, color bool) error {
+	if color && colorCode != "" {
+		line = colorCode + line + colorReset
+	}
+
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+var streamingWidths = []int{10, 30, 20, 24, 10, 8}
+
+type tableWriter struct {
+	w         io.Writer
+	color     bool
+	streaming bool
+	header    bool
+	results   []address.BatchResult
+}
+
+func newTableWriter(w io.Writer, color bool, streaming bool) *tableWriter {
+	return &tableWriter{w: w, color: color, streaming: streaming}
+}
+
+func (t *tableWriter) Write(result address.BatchResult) error {
+	if !t.streaming {
+		t.results = append(t.results, result)
+		return nil
+	}
+
+	if !t.header {
+		t.header = true
+		if err := writeTableLine(t.w, tableHeader, streamingWidths, colorHeader, t.color); err != nil {
+			return err
+		}
+	}
+
+	if result.Err != nil {
+		line := pad(truncate(result.CEP, streamingWidths[0]), streamingWidths[0]) + "  error: " + result.Err.Error()
+		return writeColored(t.w, line, colorError, t.color)
+	}
+
+	row := tableRow(result)
+	for i, value := range row {
+		row[i] = truncate(value, streamingWidths[i])
+	}
+
+	return writeTableLine(t.w, row, streamingWidths, "", t.color)
+}
+
+func (t *tableWriter) Close() error {
+	if !t.streaming {
+		return writeTable(t.w, t.results, t.color)
+	}
+
+	return nil
+}
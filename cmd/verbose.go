@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+func writeReport(w io.Writer, report *address.Report, level int) {
+	if report == nil {
+		return
+	}
+
+	winner := report.Winner
+	switch {
+	case report.Cached:
+		winner = "cache"
+	case winner == "":
+		winner = "none"
+	}
+
+	fmt.Fprintf(w, "[%s] %s winner=%s\n", report.CEP, formatDuration(report.Duration), winner)
+
+	for _, attempt := range report.Attempts {
+		status := "-"
+		if attempt.StatusCode != 0 {
+			status = fmt.Sprint(attempt.StatusCode)
+		}
+
+		duration := "-"
+		if attempt.Outcome != address.OUTCOME_PENDING && attempt.Outcome != address.OUTCOME_CANCELLED {
+			duration = formatDuration(attempt.Duration)
+		}
+
+		fmt.Fprintf(w, "  %-10s attempt %d  start %-6s status %-4s %-7s %s",
+			attempt.Provider, attempt.Number, formatDuration(attempt.Start), status, duration, attempt.Outcome)
+		if attempt.Err != nil {
+			fmt.Fprintf(w, ": %s", attempt.Err.Error())
+		}
+		fmt.Fprintln(w)
+
+		for _, redirect := range attempt.Redirects {
+			fmt.Fprintf(w, "    redirected to %s\n", redirect)
+		}
+
+		if level > 1 && attempt.Trace != nil {
+			trace := attempt.Trace
+			fmt.Fprintf(w, "    dns %s  connect %s  tls %s  first byte %s  reused %t\n",
+				formatDuration(trace.DNS), formatDuration(trace.Connect), formatDuration(trace.TLS), formatDuration(trace.FirstByte), trace.Reused)
+		}
+
+		if level > 1 {
+			protocol := attempt.Protocol
+			if protocol == "" {
+				protocol = "-"
+			}
+			fmt.Fprintf(w, "    client %s  protocol %s\n", attempt.Client, protocol)
+
+			names := make([]string, 0, len(attempt.Header))
+			for name := range attempt.Header {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				fmt.Fprintf(w, "    %s: %s\n", name, strings.Join(attempt.Header[name], ", "))
+			}
+		}
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
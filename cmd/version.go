@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/wendellnd/multithreading-challenge/cmd.Version=1.2.0 \
+//		-X github.com/wendellnd/multithreading-challenge/cmd.Commit=$(git rev-parse HEAD) \
+//		-X github.com/wendellnd/multithreading-challenge/cmd.BuildDate=$(date -u +%FT%TZ)"
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
+)
+
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+}
+
+// newVersionInfo prefers the -ldflags values and falls back to the module
+// and VCS data embedded by the Go toolchain, then to "devel"/"unknown".
+func newVersionInfo(version string, commit string, buildDate string, info *debug.BuildInfo) versionInfo {
+	result := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if info != nil {
+		result.Module = info.Main.Path
+
+		if result.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			result.Version = info.Main.Version
+		}
+
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && result.Commit == "":
+				result.Commit = setting.Value
+			case setting.Key == "vcs.time" && result.BuildDate == "":
+				result.BuildDate = setting.Value
+			}
+		}
+	}
+
+	if result.Version == "" {
+		result.Version = "devel"
+	}
+	if result.Commit == "" {
+		result.Commit = "unknown"
+	}
+	if result.BuildDate == "" {
+		result.BuildDate = "unknown"
+	}
+
+	return result
+}
+
+func currentVersion() versionInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		info = nil
+	}
+
+	return newVersionInfo(Version, Commit, BuildDate, info)
+}
+
+func (v versionInfo) String() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s, %s)", PROGRAM_NAME, v.Version, v.Commit, v.BuildDate, v.GoVersion)
+}
+
+func writeVersion(w io.Writer, info versionInfo, jsonOutput bool) error {
+	if jsonOutput {
+		return json.NewEncoder(w).Encode(info)
+	}
+
+	_, err := fmt.Fprintln(w, info.String())
+	return err
+}
+
+func runVersion(ctx context.Context, env *environment, args []string) int {
+	flags := newFlagSet(env, "version")
+	jsonOutput := flags.Bool("json", false, "print version information as JSON")
+
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if err := writeVersion(env.stdout, currentVersion(), *jsonOutput); err != nil {
+		fmt.Fprintln(env.stderr, err.Error())
+		return EXIT_ERROR
+	}
+
+	return EXIT_SUCCESS
+}
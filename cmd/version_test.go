@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// setBuildInfo sets the -ldflags variables for the test.
+func setBuildInfo(t *testing.T, version, commit, buildDate string) {
+	t.Helper()
+
+	saved := [3]string{Version, Commit, BuildDate}
+	t.Cleanup(func() { Version, Commit, BuildDate = saved[0], saved[1], saved[2] })
+	Version, Commit, BuildDate = version, commit, buildDate
+}
+
+func TestVersionCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		// ldflags are Version, Commit and BuildDate.
+		ldflags [3]string
+		args    []string
+		want    string
+	}{
+		{
+			name:    "injected",
+			ldflags: [3]string{"1.2.0", "0a1b2c3", "2026-10-01T12:00:00Z"},
+			args:    []string{"version"},
+			want:    "cep-lookup 1.2.0 (commit 0a1b2c3, built 2026-10-01T12:00:00Z, " + runtime.Version() + ")\n",
+		},
+		{
+			name:    "injected, as a flag",
+			ldflags: [3]string{"1.2.0", "0a1b2c3", "2026-10-01T12:00:00Z"},
+			args:    []string{"--version"},
+			want:    "cep-lookup 1.2.0 (commit 0a1b2c3, built 2026-10-01T12:00:00Z, " + runtime.Version() + ")\n",
+		},
+		{
+			// A test binary carries no module version or VCS data.
+			name: "fallback",
+			args: []string{"version"},
+			want: "cep-lookup devel (commit unknown, built unknown, " + runtime.Version() + ")\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setBuildInfo(t, test.ldflags[0], test.ldflags[1], test.ldflags[2])
+			env := newTestEnv("")
+
+			if code := env.run(context.Background(), test.args); code != EXIT_SUCCESS {
+				t.Fatalf("exit code %d\n%s", code, env.stderr.String())
+			}
+			if got := env.stdout.String(); got != test.want {
+				t.Errorf("stdout = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVersionCommandJSON(t *testing.T) {
+	setBuildInfo(t, "1.2.0", "0a1b2c3", "")
+	env := newTestEnv("")
+
+	if code := env.run(context.Background(), []string{"version", "--json"}); code != EXIT_SUCCESS {
+		t.Fatalf("exit code %d\n%s", code, env.stderr.String())
+	}
+
+	var got versionInfo
+	if err := json.Unmarshal(env.stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, env.stdout.String())
+	}
+	got.Module = ""
+	want := versionInfo{Version: "1.2.0", Commit: "0a1b2c3", BuildDate: "unknown", GoVersion: runtime.Version()}
+	if got != want {
+		t.Errorf("version = %+v, want %+v", got, want)
+	}
+}
+
+func TestVersionFallsBackToTheBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/wendellnd/multithreading-challenge", Version: "v1.3.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "fedcba9"},
+			{Key: "vcs.time", Value: "2026-09-30T08:00:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		ldflags [3]string
+		want    versionInfo
+	}{
+		{"build info", [3]string{}, versionInfo{Version: "v1.3.0", Commit: "fedcba9", BuildDate: "2026-09-30T08:00:00Z"}},
+		{"ldflags first", [3]string{"1.2.0", "0a1b2c3", ""}, versionInfo{Version: "1.2.0", Commit: "0a1b2c3", BuildDate: "2026-09-30T08:00:00Z"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := newVersionInfo(test.ldflags[0], test.ldflags[1], test.ldflags[2], info)
+			test.want.GoVersion = runtime.Version()
+			test.want.Module = info.Main.Path
+			if got != test.want {
+				t.Errorf("version = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package addresspb holds the protobuf definition of the gRPC API and the
+// code generated from it. The Address message comes from proto/address.proto.
+// Regenerate after editing either file.
+package addresspb
+
+//go:generate protoc -I../.. --go_out=../.. --go_opt=module=github.com/wendellnd/multithreading-challenge --go-grpc_out=../.. --go-grpc_opt=module=github.com/wendellnd/multithreading-challenge grpcapi/addresspb/address.proto
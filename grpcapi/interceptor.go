@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// address_grpc_requests_total{method,code}: calls served.
+	METRIC_GRPC_REQUESTS_TOTAL = "address_grpc_requests_total"
+	// address_grpc_request_duration_seconds{method,code}: time to serve a
+	// call, streaming included.
+	METRIC_GRPC_REQUEST_DURATION = "address_grpc_request_duration_seconds"
+)
+
+type metrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: METRIC_GRPC_REQUESTS_TOTAL,
+			Help: "gRPC calls served, by method and status code.",
+		}, []string{"method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    METRIC_GRPC_REQUEST_DURATION,
+			Help:    "Duration of gRPC calls, by method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+}
+
+// RegisterMetrics exposes the call metrics of s on registerer, typically the
+// registry the HTTP server already serves on /metrics.
+func (s *Server) RegisterMetrics(registerer prometheus.Registerer) *Server {
+	registerer.MustRegister(s.metrics.requests, s.metrics.requestDuration)
+	return s
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (response any, err error) {
+	start := time.Now()
+	defer func() {
+		err = s.recoverPanic(recover(), info.FullMethod, err)
+		s.observe(ctx, info.FullMethod, start, err)
+	}()
+
+	return handler(ctx, request)
+}
+
+func (s *Server) streamInterceptor(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		err = s.recoverPanic(recover(), info.FullMethod, err)
+		s.observe(stream.Context(), info.FullMethod, start, err)
+	}()
+
+	return handler(server, stream)
+}
+
+// recoverPanic turns a panic in a handler into an Internal error, so one bad
+// call does not take the whole server down.
+func (s *Server) recoverPanic(recovered any, method string, err error) error {
+	if recovered == nil {
+		return err
+	}
+
+	s.logger.Error("panic serving gRPC call", "method", method, "panic", recovered, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (s *Server) observe(ctx context.Context, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	s.metrics.requests.WithLabelValues(method, code.String()).Inc()
+	s.metrics.requestDuration.WithLabelValues(method, code.String()).Observe(duration.Seconds())
+
+	attrs := []any{
+		"method", method,
+		"code", code.String(),
+		"duration", duration,
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, "peer", p.Addr.String())
+	}
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+
+	s.logger.Log(ctx, level, "grpc call", attrs...)
+}
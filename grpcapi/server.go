@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/grpcapi/addresspb"
+)
+
+const DEFAULT_MAX_BATCH_SIZE = 100
+
+// Server implements addresspb.AddressServiceServer on top of an
+// AddressService. Deadlines and cancellation of the gRPC call apply to the
+// lookups it makes.
+type Server struct {
+	addresspb.UnimplementedAddressServiceServer
+
+	service      *address.AddressService
+	logger       *slog.Logger
+	metrics      *metrics
+	maxBatchSize int
+}
+
+func NewServer(service *address.AddressService) *Server {
+	return &Server{
+		service:      service,
+		logger:       slog.Default(),
+		metrics:      newMetrics(),
+		maxBatchSize: DEFAULT_MAX_BATCH_SIZE,
+	}
+}
+
+// SetLogger sets where the call logs and recovered panics are written.
+func (s *Server) SetLogger(logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = address.NopLogger()
+	}
+
+	s.logger = logger
+	return s
+}
+
+// SetMaxBatchSize limits how many CEPs one BatchLookup may carry; larger
+// requests fail with InvalidArgument.
+func (s *Server) SetMaxBatchSize(size int) *Server {
+	s.maxBatchSize = size
+	return s
+}
+
+// GRPCServer returns a grpc.Server serving s behind the logging, metrics and
+// panic recovery interceptors.
+func (s *Server) GRPCServer(options ...grpc.ServerOption) *grpc.Server {
+	options = append(options,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+
+	server := grpc.NewServer(options...)
+	addresspb.RegisterAddressServiceServer(server, s)
+
+	return server
+}
+
+func (s *Server) Lookup(ctx context.Context, request *addresspb.LookupRequest) (*addresspb.LookupResponse, error) {
+	result, err := s.service.ExecuteContext(ctx, request.GetCep())
+	if err != nil {
+		return nil, status.Error(codeFor(err), err.Error())
+	}
+
+	return &addresspb.LookupResponse{Address: address.ToProto(result)}, nil
+}
+
+func (s *Server) BatchLookup(request *addresspb.BatchLookupRequest, stream addresspb.AddressService_BatchLookupServer) error {
+	ceps := request.GetCeps()
+	if len(ceps) == 0 {
+		return status.Error(codes.InvalidArgument, "ceps must not be empty")
+	}
+
+	if len(ceps) > s.maxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "batch has %d CEPs, the limit is %d", len(ceps), s.maxBatchSize)
+	}
+
+	// A failed Send means the client is gone, so the remaining lookups are
+	// cancelled; the results still have to be drained.
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendErr error
+	for result := range s.service.StreamBatchContext(ctx, ceps) {
+		if sendErr != nil {
+			continue
+		}
+
+		if sendErr = stream.Send(toBatchResponse(result)); sendErr != nil {
+			cancel()
+		}
+	}
+
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	return nil
+}
+
+func codeFor(err error) codes.Code {
+	switch {
+	case errors.Is(err, address.ErrInvalidCEP):
+		return codes.InvalidArgument
+	case errors.Is(err, address.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, address.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+
+	return codes.Unavailable
+}
+
+func toBatchResponse(result address.BatchResult) *addresspb.BatchLookupResponse {
+	response := &addresspb.BatchLookupResponse{
+		Index: int32(result.Index),
+		Cep:   result.CEP,
+	}
+
+	if result.Err != nil {
+		response.Result = &addresspb.BatchLookupResponse_Error{
+			Error: &addresspb.LookupError{
+				Code:    uint32(codeFor(result.Err)),
+				Message: result.Err.Error(),
+			},
+		}
+	} else {
+		response.Result = &addresspb.BatchLookupResponse_Address{Address: address.ToProto(result.Address)}
+	}
+
+	return response
+}
@@ -0,0 +1,166 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/grpcapi"
+	"github.com/wendellnd/multithreading-challenge/grpcapi/addresspb"
+)
+
+var sé = address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"}
+
+// newClient serves a quiet service racing providers, and only them, over an
+// in-memory connection, and returns a client of it.
+func newClient(t *testing.T, providers ...address.Provider) addresspb.AddressServiceClient {
+	t.Helper()
+
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		service.RegisterProvider(provider)
+		names[i] = provider.Name()
+	}
+	if err := service.SetProviders(names...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcapi.NewServer(service).SetLogger(address.NopLogger()).SetMaxBatchSize(3).GRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return addresspb.NewAddressServiceClient(conn)
+}
+
+func TestLookup(t *testing.T) {
+	client := newClient(t, addresstest.NewMockProvider("Mock").Returns(sé))
+
+	response, err := client.Lookup(context.Background(), &addresspb.LookupRequest{Cep: "01001-000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.GetAddress(); got.GetCep() != "01001000" || got.GetStreet() != sé.Street || got.GetState() != "SP" {
+		t.Errorf("address %v, want Praça da Sé", got)
+	}
+}
+
+func TestLookupCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *addresstest.MockProvider
+		cep      string
+		code     codes.Code
+	}{
+		{"invalid CEP", addresstest.NewMockProvider("Mock").Returns(sé), "123", codes.InvalidArgument},
+		{"not found", addresstest.NewMockProvider("Mock"), "99999999", codes.NotFound},
+		{"providers failed", addresstest.NewMockProvider("Mock").Fails(errors.New("connection refused")), "01001000", codes.Unavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newClient(t, test.provider).Lookup(context.Background(), &addresspb.LookupRequest{Cep: test.cep})
+			if got := status.Code(err); got != test.code {
+				t.Errorf("code %v (%v), want %v", got, err, test.code)
+			}
+		})
+	}
+}
+
+func TestLookupDeadline(t *testing.T) {
+	deadlines := make(chan time.Time, 1)
+	stuck := address.NewProvider("Stuck", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		<-ctx.Done()
+		return address.AddressResult{}, ctx.Err()
+	})
+	client := newClient(t, stuck)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	sent, _ := ctx.Deadline()
+
+	start := time.Now()
+	_, err := client.Lookup(ctx, &addresspb.LookupRequest{Cep: "01001000"})
+	if got := status.Code(err); got != codes.DeadlineExceeded {
+		t.Fatalf("code %v (%v), want DeadlineExceeded", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %s with a 50ms deadline", elapsed)
+	}
+
+	// The provider ran under the client's deadline, not the service's 30s.
+	select {
+	case deadline := <-deadlines:
+		if deadline.IsZero() || deadline.After(sent.Add(10*time.Millisecond)) {
+			t.Errorf("provider deadline %v, want at most the client's %v", deadline, sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the provider was never called")
+	}
+}
+
+func TestBatchLookup(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Script(addresstest.Answer(sé), addresstest.Fail(address.ErrNotFound))
+	client := newClient(t, provider)
+
+	stream, err := client.BatchLookup(context.Background(), &addresspb.BatchLookupRequest{Ceps: []string{"01001000", "123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[int32]*addresspb.BatchLookupResponse)
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[response.GetIndex()] = response
+	}
+
+	if len(got) != 2 || got[0].GetAddress().GetStreet() != sé.Street {
+		t.Errorf("responses %v, want 01001000 resolved", got)
+	}
+	if code := codes.Code(got[1].GetError().GetCode()); code != codes.InvalidArgument || got[1].GetCep() != "123" {
+		t.Errorf("response for 123 %v, want InvalidArgument", got[1])
+	}
+
+	for _, ceps := range [][]string{nil, {"1", "2", "3", "4"}} {
+		stream, err := client.BatchLookup(context.Background(), &addresspb.BatchLookupRequest{Ceps: ceps})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if got := status.Code(err); got != codes.InvalidArgument {
+			t.Errorf("%d CEPs: code %v, want InvalidArgument", len(ceps), got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const API_KEY_HEADER = "X-API-Key"
+
+type APIKey struct {
+	Label string
+	Key   string
+}
+
+type hashedKey struct {
+	label string
+	hash  [sha256.Size]byte
+}
+
+// APIKeys is the set of keys accepted by the server. It can be replaced while
+// serving, e.g. on SIGHUP, without affecting requests in flight.
+type APIKeys struct {
+	mu   sync.RWMutex
+	keys []hashedKey
+}
+
+func NewAPIKeys(keys []APIKey) *APIKeys {
+	apiKeys := &APIKeys{}
+	apiKeys.Set(keys)
+	return apiKeys
+}
+
+// Set replaces every accepted key with keys.
+func (k *APIKeys) Set(keys []APIKey) {
+	hashed := make([]hashedKey, len(keys))
+	for i, key := range keys {
+		hashed[i] = hashedKey{label: key.Label, hash: sha256.Sum256([]byte(key.Key))}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = hashed
+}
+
+func (k *APIKeys) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return len(k.keys)
+}
+
+// label returns the label of key. Every stored key is compared, in constant
+// time, so the time taken does not reveal how close a guess was.
+func (k *APIKeys) label(key string) (string, bool) {
+	hash := sha256.Sum256([]byte(key))
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	label := ""
+	found := false
+	for _, stored := range k.keys {
+		if subtle.ConstantTimeCompare(hash[:], stored.hash[:]) == 1 {
+			label = stored.label
+			found = true
+		}
+	}
+
+	return label, found
+}
+
+// ParseAPIKeys reads one "label:key" pair per line, skipping blank lines and
+// '#' comments.
+func ParseAPIKeys(r io.Reader) ([]APIKey, error) {
+	var keys []APIKey
+	labels := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := parseAPIKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if labels[key.Label] {
+			return nil, fmt.Errorf("line %d: duplicate label %q", lineNumber, key.Label)
+		}
+		labels[key.Label] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys, scanner.Err()
+}
+
+func parseAPIKey(value string) (APIKey, error) {
+	label, key, ok := strings.Cut(value, ":")
+	label = strings.TrimSpace(label)
+	key = strings.TrimSpace(key)
+
+	if !ok || label == "" || key == "" {
+		return APIKey{}, fmt.Errorf("expected label:key, got %q", value)
+	}
+
+	return APIKey{Label: label, Key: key}, nil
+}
+
+// ParseAPIKeyList parses comma-separated "label:key" pairs, as used by
+// environment variables.
+func ParseAPIKeyList(value string) ([]APIKey, error) {
+	return ParseAPIKeys(strings.NewReader(strings.ReplaceAll(value, ",", "\n")))
+}
+
+// APIKeyLabel returns the label of the key that authenticated the request ctx
+// belongs to, or "" when authentication is disabled.
+func APIKeyLabel(ctx context.Context) string {
+	return infoFromContext(ctx).apiKeyLabel
+}
+
+// SetAPIKeys requires every API request to carry one of keys, either as
+// "Authorization: Bearer <key>" or in X-API-Key. Probes and metrics stay
+// open. A nil keys disables authentication.
+func (h *Handler) SetAPIKeys(keys *APIKeys) *Handler {
+	h.apiKeys = keys
+	return h
+}
+
+func requestAPIKey(r *http.Request) string {
+	if scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(key)
+	}
+
+	return r.Header.Get(API_KEY_HEADER)
+}
+
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	if h.apiKeys == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := requestAPIKey(r)
+		if key == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cep"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing API key"})
+			return
+		}
+
+		label, ok := h.apiKeys.label(key)
+		if !ok {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "unknown API key"})
+			return
+		}
+
+		info := infoFromContext(r.Context())
+		info.apiKeyLabel = label
+
+		next.ServeHTTP(w, r)
+	})
+}
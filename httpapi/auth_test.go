@@ -0,0 +1,74 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+func TestAPIKeys(t *testing.T) {
+	keys := httpapi.NewAPIKeys([]httpapi.APIKey{{Label: "shop", Key: "s3cret"}})
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetAPIKeys(keys)
+
+	tests := []struct {
+		name   string
+		header []string
+		status int
+	}{
+		{"missing", nil, http.StatusUnauthorized},
+		{"unknown", []string{httpapi.API_KEY_HEADER, "guess"}, http.StatusForbidden},
+		{"header", []string{httpapi.API_KEY_HEADER, "s3cret"}, http.StatusOK},
+		{"bearer", []string{"Authorization", "Bearer s3cret"}, http.StatusOK},
+		{"bearer any case", []string{"Authorization", "bearer s3cret"}, http.StatusOK},
+		{"other scheme", []string{"Authorization", "Basic s3cret"}, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := get(handler, "/cep/01001000", test.header...)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if challenge := response.Header().Get("WWW-Authenticate"); (test.status == http.StatusUnauthorized) != strings.HasPrefix(challenge, "Bearer") {
+				t.Errorf("WWW-Authenticate %q with status %d", challenge, response.Code)
+			}
+		})
+	}
+
+	if got := get(handler, "/healthz").Code; got != http.StatusOK {
+		t.Errorf("status %d for /healthz without a key, want probes left open", got)
+	}
+
+	// Replaced keys apply to the next request.
+	keys.Set([]httpapi.APIKey{{Label: "shop", Key: "rotated"}})
+	if got := get(handler, "/cep/01001000", httpapi.API_KEY_HEADER, "s3cret").Code; got != http.StatusForbidden {
+		t.Errorf("status %d with a rotated-out key, want 403", got)
+	}
+	if got := get(handler, "/cep/01001000", httpapi.API_KEY_HEADER, "rotated").Code; got != http.StatusOK {
+		t.Errorf("status %d with the new key, want 200", got)
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := httpapi.ParseAPIKeys(strings.NewReader("# keys\nshop: s3cret\n\nops:k:2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []httpapi.APIKey{{Label: "shop", Key: "s3cret"}, {Label: "ops", Key: "k:2"}}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys %+v, want %+v", keys, want)
+	}
+
+	for _, input := range []string{"shop\n", "shop:\n", ":key\n", "shop:a\nshop:b\n"} {
+		if _, err := httpapi.ParseAPIKeys(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseAPIKeys(%q) accepted it", input)
+		}
+	}
+
+	if keys, err := httpapi.ParseAPIKeyList("shop:a,ops:b"); err != nil || len(keys) != 2 {
+		t.Errorf("ParseAPIKeyList = %+v, %v, want two keys", keys, err)
+	}
+}
@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SetCacheMaxAge lets HTTP caches keep successful lookups for maxAge. Zero
+// leaves Cache-Control out of successful responses.
+func (h *Handler) SetCacheMaxAge(maxAge time.Duration) *Handler {
+	h.cacheMaxAge = maxAge
+	return h
+}
+
+// strongETag derives a strong validator from the exact response body.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header lists etag. Weak
+// validators match their strong counterpart, as If-None-Match uses the weak
+// comparison.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeCacheable answers with value and the validators HTTP caches need,
+// or with 304 when the client already holds the same representation.
+func (h *Handler) writeCacheable(w http.ResponseWriter, r *http.Request, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	body = append(body, '\n')
+
+	etag := strongETag(body)
+	w.Header().Set("ETag", etag)
+	if h.cacheMaxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.cacheMaxAge.Seconds())))
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// writeUncacheable answers with an error that no cache should keep.
+func writeUncacheable(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, value)
+}
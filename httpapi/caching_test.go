@@ -0,0 +1,63 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestETag(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).
+		SetCacheMaxAge(time.Hour).
+		SetGzipMinSize(-1)
+
+	first := get(handler, "/cep/01001000")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || etag[0] != '"' {
+		t.Fatalf("status %d, ETag %q, want 200 with a strong ETag", first.Code, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Cache-Control %q, want public, max-age=3600", got)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"same", etag, http.StatusNotModified},
+		{"weak", "W/" + etag, http.StatusNotModified},
+		{"in a list", `"other", ` + etag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"other", `"other"`, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := get(handler, "/cep/01001000", "If-None-Match", test.ifNoneMatch)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d", response.Code, test.status)
+			}
+			if got := response.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag %q, want %q", got, etag)
+			}
+			if test.status == http.StatusNotModified && response.Body.Len() != 0 {
+				t.Errorf("304 with body %q", response.Body)
+			}
+		})
+	}
+}
+
+func TestCacheMaxAgeOff(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé))
+
+	response := get(handler, "/cep/01001000")
+	if got := response.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Cache-Control %q without a max age", got)
+	}
+	if response.Header().Get("ETag") == "" {
+		t.Error("no ETag without a max age, want one still")
+	}
+}
@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// flight is one lookup shared by every request for the same CEP that
+// arrives while it runs.
+type flight struct {
+	done   chan struct{}
+	result address.AddressResult
+	err    error
+	cancel context.CancelFunc
+
+	// waiters counts the requests waiting on the flight by their context.
+	waiters map[context.Context]int
+}
+
+// abandoned reports whether every request waiting on f has given up, even if
+// some have yet to leave. A request that timed out may answer its client
+// before do gets to drop it from the flight.
+func (f *flight) abandoned() bool {
+	for ctx := range f.waiters {
+		if ctx.Err() == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// flightGroup coalesces concurrent lookups of the same CEP, so a burst of
+// requests for a hot CEP costs one upstream lookup and one cache fill.
+// Unlike golang.org/x/sync/singleflight, the shared lookup does not run
+// under the context of whichever request started it: it is cancelled only
+// once every request waiting on it has gone.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// do returns the result of lookup for key, joining the flight already
+// running for key if there is one. shared reports whether it did. When ctx
+// is done first, do returns ctx's error without waiting for the flight.
+func (g *flightGroup) do(ctx context.Context, key string, lookup func(context.Context) (address.AddressResult, error)) (result address.AddressResult, err error, shared bool) {
+	g.mu.Lock()
+	f, shared := g.flights[key]
+	if shared && f.abandoned() {
+		// Joining would only inherit a lookup about to be cancelled.
+		f.cancel()
+		g.forget(key, f)
+		shared = false
+	}
+	if !shared {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel, waiters: make(map[context.Context]int)}
+		g.flights[key] = f
+
+		go func() {
+			f.result, f.err = lookup(flightCtx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, f)
+			g.mu.Unlock()
+
+			close(f.done)
+		}()
+	}
+	f.waiters[ctx]++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, f.err, shared
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	if f.waiters[ctx]--; f.waiters[ctx] == 0 {
+		delete(f.waiters, ctx)
+	}
+	if len(f.waiters) == 0 {
+		// Nobody is left to answer; later requests start a fresh flight
+		// instead of joining a cancelled one.
+		f.cancel()
+		g.forget(key, f)
+	}
+	g.mu.Unlock()
+
+	return address.AddressResult{}, ctx.Err(), shared
+}
+
+// forget removes f from the group unless a newer flight took its place.
+// g.mu must be held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// lookup resolves cep, sharing the upstream lookup with the concurrent
+// requests for the same CEP.
+func (h *Handler) lookup(ctx context.Context, cep string) (address.AddressResult, error) {
+	key, err := address.NormalizeCEP(cep)
+	if err != nil {
+		return h.service.ExecuteContext(ctx, cep)
+	}
+
+	result, err, shared := h.flights.do(ctx, key, func(ctx context.Context) (address.AddressResult, error) {
+		return h.service.ExecuteContext(ctx, key)
+	})
+	if shared {
+		h.metrics.coalesced.Inc()
+	}
+
+	return result, err
+}
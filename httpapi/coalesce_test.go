@@ -0,0 +1,79 @@
+package httpapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestConcurrentRequestsShareOneLookup(t *testing.T) {
+	const REQUESTS = 200
+
+	// The latency keeps the first lookup in flight while the others arrive.
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).SetLatency(200 * time.Millisecond)
+	handler := newHandler(t, provider).HandleMetrics()
+
+	var start, done sync.WaitGroup
+	start.Add(1)
+	statuses := make(chan int, REQUESTS)
+	for i := range REQUESTS {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			start.Wait()
+			// Spelled both ways, as they share the normalized CEP.
+			cep := "01001000"
+			if i%2 == 0 {
+				cep = "01001-000"
+			}
+			statuses <- get(handler, "/cep/"+cep).Code
+		}()
+	}
+	start.Done()
+	done.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("status %d, want every request answered", status)
+		}
+	}
+	provider.AssertCalls(t, 1)
+
+	if body := get(handler, "/metrics").Body.String(); !strings.Contains(body, "address_http_coalesced_requests_total 199") {
+		t.Errorf("coalesced requests not counted as 199:\n%s", body)
+	}
+
+	// Once the flight lands, the next request starts a new one.
+	get(handler, "/cep/01001000")
+	provider.AssertCalls(t, 2)
+}
+
+func TestCoalescedLookupOutlivesOneClient(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).SetLatency(100 * time.Millisecond)
+	handler := newHandler(t, provider)
+
+	// The request that starts the flight gives up; the one that joined it
+	// still gets the answer.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cep/01001000", nil).WithContext(ctx))
+	}()
+	time.AfterFunc(20*time.Millisecond, cancel)
+	time.Sleep(10 * time.Millisecond)
+
+	if got := get(handler, "/cep/01001000").Code; got != http.StatusOK {
+		t.Errorf("status %d for the joined request, want 200", got)
+	}
+	<-gone
+	provider.AssertCalls(t, 1)
+}
@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DEFAULT_CORS_METHODS = []string{"GET", "POST"}
+	DEFAULT_CORS_HEADERS = []string{"Authorization", "Content-Type", API_KEY_HEADER, REQUEST_ID_HEADER}
+)
+
+const DEFAULT_CORS_MAX_AGE = 10 * time.Minute
+
+// corsExposedHeaders are the response headers browsers may read besides the
+// CORS-safelisted ones.
+var corsExposedHeaders = []string{"ETag", "Retry-After", REQUEST_ID_HEADER}
+
+// CORS configures which browser origins may call the API. Origins are exact
+// ("https://shop.example.com"), wildcard subdomains
+// ("https://*.example.com") or "*" for any origin.
+type CORS struct {
+	Origins          []string
+	Methods          []string
+	Headers          []string
+	MaxAge           time.Duration
+	AllowCredentials bool
+}
+
+// SetCORS enables CORS on the API routes. Preflight requests are answered
+// before authentication and rate limiting. A nil cors disables CORS.
+func (h *Handler) SetCORS(cors *CORS) *Handler {
+	h.cors = cors
+	return h
+}
+
+func (c *CORS) allowsOrigin(origin string) bool {
+	for _, pattern := range c.Origins {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CORS) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		if !slices.ContainsFunc(c.Headers, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allowOrigin echoes origin back unless any origin is accepted without
+// credentials, where the literal "*" is enough.
+func (c *CORS) allowOrigin(header http.Header, origin string) {
+	if slices.Contains(c.Origins, "*") && !c.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (h *Handler) handleCORS(next http.Handler) http.Handler {
+	cors := h.cors
+	if cors == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			if origin != "" && cors.allowsOrigin(origin) {
+				cors.allowOrigin(header, origin)
+				header.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		if origin == "" || !cors.allowsOrigin(origin) || !slices.Contains(cors.Methods, method) || !cors.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "CORS request not allowed"})
+			return
+		}
+
+		cors.allowOrigin(header, origin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(cors.Methods, ", "))
+		header.Set("Access-Control-Allow-Headers", strings.Join(cors.Headers, ", "))
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
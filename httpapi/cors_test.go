@@ -0,0 +1,165 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+// preflight serves the OPTIONS request a browser sends before calling
+// method on path from origin.
+func preflight(handler http.Handler, path string, origin string, method string, headers string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("OPTIONS", path, nil)
+	request.Header.Set("Origin", origin)
+	request.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		request.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func newCORSHandler(t *testing.T, cors *httpapi.CORS) *httpapi.Handler {
+	t.Helper()
+
+	return newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).
+		SetAPIKeys(httpapi.NewAPIKeys([]httpapi.APIKey{{Label: "shop", Key: "s3cret"}})).
+		SetCORS(cors)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := newCORSHandler(t, &httpapi.CORS{
+		Origins: []string{"https://shop.example.com", "https://*.example.org"},
+		Methods: httpapi.DEFAULT_CORS_METHODS,
+		Headers: httpapi.DEFAULT_CORS_HEADERS,
+		MaxAge:  10 * time.Minute,
+	})
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		status  int
+	}{
+		{"allowed", "https://shop.example.com", "GET", "x-api-key, Content-Type", http.StatusNoContent},
+		{"wildcard subdomain", "https://a.b.example.org", "POST", "", http.StatusNoContent},
+		{"wildcard is not the apex", "https://example.org", "GET", "", http.StatusForbidden},
+		{"other scheme", "http://shop.example.com", "GET", "", http.StatusForbidden},
+		{"other origin", "https://evil.example.net", "GET", "", http.StatusForbidden},
+		{"method", "https://shop.example.com", "DELETE", "", http.StatusForbidden},
+		{"header", "https://shop.example.com", "GET", "X-Debug", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// No API key: preflights are answered before authentication.
+			response := preflight(handler, "/cep/01001000", test.origin, test.method, test.headers)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if vary := response.Header().Values("Vary"); !containsAll(vary, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers") {
+				t.Errorf("Vary %v, want the preflight headers", vary)
+			}
+
+			header := response.Header()
+			if test.status != http.StatusNoContent {
+				if got := header.Get("Access-Control-Allow-Origin"); got != "" {
+					t.Errorf("Access-Control-Allow-Origin %q on a refused preflight", got)
+				}
+				return
+			}
+
+			if got := header.Get("Access-Control-Allow-Origin"); got != test.origin {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got, test.origin)
+			}
+			if got := header.Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+				t.Errorf("Access-Control-Allow-Methods %q", got)
+			}
+			if got := header.Get("Access-Control-Allow-Headers"); got != strings.Join(httpapi.DEFAULT_CORS_HEADERS, ", ") {
+				t.Errorf("Access-Control-Allow-Headers %q", got)
+			}
+			if got := header.Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age %q, want 600", got)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != "" {
+				t.Errorf("Access-Control-Allow-Credentials %q without credentials", got)
+			}
+		})
+	}
+}
+
+func TestCORSRequest(t *testing.T) {
+	handler := newCORSHandler(t, &httpapi.CORS{Origins: []string{"https://shop.example.com"}, Methods: httpapi.DEFAULT_CORS_METHODS})
+
+	response := get(handler, "/cep/01001000", "Origin", "https://shop.example.com", httpapi.API_KEY_HEADER, "s3cret")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", response.Code, response.Body)
+	}
+	if got := response.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.com" {
+		t.Errorf("Access-Control-Allow-Origin %q", got)
+	}
+	if got := response.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "ETag") || !strings.Contains(got, "Retry-After") {
+		t.Errorf("Access-Control-Expose-Headers %q, want ETag and Retry-After", got)
+	}
+
+	// Other origins get their answer, without the headers that let a
+	// browser read it.
+	response = get(handler, "/cep/01001000", "Origin", "https://evil.example.net", httpapi.API_KEY_HEADER, "s3cret")
+	if got := response.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin %q for another origin", got)
+	}
+
+	// Authentication still applies to the request itself.
+	if got := get(handler, "/cep/01001000", "Origin", "https://shop.example.com").Code; got != http.StatusUnauthorized {
+		t.Errorf("status %d without a key, want 401", got)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials bool
+		want        string
+	}{
+		{"without credentials", false, "*"},
+		{"with credentials", true, "https://shop.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newCORSHandler(t, &httpapi.CORS{Origins: []string{"*"}, Methods: []string{"GET"}, AllowCredentials: test.credentials})
+
+			header := preflight(handler, "/cep/01001000", "https://shop.example.com", "GET", "").Header()
+			if got := header.Get("Access-Control-Allow-Origin"); got != test.want {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got, test.want)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); (got == "true") != test.credentials {
+				t.Errorf("Access-Control-Allow-Credentials %q", got)
+			}
+		})
+	}
+}
+
+func containsAll(values []string, want ...string) bool {
+	for _, w := range want {
+		found := false
+		for _, value := range values {
+			if value == w {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
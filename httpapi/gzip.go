@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_GZIP_MIN_SIZE is the smallest body worth compressing; below it the
+// gzip framing costs more than it saves.
+const DEFAULT_GZIP_MIN_SIZE = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// SetGzipMinSize compresses responses of at least minSize bytes for clients
+// that accept gzip. A negative minSize disables compression.
+func (h *Handler) SetGzipMinSize(minSize int) *Handler {
+	h.gzipMinSize = minSize
+	return h
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		name, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.TrimSpace(name) == "q" {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil && q > 0
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (h *Handler) compress(next http.Handler) http.Handler {
+	if h.gzipMinSize < 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: h.gzipMinSize, status: http.StatusOK}
+		defer gw.close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter holds the body back until it reaches minSize, then
+// decides once whether the response is compressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	wroteHeader bool
+	decided     bool
+	buffer      []byte
+	gzip        *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+
+	// Informational and bodiless responses go straight through.
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decided = true
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+
+	if w.decided {
+		if w.gzip != nil {
+			return w.gzip.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buffer = append(w.buffer, data...)
+	if len(w.buffer) >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+// decide sends the header and the buffered body, compressed when it is big
+// enough and nobody encoded it already.
+func (w *gzipResponseWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+
+	if len(w.buffer) >= w.minSize && header.Get("Content-Encoding") == "" {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+
+		// The compressed bytes are a different representation, so a strong
+		// validator computed on the plain body can only stay as a weak one.
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.gzip = gzipWriters.Get().(*gzip.Writer)
+		w.gzip.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.gzip != nil {
+		_, err = w.gzip.Write(buffer)
+	} else {
+		_, err = w.ResponseWriter.Write(buffer)
+	}
+
+	return err
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.gzip != nil {
+		w.gzip.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.gzip != nil {
+		w.gzip.Close()
+		gzipWriters.Put(w.gzip)
+		w.gzip = nil
+	}
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
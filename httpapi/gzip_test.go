@@ -0,0 +1,72 @@
+package httpapi_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestGzip(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetGzipMinSize(64)
+
+	plain := get(handler, "/cep/01001000")
+	compressed := get(handler, "/cep/01001000", "Accept-Encoding", "br, gzip")
+
+	if got := compressed.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding %q, want gzip", got)
+	}
+	if got := compressed.Header().Get("Vary"); !strings.Contains(got, "Accept-Encoding") {
+		t.Errorf("Vary %q, want Accept-Encoding", got)
+	}
+	if got, want := compressed.Header().Get("ETag"), "W/"+plain.Header().Get("ETag"); got != want {
+		t.Errorf("ETag %q, want the plain body's made weak, %q", got, want)
+	}
+
+	reader, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != plain.Body.String() {
+		t.Errorf("decompressed body %q, want %q", body, plain.Body)
+	}
+
+	// The weak ETag still revalidates.
+	if got := get(handler, "/cep/01001000", "Accept-Encoding", "gzip", "If-None-Match", compressed.Header().Get("ETag")).Code; got != http.StatusNotModified {
+		t.Errorf("status %d revalidating the gzip ETag, want 304", got)
+	}
+}
+
+func TestGzipSkipped(t *testing.T) {
+	tests := []struct {
+		name           string
+		minSize        int
+		acceptEncoding string
+	}{
+		{"small body", 4096, "gzip"},
+		{"refused", 64, "gzip;q=0, identity"},
+		{"not accepted", 64, ""},
+		{"disabled", -1, "gzip"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetGzipMinSize(test.minSize)
+
+			response := get(handler, "/cep/01001000", "Accept-Encoding", test.acceptEncoding)
+			if got := response.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding %q, want none", got)
+			}
+			if !strings.Contains(response.Body.String(), sé.Street) {
+				t.Errorf("body %q, want the plain address", response.Body)
+			}
+		})
+	}
+}
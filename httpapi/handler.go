@@ -0,0 +1,226 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const DEFAULT_MAX_BATCH_SIZE = 100
+
+// MAX_BATCH_BODY_BYTES caps the request body of POST /cep/batch.
+const MAX_BATCH_BODY_BYTES = 1 << 20
+
+type errorResponse struct {
+	CEP   string `json:"cep,omitempty"`
+	Error string `json:"error"`
+}
+
+type batchRequest struct {
+	CEPs []string `json:"ceps"`
+}
+
+type batchItem struct {
+	CEP     string                 `json:"cep"`
+	Address *address.AddressResult `json:"address,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// Handler serves the lookup API on api and the probes on mux. Probes are
+// kept off api so middleware wrapped around the API never applies to them.
+type Handler struct {
+	service      *address.AddressService
+	mux          *http.ServeMux
+	api          *http.ServeMux
+	readiness    *readiness
+	flights      *flightGroup
+	metrics      *metrics
+	logger       *slog.Logger
+	trustProxy   bool
+	maxBatchSize int
+	cacheMaxAge  time.Duration
+	gzipMinSize  int
+
+	requestTimeout time.Duration
+
+	limiter         *rateLimiter
+	rateLimitHeader string
+	apiKeys         *APIKeys
+	cors            *CORS
+}
+
+func NewHandler(service *address.AddressService) *Handler {
+	handler := &Handler{
+		service:      service,
+		mux:          http.NewServeMux(),
+		api:          http.NewServeMux(),
+		readiness:    newReadiness(service),
+		flights:      newFlightGroup(),
+		metrics:      newMetrics(),
+		logger:       slog.Default(),
+		maxBatchSize: DEFAULT_MAX_BATCH_SIZE,
+		gzipMinSize:  DEFAULT_GZIP_MIN_SIZE,
+	}
+
+	service.SetObserver(handler.metrics)
+
+	handler.handle(handler.api, "GET", "/cep/{cep}", handler.getCEP)
+	handler.handle(handler.api, "POST", "/cep/batch", handler.postBatch)
+
+	handler.handle(handler.mux, "GET", "/healthz", handler.healthz)
+	handler.handle(handler.mux, "GET", "/readyz", handler.readyz)
+	handler.mux.HandleFunc("/", handler.serveAPI)
+
+	return handler
+}
+
+// serveAPI runs the API routes behind the middleware that does not apply to
+// probes and metrics.
+func (h *Handler) serveAPI(w http.ResponseWriter, r *http.Request) {
+	h.handleCORS(h.authenticate(h.limitRate(h.api))).ServeHTTP(w, r)
+}
+
+func (h *Handler) handle(mux *http.ServeMux, method string, route string, fn http.HandlerFunc) {
+	mux.HandleFunc(method+" "+route, h.metrics.instrument(route, h.limitDuration(fn)))
+}
+
+// Metrics serves the Prometheus metrics of this handler and its service, for
+// callers that expose them on a separate admin listener.
+func (h *Handler) Metrics() http.Handler {
+	return h.metrics.handler()
+}
+
+// Registry is where the metrics of this handler are registered, so servers
+// sharing its service can expose theirs alongside.
+func (h *Handler) Registry() *prometheus.Registry {
+	return h.metrics.registry
+}
+
+// HandleMetrics also serves the metrics on GET /metrics of the handler
+// itself.
+func (h *Handler) HandleMetrics() *Handler {
+	h.mux.Handle("GET /metrics", h.Metrics())
+	return h
+}
+
+// SetMaxBatchSize limits how many CEPs one POST /cep/batch may carry; larger
+// requests are rejected with 413.
+func (h *Handler) SetMaxBatchSize(size int) *Handler {
+	h.maxBatchSize = size
+	return h
+}
+
+// SetLogger sets where access logs and recovered panics are written.
+func (h *Handler) SetLogger(logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = address.NopLogger()
+	}
+
+	h.logger = logger
+	return h
+}
+
+// SetTrustProxy makes the handler take the client IP from X-Forwarded-For.
+// Only enable it behind a proxy that overwrites that header.
+func (h *Handler) SetTrustProxy(trust bool) *Handler {
+	h.trustProxy = trust
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	withRequestID(h.logRequests(h.compress(h.recoverPanics(h.mux)))).ServeHTTP(w, r)
+}
+
+func (h *Handler) getCEP(w http.ResponseWriter, r *http.Request) {
+	cep := r.PathValue("cep")
+
+	result, err := h.lookup(r.Context(), cep)
+	if err != nil {
+		writeUncacheable(w, statusFor(err), errorResponse{CEP: cep, Error: err.Error()})
+		return
+	}
+
+	h.writeCacheable(w, r, result)
+}
+
+// postBatch resolves every CEP of the request through the service's batch
+// executor and answers with one item per CEP, in request order.
+func (h *Handler) postBatch(w http.ResponseWriter, r *http.Request) {
+	var request batchRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BATCH_BODY_BYTES))
+	if err := decoder.Decode(&request); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+
+		writeJSON(w, status, errorResponse{Error: describeDecodeError(err)})
+		return
+	}
+
+	if len(request.CEPs) > h.maxBatchSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: fmt.Sprintf("batch of %d CEPs exceeds the limit of %d", len(request.CEPs), h.maxBatchSize)})
+		return
+	}
+
+	results := h.service.ExecuteBatchContext(r.Context(), request.CEPs)
+	items := make([]batchItem, len(results))
+	for i, result := range results {
+		items[i] = batchItem{CEP: result.CEP}
+		if result.Err != nil {
+			items[i].Error = result.Err.Error()
+			continue
+		}
+
+		addressResult := result.Address
+		items[i].Address = &addressResult
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// describeDecodeError points at where a request body stopped being valid.
+func describeDecodeError(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at byte %d: %s", syntaxErr.Offset, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("invalid value for %q at byte %d: expected %s, got %s", typeErr.Field, typeErr.Offset, typeErr.Type, typeErr.Value)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return "malformed JSON: unexpected end of body"
+	}
+
+	return "malformed JSON: " + err.Error()
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, address.ErrInvalidCEP):
+		return http.StatusBadRequest
+	case errors.Is(err, address.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, address.ErrTimeout):
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,225 @@
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+var sé = address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", Neighborhood: "Sé", City: "São Paulo", State: "SP"}
+
+// newHandler returns a quiet handler whose service races providers, and
+// only them.
+func newHandler(t *testing.T, providers ...address.Provider) *httpapi.Handler {
+	t.Helper()
+
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	names := make([]string, len(providers))
+	for i, provider := range providers {
+		service.RegisterProvider(provider)
+		names[i] = provider.Name()
+	}
+	if err := service.SetProviders(names...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(service.Close)
+
+	return httpapi.NewHandler(service).SetLogger(address.NopLogger())
+}
+
+// get serves GET path with the given header pairs.
+func get(handler http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		request.Header.Set(header[i], header[i+1])
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestGetCEP(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *addresstest.MockProvider
+		cep      string
+		status   int
+	}{
+		{"found", addresstest.NewMockProvider("Mock").Returns(sé), "01001-000", http.StatusOK},
+		{"invalid", addresstest.NewMockProvider("Mock").Returns(sé), "123", http.StatusBadRequest},
+		{"not found", addresstest.NewMockProvider("Mock"), "99999999", http.StatusNotFound},
+		{"providers failed", addresstest.NewMockProvider("Mock").Fails(errors.New("connection refused")), "01001000", http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := get(newHandler(t, test.provider), "/cep/"+test.cep)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if got := response.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type %q, want application/json", got)
+			}
+			if response.Header().Get(httpapi.REQUEST_ID_HEADER) == "" {
+				t.Errorf("no %s on the response", httpapi.REQUEST_ID_HEADER)
+			}
+
+			if test.status == http.StatusOK {
+				var result address.AddressResult
+				if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil || result.Street != sé.Street {
+					t.Errorf("body %s, %v, want the address", response.Body, err)
+				}
+				return
+			}
+
+			if got := response.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control %q on an error, want no-store", got)
+			}
+			if !strings.Contains(response.Body.String(), `"error"`) {
+				t.Errorf("body %s, want an error", response.Body)
+			}
+		})
+	}
+}
+
+func TestRequestIDIsKept(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé))
+
+	if got := get(handler, "/healthz", httpapi.REQUEST_ID_HEADER, "abc-123").Header().Get(httpapi.REQUEST_ID_HEADER); got != "abc-123" {
+		t.Errorf("request ID %q, want the client's", got)
+	}
+	if got := get(handler, "/healthz", httpapi.REQUEST_ID_HEADER, "bad id").Header().Get(httpapi.REQUEST_ID_HEADER); got == "bad id" || got == "" {
+		t.Errorf("request ID %q, want a generated one for an invalid ID", got)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé).SetLatency(time.Hour)
+	handler := newHandler(t, provider).SetRequestTimeout(20 * time.Millisecond)
+
+	response := get(handler, "/cep/01001000")
+	if response.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d, want 504: %s", response.Code, response.Body)
+	}
+	if got := response.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control %q, want no-store", got)
+	}
+	if !strings.Contains(response.Body.String(), "upstream timeout") {
+		t.Errorf("body %s, want the timeout named", response.Body)
+	}
+
+	// A request finishing in time keeps its own status and headers.
+	provider.SetLatency(0)
+	response = get(handler, "/cep/01001000")
+	if response.Code != http.StatusOK || response.Header().Get("ETag") == "" {
+		t.Errorf("status %d, ETag %q, want 200 with an ETag", response.Code, response.Header().Get("ETag"))
+	}
+}
+
+// post serves POST path with body.
+func post(handler http.Handler, path string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("POST", path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+type batchItem struct {
+	CEP     string                 `json:"cep"`
+	Address *address.AddressResult `json:"address"`
+	Error   string                 `json:"error"`
+}
+
+func TestPostBatch(t *testing.T) {
+	byCEP := address.NewProvider("ByCEP", func(ctx context.Context, client *http.Client, cep string) (address.AddressResult, error) {
+		if cep == "01001000" {
+			return sé, nil
+		}
+		return address.AddressResult{}, address.ErrNotFound
+	})
+	handler := newHandler(t, byCEP).SetMaxBatchSize(5)
+
+	// Duplicates are answered once per occurrence, in request order.
+	response := post(handler, "/cep/batch", `{"ceps": ["01001000", "99999999", "123", "01001-000", "01001000"]}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", response.Code, response.Body)
+	}
+
+	var items []batchItem
+	if err := json.Unmarshal(response.Body.Bytes(), &items); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+
+	want := []struct {
+		cep   string
+		found bool
+		error string
+	}{
+		{"01001000", true, ""},
+		{"99999999", false, "not found"},
+		{"123", false, "invalid CEP"},
+		{"01001-000", true, ""},
+		{"01001000", true, ""},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("%d items, want %d: %s", len(items), len(want), response.Body)
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.CEP != w.cep {
+			t.Errorf("item %d is for %q, want %q", i, item.CEP, w.cep)
+		}
+		if found := item.Address != nil && item.Address.Street == sé.Street; found != w.found {
+			t.Errorf("item %d address %+v, want found %v", i, item.Address, w.found)
+		}
+		if !strings.Contains(item.Error, w.error) || (w.error == "") != (item.Error == "") {
+			t.Errorf("item %d error %q, want %q", i, item.Error, w.error)
+		}
+	}
+}
+
+func TestPostBatchRejects(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetMaxBatchSize(3)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{"over the cap", `{"ceps": ["01001000", "01001001", "01001002", "01001003"]}`, http.StatusRequestEntityTooLarge, "exceeds the limit of 3"},
+		{"malformed", `{"ceps": ["01001000",}`, http.StatusBadRequest, "malformed JSON at byte 22"},
+		{"wrong type", `{"ceps": "01001000"}`, http.StatusBadRequest, `invalid value for "ceps"`},
+		{"truncated", `{"ceps": [`, http.StatusBadRequest, "unexpected end of body"},
+		{"empty body", ``, http.StatusBadRequest, "malformed JSON"},
+		{"too large a body", `{"ceps": ["` + strings.Repeat("0", httpapi.MAX_BATCH_BODY_BYTES) + `"]}`, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(handler, "/cep/batch", test.body)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil || !strings.Contains(body.Error, test.error) {
+				t.Errorf("body %s, want an error naming %q", response.Body, test.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// READINESS_CACHE_TTL bounds how often /readyz contacts the upstream
+// providers; probes in between are answered from the last check.
+const READINESS_CACHE_TTL = 30 * time.Second
+
+type statusResponse struct {
+	Status    string           `json:"status"`
+	Providers []providerHealth `json:"providers,omitempty"`
+}
+
+type providerHealth struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// readiness caches the result of the provider health check. The mutex is
+// held while checking, so concurrent probes share a single round of
+// upstream requests.
+type readiness struct {
+	mu        sync.Mutex
+	check     func(ctx context.Context) []address.ProviderStatus
+	ttl       time.Duration
+	checkedAt time.Time
+	statuses  []address.ProviderStatus
+}
+
+func newReadiness(service *address.AddressService) *readiness {
+	return &readiness{
+		check: func(ctx context.Context) []address.ProviderStatus {
+			return service.CheckProviders(ctx, address.DEFAULT_HEALTH_CHECK_TIMEOUT)
+		},
+		ttl: READINESS_CACHE_TTL,
+	}
+}
+
+func (r *readiness) providers(ctx context.Context) []address.ProviderStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.statuses == nil || time.Since(r.checkedAt) >= r.ttl {
+		// A probe that gives up early must not leave a cancelled result
+		// cached for everyone else.
+		r.statuses = r.check(context.WithoutCancel(ctx))
+		r.checkedAt = time.Now()
+	}
+
+	return r.statuses
+}
+
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+}
+
+// readyz reports ready while at least one enabled provider is healthy.
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	response := statusResponse{Status: "unavailable"}
+
+	for _, status := range h.readiness.providers(r.Context()) {
+		if !status.Enabled {
+			continue
+		}
+
+		health := providerHealth{
+			Name:      status.Name,
+			Healthy:   status.Healthy(),
+			LatencyMS: status.Latency.Milliseconds(),
+		}
+		if status.Err != nil {
+			health.Error = status.Err.Error()
+		}
+
+		if health.Healthy {
+			response.Status = "ready"
+		}
+
+		response.Providers = append(response.Providers, health)
+	}
+
+	if response.Status != "ready" {
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,93 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+type readyzResponse struct {
+	Status    string `json:"status"`
+	Providers []struct {
+		Name    string `json:"name"`
+		Healthy bool   `json:"healthy"`
+		Error   string `json:"error"`
+	} `json:"providers"`
+}
+
+func readyz(t *testing.T, handler http.Handler) (int, readyzResponse) {
+	t.Helper()
+
+	response := get(handler, "/readyz")
+	var body readyzResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+
+	return response.Code, body
+}
+
+func TestReadyz(t *testing.T) {
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		providers []*addresstest.MockProvider
+		status    int
+		failing   []string
+	}{
+		{"all healthy", []*addresstest.MockProvider{addresstest.NewMockProvider("A").Returns(sé), addresstest.NewMockProvider("B").Returns(sé)}, http.StatusOK, nil},
+		{"one healthy", []*addresstest.MockProvider{addresstest.NewMockProvider("A").Fails(down), addresstest.NewMockProvider("B").Returns(sé)}, http.StatusOK, []string{"A"}},
+		{"none healthy", []*addresstest.MockProvider{addresstest.NewMockProvider("A").Fails(down), addresstest.NewMockProvider("B").Fails(down)}, http.StatusServiceUnavailable, []string{"A", "B"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newHandler(t, test.providers[0], test.providers[1])
+
+			status, body := readyz(t, handler)
+			if status != test.status {
+				t.Fatalf("status %d, want %d: %+v", status, test.status, body)
+			}
+			if len(body.Providers) != 2 {
+				t.Fatalf("providers %+v, want both listed", body.Providers)
+			}
+
+			var failing []string
+			for _, provider := range body.Providers {
+				if !provider.Healthy {
+					failing = append(failing, provider.Name)
+					if provider.Error == "" {
+						t.Errorf("%s is failing without an error", provider.Name)
+					}
+				}
+			}
+			if !slices.Equal(failing, test.failing) {
+				t.Errorf("failing %v, want %v", failing, test.failing)
+			}
+		})
+	}
+}
+
+func TestReadyzCachesTheCheck(t *testing.T) {
+	provider := addresstest.NewMockProvider("Mock").Returns(sé)
+	handler := newHandler(t, provider)
+
+	if status, _ := readyz(t, handler); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+
+	// Within READINESS_CACHE_TTL the providers are not contacted again, so
+	// the outage is not seen yet.
+	provider.Fails(errors.New("connection refused"))
+	for range 5 {
+		if status, _ := readyz(t, handler); status != http.StatusOK {
+			t.Errorf("status %d, want the cached 200", status)
+		}
+	}
+	provider.AssertCalls(t, 1)
+}
@@ -0,0 +1,168 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// Metric names are part of the server's public interface; dashboards and
+// alerts depend on them, so rename only with a deprecation period.
+const (
+	// address_lookups_total{result}: lookups finished by the service, where
+	// result is ok, invalid_cep, not_found, timeout, providers_failed or error.
+	METRIC_LOOKUPS_TOTAL = "address_lookups_total"
+	// address_lookup_duration_seconds: wall time of a lookup, all providers
+	// included.
+	METRIC_LOOKUP_DURATION = "address_lookup_duration_seconds"
+	// address_provider_attempts_total{provider,outcome}: requests made to each
+	// provider, by the outcome recorded in the lookup report.
+	METRIC_PROVIDER_ATTEMPTS_TOTAL = "address_provider_attempts_total"
+	// address_provider_attempt_duration_seconds{provider}: duration of the
+	// attempts that finished.
+	METRIC_PROVIDER_ATTEMPT_DURATION = "address_provider_attempt_duration_seconds"
+	// address_http_requests_total{route,method,status}: requests served.
+	METRIC_HTTP_REQUESTS_TOTAL = "address_http_requests_total"
+	// address_http_request_duration_seconds{route,method,status}: time to
+	// serve a request.
+	METRIC_HTTP_REQUEST_DURATION = "address_http_request_duration_seconds"
+	// address_http_requests_in_flight: requests being served right now.
+	METRIC_HTTP_REQUESTS_IN_FLIGHT = "address_http_requests_in_flight"
+	// address_http_coalesced_requests_total: requests answered by joining a
+	// lookup of the same CEP already in flight instead of starting one.
+	METRIC_HTTP_COALESCED_REQUESTS_TOTAL = "address_http_coalesced_requests_total"
+)
+
+// metrics owns a dedicated registry, so handlers never share series with each
+// other or with the global default registry.
+type metrics struct {
+	registry         *prometheus.Registry
+	lookups          *prometheus.CounterVec
+	lookupDuration   prometheus.Histogram
+	attempts         *prometheus.CounterVec
+	attemptDuration  *prometheus.HistogramVec
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	coalesced        prometheus.Counter
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: METRIC_LOOKUPS_TOTAL,
+			Help: "Lookups finished by the address service, by result.",
+		}, []string{"result"}),
+		lookupDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    METRIC_LOOKUP_DURATION,
+			Help:    "Duration of address lookups.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: METRIC_PROVIDER_ATTEMPTS_TOTAL,
+			Help: "Requests made to each provider, by outcome.",
+		}, []string{"provider", "outcome"}),
+		attemptDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    METRIC_PROVIDER_ATTEMPT_DURATION,
+			Help:    "Duration of finished provider requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"provider"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: METRIC_HTTP_REQUESTS_TOTAL,
+			Help: "HTTP requests served, by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    METRIC_HTTP_REQUEST_DURATION,
+			Help:    "Duration of HTTP requests, by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: METRIC_HTTP_REQUESTS_IN_FLIGHT,
+			Help: "HTTP requests currently being served.",
+		}),
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: METRIC_HTTP_COALESCED_REQUESTS_TOTAL,
+			Help: "HTTP requests that shared a lookup already in flight for the same CEP.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.lookups,
+		m.lookupDuration,
+		m.attempts,
+		m.attemptDuration,
+		m.requests,
+		m.requestDuration,
+		m.requestsInFlight,
+		m.coalesced,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveLookup implements address.Observer.
+func (m *metrics) ObserveLookup(report *address.Report, err error) {
+	m.lookups.WithLabelValues(lookupResult(err)).Inc()
+
+	if report == nil {
+		return
+	}
+
+	m.lookupDuration.Observe(report.Duration.Seconds())
+
+	for _, attempt := range report.Attempts {
+		m.attempts.WithLabelValues(attempt.Provider, attempt.Outcome).Inc()
+		if attempt.Outcome != address.OUTCOME_CANCELLED && attempt.Outcome != address.OUTCOME_PENDING {
+			m.attemptDuration.WithLabelValues(attempt.Provider).Observe(attempt.Duration.Seconds())
+		}
+	}
+}
+
+func lookupResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, address.ErrInvalidCEP):
+		return "invalid_cep"
+	case errors.Is(err, address.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, address.ErrTimeout):
+		return "timeout"
+	case errors.Is(err, address.ErrAllProvidersFailed):
+		return "providers_failed"
+	}
+
+	return "error"
+}
+
+// instrument records request count, duration and in-flight requests for
+// route. The route is the registered pattern, never the raw path, so label
+// cardinality stays bounded.
+func (m *metrics) instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.requestsInFlight.Inc()
+		defer m.requestsInFlight.Dec()
+
+		start := time.Now()
+		recorder := newResponseRecorder(w)
+		next(recorder, r)
+
+		labels := []string{route, r.Method, strconv.Itoa(recorder.status)}
+		m.requests.WithLabelValues(labels...).Inc()
+		m.requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
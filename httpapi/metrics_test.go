@@ -0,0 +1,52 @@
+package httpapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+func TestMetrics(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).HandleMetrics()
+
+	get(handler, "/cep/01001000")
+	get(handler, "/cep/01001000")
+	get(handler, "/cep/123")
+
+	response := get(handler, "/metrics")
+	if response.Code != 200 {
+		t.Fatalf("status %d for /metrics", response.Code)
+	}
+
+	body := response.Body.String()
+	for _, want := range []string{
+		`address_http_requests_total{method="GET",route="/cep/{cep}",status="200"} 2`,
+		`address_http_requests_total{method="GET",route="/cep/{cep}",status="400"} 1`,
+		`address_lookups_total{result="ok"} 2`,
+		`address_lookups_total{result="invalid_cep"} 1`,
+		`address_provider_attempts_total{outcome="won",provider="Mock"} 2`,
+		`address_http_requests_in_flight 0`,
+		`address_http_request_duration_seconds_count{method="GET",route="/cep/{cep}",status="200"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+
+	// The route label is the pattern, so raw paths never become series.
+	if strings.Contains(body, `route="/cep/01001000"`) {
+		t.Error("a raw path is used as a route label")
+	}
+}
+
+func TestMetricsOnTheirOwnHandler(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé))
+
+	if got := get(handler, "/metrics").Code; got == 200 {
+		t.Errorf("status %d for /metrics without HandleMetrics", got)
+	}
+	if response := get(handler.Metrics(), "/metrics"); response.Code != 200 || !strings.Contains(response.Body.String(), "address_http_requests_in_flight") {
+		t.Errorf("status %d from Metrics(), want the metrics", response.Code)
+	}
+}
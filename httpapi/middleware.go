@@ -0,0 +1,195 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// MAX_REQUEST_ID_LENGTH bounds the request IDs accepted from clients; longer
+// or non-printable ones are replaced by a generated ID.
+const MAX_REQUEST_ID_LENGTH = 128
+
+type requestInfoKey struct{}
+
+// requestInfo is shared by pointer through the request context so outer
+// middleware, such as the access log, sees what inner middleware learned.
+type requestInfo struct {
+	id          string
+	apiKeyLabel string
+}
+
+func infoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	if info == nil {
+		return &requestInfo{}
+	}
+
+	return info
+}
+
+// RequestID returns the ID assigned to the request ctx belongs to, or "" when
+// ctx did not come from the Handler.
+func RequestID(ctx context.Context) string {
+	return infoFromContext(ctx).id
+}
+
+// responseRecorder remembers the status and size of a response for the
+// middleware that wraps it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(data)
+	r.size += n
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withRequestID reuses a sane X-Request-ID from the client or generates one,
+// echoes it on the response and stores it in the request context, where
+// provider headers can pick it up too.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(REQUEST_ID_HEADER, id)
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{id: id})
+		next.ServeHTTP(w, r.WithContext(address.WithRequestID(ctx, id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// logRequests writes one access log line per request once the response is
+// complete. Server errors are logged at error level, everything else at info.
+func (h *Handler) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newResponseRecorder(w)
+
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("size", recorder.size),
+			slog.String("client_ip", h.clientIP(r)),
+			slog.String("request_id", RequestID(r.Context())),
+		}
+		if label := APIKeyLabel(r.Context()); label != "" {
+			attrs = append(attrs, slog.String("api_key", label))
+		}
+
+		h.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// recoverPanics turns a panicking handler into a 500 response, provided
+// nothing was written yet, and logs the panic with its stack.
+func (h *Handler) recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := newResponseRecorder(w)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			h.logger.Error("handler panicked",
+				"panic", recovered,
+				"request_id", RequestID(r.Context()),
+				"stack", string(debug.Stack()),
+			)
+
+			if !recorder.wroteHeader {
+				writeJSON(recorder, http.StatusInternalServerError, errorResponse{Error: http.StatusText(http.StatusInternalServerError)})
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// clientIP is the peer address, or the first X-Forwarded-For entry when the
+// handler sits behind a trusted proxy.
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
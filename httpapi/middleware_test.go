@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+)
+
+// accessLogs decodes the access log lines among the JSON logs.
+func accessLogs(t *testing.T, logs string) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if fields["msg"] == "request" {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
+
+func TestAccessLog(t *testing.T) {
+	service := address.NewAddressService(context.Background()).
+		SetLogger(address.NopLogger()).
+		RegisterProvider(addresstest.NewMockProvider("Mock").Returns(address.AddressResult{ZipCode: "01001000", Street: "Praça da Sé", State: "SP"}))
+	service.SetProviders("Mock")
+	t.Cleanup(service.Close)
+
+	var logs logBuffer
+	handler := NewHandler(service).
+		SetLogger(slog.New(slog.NewJSONHandler(&logs, nil))).
+		SetTrustProxy(true)
+	handler.handle(handler.api, "GET", "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		level  string
+	}{
+		{"found", "/cep/01001000", http.StatusOK, "INFO"},
+		{"unrouted", "/nowhere", http.StatusNotFound, "INFO"},
+		{"panic", "/panic", http.StatusInternalServerError, "ERROR"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs.mu.Lock()
+			logs.buf.Reset()
+			logs.mu.Unlock()
+
+			request := httptest.NewRequest("GET", test.path, nil)
+			request.RemoteAddr = "10.0.0.1:54321"
+			request.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+			request.Header.Set(REQUEST_ID_HEADER, "req-"+test.name)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d", recorder.Code, test.status)
+			}
+
+			lines := accessLogs(t, logs.String())
+			if len(lines) != 1 {
+				t.Fatalf("%d access log lines, want exactly one:\n%s", len(lines), logs.String())
+			}
+
+			line := lines[0]
+			want := map[string]any{
+				"level":      test.level,
+				"method":     "GET",
+				"path":       test.path,
+				"status":     float64(test.status),
+				"size":       float64(recorder.Body.Len()),
+				"client_ip":  "203.0.113.7",
+				"request_id": "req-" + test.name,
+			}
+			for key, value := range want {
+				if line[key] != value {
+					t.Errorf("%s = %v, want %v", key, line[key], value)
+				}
+			}
+			if duration, ok := line["duration"].(float64); !ok || duration <= 0 {
+				t.Errorf("duration = %v, want a positive duration", line["duration"])
+			}
+
+			if test.status == http.StatusInternalServerError && !strings.Contains(logs.String(), `"panic":"boom"`) {
+				t.Errorf("logs = %s, want the panic logged", logs.String())
+			}
+		})
+	}
+}
+
+func TestClientIPIgnoresForwardedForUnlessTrusted(t *testing.T) {
+	request := httptest.NewRequest("GET", "/cep/01001000", nil)
+	request.RemoteAddr = "10.0.0.1:54321"
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	if got := (&Handler{}).clientIP(request); got != "10.0.0.1" {
+		t.Errorf("client IP %q, want the peer address", got)
+	}
+	if got := (&Handler{trustProxy: true}).clientIP(request); got != "203.0.113.7" {
+		t.Errorf("client IP %q behind a trusted proxy, want the forwarded one", got)
+	}
+}
@@ -0,0 +1,142 @@
+package httpapi
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RATE_LIMIT_IDLE_TTL is how long a client may stay quiet before its bucket
+// is dropped. A bucket idle for longer than it takes to refill is
+// indistinguishable from a new one, so dropping it never grants extra
+// requests.
+const RATE_LIMIT_IDLE_TTL = 5 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps one token bucket per client key. Idle buckets are swept
+// while serving requests, at most once per ttl.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	ttl       time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	ttl := RATE_LIMIT_IDLE_TTL
+	if refill := time.Duration(float64(burst) / perSecond * float64(time.Second)); refill > ttl {
+		ttl = refill
+	}
+
+	return &rateLimiter{
+		rate:    perSecond,
+		burst:   float64(burst),
+		ttl:     ttl,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from key's bucket. When the bucket is empty it reports
+// how long until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.ttl {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.ttl {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// SetRateLimit limits every client to perSecond requests with bursts of up
+// to burst. Clients are told apart by API key when authentication is on,
+// otherwise by IP or by SetRateLimitHeader. A non-positive perSecond
+// disables the limit.
+func (h *Handler) SetRateLimit(perSecond float64, burst int) *Handler {
+	if perSecond <= 0 {
+		h.limiter = nil
+		return h
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	h.limiter = newRateLimiter(perSecond, burst)
+	return h
+}
+
+// SetRateLimitHeader keys rate limiting by the value of header, falling back
+// to the client IP when a request does not carry it.
+func (h *Handler) SetRateLimitHeader(header string) *Handler {
+	h.rateLimitHeader = header
+	return h
+}
+
+func (h *Handler) rateLimitKey(r *http.Request) string {
+	if label := APIKeyLabel(r.Context()); label != "" {
+		return "key:" + label
+	}
+
+	if h.rateLimitHeader != "" {
+		if value := r.Header.Get(h.rateLimitHeader); value != "" {
+			return h.rateLimitHeader + ":" + value
+		}
+	}
+
+	return "ip:" + h.clientIP(r)
+}
+
+func (h *Handler) limitRate(next http.Handler) http.Handler {
+	if h.limiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := h.limiter.allow(h.rateLimitKey(r))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "rate limit exceeded"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,108 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+// from serves GET path as if sent from remoteAddr.
+func from(handler http.Handler, remoteAddr string, path string, header ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", path, nil)
+	request.RemoteAddr = remoteAddr
+	for i := 0; i+1 < len(header); i += 2 {
+		request.Header.Set(header[i], header[i+1])
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetRateLimit(0.5, 2)
+
+	for i := range 2 {
+		if got := from(handler, "192.0.2.1:1234", "/cep/01001000").Code; got != http.StatusOK {
+			t.Fatalf("request %d: status %d within the burst, want 200", i, got)
+		}
+	}
+
+	limited := from(handler, "192.0.2.1:5678", "/cep/01001000")
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d past the burst, want 429", limited.Code)
+	}
+	retryAfter, err := strconv.Atoi(limited.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 2 {
+		t.Errorf("Retry-After %q, want the 2s until the next token", limited.Header().Get("Retry-After"))
+	}
+
+	// Other clients and the probes are not held back.
+	if got := from(handler, "192.0.2.2:1234", "/cep/01001000").Code; got != http.StatusOK {
+		t.Errorf("status %d for another client, want 200", got)
+	}
+	if got := from(handler, "192.0.2.1:1234", "/healthz").Code; got != http.StatusOK {
+		t.Errorf("status %d for /healthz, want probes left unlimited", got)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*httpapi.Handler)
+		first   []string
+		second  []string
+		limited bool
+	}{
+		{
+			name:    "header",
+			setup:   func(h *httpapi.Handler) { h.SetRateLimitHeader("X-Client") },
+			first:   []string{"X-Client", "a"},
+			second:  []string{"X-Client", "b"},
+			limited: false,
+		},
+		{
+			name:    "same header",
+			setup:   func(h *httpapi.Handler) { h.SetRateLimitHeader("X-Client") },
+			first:   []string{"X-Client", "a"},
+			second:  []string{"X-Client", "a"},
+			limited: true,
+		},
+		{
+			name: "API key",
+			setup: func(h *httpapi.Handler) {
+				h.SetAPIKeys(httpapi.NewAPIKeys([]httpapi.APIKey{{Label: "shop", Key: "k1"}, {Label: "ops", Key: "k2"}}))
+			},
+			first:   []string{httpapi.API_KEY_HEADER, "k1"},
+			second:  []string{httpapi.API_KEY_HEADER, "k2"},
+			limited: false,
+		},
+		{
+			name:    "forwarded IP",
+			setup:   func(h *httpapi.Handler) { h.SetTrustProxy(true) },
+			first:   []string{"X-Forwarded-For", "203.0.113.1, 10.0.0.1"},
+			second:  []string{"X-Forwarded-For", "203.0.113.2"},
+			limited: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newHandler(t, addresstest.NewMockProvider("Mock").Returns(sé)).SetRateLimit(0.001, 1)
+			test.setup(handler)
+
+			// Every request comes from the same peer.
+			if got := from(handler, "192.0.2.1:1234", "/cep/01001000", test.first...).Code; got != http.StatusOK {
+				t.Fatalf("first request: status %d", got)
+			}
+			got := from(handler, "192.0.2.1:1234", "/cep/01001000", test.second...).Code
+			if limited := got == http.StatusTooManyRequests; limited != test.limited {
+				t.Errorf("second request: status %d, want limited %t", got, test.limited)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterClientsAreIndependent(t *testing.T) {
+	limiter := newRateLimiter(1, 5)
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	// One client hammers the limiter while another makes a few requests.
+	var greedy, polite atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, _ := limiter.allow("ip:192.0.2.1"); allowed {
+				greedy.Add(1)
+			}
+			if i%50 == 0 {
+				if allowed, _ := limiter.allow("ip:192.0.2.2"); allowed {
+					polite.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := greedy.Load(); got != 5 {
+		t.Errorf("the hammering client got %d requests through, want its burst of 5", got)
+	}
+	if got := polite.Load(); got != 4 {
+		t.Errorf("the other client got %d of its 4 requests through", got)
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	limiter := newRateLimiter(1, 2)
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("idle")
+	limiter.allow("active")
+	now = now.Add(RATE_LIMIT_IDLE_TTL / 2)
+	limiter.allow("active")
+
+	// The next sweep is due a TTL after the first one.
+	now = now.Add(RATE_LIMIT_IDLE_TTL / 2)
+	limiter.allow("new")
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("the bucket idle for a whole TTL was kept")
+	}
+	if _, ok := limiter.buckets["active"]; !ok {
+		t.Error("the bucket used within the TTL was dropped")
+	}
+	if got := len(limiter.buckets); got != 2 {
+		t.Errorf("%d buckets, want 2", got)
+	}
+}
@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+const (
+	DEFAULT_ADDR                = ":8080"
+	DEFAULT_READ_HEADER_TIMEOUT = 5 * time.Second
+	DEFAULT_READ_TIMEOUT        = 15 * time.Second
+	DEFAULT_WRITE_TIMEOUT       = 30 * time.Second
+	DEFAULT_IDLE_TIMEOUT        = 2 * time.Minute
+	DEFAULT_MAX_HEADER_BYTES    = 64 << 10
+)
+
+var ErrInvalidServerConfig = errors.New("invalid server config")
+
+// ServerConfig holds the listener settings of the HTTP server. TLS is served
+// when both TLSCertFile and TLSKeyFile are set.
+type ServerConfig struct {
+	Addr              string
+	TLSCertFile       string
+	TLSKeyFile        string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+// DefaultServerConfig has a timeout on every phase of a connection, unlike
+// http.Server's zero values, which never time out.
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:              DEFAULT_ADDR,
+		ReadHeaderTimeout: DEFAULT_READ_HEADER_TIMEOUT,
+		ReadTimeout:       DEFAULT_READ_TIMEOUT,
+		WriteTimeout:      DEFAULT_WRITE_TIMEOUT,
+		IdleTimeout:       DEFAULT_IDLE_TIMEOUT,
+		MaxHeaderBytes:    DEFAULT_MAX_HEADER_BYTES,
+	}
+}
+
+func (c ServerConfig) TLS() bool {
+	return c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
+
+func (c ServerConfig) Validate() error {
+	_, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Errorf("%w: addr %q: %w", ErrInvalidServerConfig, c.Addr, err)
+	}
+
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("%w: addr %q: invalid port %q", ErrInvalidServerConfig, c.Addr, port)
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("%w: TLS needs both a certificate and a key", ErrInvalidServerConfig)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read header timeout", c.ReadHeaderTimeout},
+		{"read timeout", c.ReadTimeout},
+		{"write timeout", c.WriteTimeout},
+		{"idle timeout", c.IdleTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("%w: %s must be positive, got %s", ErrInvalidServerConfig, timeout.name, timeout.value)
+		}
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		return fmt.Errorf("%w: max header bytes must be positive, got %d", ErrInvalidServerConfig, c.MaxHeaderBytes)
+	}
+
+	return nil
+}
+
+// Server is an http.Server serving API with the listener settings of a
+// ServerConfig.
+type Server struct {
+	*http.Server
+	API *Handler
+	tls bool
+}
+
+// NewServer validates cfg and loads the TLS certificate up front, so
+// misconfiguration fails at startup instead of on the first request. A
+// service with SetInsecureSkipVerify on is refused: it is for debugging, and
+// a server would hand forged addresses to its clients.
+func NewServer(cfg ServerConfig, service *address.AddressService) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if service.InsecureSkipVerify() {
+		return nil, fmt.Errorf("%w: the service does not verify provider certificates", ErrInvalidServerConfig)
+	}
+
+	handler := NewHandler(service)
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+
+	if cfg.TLS() {
+		certificate, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: loading TLS certificate: %w", ErrInvalidServerConfig, err)
+		}
+
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	return &Server{Server: server, API: handler, tls: cfg.TLS()}, nil
+}
+
+// ListenAndServe serves HTTPS when the config has a certificate and plain
+// HTTP otherwise.
+func (s *Server) ListenAndServe() error {
+	if s.tls {
+		return s.Server.ListenAndServeTLS("", "")
+	}
+
+	return s.Server.ListenAndServe()
+}
+
+// Serve is ListenAndServe on an existing listener.
+func (s *Server) Serve(listener net.Listener) error {
+	if s.tls {
+		return s.Server.ServeTLS(listener, "", "")
+	}
+
+	return s.Server.Serve(listener)
+}
@@ -0,0 +1,187 @@
+package httpapi_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/address/addresstest"
+	"github.com/wendellnd/multithreading-challenge/httpapi"
+)
+
+func TestNewServerRefusesInsecureServices(t *testing.T) {
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	t.Cleanup(service.Close)
+
+	if _, err := httpapi.NewServer(httpapi.DefaultServerConfig(), service); err != nil {
+		t.Fatalf("NewServer = %v", err)
+	}
+
+	if err := service.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := httpapi.NewServer(httpapi.DefaultServerConfig(), service); !errors.Is(err, httpapi.ErrInvalidServerConfig) {
+		t.Errorf("NewServer = %v, want ErrInvalidServerConfig", err)
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*httpapi.ServerConfig)
+		valid  bool
+	}{
+		{"defaults", func(*httpapi.ServerConfig) {}, true},
+		{"host and port", func(c *httpapi.ServerConfig) { c.Addr = "127.0.0.1:8443" }, true},
+		{"named port", func(c *httpapi.ServerConfig) { c.Addr = ":http" }, true},
+		{"TLS", func(c *httpapi.ServerConfig) { c.TLSCertFile, c.TLSKeyFile = "cert.pem", "key.pem" }, true},
+		{"no port", func(c *httpapi.ServerConfig) { c.Addr = "localhost" }, false},
+		{"bad port", func(c *httpapi.ServerConfig) { c.Addr = ":99999" }, false},
+		{"unknown port name", func(c *httpapi.ServerConfig) { c.Addr = ":nope" }, false},
+		{"certificate without key", func(c *httpapi.ServerConfig) { c.TLSCertFile = "cert.pem" }, false},
+		{"key without certificate", func(c *httpapi.ServerConfig) { c.TLSKeyFile = "key.pem" }, false},
+		{"no read header timeout", func(c *httpapi.ServerConfig) { c.ReadHeaderTimeout = 0 }, false},
+		{"negative read timeout", func(c *httpapi.ServerConfig) { c.ReadTimeout = -time.Second }, false},
+		{"no write timeout", func(c *httpapi.ServerConfig) { c.WriteTimeout = 0 }, false},
+		{"no idle timeout", func(c *httpapi.ServerConfig) { c.IdleTimeout = 0 }, false},
+		{"no max header bytes", func(c *httpapi.ServerConfig) { c.MaxHeaderBytes = 0 }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := httpapi.DefaultServerConfig()
+			test.change(&config)
+
+			err := config.Validate()
+			if test.valid && err != nil {
+				t.Errorf("Validate = %v, want nil", err)
+			}
+			if !test.valid && !errors.Is(err, httpapi.ErrInvalidServerConfig) {
+				t.Errorf("Validate = %v, want ErrInvalidServerConfig", err)
+			}
+		})
+	}
+}
+
+// selfSignedCertificate writes a certificate for 127.0.0.1 and its key to
+// dir and returns their paths and a pool trusting the certificate.
+func selfSignedCertificate(t *testing.T, dir string) (certFile string, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cep-lookup test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pool = x509.NewCertPool()
+	pool.AddCert(certificate)
+	return certFile, keyFile, pool
+}
+
+func TestNewServerTLS(t *testing.T) {
+	service := address.NewAddressService(context.Background()).
+		SetLogger(address.NopLogger()).
+		RegisterProvider(addresstest.NewMockProvider("Mock").Returns(sé))
+	service.SetProviders("Mock")
+	t.Cleanup(service.Close)
+
+	dir := t.TempDir()
+	config := httpapi.DefaultServerConfig()
+	config.Addr = "127.0.0.1:0"
+
+	// A missing certificate fails at startup, not on the first request.
+	config.TLSCertFile, config.TLSKeyFile = filepath.Join(dir, "missing.pem"), filepath.Join(dir, "missing-key.pem")
+	if _, err := httpapi.NewServer(config, service); !errors.Is(err, httpapi.ErrInvalidServerConfig) {
+		t.Fatalf("NewServer with a missing certificate = %v, want ErrInvalidServerConfig", err)
+	}
+
+	var pool *x509.CertPool
+	config.TLSCertFile, config.TLSKeyFile, pool = selfSignedCertificate(t, dir)
+	server, err := httpapi.NewServer(config, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.API.SetLogger(address.NopLogger())
+	server.ErrorLog = log.New(io.Discard, "", 0)
+
+	listener, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	t.Cleanup(client.CloseIdleConnections)
+
+	response, err := client.Get("https://" + listener.Addr().String() + "/cep/01001000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var result address.AddressResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil || response.StatusCode != http.StatusOK || result.Street != sé.Street {
+		t.Errorf("status %d, address %+v, %v, want Praça da Sé", response.StatusCode, result, err)
+	}
+	if response.TLS == nil {
+		t.Error("the response did not come over TLS")
+	}
+
+	// The listener speaks TLS only.
+	plain, err := http.Get("http://" + listener.Addr().String() + "/healthz")
+	if err == nil {
+		plain.Body.Close()
+		if plain.StatusCode == http.StatusOK {
+			t.Error("a plain HTTP request was served")
+		}
+	}
+}
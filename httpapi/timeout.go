@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// SetRequestTimeout bounds how long a request may take. When it runs out the
+// client gets a 504 and the lookups of the request are cancelled. Zero
+// disables the limit.
+func (h *Handler) SetRequestTimeout(timeout time.Duration) *Handler {
+	h.requestTimeout = timeout
+	return h
+}
+
+// limitDuration runs the handler on its own goroutine against a buffering
+// writer, so that whichever of the handler and the deadline finishes first
+// commits the response and the other one is discarded.
+func (h *Handler) limitDuration(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.requestTimeout <= 0 {
+			next(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout)
+		defer cancel()
+
+		tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK}
+		done := make(chan struct{})
+		panics := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				// Once the 504 is out, nothing is left to re-panic into;
+				// the panic is logged rather than lost.
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				if !tw.committed {
+					panics <- recovered
+					return
+				}
+
+				h.logger.Error("handler panicked after the request timed out",
+					"panic", recovered,
+					"request_id", RequestID(r.Context()),
+					"stack", string(debug.Stack()),
+				)
+			}()
+
+			next(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case recovered := <-panics:
+			panic(recovered)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.committed = true
+			for key, values := range tw.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.committed = true
+			writeUncacheable(w, http.StatusGatewayTimeout, errorResponse{CEP: r.PathValue("cep"), Error: "upstream timeout"})
+
+			// A panic that beat the deadline to the lock lost the select.
+			select {
+			case recovered := <-panics:
+				h.logger.Error("handler panicked after the request timed out",
+					"panic", recovered,
+					"request_id", RequestID(r.Context()),
+				)
+			default:
+			}
+		}
+	}
+}
+
+// timeoutWriter collects the handler's response until limitDuration commits
+// it. Writes after that are dropped.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	committed   bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.committed || tw.wroteHeader {
+		return
+	}
+
+	tw.status = status
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.committed {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.wroteHeader = true
+	return tw.body.Write(data)
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/address"
+)
+
+// logBuffer is a bytes.Buffer safe to log to from several goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestPanicAfterTimeoutIsLogged(t *testing.T) {
+	service := address.NewAddressService(context.Background()).SetLogger(address.NopLogger())
+	t.Cleanup(service.Close)
+
+	var logs logBuffer
+	handler := NewHandler(service).
+		SetLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		SetRequestTimeout(10 * time.Millisecond)
+
+	late := handler.limitDuration(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		panic("too late")
+	})
+
+	recorder := httptest.NewRecorder()
+	late(recorder, httptest.NewRequest("GET", "/cep/01001000", nil))
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d, want 504", recorder.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "too late") {
+		if time.Now().After(deadline) {
+			t.Fatalf("logs = %q, want the late panic", logs.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), "after the request timed out") {
+		t.Errorf("logs = %q, want the panic told apart from one that could still be answered", logs.String())
+	}
+}
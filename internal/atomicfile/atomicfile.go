@@ -0,0 +1,96 @@
+// Package atomicfile replaces files in one step, so that readers see either
+// the old content or the new, never a partial write.
+package atomicfile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// NEW_FILE_MODE is the mode of a file that did not exist before. CreateTemp
+// makes the temporary file 0600, which would otherwise survive the rename.
+const NEW_FILE_MODE fs.FileMode = 0o644
+
+// File buffers output in a temporary file next to path and only replaces
+// path when Commit is called.
+type File struct {
+	*os.File
+	path string
+	done bool
+}
+
+func Create(path string) (*File, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	file, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{File: file, path: path}, nil
+}
+
+// Commit replaces path with what was written, keeping the mode of the file
+// it replaces, or NEW_FILE_MODE.
+func (f *File) Commit() error {
+	f.done = true
+
+	mode := NEW_FILE_MODE
+	if info, err := os.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Abort drops what was written, leaving path as it was. It does nothing
+// after Commit.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile replaces path with data.
+func WriteFile(path string, data []byte) error {
+	file, err := Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Commit()
+}
@@ -0,0 +1,76 @@
+package atomicfile_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wendellnd/multithreading-challenge/internal/atomicfile"
+)
+
+func TestCommitMode(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.json")
+	if err := os.WriteFile(existing, []byte("[]"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile is subject to the umask; Chmod is not.
+	if err := os.Chmod(existing, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want fs.FileMode
+	}{
+		{filepath.Join(dir, "new.json"), atomicfile.NEW_FILE_MODE},
+		{existing, 0o640},
+	}
+
+	for _, test := range tests {
+		t.Run(filepath.Base(test.path), func(t *testing.T) {
+			file, err := atomicfile.Create(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := file.WriteString("[]\n"); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := os.Stat(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != test.want {
+				t.Errorf("mode = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := atomicfile.WriteFile(path, []byte("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != atomicfile.NEW_FILE_MODE {
+		t.Errorf("mode = %v, want %v", got, atomicfile.NEW_FILE_MODE)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the written one", len(entries))
+	}
+}
@@ -2,25 +2,11 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"time"
+	"os"
 
-	"github.com/wendellnd/multithreading-challenge/address"
+	"github.com/wendellnd/multithreading-challenge/cmd"
 )
 
 func main() {
-	cep := "01001000"
-	ctx := context.Background()
-
-	addressService := address.NewAddressService(ctx)
-	addressService.SetTimeout(1 * time.Second)
-
-	result, err := addressService.Execute(cep)
-	if err != nil {
-		log.Println(err.Error())
-		return
-	}
-
-	fmt.Printf("%+v", result)
+	os.Exit(cmd.Run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,6 @@
+// Package addresspb holds the code generated from proto/address.proto, the
+// binary encoding of an address.AddressResult. Regenerate after editing the
+// definition.
+package addresspb
+
+//go:generate protoc -I../.. --go_out=../.. --go_opt=module=github.com/wendellnd/multithreading-challenge proto/address.proto
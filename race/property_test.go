@@ -0,0 +1,84 @@
+package race_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/race"
+)
+
+// SEED fixes the random cases, so a failure can be replayed.
+const SEED = 183
+
+func TestFirstReturnsASuccessWheneverOneExists(t *testing.T) {
+	random := rand.New(rand.NewPCG(SEED, 0))
+
+	for run := range 300 {
+		n := 1 + random.IntN(12)
+		fns := make([]func(context.Context) (string, error), n)
+		succeeding := make(map[string]bool)
+		for i := range fns {
+			latency := time.Duration(random.IntN(5000)) * time.Microsecond
+			if random.IntN(3) == 0 {
+				value := fmt.Sprintf("fn %d", i)
+				succeeding[value] = true
+				fns[i] = after(latency, value, nil)
+			} else {
+				fns[i] = after(latency, "", fmt.Errorf("fn %d failed", i))
+			}
+		}
+
+		value, err := race.First(context.Background(), time.Second, fns...)
+		switch {
+		case len(succeeding) > 0 && (err != nil || !succeeding[value]):
+			t.Fatalf("run %d, %d fns, %d succeed: First = (%q, %v), want a success", run, n, len(succeeding), value, err)
+		case len(succeeding) == 0 && err == nil:
+			t.Fatalf("run %d, %d fns, none succeed: First = %q, want an error", run, n, value)
+		}
+	}
+}
+
+func TestFirstJoinsErrorsInTheOrderTheyFailed(t *testing.T) {
+	random := rand.New(rand.NewPCG(SEED, 1))
+
+	for run := range 300 {
+		n := 1 + random.IntN(6)
+		order := random.Perm(n)
+		// Each fn fails once the race saw the one ranked before it fail, so
+		// the order does not depend on the scheduler.
+		turns := make([]chan struct{}, n+1)
+		for i := range turns {
+			turns[i] = make(chan struct{})
+		}
+		close(turns[0])
+
+		fns := make([]func(context.Context) (string, error), n)
+		want := make([]string, n)
+		for i, rank := range order {
+			message := fmt.Sprintf("fn %d failed", i)
+			fns[i] = func(context.Context) (string, error) {
+				<-turns[rank]
+				return "", errors.New(message)
+			}
+			want[rank] = message
+		}
+
+		r := race.Race[string]{
+			Observe: func(result race.Result[string]) {
+				close(turns[order[result.Index]+1])
+			},
+		}
+		_, err := r.First(context.Background(), fns...)
+		if err == nil {
+			t.Fatalf("run %d: First succeeded with every fn failing", run)
+		}
+		if got := err.Error(); got != strings.Join(want, "\n") {
+			t.Fatalf("run %d, order %v: err = %q, want %q", run, order, got, strings.Join(want, "\n"))
+		}
+	}
+}
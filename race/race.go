@@ -0,0 +1,280 @@
+// Package race runs functions concurrently and keeps the first one that
+// succeeds.
+package race
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrNoFunctions = errors.New("race: no functions")
+
+// Result is what the function at Index returned.
+type Result[T any] struct {
+	Index int
+	Value T
+	Err   error
+}
+
+// Start calls every fn in its own goroutine with ctx and sends each result
+// on the returned, unbuffered channel, which is closed once they have all
+// returned. Results are only sent while ctx is not done: after that they
+// are dropped, so the channel may close with fewer results than functions.
+// A caller that stops reading must cancel ctx, and then no function blocks
+// on the channel, however many there are.
+func Start[T any](ctx context.Context, fns ...func(context.Context) (T, error)) <-chan Result[T] {
+	ch := make(chan Result[T])
+
+	// A failure is a result like any other, so no function fails the
+	// group; it only tells when the last one returned.
+	var group errgroup.Group
+	for i, fn := range fns {
+		group.Go(func() error {
+			value, err := fn(ctx)
+			select {
+			case ch <- Result[T]{Index: i, Value: value, Err: err}:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	}
+
+	go func() {
+		group.Wait()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// First calls every fn concurrently and returns the value of the first one
+// that succeeds, cancelling the context the others run with. When they all
+// fail, their errors are joined in the order they failed. When ctx is done,
+// or timeout passes first, ctx's error or context.DeadlineExceeded is
+// returned; a timeout of zero or less means no timeout. First returns
+// without waiting for functions that ignore their context.
+func First[T any](ctx context.Context, timeout time.Duration, fns ...func(context.Context) (T, error)) (T, error) {
+	var r Race[T]
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		r.Deadline = timer.C
+	}
+
+	return r.First(ctx, fns...)
+}
+
+// Timer is the part of time.Timer a Race uses for its grace period.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// Race is First for callers that need more say over the winner: a deadline
+// from their own clock, a function worth waiting a little longer for, and a
+// look at every result. The zero Race is ready to use; a Race runs once.
+type Race[T any] struct {
+	// Deadline ends the race when it receives, as a timeout would. A nil
+	// channel never does.
+	Deadline <-chan time.Time
+
+	// Preferred tells whether the function at index is worth waiting for.
+	// While it has not returned, a success from another function is held
+	// for up to Grace; the held value wins once the preferred function
+	// failed, Grace passed, the race expired or every function returned.
+	// A nil Preferred prefers none.
+	Preferred func(index int) bool
+	Grace     time.Duration
+
+	// NewTimer starts the grace timer; nil means time.NewTimer.
+	NewTimer func(d time.Duration) Timer
+
+	// Observe is called, on the goroutine running the race, with every
+	// result received before it ended, those already waiting when the
+	// winner was picked included.
+	Observe func(Result[T])
+
+	ch      <-chan Result[T]
+	expired bool
+}
+
+// First is the package's First, with the race's hooks. It returns
+// context.DeadlineExceeded when the race expired, and ctx's cause when ctx
+// was done, before a winner was picked.
+func (r *Race[T]) First(ctx context.Context, fns ...func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if len(fns) == 0 {
+		return zero, ErrNoFunctions
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.ch = Start(ctx, fns...)
+
+	// waiting is set while a preferred function has not returned. held is
+	// the success kept meanwhile; grace stays nil, and so never fires,
+	// until there is one.
+	var waiting bool
+	for i := range fns {
+		waiting = waiting || r.Preferred != nil && r.Preferred(i)
+	}
+	var held *Result[T]
+	var grace <-chan time.Time
+
+	var errs []error
+	for {
+		select {
+		case <-r.Deadline:
+			r.expired = true
+			if held != nil {
+				return r.win(*held)
+			}
+			return zero, context.DeadlineExceeded
+		case <-grace:
+			return r.win(*held)
+		case <-ctx.Done():
+			return zero, context.Cause(ctx)
+		case result, ok := <-r.ch:
+			if !ok {
+				if held != nil {
+					return r.win(*held)
+				}
+				// Results are dropped once ctx is done, so a channel that
+				// closes before every function failed means ctx ended.
+				if len(errs) < len(fns) {
+					return zero, context.Cause(ctx)
+				}
+				return zero, errors.Join(errs...)
+			}
+			r.observe(result)
+
+			preferred := waiting && r.Preferred(result.Index)
+			if preferred {
+				waiting = false
+			}
+
+			if result.Err != nil {
+				errs = append(errs, result.Err)
+				if preferred && held != nil {
+					return r.win(*held)
+				}
+				continue
+			}
+
+			if !waiting {
+				return r.win(result)
+			}
+
+			if held == nil {
+				held = &result
+				timer := r.newTimer(r.Grace)
+				defer timer.Stop()
+				grace = timer.C()
+			}
+		}
+	}
+}
+
+// All calls every fn concurrently like First, but waits for all of them,
+// handing each result to Observe; Preferred does not apply. It returns nil
+// once they all returned, context.DeadlineExceeded when the race expired
+// first, and ctx's cause when ctx was done first.
+func (r *Race[T]) All(ctx context.Context, fns ...func(context.Context) (T, error)) error {
+	if len(fns) == 0 {
+		return ErrNoFunctions
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.ch = Start(ctx, fns...)
+
+	for pending := len(fns); pending > 0; pending-- {
+		select {
+		case <-r.Deadline:
+			r.expired = true
+			return context.DeadlineExceeded
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case result, ok := <-r.ch:
+			if !ok {
+				return context.Cause(ctx)
+			}
+			r.observe(result)
+		}
+	}
+
+	return nil
+}
+
+// Expired tells whether the race ended because Deadline received, even if a
+// held value won then.
+func (r *Race[T]) Expired() bool {
+	return r.expired
+}
+
+// Settle waits, once the race ended, until every function returned or
+// Deadline received, dropping their results. It returns right away when the
+// race already expired.
+func (r *Race[T]) Settle() {
+	if r.expired {
+		return
+	}
+
+	for {
+		select {
+		case _, ok := <-r.ch:
+			if !ok {
+				return
+			}
+		case <-r.Deadline:
+			r.expired = true
+			return
+		}
+	}
+}
+
+// win picks result, observing the results that already arrived without
+// waiting for more.
+func (r *Race[T]) win(result Result[T]) (T, error) {
+	for {
+		select {
+		case other, ok := <-r.ch:
+			if !ok {
+				return result.Value, nil
+			}
+			r.observe(other)
+		default:
+			return result.Value, nil
+		}
+	}
+}
+
+func (r *Race[T]) observe(result Result[T]) {
+	if r.Observe != nil {
+		r.Observe(result)
+	}
+}
+
+func (r *Race[T]) newTimer(d time.Duration) Timer {
+	if r.NewTimer != nil {
+		return r.NewTimer(d)
+	}
+	return realTimer{time.NewTimer(d)}
+}
@@ -0,0 +1,349 @@
+package race_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wendellnd/multithreading-challenge/race"
+)
+
+// block waits for its context and then, ignoring it, succeeds, so its result
+// is only ever dropped.
+func block(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "late", nil
+}
+
+func TestFirstReturnsContextErrorOnceCancelled(t *testing.T) {
+	failed := errors.New("failed")
+	// Most functions fail right away, keeping First busy while ctx is
+	// cancelled; the rest only return once it is, so their results are
+	// dropped and the channel closes early.
+	fns := make([]func(context.Context) (string, error), 200)
+	for i := range fns {
+		fns[i] = func(context.Context) (string, error) { return "", failed }
+	}
+	fns = append(fns, block, block)
+
+	for i := 0; i < 200; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		fns[0] = func(context.Context) (string, error) {
+			cancel()
+			return "", failed
+		}
+
+		value, err := race.First(ctx, 0, fns...)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("run %d: First = (%q, %v), want context.Canceled", i, value, err)
+		}
+	}
+}
+
+func TestStartDropsResultsOnceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := race.Start(ctx, block, block)
+	cancel()
+
+	select {
+	case result, ok := <-ch:
+		for ok {
+			if result.Value != "late" {
+				t.Fatalf("unexpected result %+v", result)
+			}
+			result, ok = <-ch
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancellation")
+	}
+}
+
+func after(d time.Duration, value string, err error) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(d):
+			return value, err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func TestFirstReturnsFirstSuccess(t *testing.T) {
+	slowFailure := errors.New("slow failure")
+	value, err := race.First(context.Background(), time.Second,
+		after(time.Second, "slow", nil),
+		after(10*time.Millisecond, "", slowFailure),
+		after(20*time.Millisecond, "fast", nil),
+	)
+	if err != nil || value != "fast" {
+		t.Fatalf("First = (%q, %v), want fast", value, err)
+	}
+}
+
+func TestFirstJoinsErrorsWhenAllFail(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	// The second error is only returned once the race saw the first.
+	failed := make(chan struct{})
+	r := race.Race[string]{
+		Observe: func(result race.Result[string]) {
+			if result.Err == first {
+				close(failed)
+			}
+		},
+	}
+	_, err := r.First(context.Background(),
+		func(context.Context) (string, error) {
+			<-failed
+			return "", second
+		},
+		func(context.Context) (string, error) { return "", first },
+	)
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("err = %v, want both errors", err)
+	}
+	if err.Error() != "first\nsecond" {
+		t.Errorf("err = %q, want the errors in the order they failed", err)
+	}
+}
+
+func TestFirstTimesOut(t *testing.T) {
+	start := time.Now()
+	_, err := race.First(context.Background(), 20*time.Millisecond, block, block)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("First took %v to time out", elapsed)
+	}
+}
+
+func TestFirstWithoutFunctions(t *testing.T) {
+	if _, err := race.First[string](context.Background(), time.Second); !errors.Is(err, race.ErrNoFunctions) {
+		t.Fatalf("err = %v, want ErrNoFunctions", err)
+	}
+}
+
+// timer is a grace timer that fires when its channel is sent to.
+type timer chan time.Time
+
+func (t timer) C() <-chan time.Time {
+	return t
+}
+
+func (t timer) Stop() bool {
+	return true
+}
+
+// preferredRace prefers the function at index 0 and calls started with the
+// grace timer's channel once it holds another success.
+func preferredRace(started func(grace chan time.Time)) *race.Race[string] {
+	return &race.Race[string]{
+		Preferred: func(index int) bool { return index == 0 },
+		Grace:     time.Hour,
+		NewTimer: func(time.Duration) race.Timer {
+			grace := make(timer, 1)
+			started(grace)
+			return grace
+		},
+	}
+}
+
+func TestRaceWaitsForThePreferredFunction(t *testing.T) {
+	holding := make(chan struct{})
+	r := preferredRace(func(chan time.Time) { close(holding) })
+
+	value, err := r.First(context.Background(),
+		func(context.Context) (string, error) {
+			<-holding
+			return "preferred", nil
+		},
+		func(context.Context) (string, error) { return "other", nil },
+	)
+	if err != nil || value != "preferred" {
+		t.Fatalf("First = (%q, %v), want preferred", value, err)
+	}
+}
+
+func TestRaceHeldValueWins(t *testing.T) {
+	failed := errors.New("failed")
+	other := func(context.Context) (string, error) { return "other", nil }
+
+	tests := []struct {
+		name      string
+		preferred func(holding <-chan struct{}) func(context.Context) (string, error)
+		// deadline and grace fire once the value is held, when set.
+		deadline, grace bool
+		expired         bool
+	}{
+		{
+			name: "preferred fails",
+			preferred: func(holding <-chan struct{}) func(context.Context) (string, error) {
+				return func(context.Context) (string, error) {
+					<-holding
+					return "", failed
+				}
+			},
+		},
+		{
+			name:      "grace passes",
+			preferred: func(<-chan struct{}) func(context.Context) (string, error) { return block },
+			grace:     true,
+		},
+		{
+			name:      "race expires",
+			preferred: func(<-chan struct{}) func(context.Context) (string, error) { return block },
+			deadline:  true,
+			expired:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holding := make(chan struct{})
+			deadline := make(chan time.Time, 1)
+			r := preferredRace(func(grace chan time.Time) {
+				if tt.grace {
+					grace <- time.Time{}
+				}
+				if tt.deadline {
+					deadline <- time.Time{}
+				}
+				close(holding)
+			})
+			r.Deadline = deadline
+
+			value, err := r.First(context.Background(), tt.preferred(holding), other)
+			if err != nil || value != "other" {
+				t.Fatalf("First = (%q, %v), want other", value, err)
+			}
+			if r.Expired() != tt.expired {
+				t.Errorf("Expired() = %v, want %v", r.Expired(), tt.expired)
+			}
+		})
+	}
+}
+
+func TestRaceObservesEveryResultBeforeTheWinner(t *testing.T) {
+	failed := errors.New("failed")
+	// Each function returns once the race saw the one before it.
+	turns := []chan struct{}{make(chan struct{}), make(chan struct{})}
+	var observed []int
+	r := race.Race[string]{
+		Observe: func(result race.Result[string]) {
+			observed = append(observed, result.Index)
+			if result.Index < len(turns) {
+				close(turns[result.Index])
+			}
+		},
+	}
+	value, err := r.First(context.Background(),
+		func(context.Context) (string, error) { return "", failed },
+		func(context.Context) (string, error) {
+			<-turns[0]
+			return "", failed
+		},
+		func(context.Context) (string, error) {
+			<-turns[1]
+			return "won", nil
+		},
+	)
+	if err != nil || value != "won" {
+		t.Fatalf("First = (%q, %v), want won", value, err)
+	}
+	if !slices.Equal(observed, []int{0, 1, 2}) {
+		t.Errorf("observed %v, want [0 1 2]", observed)
+	}
+}
+
+func TestRaceSettleWaitsForTheLosers(t *testing.T) {
+	var returned atomic.Bool
+	loser := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		returned.Store(true)
+		return "", ctx.Err()
+	}
+
+	var r race.Race[string]
+	value, err := r.First(context.Background(), loser, func(context.Context) (string, error) { return "won", nil })
+	if err != nil || value != "won" {
+		t.Fatalf("First = (%q, %v), want won", value, err)
+	}
+
+	r.Settle()
+	if !returned.Load() {
+		t.Error("Settle returned before the loser did")
+	}
+}
+
+func TestRaceSettleStopsAtTheDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	deadline := make(chan time.Time, 1)
+	r := race.Race[string]{Deadline: deadline}
+	_, err := r.First(context.Background(),
+		func(context.Context) (string, error) {
+			<-release
+			return "", nil
+		},
+		func(context.Context) (string, error) { return "won", nil },
+	)
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+
+	deadline <- time.Time{}
+	r.Settle()
+	if !r.Expired() {
+		t.Error("Expired() = false after Settle stopped at the deadline")
+	}
+}
+
+func TestRaceAll(t *testing.T) {
+	failed := errors.New("failed")
+	results := make([]race.Result[string], 3)
+	r := race.Race[string]{
+		Observe: func(result race.Result[string]) {
+			results[result.Index] = result
+		},
+	}
+
+	err := r.All(context.Background(),
+		func(context.Context) (string, error) { return "a", nil },
+		func(context.Context) (string, error) { return "", failed },
+		func(context.Context) (string, error) { return "c", nil },
+	)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if results[0].Value != "a" || results[1].Err != failed || results[2].Value != "c" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestRaceAllExpires(t *testing.T) {
+	deadline := make(chan time.Time, 1)
+	var answered []int
+	r := race.Race[string]{
+		Deadline: deadline,
+		Observe: func(result race.Result[string]) {
+			answered = append(answered, result.Index)
+			deadline <- time.Time{}
+		},
+	}
+
+	err := r.All(context.Background(), block, func(context.Context) (string, error) { return "fast", nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if !r.Expired() {
+		t.Error("Expired() = false")
+	}
+	if !slices.Equal(answered, []int{1}) {
+		t.Errorf("answered %v, want [1]", answered)
+	}
+}